@@ -3,6 +3,7 @@ module demo.grpc
 go 1.16
 
 require (
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/fullstorydev/grpcurl v1.7.0
 	github.com/go-sql-driver/mysql v1.5.0
 	github.com/golang/glog v0.0.0-20160126235308-23def4e6c14b
@@ -23,5 +24,5 @@ require (
 	google.golang.org/genproto v0.0.0-20201116205149-79184cff4dfe // indirect
 	google.golang.org/grpc v1.38.0
 	google.golang.org/protobuf v1.26.0
-	gopkg.in/yaml.v2 v2.3.0 // indirect
+	gopkg.in/yaml.v2 v2.3.0
 )
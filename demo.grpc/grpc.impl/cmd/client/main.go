@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -27,7 +29,37 @@ func init() {
 
 // deposit grpc server: grpc.reflect/svc_bin/grpc_deposit
 
+var (
+	method   string
+	data     string
+	protoset string
+	reflect  bool
+	cacheDir string
+)
+
 func main() {
+	flag.StringVar(&method, "method", "", "Full method to call by json, i.e. /greeter.StreamGreeter/SayHelloBidiStream. Run demo calls if empty.")
+	flag.StringVar(&data, "d", "-", "File of newline-delimited json request messages, and read from stdin if \"-\".")
+	flag.StringVar(&protoset, "protoset", "", "Load descriptors from FileDescriptorSet file instead of proto files.")
+	flag.BoolVar(&reflect, "reflect", false, "Load descriptors from grpc server by reflection instead of proto files.")
+	flag.StringVar(&cacheDir, "cache-dir", "", "Cache dir of descriptors loaded by reflection, and used if server reflection is not available.")
+	flag.Parse()
+
+	var reflectTarget string
+	if reflect {
+		reflectTarget = fmt.Sprintf("%s:%s", address, port)
+	}
+	if err := application.InitProtoCoderFrom(context.Background(), protoset, reflectTarget, cacheDir); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(method) > 0 {
+		if err := callByJsonStream(method, data); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	callDepositByPb()
 	time.Sleep(time.Second)
 	callDepositByInvoke()
@@ -160,3 +192,25 @@ func callSayHelloByProto() {
 	}
 	log.Println("sayhello resp:", resp.String())
 }
+
+// callByJsonStream calls unary or streaming method with newline-delimited json request messages, i.e.
+// printf '{"name":"foo"}\n{"name":"bar"}\n' | go run cmd/client/main.go -method /greeter.StreamGreeter/SayHelloClientStream
+func callByJsonStream(method, path string) error {
+	log.Println(strings.Repeat("*", 10), "call grpc api by json stream:", method)
+	var reqs io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		reqs = f
+	}
+
+	target := fmt.Sprintf("%s:%s", address, port)
+	coder := application.GetProtoCoder()
+	return application.GrpcStreamCallWithJson(context.Background(), coder, target, method, reqs, func(resp []byte) error {
+		fmt.Println(string(resp))
+		return nil
+	})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"demo.grpc/grpc.impl/pkg/application"
+	"demo.grpc/grpc.impl/pkg/proxy"
+)
+
+/*
+Record:
+go run cmd/proxy/main.go -upstream localhost:50051 -reflect -record records.jsonl
+
+Replay (records => mock rules, and served by mock server):
+go run cmd/proxy/main.go -record records.jsonl -to-mock mocks/recorded.json
+*/
+
+var (
+	port       string
+	upstream   string
+	recordPath string
+	mockPath   string
+	protoset   string
+	reflect    bool
+	cacheDir   string
+)
+
+func main() {
+	flag.StringVar(&port, "port", "50052", "Listen port of proxy.")
+	flag.StringVar(&upstream, "upstream", "", "Upstream grpc server (host:port) which calls are forwarded to.")
+	flag.StringVar(&recordPath, "record", "", "Json lines file which calls are recorded to. Skip record if empty.")
+	flag.StringVar(&mockPath, "to-mock", "", "Convert records to mock rules file, and exit.")
+	flag.StringVar(&protoset, "protoset", "", "Load descriptors from FileDescriptorSet file instead of proto files.")
+	flag.BoolVar(&reflect, "reflect", false, "Load descriptors from upstream by reflection instead of proto files.")
+	flag.StringVar(&cacheDir, "cache-dir", "", "Cache dir of descriptors loaded by reflection, and used if upstream reflection is not available.")
+	flag.Parse()
+
+	if len(mockPath) > 0 {
+		records, err := proxy.LoadRecords(recordPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := proxy.WriteMockRules(mockPath, records); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("write %d records as mock rules to: %s", len(records), mockPath)
+		return
+	}
+
+	if len(upstream) == 0 {
+		flag.Usage()
+		log.Fatal("flag -upstream is required")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var reflectTarget string
+	if reflect {
+		reflectTarget = upstream
+	}
+	if err := application.InitProtoCoderFrom(ctx, protoset, reflectTarget, cacheDir); err != nil {
+		log.Fatal(err)
+	}
+	application.GetProtoCoder()
+
+	if err := application.RunGrpcProxy(ctx, port, upstream, recordPath); err != nil {
+		log.Fatal(err)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	<-sig
+	log.Println("grpc proxy exit")
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
@@ -10,15 +12,35 @@ import (
 	"demo.grpc/grpc.impl/pkg/application"
 )
 
+var (
+	port          string
+	mockDir       string
+	protoset      string
+	reflectTarget string
+	cacheDir      string
+)
+
 func init() {
 	subPath := "Workspaces/zj_repos/zj_new_go_project/demo.grpc"
 	os.Setenv("PROJECT_ROOT", filepath.Join(os.Getenv("HOME"), subPath))
-	application.GetProtoCoder()
 }
 
 func main() {
-	port := "50051"
-	if err := application.RunGrpcServer(port); err != nil {
+	flag.StringVar(&port, "port", "50051", "Listen port of mock server.")
+	flag.StringVar(&mockDir, "mock-dir", filepath.Join(os.Getenv("PROJECT_ROOT"), "grpc.impl/mocks"), "Dir of mock rule files.")
+	flag.StringVar(&protoset, "protoset", "", "Load descriptors from FileDescriptorSet file instead of proto files.")
+	flag.StringVar(&reflectTarget, "reflect-target", "", "Load descriptors from grpc server (host:port) by reflection instead of proto files.")
+	flag.StringVar(&cacheDir, "cache-dir", "", "Cache dir of descriptors loaded by reflection, and used if reflect target is not available.")
+	flag.Parse()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := application.InitProtoCoderFrom(ctx, protoset, reflectTarget, cacheDir); err != nil {
+		log.Fatal(err)
+	}
+	application.GetProtoCoder()
+
+	if err := application.RunGrpcServer(ctx, port, mockDir); err != nil {
 		log.Fatal(err)
 	}
 
@@ -1,12 +1,18 @@
 package application
 
 import (
+	"bufio"
 	"context"
+	"fmt"
+	"io"
 	"log"
+	"strings"
 	"time"
 
 	"demo.grpc/grpc.impl/pkg/codec"
 	"demo.grpc/grpc.impl/pkg/interceptor"
+	"demo.grpc/grpc.impl/pkg/protoc"
+	"github.com/golang/protobuf/jsonpb"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
@@ -32,6 +38,121 @@ func GrpcCallWithJson(ctx context.Context, target, fullMethod string, req []byte
 	return resp.RawData, err
 }
 
+// GrpcStreamCallWithJson calls rpc (unary or streaming) by method descriptor loaded in coder. Request messages are
+// read from newline-delimited json, and handler is called with json of each response message.
+func GrpcStreamCallWithJson(ctx context.Context, coder protoc.Coder, target, fullMethod string, reqs io.Reader, handler func(resp []byte) error, opts ...grpc.CallOption) error {
+	methodDesc, err := coder.GetMethodDescriptor(fullMethod)
+	if err != nil {
+		return err
+	}
+	conn, err := getGrpcClientConn(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	md := metadata.MD{}
+	md.Set("msg", "grpc.app client")
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(ctx, md))
+	defer cancel()
+
+	streamDesc := &grpc.StreamDesc{
+		StreamName:    methodDesc.GetName(),
+		ClientStreams: methodDesc.IsClientStreaming(),
+		ServerStreams: methodDesc.IsServerStreaming(),
+	}
+	stream, err := conn.NewStream(ctx, streamDesc, fullMethod, opts...)
+	if err != nil {
+		return err
+	}
+
+	sendErr := make(chan error, 1)
+	go func() {
+		err := sendJsonMessages(stream, coder, fullMethod, reqs, streamDesc.ClientStreams)
+		sendErr <- err
+		if err != nil {
+			// cancel stream to stop receiving
+			cancel()
+		}
+	}()
+
+	marshaler := jsonpb.Marshaler{OrigName: true}
+	for {
+		resp, err := coder.NewRespProtoMessage(fullMethod)
+		if err != nil {
+			return err
+		}
+		if err := stream.RecvMsg(resp); err != nil {
+			if err == io.EOF {
+				break
+			}
+			select {
+			case e := <-sendErr:
+				if e != nil {
+					return e
+				}
+			default:
+			}
+			return err
+		}
+		b, err := marshaler.MarshalToString(resp)
+		if err != nil {
+			return fmt.Errorf("marshal resp proto msg error: %v", err)
+		}
+		if err := handler([]byte(b)); err != nil {
+			return err
+		}
+		if !streamDesc.ServerStreams {
+			break
+		}
+	}
+	return <-sendErr
+}
+
+// sendJsonMessages sends request messages from newline-delimited json, and empty json is sent for non client
+// streaming method if no message.
+func sendJsonMessages(stream grpc.ClientStream, coder protoc.Coder, fullMethod string, reqs io.Reader, clientStreams bool) error {
+	scanner := bufio.NewScanner(reqs)
+	scanner.Buffer(make([]byte, 64*1024), 8*1024*1024)
+	count := 0
+	for scanner.Scan() {
+		body := strings.TrimSpace(scanner.Text())
+		if len(body) == 0 {
+			continue
+		}
+		if count > 0 && !clientStreams {
+			return fmt.Errorf("only one request message is allowed for non client streaming method: %s", fullMethod)
+		}
+		if err := sendJsonMessage(stream, coder, fullMethod, body); err != nil {
+			return err
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read request messages error: %v", err)
+	}
+	if count == 0 && !clientStreams {
+		if err := sendJsonMessage(stream, coder, fullMethod, "{}"); err != nil {
+			return err
+		}
+	}
+	return stream.CloseSend()
+}
+
+func sendJsonMessage(stream grpc.ClientStream, coder protoc.Coder, fullMethod, body string) error {
+	req, err := coder.BuildReqProtoMessage(fullMethod, body)
+	if err != nil {
+		return fmt.Errorf("build req proto msg error: %v", err)
+	}
+	if err := stream.SendMsg(req); err != nil {
+		// io.EOF is returned if stream is closed by server, and the status is returned by RecvMsg
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
 func GrpcCall(ctx context.Context, target, fullMethod string, req interface{}, resp interface{}, opts ...grpc.CallOption) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
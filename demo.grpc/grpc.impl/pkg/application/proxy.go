@@ -0,0 +1,195 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+
+	"demo.grpc/grpc.impl/pkg/interceptor"
+	"demo.grpc/grpc.impl/pkg/protoc"
+	"demo.grpc/grpc.impl/pkg/proxy"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RunGrpcProxy runs grpc proxy which forwards all calls to upstream, and calls are recorded to recordPath (json
+// lines) if it's not empty.
+func RunGrpcProxy(ctx context.Context, port, upstream, recordPath string) error {
+	conn, err := createGrpcClientConn(ctx, upstream)
+	if err != nil {
+		return fmt.Errorf("connect to upstream [%s] error: %v", upstream, err)
+	}
+
+	var recorder *proxy.Recorder
+	if len(recordPath) > 0 {
+		if recorder, err = proxy.NewRecorder(recordPath); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	interceptors := []grpc.StreamServerInterceptor{
+		interceptor.RecoverStreamServerInterceptor(),
+		interceptor.LoggingStreamServerInterceptor(),
+	}
+	grpcServer := grpc.NewServer(
+		grpc.MaxRecvMsgSize(8*1024*1024),
+		grpc.UnknownServiceHandler(newProxySsHandler(conn, recorder)),
+		grpc_middleware.WithStreamServerChain(interceptors...),
+	)
+	go func() {
+		log.Printf("grpc proxy listen at: %s, upstream: %s", port, upstream)
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Println("grpc proxy error:", err)
+		}
+		grpcServer.GracefulStop()
+		conn.Close()
+		if recorder != nil {
+			recorder.Close()
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		grpcServer.Stop()
+	}()
+	return nil
+}
+
+// headers which are set by grpc, and not forwarded to upstream
+var reservedHeaders = map[string]struct{}{
+	":authority":   {},
+	"content-type": {},
+	"user-agent":   {},
+}
+
+func newProxySsHandler(upstream *grpc.ClientConn, recorder *proxy.Recorder) grpc.StreamHandler {
+	return func(srv interface{}, serverStream grpc.ServerStream) error {
+		method, ok := grpc.MethodFromServerStream(serverStream)
+		if !ok {
+			return fmt.Errorf("get method from server stream failed")
+		}
+		log.Println(strings.Repeat("*", 10), "proxy:", method)
+
+		coder := GetProtoCoder()
+		methodDesc, err := coder.GetMethodDescriptor(method)
+		if err != nil {
+			return status.Errorf(codes.Unimplemented, "%v", err)
+		}
+
+		md, _ := metadata.FromIncomingContext(serverStream.Context())
+		outMd := metadata.MD{}
+		for k, v := range md {
+			if _, ok := reservedHeaders[k]; !ok {
+				outMd[k] = v
+			}
+		}
+		ctx, cancel := context.WithCancel(serverStream.Context())
+		defer cancel()
+
+		streamDesc := &grpc.StreamDesc{
+			StreamName:    methodDesc.GetName(),
+			ClientStreams: methodDesc.IsClientStreaming(),
+			ServerStreams: methodDesc.IsServerStreaming(),
+		}
+		clientStream, err := upstream.NewStream(metadata.NewOutgoingContext(ctx, outMd), streamDesc, method)
+		if err != nil {
+			return err
+		}
+
+		record := proxy.NewRecord(method, outMd)
+		err = forwardStream(serverStream, clientStream, coder, method, record, cancel)
+		if recorder != nil {
+			record.Finish(getHeader(clientStream), clientStream.Trailer(), status.Convert(err))
+			if err := recorder.Save(record); err != nil {
+				log.Println("save record error:", err)
+			}
+		}
+		return err
+	}
+}
+
+// forwardStream forwards request messages from client to upstream, and response messages, header and trailer
+// from upstream to client. It returns status error of upstream.
+func forwardStream(serverStream grpc.ServerStream, clientStream grpc.ClientStream, coder protoc.Coder, method string,
+	record *proxy.Record, cancel context.CancelFunc) error {
+	marshaler := jsonpb.Marshaler{OrigName: true, EmitDefaults: true}
+	toJson := func(msg proto.Message) []byte {
+		body, err := marshaler.MarshalToString(msg)
+		if err != nil {
+			log.Println("marshal proto msg error:", err)
+		}
+		return []byte(body)
+	}
+
+	// client => upstream
+	go func() {
+		for {
+			req, err := coder.NewReqProtoMessage(method)
+			if err != nil {
+				log.Println("new req proto msg error:", err)
+				cancel()
+				return
+			}
+			if err := serverStream.RecvMsg(req); err != nil {
+				if err == io.EOF {
+					clientStream.CloseSend()
+				} else {
+					cancel()
+				}
+				return
+			}
+			record.AddRequest(toJson(req))
+			if err := clientStream.SendMsg(req); err != nil {
+				// status is returned by RecvMsg of upstream
+				return
+			}
+		}
+	}()
+
+	// upstream => client
+	header, err := clientStream.Header()
+	if err == nil && len(header) > 0 {
+		if err := serverStream.SendHeader(header); err != nil {
+			return err
+		}
+	}
+	for {
+		resp, err := coder.NewRespProtoMessage(method)
+		if err != nil {
+			return err
+		}
+		if err := clientStream.RecvMsg(resp); err != nil {
+			serverStream.SetTrailer(clientStream.Trailer())
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		record.AddResponse(toJson(resp))
+		if err := serverStream.SendMsg(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func getHeader(clientStream grpc.ClientStream) metadata.MD {
+	header, err := clientStream.Header()
+	if err != nil {
+		return nil
+	}
+	return header
+}
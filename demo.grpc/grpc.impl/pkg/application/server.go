@@ -1,35 +1,45 @@
 package application
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"demo.grpc/grpc.impl/pkg/interceptor"
+	"demo.grpc/grpc.impl/pkg/mock"
 	"demo.grpc/grpc.impl/pkg/protoc"
+	"github.com/golang/protobuf/jsonpb"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/jhump/protoreflect/desc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-var mockData = map[string]string{
-	"/account.DepositService/Deposit":       `{"ok": true}`,
-	"/account.DepositService/CreateAccount": `{"return_code": 900100}`,
-	"/greeter.Greeter/SayHello":             `{"content": "from grpc.app"}`,
-}
+// RunGrpcServer runs mock grpc server, and mock rules are loaded from mockDir and reloaded when files changed.
+func RunGrpcServer(ctx context.Context, port, mockDir string) error {
+	store, err := mock.NewRuleStore(mockDir)
+	if err != nil {
+		return err
+	}
+	if err := store.Watch(ctx); err != nil {
+		return err
+	}
 
-func RunGrpcServer(port string) error {
 	lis, err := net.Listen("tcp", ":"+port)
 	if err != nil {
 		return err
 	}
 
-	grpcServer := newGrpcServer()
+	grpcServer := newGrpcServer(store)
 	go func() {
 		log.Println("grpc server listen at:", port)
 		if err := grpcServer.Serve(lis); err != nil {
@@ -40,7 +50,7 @@ func RunGrpcServer(port string) error {
 	return nil
 }
 
-func newGrpcServer() *grpc.Server {
+func newGrpcServer(store *mock.RuleStore) *grpc.Server {
 	interceptors := []grpc.StreamServerInterceptor{
 		interceptor.RecoverStreamServerInterceptor(),
 		interceptor.LoggingStreamServerInterceptor(),
@@ -50,13 +60,19 @@ func newGrpcServer() *grpc.Server {
 		// use encoding.RegisterCodec instead
 		// grpc.CustomCodec(&codec.ProtoJson{}),
 		grpc.MaxRecvMsgSize(8 * 1024 * 1024),
-		grpc.UnknownServiceHandler(unKnownSsHandler),
+		grpc.UnknownServiceHandler(newUnknownSsHandler(store)),
 		grpc_middleware.WithStreamServerChain(interceptors...),
 	}
 	return grpc.NewServer(serverOpts...)
 }
 
-func unKnownSsHandler(srv interface{}, serverStream grpc.ServerStream) error {
+func newUnknownSsHandler(store *mock.RuleStore) grpc.StreamHandler {
+	return func(srv interface{}, serverStream grpc.ServerStream) error {
+		return unKnownSsHandler(store, serverStream)
+	}
+}
+
+func unKnownSsHandler(store *mock.RuleStore, serverStream grpc.ServerStream) error {
 	method, ok := grpc.MethodFromServerStream(serverStream)
 	if !ok {
 		return fmt.Errorf("get method from server stream failed")
@@ -72,28 +88,133 @@ func unKnownSsHandler(srv interface{}, serverStream grpc.ServerStream) error {
 		log.Printf("metadata: key=%v,value=%v", k, v)
 	}
 
-	// handle request
 	coder := GetProtoCoder()
+	methodDesc, err := coder.GetMethodDescriptor(method)
+	if err != nil {
+		return status.Errorf(codes.Unimplemented, "%v", err)
+	}
+
+	// bidi streaming: reply for each request message
+	if methodDesc.IsClientStreaming() && methodDesc.IsServerStreaming() {
+		for {
+			body, err := recvJsonMessage(serverStream, coder, method)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			mockReq, err := mock.NewRequest(method, md, body)
+			if err != nil {
+				return err
+			}
+			if err := replyMockRequest(store, serverStream, coder, mockReq, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	// handle request, and receive all request messages for client streaming
+	bodies := make([][]byte, 0, 1)
+	for {
+		body, err := recvJsonMessage(serverStream, coder, method)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		bodies = append(bodies, body)
+		if !methodDesc.IsClientStreaming() {
+			break
+		}
+	}
+	if len(bodies) == 0 {
+		return status.Errorf(codes.InvalidArgument, "no request message received for: %s", method)
+	}
+	mockReq, err := mock.NewStreamRequest(method, md, bodies)
+	if err != nil {
+		return err
+	}
+	return replyMockRequest(store, serverStream, coder, mockReq, methodDesc.IsServerStreaming())
+}
+
+// recvJsonMessage receives request message from stream, and returns json body of it.
+func recvJsonMessage(serverStream grpc.ServerStream, coder protoc.Coder, method string) ([]byte, error) {
 	req, err := coder.NewReqProtoMessage(method)
 	if err != nil {
-		return fmt.Errorf("new req proto msg error: %v", err)
+		return nil, fmt.Errorf("new req proto msg error: %v", err)
 	}
 	if err := serverStream.RecvMsg(req); err != nil {
-		return err
+		return nil, err
 	}
 	log.Println("receive msg:", req.String())
 
-	// handle response
-	respBody, ok := mockData[method] // mock 逻辑
-	if !ok {
-		return fmt.Errorf("no matched resp found")
+	marshaler := jsonpb.Marshaler{OrigName: true, EmitDefaults: true}
+	body, err := marshaler.MarshalToString(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal req proto msg error: %v", err)
 	}
-	resp, err := coder.BuildRespProtoMessage(method, respBody)
+	return []byte(body), nil
+}
+
+// replyMockRequest matches mock rule for request, and sends rendered response messages and status.
+func replyMockRequest(store *mock.RuleStore, serverStream grpc.ServerStream, coder protoc.Coder, mockReq *mock.Request, serverStreaming bool) error {
+	rule, err := store.Match(mockReq)
 	if err != nil {
-		return fmt.Errorf("build resp proto msg error: %v", err)
+		return status.Errorf(codes.Internal, "match mock rule error: %v", err)
+	}
+	if rule == nil {
+		return status.Errorf(codes.Unimplemented, "no mock rule matched for: %s", mockReq.Method)
+	}
+	log.Println("matched mock rule:", rule.Key)
+
+	mockResp, err := rule.Render(mockReq)
+	if err != nil {
+		return status.Errorf(codes.Internal, "render mock rule error: %v", err)
+	}
+	if !serverStreaming && mockResp.Status == nil && len(mockResp.Messages) != 1 {
+		return status.Errorf(codes.Internal, "mock rule [%s] should reply one message for non server streaming method", rule.Key)
+	}
+	if err := sleepWithContext(serverStream.Context(), mockResp.Delay); err != nil {
+		return err
+	}
+
+	// handle response
+	for _, msg := range mockResp.Messages {
+		if !serverStreaming && mockResp.Status != nil {
+			// status error is returned without message for non server streaming method
+			break
+		}
+		if err := sleepWithContext(serverStream.Context(), msg.Delay); err != nil {
+			return err
+		}
+		resp, err := coder.BuildRespProtoMessage(mockReq.Method, msg.Body)
+		if err != nil {
+			return fmt.Errorf("build resp proto msg error: %v", err)
+		}
+		log.Println("send msg:", resp.String())
+		if err := serverStream.SendMsg(resp); err != nil {
+			return err
+		}
+	}
+	if mockResp.Status != nil {
+		log.Println("send status:", mockResp.Status.Code(), mockResp.Status.Message())
+		return mockResp.Status.Err()
+	}
+	return nil
+}
+
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	log.Println("send msg:", resp.String())
-	return serverStream.SendMsg(resp)
 }
 
 // ProtoCoder
@@ -103,6 +224,46 @@ var (
 	protoCoderOnce sync.Once
 )
 
+// InitProtoCoder sets coder (i.e. created by reflection or descriptor set file) instead of loading from proto
+// files, and it should be called before GetProtoCoder.
+func InitProtoCoder(coder protoc.Coder) {
+	protoCoderOnce.Do(func() {
+		protoCoder = coder
+	})
+}
+
+// InitProtoCoderFrom inits coder from descriptor set file, or by reflection of target (descriptors are cached in
+// cacheDir), and coder is loaded from proto files if both are empty.
+func InitProtoCoderFrom(ctx context.Context, protoset, reflectTarget, cacheDir string) error {
+	if len(protoset) > 0 {
+		coder, err := protoc.NewCoderFromDescriptorSet(protoset)
+		if err != nil {
+			return err
+		}
+		InitProtoCoder(coder)
+		return nil
+	}
+	if len(reflectTarget) == 0 {
+		return nil
+	}
+
+	var cc grpc.ClientConnInterface
+	conn, err := createGrpcClientConn(ctx, reflectTarget)
+	if err != nil {
+		log.Printf("connect to [%s] error: %v", reflectTarget, err)
+	} else {
+		defer conn.Close()
+		cc = conn
+	}
+	coder, err := protoc.NewDescriptorCache(cacheDir).GetCoder(ctx, reflectTarget, cc, false)
+	if err != nil {
+		return err
+	}
+	InitProtoCoder(coder)
+	return nil
+}
+
+// GetProtoCoder returns coder which is set by InitProtoCoder, or loaded from proto files by default.
 func GetProtoCoder() protoc.Coder {
 	protoCoderOnce.Do(func() {
 		mds, err := loadProto()
@@ -0,0 +1,474 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v2"
+)
+
+/*
+Mock rule definition (yaml or json file), rules are matched in order of file name and definition.
+
+- key: greeter_hello_foo
+  expect:
+    grpc:
+      service: greeter.Greeter
+      method: SayHello
+    metadata:
+      x-env: test
+    fields:
+      name: foo
+    condition: '{{ .Request | jsonPath "name" | eq "foo" }}'
+  reply_grpc:
+    payload: '{"content": "hello {{ .Request.name }}"}'
+    payload_from_file: './files/hello.json'
+    code: NotFound
+    message: 'user {{ .Request.name }} not found'
+    delay: 100ms
+
+Reply of streaming method (a sequence of messages with per-message delay, and status is returned at last):
+
+  reply_grpc:
+    stream:
+      - payload: '{"content": "hello {{ .Request.name }}"}'
+      - payload_from_file: './files/hello.json'
+        delay: 200ms
+*/
+
+// Rule mock rule, returns reply if request matches expect.
+type Rule struct {
+	Key    string `yaml:"key" json:"key"`
+	Expect Expect `yaml:"expect" json:"expect"`
+	Reply  Reply  `yaml:"reply_grpc" json:"reply_grpc"`
+
+	file      string
+	condition *template.Template
+	messages  []replyMessage
+	message   *template.Template
+	code      codes.Code
+	delay     time.Duration
+}
+
+type replyMessage struct {
+	payload *template.Template
+	delay   time.Duration
+}
+
+// Expect matches request by method, metadata, request fields and condition.
+type Expect struct {
+	Grpc GrpcExpect `yaml:"grpc" json:"grpc"`
+	// Metadata metadata key => value, matched if any value of key equals
+	Metadata map[string]string `yaml:"metadata" json:"metadata,omitempty"`
+	// Fields json path (i.e. "user.name", "items.0.id") => value of request
+	Fields map[string]string `yaml:"fields" json:"fields,omitempty"`
+	// Condition template which is matched if it renders "true"
+	Condition string `yaml:"condition" json:"condition,omitempty"`
+}
+
+// GrpcExpect full method is "/{service}/{method}", and empty service or method matches any.
+type GrpcExpect struct {
+	Service string `yaml:"service" json:"service,omitempty"`
+	Method  string `yaml:"method" json:"method,omitempty"`
+}
+
+// Reply templated response body (or stream messages), status and delay.
+type Reply struct {
+	Payload         string `yaml:"payload" json:"payload,omitempty"`
+	PayloadFromFile string `yaml:"payload_from_file" json:"payload_from_file,omitempty"`
+	// Stream response messages of server (or bidi) streaming method
+	Stream []StreamReply `yaml:"stream" json:"stream,omitempty"`
+	// Code grpc status code name (i.e. "NotFound") or number, and default "OK"
+	Code    string `yaml:"code" json:"code,omitempty"`
+	Message string `yaml:"message" json:"message,omitempty"`
+	Delay   string `yaml:"delay" json:"delay,omitempty"`
+}
+
+// StreamReply templated response message in stream, and it's sent after delay.
+type StreamReply struct {
+	Payload         string `yaml:"payload" json:"payload,omitempty"`
+	PayloadFromFile string `yaml:"payload_from_file" json:"payload_from_file,omitempty"`
+	Delay           string `yaml:"delay" json:"delay,omitempty"`
+}
+
+// Request mock request of grpc method.
+type Request struct {
+	Method   string
+	Metadata metadata.MD
+	// Body json body of request message, and it's the last message for client streaming method
+	Body map[string]interface{}
+	// Stream json bodies of all request messages for client streaming method
+	Stream []map[string]interface{}
+}
+
+// NewRequest creates mock request by json body of request message.
+func NewRequest(method string, md metadata.MD, body []byte) (*Request, error) {
+	return NewStreamRequest(method, md, [][]byte{body})
+}
+
+// NewStreamRequest creates mock request by json bodies of request messages in stream.
+func NewStreamRequest(method string, md metadata.MD, bodies [][]byte) (*Request, error) {
+	req := &Request{
+		Method:   method,
+		Metadata: md,
+		Body:     make(map[string]interface{}),
+		Stream:   make([]map[string]interface{}, 0, len(bodies)),
+	}
+	for _, body := range bodies {
+		msg := make(map[string]interface{})
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, &msg); err != nil {
+				return nil, fmt.Errorf("unmarshal request body error: %w", err)
+			}
+		}
+		req.Stream = append(req.Stream, msg)
+		req.Body = msg
+	}
+	return req, nil
+}
+
+// Response mock response rendered by rule.
+type Response struct {
+	// Messages response messages, and there's only one message for non server streaming method
+	Messages []Message
+	// Status is nil for OK, and it's returned after messages are sent
+	Status *status.Status
+	Delay  time.Duration
+}
+
+// Message response message which is sent after delay.
+type Message struct {
+	Body  string
+	Delay time.Duration
+}
+
+// templateData data used in rule templates.
+type templateData struct {
+	Method   string
+	Metadata metadata.MD
+	Request  map[string]interface{}
+	Stream   []map[string]interface{}
+}
+
+// MD returns the first value of metadata key.
+func (d templateData) MD(key string) string {
+	if values := d.Metadata.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+var templateFuncs = template.FuncMap{
+	"jsonPath": jsonPath,
+	"atof": func(s string) (float64, error) {
+		return strconv.ParseFloat(s, 64)
+	},
+	"toJson": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// LoadRules loads rules from yaml (.yaml, .yml) and json files in dir.
+func LoadRules(dir string) ([]*Rule, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]*Rule, 0, len(entries))
+	keys := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !isRuleFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		fileRules, err := loadRuleFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("load rule file [%s] error: %w", path, err)
+		}
+		for _, rule := range fileRules {
+			if file, ok := keys[rule.Key]; ok {
+				return nil, fmt.Errorf("duplicated rule key [%s] in files: %s, %s", rule.Key, file, path)
+			}
+			keys[rule.Key] = path
+		}
+		rules = append(rules, fileRules...)
+	}
+	return rules, nil
+}
+
+func isRuleFile(name string) bool {
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func loadRuleFile(path string) ([]*Rule, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// json is valid yaml
+	rules := make([]*Rule, 0)
+	if err := yaml.Unmarshal(b, &rules); err != nil {
+		return nil, err
+	}
+	for i, rule := range rules {
+		if len(rule.Key) == 0 {
+			rule.Key = fmt.Sprintf("%s#%d", filepath.Base(path), i)
+		}
+		rule.file = path
+		if err := rule.init(); err != nil {
+			return nil, fmt.Errorf("invalid rule [%s]: %w", rule.Key, err)
+		}
+	}
+	return rules, nil
+}
+
+// init parses templates, status code and delay of rule.
+func (r *Rule) init() (err error) {
+	if len(r.Expect.Condition) > 0 {
+		if r.condition, err = newTemplate("condition", r.Expect.Condition); err != nil {
+			return err
+		}
+	}
+
+	hasPayload := len(r.Reply.Payload) > 0 || len(r.Reply.PayloadFromFile) > 0
+	if hasPayload && len(r.Reply.Stream) > 0 {
+		return fmt.Errorf("payload and stream are exclusive")
+	}
+	if hasPayload {
+		msg, err := r.newReplyMessage(StreamReply{Payload: r.Reply.Payload, PayloadFromFile: r.Reply.PayloadFromFile})
+		if err != nil {
+			return err
+		}
+		r.messages = []replyMessage{msg}
+	}
+	for i, reply := range r.Reply.Stream {
+		msg, err := r.newReplyMessage(reply)
+		if err != nil {
+			return fmt.Errorf("invalid stream message [%d]: %w", i, err)
+		}
+		r.messages = append(r.messages, msg)
+	}
+	if len(r.Reply.Message) > 0 {
+		if r.message, err = newTemplate("message", r.Reply.Message); err != nil {
+			return err
+		}
+	}
+
+	if r.code, err = parseCode(r.Reply.Code); err != nil {
+		return err
+	}
+	if len(r.Reply.Delay) > 0 {
+		if r.delay, err = time.ParseDuration(r.Reply.Delay); err != nil {
+			return fmt.Errorf("invalid delay: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) newReplyMessage(reply StreamReply) (msg replyMessage, err error) {
+	payload := reply.Payload
+	if len(reply.PayloadFromFile) > 0 {
+		path := reply.PayloadFromFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(r.file), path)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return msg, fmt.Errorf("read payload file error: %w", err)
+		}
+		payload = string(b)
+	}
+	if len(payload) == 0 {
+		return msg, fmt.Errorf("empty payload")
+	}
+	if msg.payload, err = newTemplate("payload", payload); err != nil {
+		return msg, err
+	}
+	if len(reply.Delay) > 0 {
+		if msg.delay, err = time.ParseDuration(reply.Delay); err != nil {
+			return msg, fmt.Errorf("invalid delay: %w", err)
+		}
+	}
+	return msg, nil
+}
+
+func newTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s template error: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// parseCode parses grpc status code by name (case insensitive, i.e. "NotFound", "not_found") or number.
+func parseCode(value string) (codes.Code, error) {
+	if len(value) == 0 {
+		return codes.OK, nil
+	}
+	if num, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return codes.Code(num), nil
+	}
+	name := strings.ReplaceAll(value, "_", "")
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if strings.EqualFold(c.String(), name) {
+			return c, nil
+		}
+	}
+	return codes.Unknown, fmt.Errorf("invalid status code: %s", value)
+}
+
+// Match returns true if request matches expect of rule.
+func (r *Rule) Match(req *Request) (bool, error) {
+	if !r.matchMethod(req.Method) {
+		return false, nil
+	}
+	for key, value := range r.Expect.Metadata {
+		if !containsString(req.Metadata.Get(key), value) {
+			return false, nil
+		}
+	}
+	for path, value := range r.Expect.Fields {
+		field, ok := jsonPathLookup(req.Body, path)
+		if !ok || formatValue(field) != value {
+			return false, nil
+		}
+	}
+
+	if r.condition != nil {
+		result, err := r.execute(r.condition, req)
+		if err != nil {
+			return false, err
+		}
+		return strings.TrimSpace(result) == "true", nil
+	}
+	return true, nil
+}
+
+func (r *Rule) matchMethod(fullMethod string) bool {
+	items := strings.Split(strings.TrimPrefix(fullMethod, "/"), "/")
+	if len(items) != 2 {
+		return false
+	}
+	service, method := items[0], items[1]
+	return (len(r.Expect.Grpc.Service) == 0 || r.Expect.Grpc.Service == service) &&
+		(len(r.Expect.Grpc.Method) == 0 || r.Expect.Grpc.Method == method)
+}
+
+// Render renders reply messages and status of rule for request.
+func (r *Rule) Render(req *Request) (*Response, error) {
+	resp := &Response{
+		Messages: make([]Message, 0, len(r.messages)),
+		Delay:    r.delay,
+	}
+	for _, msg := range r.messages {
+		body, err := r.execute(msg.payload, req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Messages = append(resp.Messages, Message{Body: body, Delay: msg.delay})
+	}
+
+	if r.code != codes.OK {
+		var msg string
+		if r.message != nil {
+			var err error
+			if msg, err = r.execute(r.message, req); err != nil {
+				return nil, err
+			}
+		}
+		resp.Status = status.New(r.code, msg)
+	}
+	return resp, nil
+}
+
+func (r *Rule) execute(tmpl *template.Template, req *Request) (string, error) {
+	data := templateData{
+		Method:   req.Method,
+		Metadata: req.Metadata,
+		Request:  req.Body,
+		Stream:   req.Stream,
+	}
+	buf := bytes.Buffer{}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("execute %s template of rule [%s] error: %w", tmpl.Name(), r.Key, err)
+	}
+	return buf.String(), nil
+}
+
+//
+// Helper
+//
+
+// jsonPath returns formatted value of path in v, and empty if not found. It's used in template as:
+// {{ .Request | jsonPath "user.name" }}
+func jsonPath(path string, v interface{}) string {
+	if value, ok := jsonPathLookup(v, path); ok {
+		return formatValue(value)
+	}
+	return ""
+}
+
+// jsonPathLookup gets value by path like "user.name" or "items.0.id" from unmarshalled json.
+func jsonPathLookup(v interface{}, path string) (interface{}, bool) {
+	cur := v
+	for _, key := range strings.Split(path, ".") {
+		switch value := cur.(type) {
+		case map[string]interface{}:
+			field, ok := value[key]
+			if !ok {
+				return nil, false
+			}
+			cur = field
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(value) {
+				return nil, false
+			}
+			cur = value[idx]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func formatValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(value)
+		return string(b)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package mock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestParseCode(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		code  codes.Code
+	}{
+		{"", codes.OK},
+		{"NotFound", codes.NotFound},
+		{"not_found", codes.NotFound},
+		{"INVALID_ARGUMENT", codes.InvalidArgument},
+		{"14", codes.Unavailable},
+	} {
+		code, err := parseCode(tc.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tc.code {
+			t.Errorf("parse code [%s]: want %v, got %v", tc.value, tc.code, code)
+		}
+	}
+
+	if _, err := parseCode("not_exist"); err == nil {
+		t.Error("want error for invalid code")
+	}
+}
+
+func TestJsonPath(t *testing.T) {
+	req, err := NewRequest("/greeter.Greeter/SayHello", nil, []byte(`{"user":{"name":"foo","age":18},"items":[{"id":"1"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"user.name":  "foo",
+		"user.age":   "18",
+		"items.0.id": "1",
+		"items.1.id": "",
+		"user.email": "",
+	} {
+		if got := jsonPath(path, req.Body); got != want {
+			t.Errorf("json path [%s]: want %s, got %s", path, want, got)
+		}
+	}
+}
+
+func TestRuleStoreMatch(t *testing.T) {
+	store, err := NewRuleStore("../../mocks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		method string
+		md     metadata.MD
+		body   string
+		key    string
+		resp   string
+		code   codes.Code
+	}{
+		{
+			method: "/greeter.Greeter/SayHello",
+			md:     metadata.Pairs("x-env", "test"),
+			body:   `{"name":"foo"}`,
+			key:    "greeter_hello_test_env",
+			resp:   `{"content": "hello foo, env=test"}`,
+		},
+		{
+			method: "/greeter.Greeter/SayHello",
+			body:   `{"name":"unknown"}`,
+			key:    "greeter_hello_unknown",
+			code:   codes.NotFound,
+		},
+		{
+			method: "/greeter.Greeter/SayHello",
+			body:   `{"name":"bar"}`,
+			key:    "greeter_hello",
+			resp:   `{"content": "from grpc.app"}`,
+		},
+		{
+			method: "/account.DepositService/Deposit",
+			body:   `{"amount":-10}`,
+			key:    "account_deposit_invalid_amount",
+			code:   codes.InvalidArgument,
+		},
+		{
+			method: "/account.DepositService/Deposit",
+			body:   `{"amount":10}`,
+			key:    "account_deposit",
+			resp:   `{"ok": true}`,
+		},
+	} {
+		req, err := NewRequest(tc.method, tc.md, []byte(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rule, err := store.Match(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rule == nil || rule.Key != tc.key {
+			t.Fatalf("match %s %s: want rule %s, got %v", tc.method, tc.body, tc.key, rule)
+		}
+
+		resp, err := rule.Render(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.code != codes.OK {
+			if resp.Status == nil || resp.Status.Code() != tc.code {
+				t.Errorf("rule %s: want status %v, got %v", tc.key, tc.code, resp.Status)
+			}
+			continue
+		}
+		if len(resp.Messages) != 1 || strings.TrimSpace(resp.Messages[0].Body) != tc.resp {
+			t.Errorf("rule %s: want resp %s, got %v", tc.key, tc.resp, resp.Messages)
+		}
+	}
+
+	req, err := NewRequest("/greeter.Greeter/SayBye", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule, err := store.Match(req); err != nil || rule != nil {
+		t.Errorf("want no rule matched, got %v, %v", rule, err)
+	}
+}
+
+func TestRenderStreamReply(t *testing.T) {
+	store, err := NewRuleStore("../../mocks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	render := func(req *Request) *Response {
+		rule, err := store.Match(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rule == nil {
+			t.Fatalf("no rule matched for: %s", req.Method)
+		}
+		resp, err := rule.Render(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// server streaming
+	req, err := NewRequest("/greeter.StreamGreeter/SayHelloServerStream", nil, []byte(`{"name":"foo"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := render(req)
+	if len(resp.Messages) != 3 || resp.Status != nil {
+		t.Fatalf("want 3 messages, got %v, status %v", resp.Messages, resp.Status)
+	}
+	if want := `{"content": "hello foo (2/3)"}`; resp.Messages[1].Body != want || resp.Messages[1].Delay != 200*time.Millisecond {
+		t.Errorf("want message %s with delay 200ms, got %v", want, resp.Messages[1])
+	}
+
+	// client streaming
+	req, err = NewStreamRequest("/greeter.StreamGreeter/SayHelloClientStream", nil,
+		[][]byte{[]byte(`{"name":"foo"}`), []byte(`{"name":"bar"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Body["name"] != "bar" {
+		t.Errorf("want body of the last message, got %v", req.Body)
+	}
+	resp = render(req)
+	if want := `{"content": "hello foo,bar"}`; len(resp.Messages) != 1 || resp.Messages[0].Body != want {
+		t.Errorf("want message %s, got %v", want, resp.Messages)
+	}
+
+	// bidi streaming with status
+	req, err = NewRequest("/greeter.StreamGreeter/SayHelloBidiStream", nil, []byte(`{"name":"bye"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = render(req)
+	if len(resp.Messages) != 1 || resp.Status == nil || resp.Status.Code() != codes.Aborted {
+		t.Errorf("want 1 message and aborted status, got %v, status %v", resp.Messages, resp.Status)
+	}
+}
+
+func TestRuleStoreWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.yaml")
+	writeRules := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeRules(`- key: v1
+  reply_grpc:
+    payload: '{"version": 1}'`)
+
+	store, err := NewRuleStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := store.Watch(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// invalid rules are skipped, and current rules are kept
+	writeRules(`- key: invalid
+  reply_grpc:
+    code: not_exist`)
+	time.Sleep(2 * reloadDelay)
+	if rules := store.Rules(); len(rules) != 1 || rules[0].Key != "v1" {
+		t.Fatalf("want rules kept after invalid change, got %v", rules)
+	}
+
+	writeRules(`- key: v2
+  reply_grpc:
+    payload: '{"version": 2}'`)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if rules := store.Rules(); len(rules) == 1 && rules[0].Key == "v2" {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("rules are not reloaded after file changed")
+}
@@ -0,0 +1,118 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay waits for more file events before reload, as an editor may write a file several times.
+const reloadDelay = 300 * time.Millisecond
+
+// RuleStore mock rules loaded from dir, and rules are reloaded when files in dir changed.
+type RuleStore struct {
+	dir   string
+	mu    sync.RWMutex
+	rules []*Rule
+}
+
+// NewRuleStore creates rule store and loads rules from dir.
+func NewRuleStore(dir string) (*RuleStore, error) {
+	s := &RuleStore{dir: dir}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload loads rules from dir, and current rules are kept if load failed.
+func (s *RuleStore) Reload() error {
+	rules, err := LoadRules(s.dir)
+	if err != nil {
+		return fmt.Errorf("load mock rules error: %w", err)
+	}
+	s.mu.Lock()
+	s.rules = rules
+	s.mu.Unlock()
+	log.Printf("load %d mock rules from: %s", len(rules), s.dir)
+	return nil
+}
+
+// Rules returns current rules.
+func (s *RuleStore) Rules() []*Rule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rules
+}
+
+// Match returns the first rule which matches request, and returns nil if no rule matched.
+func (s *RuleStore) Match(req *Request) (*Rule, error) {
+	for _, rule := range s.Rules() {
+		ok, err := rule.Match(req)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return rule, nil
+		}
+	}
+	return nil, nil
+}
+
+// Watch reloads rules when files in dir (and sub dirs for payload files) changed, until ctx is done.
+func (s *RuleStore) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("new file watcher error: %w", err)
+	}
+	if err := filepath.WalkDir(s.dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	}); err != nil {
+		watcher.Close()
+		return fmt.Errorf("watch dir [%s] error: %w", s.dir, err)
+	}
+
+	go func() {
+		defer watcher.Close()
+		timer := time.NewTimer(reloadDelay)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&fsnotify.Chmod == event.Op {
+					continue
+				}
+				log.Println("mock rule file changed:", event)
+				timer.Reset(reloadDelay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Println("watch mock rule files error:", err)
+			case <-timer.C:
+				if err := s.Reload(); err != nil {
+					log.Println(err)
+				}
+			}
+		}
+	}()
+	return nil
+}
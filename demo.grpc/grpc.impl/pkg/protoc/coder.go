@@ -26,6 +26,15 @@ func NewCoder(mDescs map[string]*desc.MethodDescriptor) Coder {
 	}
 }
 
+// GetMethodDescriptor returns method descriptor of full method, i.e. "/greeter.Greeter/SayHello".
+func (c Coder) GetMethodDescriptor(method string) (*desc.MethodDescriptor, error) {
+	md, ok := c.methodDescs[method]
+	if !ok {
+		return nil, fmt.Errorf("method descriptor not found for: %s", method)
+	}
+	return md, nil
+}
+
 // BuildReqProtoMessage creates grpc request (proto message) from json string.
 func (c Coder) BuildReqProtoMessage(method, body string) (proto.Message, error) {
 	return c.buildProtoMessage(method, body, inputMsgType)
@@ -57,9 +66,9 @@ func (c Coder) NewReqProtoMessage(method string) (proto.Message, error) {
 }
 
 func (c Coder) newProtoMessage(method, msgType string) (proto.Message, error) {
-	md, ok := c.methodDescs[method]
-	if !ok {
-		return nil, fmt.Errorf("method descriptor not found for: %s", method)
+	md, err := c.GetMethodDescriptor(method)
+	if err != nil {
+		return nil, err
 	}
 
 	var msgDesc *desc.MessageDescriptor
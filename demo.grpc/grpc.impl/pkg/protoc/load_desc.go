@@ -0,0 +1,175 @@
+package protoc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"demo.grpc/grpc.reflect/helper"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/grpc"
+)
+
+// LoadMethodsViaReflection loads method descriptors from grpc server by reflection api.
+func LoadMethodsViaReflection(ctx context.Context, cc grpc.ClientConnInterface) (map[string]*desc.MethodDescriptor, error) {
+	svcMethods, err := helper.AllMethodsViaReflection(ctx, cc)
+	if err != nil {
+		return nil, fmt.Errorf("load methods via reflection error: %v", err)
+	}
+
+	mDescs := make(map[string]*desc.MethodDescriptor, len(svcMethods)*4)
+	for svc, methods := range svcMethods {
+		for _, md := range methods {
+			mDescs["/"+svc+"/"+md.GetName()] = md
+		}
+	}
+	return mDescs, nil
+}
+
+// LoadDescriptorSetFile loads method descriptors from compiled FileDescriptorSet file, which is generated by:
+// protoc --include_imports --descriptor_set_out=out.protoset xx.proto
+func LoadDescriptorSetFile(path string) (map[string]*desc.MethodDescriptor, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fdSet := &descriptor.FileDescriptorSet{}
+	if err := proto.Unmarshal(b, fdSet); err != nil {
+		return nil, fmt.Errorf("unmarshal descriptor set [%s] error: %v", path, err)
+	}
+	fDescs, err := desc.CreateFileDescriptorsFromSet(fdSet)
+	if err != nil {
+		return nil, fmt.Errorf("create file descriptors from set [%s] error: %v", path, err)
+	}
+
+	mDescs := make(map[string]*desc.MethodDescriptor, len(fDescs)*4)
+	for _, fd := range fDescs {
+		for _, service := range fd.GetServices() {
+			for _, method := range service.GetMethods() {
+				mDescs["/"+service.GetFullyQualifiedName()+"/"+method.GetName()] = method
+			}
+		}
+	}
+	return mDescs, nil
+}
+
+// WriteDescriptorSetFile writes files (with dependencies) of method descriptors as FileDescriptorSet file.
+func WriteDescriptorSetFile(path string, mDescs map[string]*desc.MethodDescriptor) error {
+	names := make([]string, 0, len(mDescs))
+	files := make(map[string]*desc.FileDescriptor, len(mDescs))
+	for _, md := range mDescs {
+		fd := md.GetFile()
+		if _, ok := files[fd.GetName()]; !ok {
+			files[fd.GetName()] = fd
+			names = append(names, fd.GetName())
+		}
+	}
+	sort.Strings(names)
+	fDescs := make([]*desc.FileDescriptor, 0, len(names))
+	for _, name := range names {
+		fDescs = append(fDescs, files[name])
+	}
+
+	b, err := proto.Marshal(desc.ToFileDescriptorSet(fDescs...))
+	if err != nil {
+		return fmt.Errorf("marshal descriptor set error: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// NewCoderViaReflection creates coder by method descriptors loaded from grpc server by reflection api.
+func NewCoderViaReflection(ctx context.Context, cc grpc.ClientConnInterface) (Coder, error) {
+	mDescs, err := LoadMethodsViaReflection(ctx, cc)
+	if err != nil {
+		return Coder{}, err
+	}
+	return NewCoder(mDescs), nil
+}
+
+// NewCoderFromDescriptorSet creates coder by method descriptors loaded from FileDescriptorSet file.
+func NewCoderFromDescriptorSet(path string) (Coder, error) {
+	mDescs, err := LoadDescriptorSetFile(path)
+	if err != nil {
+		return Coder{}, err
+	}
+	return NewCoder(mDescs), nil
+}
+
+//
+// Descriptor cache
+//
+
+// DescriptorCache caches method descriptors loaded by reflection of target in memory, and in dir as
+// FileDescriptorSet file if dir is not empty. Descriptors are loaded from cached file if target is not available.
+type DescriptorCache struct {
+	dir    string
+	mu     sync.Mutex
+	coders map[string]Coder
+}
+
+// NewDescriptorCache creates descriptor cache, and cached files are not used if dir is empty.
+func NewDescriptorCache(dir string) *DescriptorCache {
+	return &DescriptorCache{
+		dir:    dir,
+		coders: make(map[string]Coder),
+	}
+}
+
+// GetCoder returns coder of target from memory, or by reflection, or from cached file in order. Set refresh to
+// true to reload descriptors by reflection even if cached, and cc is nil if target is not available.
+func (c *DescriptorCache) GetCoder(ctx context.Context, target string, cc grpc.ClientConnInterface, refresh bool) (Coder, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if coder, ok := c.coders[target]; ok && !refresh {
+		return coder, nil
+	}
+
+	path := c.getFilePath(target)
+	var (
+		mDescs map[string]*desc.MethodDescriptor
+		err    = fmt.Errorf("no connection to target: %s", target)
+	)
+	if cc != nil {
+		mDescs, err = LoadMethodsViaReflection(ctx, cc)
+	}
+	if err != nil {
+		if len(path) == 0 {
+			return Coder{}, err
+		}
+		log.Printf("%v, and load from cached file: %s", err, path)
+		if mDescs, err = LoadDescriptorSetFile(path); err != nil {
+			return Coder{}, fmt.Errorf("load cached descriptors of [%s] error: %v", target, err)
+		}
+	} else if len(path) > 0 {
+		if err := WriteDescriptorSetFile(path, mDescs); err != nil {
+			log.Printf("write descriptors cache [%s] error: %v", path, err)
+		}
+	}
+
+	coder := NewCoder(mDescs)
+	c.coders[target] = coder
+	return coder, nil
+}
+
+var invalidFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func (c *DescriptorCache) getFilePath(target string) string {
+	if len(c.dir) == 0 {
+		return ""
+	}
+	return filepath.Join(c.dir, invalidFileNameChars.ReplaceAllString(target, "_")+".protoset")
+}
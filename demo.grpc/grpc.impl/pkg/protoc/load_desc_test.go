@@ -0,0 +1,90 @@
+package protoc
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"demo.grpc/grpc.impl/pb/greeter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+func newReflectionServerConn(t *testing.T) *grpc.ClientConn {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	greeter.RegisterGreeterServer(server, &greeter.UnimplementedGreeterServer{})
+	reflection.Register(server)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, lis.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestLoadMethodsViaReflection(t *testing.T) {
+	conn := newReflectionServerConn(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	method := "/greeter.Greeter/SayHello"
+	mDescs, err := LoadMethodsViaReflection(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := mDescs[method]; !ok || len(mDescs) != 1 {
+		t.Fatalf("want method %s only, got %v", method, mDescs)
+	}
+
+	// write and load descriptor set file
+	path := filepath.Join(t.TempDir(), "greeter.protoset")
+	if err := WriteDescriptorSetFile(path, mDescs); err != nil {
+		t.Fatal(err)
+	}
+	coder, err := NewCoderFromDescriptorSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := coder.BuildReqProtoMessage(method, `{"name":"foo"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log("request:", req.String())
+}
+
+func TestDescriptorCache(t *testing.T) {
+	conn := newReflectionServerConn(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	target := "localhost:50051"
+	method := "/greeter.Greeter/SayHello"
+	if _, err := NewDescriptorCache(dir).GetCoder(ctx, target, conn, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// target is not available, and descriptors are loaded from cached file
+	coder, err := NewDescriptorCache(dir).GetCoder(ctx, target, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := coder.GetMethodDescriptor(method); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewDescriptorCache("").GetCoder(ctx, target, nil, false); err == nil {
+		t.Error("want error for no connection and no cache")
+	}
+}
@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Record recorded grpc call, and messages are json of proto messages.
+type Record struct {
+	Method    string              `json:"method"`
+	Metadata  map[string][]string `json:"metadata,omitempty"`
+	Requests  []json.RawMessage   `json:"requests"`
+	Header    map[string][]string `json:"header,omitempty"`
+	Responses []json.RawMessage   `json:"responses"`
+	Trailer   map[string][]string `json:"trailer,omitempty"`
+	// Code grpc status code name, i.e. "OK", "NotFound"
+	Code      string    `json:"code"`
+	Message   string    `json:"message,omitempty"`
+	StartTime time.Time `json:"start_time"`
+	Duration  string    `json:"duration"`
+
+	mu sync.Mutex
+}
+
+// NewRecord creates record of grpc call.
+func NewRecord(method string, md metadata.MD) *Record {
+	return &Record{
+		Method:    method,
+		Metadata:  md,
+		Requests:  make([]json.RawMessage, 0, 1),
+		Responses: make([]json.RawMessage, 0, 1),
+		StartTime: time.Now(),
+	}
+}
+
+// AddRequest adds json of request message, and it's safe for concurrent use.
+func (r *Record) AddRequest(body []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Requests = append(r.Requests, json.RawMessage(body))
+}
+
+// AddResponse adds json of response message, and it's safe for concurrent use.
+func (r *Record) AddResponse(body []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Responses = append(r.Responses, json.RawMessage(body))
+}
+
+// Finish sets header, trailer, status and duration of call.
+func (r *Record) Finish(header, trailer metadata.MD, st *status.Status) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Header = header
+	r.Trailer = trailer
+	r.Code = st.Code().String()
+	r.Message = st.Message()
+	r.Duration = time.Since(r.StartTime).String()
+}
+
+// Recorder appends records to file as json lines.
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewRecorder creates recorder, and records are appended to file of path.
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: f}, nil
+}
+
+// Save writes record as a json line.
+func (r *Recorder) Save(record *Record) error {
+	record.mu.Lock()
+	b, err := json.Marshal(record)
+	record.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("marshal record error: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.file.Write(append(b, '\n'))
+	return err
+}
+
+// Close closes record file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// LoadRecords loads records from json lines file.
+func LoadRecords(path string) ([]*Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records := make([]*Record, 0, 16)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		record := &Record{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return nil, fmt.Errorf("unmarshal record at line %d error: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,150 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"demo.grpc/grpc.impl/pkg/mock"
+)
+
+/*
+Replay: records => mock rules, which are loaded by mock server.
+
+Request is matched by all fields of the (last) request message, and recorded response messages and status are
+replied. For bidi streaming, all response messages are replied to each matched request message.
+*/
+
+// RecordsToRules converts records to mock rules. For records with the same method and request, the latest one is
+// used.
+func RecordsToRules(records []*Record) ([]*mock.Rule, error) {
+	rules := make([]*mock.Rule, 0, len(records))
+	indexes := make(map[string]int, len(records))
+	for _, record := range records {
+		rule, err := recordToRule(record)
+		if err != nil {
+			return nil, fmt.Errorf("convert record of [%s] error: %v", record.Method, err)
+		}
+		if idx, ok := indexes[rule.Key]; ok {
+			rules[idx] = rule
+			continue
+		}
+		indexes[rule.Key] = len(rules)
+		rules = append(rules, rule)
+	}
+
+	// rules with more fields are matched first
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].Expect.Fields) > len(rules[j].Expect.Fields)
+	})
+	return rules, nil
+}
+
+func recordToRule(record *Record) (*mock.Rule, error) {
+	items := strings.Split(strings.TrimPrefix(record.Method, "/"), "/")
+	if len(items) != 2 {
+		return nil, fmt.Errorf("invalid method")
+	}
+
+	fields := make(map[string]string)
+	if len(record.Requests) > 0 {
+		var body interface{}
+		if err := json.Unmarshal(record.Requests[len(record.Requests)-1], &body); err != nil {
+			return nil, fmt.Errorf("unmarshal request error: %v", err)
+		}
+		flattenJson("", body, fields)
+	}
+
+	rule := &mock.Rule{
+		Key: getRuleKey(record.Method, fields),
+		Expect: mock.Expect{
+			Grpc: mock.GrpcExpect{
+				Service: items[0],
+				Method:  items[1],
+			},
+			Fields: fields,
+		},
+	}
+	if record.Code != "OK" {
+		rule.Reply.Code = record.Code
+		rule.Reply.Message = escapeTemplate(record.Message)
+	}
+	switch len(record.Responses) {
+	case 0:
+	case 1:
+		rule.Reply.Payload = escapeTemplate(string(record.Responses[0]))
+	default:
+		rule.Reply.Stream = make([]mock.StreamReply, 0, len(record.Responses))
+		for _, resp := range record.Responses {
+			rule.Reply.Stream = append(rule.Reply.Stream, mock.StreamReply{Payload: escapeTemplate(string(resp))})
+		}
+	}
+	return rule, nil
+}
+
+// WriteMockRules converts records to mock rules, and writes them to json file which can be loaded by mock server.
+func WriteMockRules(path string, records []*Record) error {
+	rules, err := RecordsToRules(records)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal mock rules error: %v", err)
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// flattenJson flattens json to path (i.e. "user.name", "items.0.id") => value, which is the same as fields of
+// mock rule expect.
+func flattenJson(prefix string, v interface{}, fields map[string]string) {
+	join := func(key string) string {
+		if len(prefix) == 0 {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if len(value) == 0 && len(prefix) > 0 {
+			fields[prefix] = "{}"
+		}
+		for key, field := range value {
+			flattenJson(join(key), field, fields)
+		}
+	case []interface{}:
+		if len(value) == 0 {
+			fields[prefix] = "[]"
+		}
+		for i, item := range value {
+			flattenJson(join(strconv.Itoa(i)), item, fields)
+		}
+	case nil:
+		fields[prefix] = ""
+	case string:
+		fields[prefix] = value
+	case float64:
+		fields[prefix] = strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		fields[prefix] = fmt.Sprint(value)
+	}
+}
+
+// getRuleKey returns key of rule by method and hash of request fields.
+func getRuleKey(method string, fields map[string]string) string {
+	b, _ := json.Marshal(fields) // keys of map are sorted
+	sum := sha256.Sum256(b)
+	name := strings.NewReplacer("/", "_", ".", "_").Replace(strings.TrimPrefix(method, "/"))
+	return fmt.Sprintf("record_%s_%s", name, hex.EncodeToString(sum[:4]))
+}
+
+// escapeTemplate escapes "{{" in recorded text, as mock reply is rendered as template.
+func escapeTemplate(text string) string {
+	return strings.ReplaceAll(text, "{{", `{{"{{"}}`)
+}
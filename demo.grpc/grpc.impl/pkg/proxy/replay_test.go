@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"demo.grpc/grpc.impl/pkg/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestFlattenJson(t *testing.T) {
+	var body interface{}
+	if err := json.Unmarshal([]byte(`{"name":"foo","age":18,"tags":[],"user":{"id":"1","roles":["a"]},"extra":null}`), &body); err != nil {
+		t.Fatal(err)
+	}
+	fields := make(map[string]string)
+	flattenJson("", body, fields)
+
+	want := map[string]string{
+		"name":         "foo",
+		"age":          "18",
+		"tags":         "[]",
+		"user.id":      "1",
+		"user.roles.0": "a",
+		"extra":        "",
+	}
+	if len(fields) != len(want) {
+		t.Fatalf("want fields %v, got %v", want, fields)
+	}
+	for k, v := range want {
+		if fields[k] != v {
+			t.Errorf("field [%s]: want %s, got %s", k, v, fields[k])
+		}
+	}
+}
+
+func TestWriteMockRules(t *testing.T) {
+	newRecord := func(method, req string, resps []string, st *status.Status) *Record {
+		record := NewRecord(method, nil)
+		record.AddRequest([]byte(req))
+		for _, resp := range resps {
+			record.AddResponse([]byte(resp))
+		}
+		record.Finish(nil, nil, st)
+		return record
+	}
+	records := []*Record{
+		newRecord("/greeter.Greeter/SayHello", `{"name":"foo"}`, []string{`{"content":"v1"}`}, status.New(codes.OK, "")),
+		// the latest record is used
+		newRecord("/greeter.Greeter/SayHello", `{"name":"foo"}`, []string{`{"content":"{{ v2 }}"}`}, status.New(codes.OK, "")),
+		newRecord("/greeter.Greeter/SayHello", `{"name":"bar"}`, nil, status.New(codes.NotFound, "bar not found")),
+		newRecord("/greeter.StreamGreeter/SayHelloServerStream", `{"name":"foo"}`,
+			[]string{`{"content":"1"}`, `{"content":"2"}`}, status.New(codes.OK, "")),
+	}
+
+	// save and load records
+	path := filepath.Join(t.TempDir(), "records.jsonl")
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if err := recorder.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recorder.Close()
+	if records, err = LoadRecords(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("want 4 records, got %d", len(records))
+	}
+
+	dir := t.TempDir()
+	if err := WriteMockRules(filepath.Join(dir, "recorded.json"), records); err != nil {
+		t.Fatal(err)
+	}
+	store, err := mock.NewRuleStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.Rules()) != 3 {
+		t.Fatalf("want 3 rules, got %d", len(store.Rules()))
+	}
+
+	for _, tc := range []struct {
+		method string
+		body   string
+		resps  []string
+		code   codes.Code
+	}{
+		{"/greeter.Greeter/SayHello", `{"name":"foo"}`, []string{`{"content":"{{ v2 }}"}`}, codes.OK},
+		{"/greeter.Greeter/SayHello", `{"name":"bar"}`, nil, codes.NotFound},
+		{"/greeter.StreamGreeter/SayHelloServerStream", `{"name":"foo"}`, []string{`{"content":"1"}`, `{"content":"2"}`}, codes.OK},
+	} {
+		req, err := mock.NewRequest(tc.method, metadata.MD{}, []byte(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rule, err := store.Match(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rule == nil {
+			t.Fatalf("no rule matched for: %s %s", tc.method, tc.body)
+		}
+		resp, err := rule.Render(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tc.code != codes.OK {
+			if resp.Status == nil || resp.Status.Code() != tc.code {
+				t.Errorf("%s %s: want status %v, got %v", tc.method, tc.body, tc.code, resp.Status)
+			}
+			continue
+		}
+		if len(resp.Messages) != len(tc.resps) {
+			t.Fatalf("%s %s: want %d messages, got %v", tc.method, tc.body, len(tc.resps), resp.Messages)
+		}
+		for i, msg := range resp.Messages {
+			if msg.Body != tc.resps[i] {
+				t.Errorf("%s %s: want message %s, got %s", tc.method, tc.body, tc.resps[i], msg.Body)
+			}
+		}
+	}
+}
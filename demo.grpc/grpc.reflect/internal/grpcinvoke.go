@@ -87,3 +87,27 @@ func CallGrpc(ctx context.Context, descSource grpcurl.DescriptorSource, cc *grpc
 	}
 	return nil
 }
+
+// InvokeGrpc invokes rpc method with headers. Request messages are read from in as json (multiple messages for
+// client stream), and response messages are written to out as json. Error is returned if rpc status is not OK.
+func InvokeGrpc(ctx context.Context, descSource grpcurl.DescriptorSource, cc *grpc.ClientConn, method string,
+	headers []string, in io.Reader, out io.Writer, verbose bool) error {
+	md, err := FindMethod(descSource, method)
+	if err != nil {
+		return err
+	}
+	parser, formatter, err := grpcurl.RequestParserAndFormatterFor(grpcurl.FormatJSON, descSource, false, true, in)
+	if err != nil {
+		return err
+	}
+
+	handler := grpcurl.NewDefaultEventHandler(out, descSource, formatter, verbose)
+	fullMethod := md.GetService().GetFullyQualifiedName() + "/" + md.GetName()
+	if err := grpcurl.InvokeRPC(ctx, descSource, cc, fullMethod, headers, handler, parser.Next); err != nil {
+		return err
+	}
+	if handler.Status.Code() != codes.OK {
+		return handler.Status.Err()
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package internal
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/fullstorydev/grpcurl"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
@@ -83,6 +84,99 @@ func PrintGrpcServiceInfo(descSource grpcurl.DescriptorSource) error {
 	return nil
 }
 
+// ListServices returns names of grpc services, and reflection service is excluded.
+func ListServices(descSource grpcurl.DescriptorSource) ([]string, error) {
+	allServices, err := grpcurl.ListServices(descSource)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]string, 0, len(allServices))
+	for _, svc := range allServices {
+		if svc == "grpc.reflection.v1alpha.ServerReflection" {
+			continue
+		}
+		services = append(services, svc)
+	}
+	return services, nil
+}
+
+// ListMethods returns fully qualified names of methods of service.
+func ListMethods(descSource grpcurl.DescriptorSource, service string) ([]string, error) {
+	return grpcurl.ListMethods(descSource, service)
+}
+
+// DescribeSymbol returns proto source of symbol, which is a service, method, message, enum or field. Method can be
+// in form of "service/method" or "service.method".
+func DescribeSymbol(descSource grpcurl.DescriptorSource, symbol string) (string, error) {
+	symbol = strings.Replace(strings.TrimPrefix(symbol, "/"), "/", ".", -1)
+	d, err := descSource.FindSymbol(symbol)
+	if err != nil {
+		return "", err
+	}
+	text, err := grpcurl.GetDescriptorText(d, descSource)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s is a %s:\n%s", d.GetFullyQualifiedName(), descriptorKind(d), text), nil
+}
+
+func descriptorKind(d desc.Descriptor) string {
+	switch d.(type) {
+	case *desc.FileDescriptor:
+		return "file"
+	case *desc.ServiceDescriptor:
+		return "service"
+	case *desc.MethodDescriptor:
+		return "method"
+	case *desc.MessageDescriptor:
+		return "message"
+	case *desc.FieldDescriptor:
+		return "field"
+	case *desc.OneOfDescriptor:
+		return "oneof"
+	case *desc.EnumDescriptor:
+		return "enum"
+	case *desc.EnumValueDescriptor:
+		return "enum value"
+	default:
+		return fmt.Sprintf("%T", d)
+	}
+}
+
+// FindMethod returns descriptor of method, which is in form of "service/method" or "service.method".
+func FindMethod(descSource grpcurl.DescriptorSource, method string) (*desc.MethodDescriptor, error) {
+	svc, name := splitMethod(method)
+	if len(svc) == 0 {
+		return nil, fmt.Errorf("method %s should be fully qualified, i.e. package.Service/Method", method)
+	}
+	d, err := descSource.FindSymbol(svc)
+	if err != nil {
+		return nil, err
+	}
+	sd, ok := d.(*desc.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", svc)
+	}
+	md := sd.FindMethodByName(name)
+	if md == nil {
+		return nil, fmt.Errorf("method %s is not found in service %s", name, svc)
+	}
+	return md, nil
+}
+
+func splitMethod(method string) (string, string) {
+	method = strings.TrimPrefix(method, "/")
+	pos := strings.LastIndex(method, "/")
+	if pos < 0 {
+		pos = strings.LastIndex(method, ".")
+	}
+	if pos < 0 {
+		return "", method
+	}
+	return method[:pos], method[pos+1:]
+}
+
 /*
 Method Metadata
 
@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+
+	pb "demo.grpc/grpc.impl/pb/greeter"
+)
+
+const testProto = `syntax = "proto3";
+
+package test;
+
+import "google/protobuf/timestamp.proto";
+import "google/protobuf/wrappers.proto";
+
+service TestService {
+  rpc Create(CreateRequest) returns (Node) {}
+}
+
+enum Kind {
+  KIND_UNKNOWN = 0;
+  KIND_FILE = 1;
+}
+
+message Node {
+  string name = 1;
+  repeated Node children = 2;
+}
+
+message CreateRequest {
+  int32 id = 1;
+  int64 size = 2;
+  bool enabled = 3;
+  Kind kind = 4;
+  repeated string tags = 5;
+  map<string, Node> nodes = 6;
+  Node root = 7;
+  oneof target {
+    string path = 8;
+    uint32 inode = 9;
+  }
+  google.protobuf.Timestamp created_at = 10;
+  google.protobuf.StringValue owner = 11;
+  repeated Kind kinds = 12;
+}
+`
+
+func TestGetRequestTemplate(t *testing.T) {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{"test.proto": testProto}),
+	}
+	fds, err := parser.ParseFiles("test.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	descSource, err := grpcurl.DescriptorSourceFromFileDescriptors(fds...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, method := range []string{"test.TestService/Create", "test.TestService.Create"} {
+		text, err := GetRequestTemplate(descSource, method)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &got); err != nil {
+			t.Fatalf("invalid template json: %v\n%s", err, text)
+		}
+
+		node := map[string]interface{}{"name": "", "children": []interface{}{map[string]interface{}{}}}
+		want := map[string]interface{}{
+			"id":        float64(0),
+			"size":      "0",
+			"enabled":   false,
+			"kind":      "KIND_UNKNOWN",
+			"kinds":     []interface{}{"KIND_UNKNOWN"},
+			"tags":      []interface{}{""},
+			"nodes":     map[string]interface{}{"key": node},
+			"root":      node,
+			"path":      "",
+			"createdAt": "1970-01-01T00:00:00Z",
+			"owner":     "",
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("unexpected template of %s:\n%s", method, text)
+		}
+	}
+
+	if _, err := GetRequestTemplate(descSource, "test.TestService/Delete"); err == nil {
+		t.Error("want error for unknown method")
+	}
+}
+
+type greeterServer struct {
+	pb.UnimplementedGreeterServer
+}
+
+func (greeterServer) SayHello(ctx context.Context, req *pb.HelloRequest) (*pb.HelloReply, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return &pb.HelloReply{Content: "Hello " + req.Name + strings.Join(md.Get("x-suffix"), "")}, nil
+}
+
+func TestInvokeGrpc(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	pb.RegisterGreeterServer(server, greeterServer{})
+	reflection.Register(server)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	ctx := context.Background()
+	cc, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	descSource := grpcurl.DescriptorSourceFromServer(ctx, grpcreflect.NewClient(ctx, reflectpb.NewServerReflectionClient(cc)))
+
+	services, err := ListServices(descSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(services, []string{"greeter.Greeter"}) {
+		t.Errorf("unexpected services: %v", services)
+	}
+	text, err := DescribeSymbol(descSource, "greeter.Greeter/SayHello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(text, "greeter.Greeter.SayHello is a method:") {
+		t.Errorf("unexpected description: %s", text)
+	}
+
+	out := &bytes.Buffer{}
+	in := strings.NewReader(`{"name": "grpc"}`)
+	if err := InvokeGrpc(ctx, descSource, cc, "greeter.Greeter/SayHello", []string{"x-suffix: !"}, in, out, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(out.String()); got != "{\n  \"content\": \"Hello grpc!\"\n}" {
+		t.Errorf("unexpected response: %s", got)
+	}
+
+	in = strings.NewReader(`{"name": 1}`)
+	if err := InvokeGrpc(ctx, descSource, cc, "greeter.Greeter/SayHello", nil, in, out, false); err == nil {
+		t.Error("want error for invalid request")
+	}
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fullstorydev/grpcurl"
+)
+
+// wellKnownTemplates json values of well-known types which are not encoded as json objects.
+var wellKnownTemplates = map[string]interface{}{
+	"google.protobuf.Timestamp": "1970-01-01T00:00:00Z",
+	"google.protobuf.Duration":  "0s",
+	"google.protobuf.FieldMask": "",
+	"google.protobuf.Struct":    map[string]interface{}{},
+	"google.protobuf.Value":     nil,
+	"google.protobuf.ListValue": []interface{}{},
+	"google.protobuf.Any":       map[string]interface{}{"@type": ""},
+}
+
+// scalarTemplates json values of scalar types, which are used for elements of repeated fields.
+var scalarTemplates = map[fieldType]interface{}{
+	typeString:   "",
+	typeBytes:    "",
+	typeInt32:    0,
+	typeInt64:    "0",
+	typeSint32:   0,
+	typeSint64:   "0",
+	typeUint32:   0,
+	typeUint64:   "0",
+	typeFixed32:  0,
+	typeFixed64:  "0",
+	typeSfixed32: 0,
+	typeSfixed64: "0",
+	typeFloat:    0.0,
+	typeDouble:   0.0,
+	typeBool:     false,
+}
+
+// GetRequestTemplate returns example request json of method, which is generated from method metadata. The first
+// choice of oneof is used, and repeated and map fields have one element.
+func GetRequestTemplate(descSource grpcurl.DescriptorSource, method string) (string, error) {
+	md, err := FindMethod(descSource, method)
+	if err != nil {
+		return "", err
+	}
+	meta, err := getMetadataForMethod(md)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.MarshalIndent(meta.messageTemplate(meta.RequestType, map[string]bool{}), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal request template error: %v", err)
+	}
+	return string(b), nil
+}
+
+// messageTemplate returns example value of message, and recursive message is left empty.
+func (s *schema) messageTemplate(typeName string, visiting map[string]bool) interface{} {
+	if val, ok := wellKnownTemplates[typeName]; ok {
+		return val
+	}
+	fields := s.MessageTypes[typeName]
+	if isWrapperType(typeName) && len(fields) == 1 {
+		// wrapper is encoded as json value of wrapped type
+		return fields[0].DefaultVal
+	}
+
+	ret := make(map[string]interface{}, len(fields))
+	if visiting[typeName] {
+		return ret
+	}
+	visiting[typeName] = true
+	defer delete(visiting, typeName)
+
+	for _, fd := range fields {
+		if fd.Type == typeOneOf {
+			if len(fd.OneOfFields) > 0 {
+				choice := fd.OneOfFields[0]
+				ret[choice.Name] = s.fieldTemplate(choice, visiting)
+			}
+			continue
+		}
+		ret[fd.Name] = s.fieldTemplate(fd, visiting)
+	}
+	return ret
+}
+
+func (s *schema) fieldTemplate(fd fieldDef, visiting map[string]bool) interface{} {
+	if fd.IsMap {
+		// map field is repeated entry message of key and value
+		key, val := "key", interface{}(nil)
+		for _, entry := range s.MessageTypes[string(fd.Type)] {
+			switch entry.ProtoName {
+			case "key":
+				if k := fmt.Sprint(s.valueTemplate(entry, visiting)); len(k) > 0 {
+					key = k
+				}
+			case "value":
+				val = s.valueTemplate(entry, visiting)
+			}
+		}
+		return map[string]interface{}{key: val}
+	}
+
+	val := s.valueTemplate(fd, visiting)
+	if fd.IsArray {
+		return []interface{}{val}
+	}
+	return val
+}
+
+func (s *schema) valueTemplate(fd fieldDef, visiting map[string]bool) interface{} {
+	if fd.IsMessage {
+		return s.messageTemplate(string(fd.Type), visiting)
+	}
+	if fd.DefaultVal != nil && !fd.IsArray {
+		return fd.DefaultVal
+	}
+	// default value of repeated field is nil
+	if fd.IsEnum {
+		if vals := s.EnumTypes[string(fd.Type)]; len(vals) > 0 {
+			return vals[0].Name
+		}
+		return nil
+	}
+	return scalarTemplates[fd.Type]
+}
+
+func isWrapperType(typeName string) bool {
+	return strings.HasPrefix(typeName, "google.protobuf.") && strings.HasSuffix(typeName, "Value")
+}
@@ -5,17 +5,62 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"time"
 
 	"demo.grpc/grpc.reflect/internal"
 	"github.com/fullstorydev/grpcurl"
 	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 )
 
-var debug bool
+const usage = `Usage: grpc.reflect -addr=host:port [flags] <command> [args]
+
+Commands:
+  list [service]           List services, or methods of service.
+  describe [symbol]        Describe all services, or symbol (service, method, message, enum or field).
+  call <method>            Invoke method with json request of -d, i.e. -d '{"name":"grpc"}', -d @req.json,
+                           or -d @ to read from stdin. Multiple json messages are sent to client stream method.
+  template <method>        Print example json request of method.
+
+Method is in form of "package.Service/Method" or "package.Service.Method". Without command, it runs in legacy mode
+by -method and -body.
+
+Flags:
+`
+
+var (
+	debug bool
+
+	headers        headerFlags
+	data           string
+	useTLS         bool
+	insecureTLS    bool
+	cacert         string
+	cert           string
+	key            string
+	serverName     string
+	maxTime        float64
+	connectTimeout float64
+	verbose        bool
+)
+
+// headerFlags repeated header flags in form of "name: value".
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(value string) error {
+	*h = append(*h, value)
+	return nil
+}
 
 func runGrpcApp(target, method, body string) error {
 	dialTime := time.Duration(10) * time.Second
@@ -28,10 +73,7 @@ func runGrpcApp(target, method, body string) error {
 	}
 
 	// get src grpc api meta desc info by reflection
-	md := grpcurl.MetadataFromHeaders(nil)
-	refCtx := metadata.NewOutgoingContext(ctx, md)
-	refClient := grpcreflect.NewClient(refCtx, reflectpb.NewServerReflectionClient(cc))
-	descSource := grpcurl.DescriptorSourceFromServer(ctx, refClient)
+	descSource := newDescSource(ctx, cc, nil)
 
 	if debug {
 		fmt.Println(strings.Repeat("*", 30), "proto info")
@@ -62,16 +104,171 @@ func runGrpcApp(target, method, body string) error {
 	return nil
 }
 
+func newDescSource(ctx context.Context, cc *grpc.ClientConn, headers []string) grpcurl.DescriptorSource {
+	md := grpcurl.MetadataFromHeaders(headers)
+	refCtx := metadata.NewOutgoingContext(ctx, md)
+	refClient := grpcreflect.NewClient(refCtx, reflectpb.NewServerReflectionClient(cc))
+	return grpcurl.DescriptorSourceFromServer(ctx, refClient)
+}
+
+// dial connects to target, and tls is used if any of tls flags is set.
+func dial(ctx context.Context, target string) (*grpc.ClientConn, error) {
+	var creds credentials.TransportCredentials
+	if useTLS || insecureTLS || len(cacert) > 0 || len(cert) > 0 || len(key) > 0 {
+		var err error
+		creds, err = grpcurl.ClientTransportCredentials(insecureTLS, cacert, cert, key)
+		if err != nil {
+			return nil, err
+		}
+		if len(serverName) > 0 {
+			if err := creds.OverrideServerName(serverName); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, seconds(connectTimeout))
+	defer cancel()
+	cc, err := grpcurl.BlockingDial(dialCtx, "tcp", target, creds)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s error: %v", target, err)
+	}
+	return cc, nil
+}
+
+func runCommand(target, command string, args []string) error {
+	ctx := context.Background()
+	if maxTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, seconds(maxTime))
+		defer cancel()
+	}
+
+	cc, err := dial(ctx, target)
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+	descSource := newDescSource(ctx, cc, headers)
+
+	switch command {
+	case "list":
+		if len(args) > 1 {
+			return errors.New("too many arguments, usage: list [service]")
+		}
+		var names []string
+		if len(args) == 0 {
+			names, err = internal.ListServices(descSource)
+		} else {
+			names, err = internal.ListMethods(descSource, args[0])
+		}
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+
+	case "describe":
+		symbols := args
+		if len(symbols) == 0 {
+			if symbols, err = internal.ListServices(descSource); err != nil {
+				return err
+			}
+		}
+		for _, symbol := range symbols {
+			text, err := internal.DescribeSymbol(descSource, symbol)
+			if err != nil {
+				return err
+			}
+			fmt.Println(text)
+		}
+
+	case "call":
+		if len(args) != 1 {
+			return errors.New("usage: call <method>")
+		}
+		in, err := requestReader(data)
+		if err != nil {
+			return err
+		}
+		if closer, ok := in.(io.Closer); ok {
+			defer closer.Close()
+		}
+		return internal.InvokeGrpc(ctx, descSource, cc, args[0], headers, in, os.Stdout, verbose)
+
+	case "template":
+		if len(args) != 1 {
+			return errors.New("usage: template <method>")
+		}
+		text, err := internal.GetRequestTemplate(descSource, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(text)
+
+	default:
+		return fmt.Errorf("unknown command: %s", command)
+	}
+	return nil
+}
+
+// requestReader returns reader of request data, which is read from stdin for "@", and from file for "@file".
+func requestReader(data string) (io.Reader, error) {
+	if data == "@" {
+		return os.Stdin, nil
+	}
+	if strings.HasPrefix(data, "@") {
+		f, err := os.Open(data[1:])
+		if err != nil {
+			return nil, fmt.Errorf("open request data file error: %v", err)
+		}
+		return f, nil
+	}
+	return strings.NewReader(data), nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// parseArgs parses flags, and flags can be mixed with command and args, i.e. "call svc/method -d '{}'".
+func parseArgs() []string {
+	flag.Parse()
+	var args []string
+	for flag.NArg() > 0 {
+		args = append(args, flag.Arg(0))
+		flag.CommandLine.Parse(flag.Args()[1:])
+	}
+	return args
+}
+
 func main() {
 	// pre-condition: reflection of grpc service is enabled.
 	target := flag.String("addr", "", "Target address of grpc service.")
-	method := flag.String("method", "", "Grpc method to be invoked.")
-	body := flag.String("body", `{"metadata":[],"data":[{"name":"tester"}]}`, "Grpc request body.")
+	method := flag.String("method", "", "Grpc method to be invoked (legacy mode).")
+	body := flag.String("body", `{"metadata":[],"data":[{"name":"tester"}]}`, "Grpc request body (legacy mode).")
 
-	flag.BoolVar(&debug, "debug", false, "Print grpc service meta info.")
+	flag.BoolVar(&debug, "debug", false, "Print grpc service meta info (legacy mode).")
 	help := flag.Bool("help", false, "Help.")
 
-	flag.Parse()
+	flag.Var(&headers, "H", `Header in form of "name: value", can be repeated.`)
+	flag.StringVar(&data, "d", "", "Json request data of call, @file to read from file, or @ to read from stdin.")
+	flag.BoolVar(&useTLS, "tls", false, "Use tls to connect to grpc service.")
+	flag.BoolVar(&insecureTLS, "insecure", false, "Skip verification of server certificate, implies -tls.")
+	flag.StringVar(&cacert, "cacert", "", "CA certificate file to verify server certificate, implies -tls.")
+	flag.StringVar(&cert, "cert", "", "Client certificate file, implies -tls.")
+	flag.StringVar(&key, "key", "", "Client private key file, implies -tls.")
+	flag.StringVar(&serverName, "servername", "", "Override server name to verify server certificate.")
+	flag.Float64Var(&maxTime, "max-time", 0, "Max time in seconds of command, including connecting and rpc call.")
+	flag.Float64Var(&connectTimeout, "connect-timeout", 10, "Timeout in seconds of connecting to grpc service.")
+	flag.BoolVar(&verbose, "v", false, "Print request and response headers and trailers of call.")
+
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	args := parseArgs()
 	if *help {
 		flag.Usage()
 		return
@@ -81,6 +278,14 @@ func main() {
 		panic(errors.New("Target address of grpc service is empty"))
 	}
 
+	if len(args) > 0 {
+		if err := runCommand(*target, args[0], args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := runGrpcApp(*target, *method, *body); err != nil {
 		panic(err)
 	}
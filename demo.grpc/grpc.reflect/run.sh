@@ -40,6 +40,22 @@ function invoke_grpc_deposit() {
     ${run_cmd} -addr=127.0.0.1:50051 -method=account.DepositService.Deposit -body="${body}"
 }
 
+function list_grpc_hello() {
+    ${run_cmd} -addr=127.0.0.1:50051 list
+    ${run_cmd} -addr=127.0.0.1:50051 list proto.Greeter
+    ${run_cmd} -addr=127.0.0.1:50051 describe proto.Greeter/SayHello
+}
+
+function call_grpc_hello() {
+    ${run_cmd} -addr=127.0.0.1:50051 template proto.Greeter/SayHello
+    ${run_cmd} -addr=127.0.0.1:50051 -max-time=3 call proto.Greeter/SayHello -d '{"name":"grpc"}' -H 'x-user-id: tester' -v
+}
+
+function call_grpc_deposit() {
+    ${run_cmd} -addr=127.0.0.1:50051 template account.DepositService/Deposit | \
+        ${run_cmd} -addr=127.0.0.1:50051 call account.DepositService/Deposit -d @
+}
+
 # main
 
 # if reflection is disabled, then get:
@@ -57,4 +73,8 @@ run_help
 # get_grpc_deposit_meta
 # invoke_grpc_deposit
 
+# list_grpc_hello
+# call_grpc_hello
+# call_grpc_deposit
+
 echo "grpc reflect demo done."
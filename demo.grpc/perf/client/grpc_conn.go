@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"demo.grpc/grpc.impl/pkg/protoc"
+)
+
+// GrpcConnect grpc connection which calls methods with json payload by proto coder.
+type GrpcConnect struct {
+	conn       *grpc.ClientConn
+	coder      protoc.Coder
+	operations map[string]*operationTemplates
+	timeout    time.Duration
+}
+
+// NewGrpcConnect connects to grpc server, and loads method descriptors by target configs.
+func NewGrpcConnect(ctx context.Context, configs *TargetConfigs) (*GrpcConnect, error) {
+	operations, err := newOperationTemplates(configs.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	target := configs.Grpc
+	dialCtx, cancel := context.WithTimeout(ctx, getTimeout(configs))
+	defer cancel()
+	conn, err := grpc.DialContext(dialCtx, target.Address, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		return nil, fmt.Errorf("connect to [%s] error: %v", target.Address, err)
+	}
+
+	coder, err := newGrpcCoder(dialCtx, conn, target)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &GrpcConnect{
+		conn:       conn,
+		coder:      coder,
+		operations: operations,
+		timeout:    getTimeout(configs),
+	}, nil
+}
+
+func newGrpcCoder(ctx context.Context, conn *grpc.ClientConn, target GrpcTarget) (protoc.Coder, error) {
+	switch {
+	case len(target.Protoset) > 0:
+		return protoc.NewCoderFromDescriptorSet(target.Protoset)
+	case target.Reflect:
+		return protoc.NewCoderViaReflection(ctx, conn)
+	case len(target.ProtoDir) > 0:
+		dirs, err := protoc.GetAllProtoDirs(target.ProtoDir)
+		if err != nil {
+			return protoc.Coder{}, err
+		}
+		mDescs, err := protoc.LoadProtoFiles(dirs...)
+		if err != nil {
+			return protoc.Coder{}, err
+		}
+		return protoc.NewCoder(mDescs), nil
+	default:
+		return protoc.Coder{}, fmt.Errorf("one of protoDir, protoset and reflect is required for grpc target")
+	}
+}
+
+// Get calls grpc method of get operation.
+func (c *GrpcConnect) Get(input interface{}) (string, error) {
+	return c.call(OperationGet, input)
+}
+
+// Create calls grpc method of create operation.
+func (c *GrpcConnect) Create(input interface{}) (string, error) {
+	return c.call(OperationCreate, input)
+}
+
+// Update calls grpc method of update operation.
+func (c *GrpcConnect) Update(input interface{}) (string, error) {
+	return c.call(OperationUpdate, input)
+}
+
+// Close closes grpc connection.
+func (c *GrpcConnect) Close() error {
+	return c.conn.Close()
+}
+
+// Call runs operation declared in target operations.
+func (c *GrpcConnect) Call(operation string, input interface{}) (string, error) {
+	return c.call(strings.ToLower(operation), input)
+}
+
+func (c *GrpcConnect) call(operation string, input interface{}) (string, error) {
+	tmpls, ok := c.operations[operation]
+	if !ok {
+		return "", fmt.Errorf("operation [%s] is not defined", operation)
+	}
+	req, err := tmpls.render(input)
+	if err != nil {
+		return "", err
+	}
+
+	reqMsg, err := c.coder.BuildReqProtoMessage(req.method, req.payload)
+	if err != nil {
+		return "", err
+	}
+	respMsg, err := c.coder.NewRespProtoMessage(req.method)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, metadata.New(req.headers))
+	if err := c.conn.Invoke(ctx, req.method, reqMsg, respMsg); err != nil {
+		return "", err
+	}
+
+	marshaler := jsonpb.Marshaler{OrigName: true}
+	return marshaler.MarshalToString(respMsg)
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPStatusError error of http response with status code >= 400.
+type HTTPStatusError struct {
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("http status: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// HTTPConnect http connection which sends requests to endpoints of operations.
+type HTTPConnect struct {
+	client     *http.Client
+	baseURL    string
+	operations map[string]*operationTemplates
+	timeout    time.Duration
+}
+
+// NewHTTPConnect creates http connection by target configs.
+func NewHTTPConnect(configs *TargetConfigs) (*HTTPConnect, error) {
+	if len(configs.HTTP.BaseURL) == 0 {
+		return nil, fmt.Errorf("baseUrl is required for http target")
+	}
+	operations, err := newOperationTemplates(configs.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 100
+	return &HTTPConnect{
+		client:     &http.Client{Transport: transport},
+		baseURL:    strings.TrimRight(configs.HTTP.BaseURL, "/"),
+		operations: operations,
+		timeout:    getTimeout(configs),
+	}, nil
+}
+
+// Get sends request of get operation.
+func (c *HTTPConnect) Get(input interface{}) (string, error) {
+	return c.send(OperationGet, input)
+}
+
+// Create sends request of create operation.
+func (c *HTTPConnect) Create(input interface{}) (string, error) {
+	return c.send(OperationCreate, input)
+}
+
+// Update sends request of update operation.
+func (c *HTTPConnect) Update(input interface{}) (string, error) {
+	return c.send(OperationUpdate, input)
+}
+
+// Close closes idle connections.
+func (c *HTTPConnect) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// Call runs operation declared in target operations.
+func (c *HTTPConnect) Call(operation string, input interface{}) (string, error) {
+	return c.send(strings.ToLower(operation), input)
+}
+
+func (c *HTTPConnect) send(operation string, input interface{}) (string, error) {
+	tmpls, ok := c.operations[operation]
+	if !ok {
+		return "", fmt.Errorf("operation [%s] is not defined", operation)
+	}
+	req, err := tmpls.render(input)
+	if err != nil {
+		return "", err
+	}
+
+	// method is like "GET /users/1"
+	items := strings.SplitN(strings.TrimSpace(req.method), " ", 2)
+	if len(items) != 2 {
+		return "", fmt.Errorf("invalid http method of operation [%s]: %s", operation, req.method)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	var body io.Reader
+	if len(req.payload) > 0 {
+		body = strings.NewReader(req.payload)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, strings.ToUpper(items[0]), c.baseURL+strings.TrimSpace(items[1]), body)
+	if err != nil {
+		return "", err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range req.headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return string(b), &HTTPStatusError{StatusCode: resp.StatusCode}
+	}
+	return string(b), nil
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"errors"
 	"math/rand"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -15,6 +16,11 @@ type Connection interface {
 	Update(interface{}) (string, error)
 }
 
+// Caller connection which runs any operation declared in target operations by name.
+type Caller interface {
+	Call(operation string, input interface{}) (string, error)
+}
+
 // MockConnect mock connect with random sleep.
 type MockConnect struct {
 	Total  int32
@@ -29,9 +35,9 @@ type MockConnect struct {
 // Get select/retrieve data action.
 func (c *MockConnect) Get(input interface{}) (string, error) {
 	log.Debug("Mock api Get process ...")
-	c.Total++
+	atomic.AddInt32(&c.Total, 1)
 	if c.isError() {
-		c.Failed++
+		atomic.AddInt32(&c.Failed, 1)
 		return "", errors.New("mock get error")
 	}
 
@@ -42,7 +48,7 @@ func (c *MockConnect) Get(input interface{}) (string, error) {
 // Create insert data action.
 func (c *MockConnect) Create(input interface{}) (string, error) {
 	log.Debug("Mock api Create process ...")
-	c.Total++
+	atomic.AddInt32(&c.Total, 1)
 	time.Sleep(time.Duration(c.wait()) * time.Millisecond)
 	return "ok", nil
 }
@@ -50,7 +56,7 @@ func (c *MockConnect) Create(input interface{}) (string, error) {
 // Update modify data action.
 func (c *MockConnect) Update(input interface{}) (string, error) {
 	log.Debug("Mock api Update process ...")
-	c.Total++
+	atomic.AddInt32(&c.Total, 1)
 	time.Sleep(time.Duration(c.wait()) * time.Millisecond)
 	return "ok", nil
 }
@@ -0,0 +1,222 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"google.golang.org/grpc/status"
+)
+
+/*
+Perf test targets declared in perf.yml:
+
+type: "grpc" # mock, grpc or http
+target:
+  timeout: 3 # seconds
+  grpc:
+    address: "localhost:50051"
+    protoDir: "../grpc.impl/proto"
+  http:
+    baseUrl: "http://localhost:8081"
+  operations:
+    get:
+      method: "/greeter.Greeter/SayHello" # grpc full method, or http method with path, i.e. "GET /users/{{ .Seq }}"
+      payload: '{"name": "perf-{{ .Worker }}-{{ .Seq }}"}'
+      headers:
+        x-env: "test"
+*/
+
+// Connection types.
+const (
+	TypeMock = "mock"
+	TypeGrpc = "grpc"
+	TypeHTTP = "http"
+)
+
+// Operations of Connection.
+const (
+	OperationGet    = "get"
+	OperationCreate = "create"
+	OperationUpdate = "update"
+)
+
+// TargetConfigs perf test target configs.
+type TargetConfigs struct {
+	Timeout    int
+	Grpc       GrpcTarget
+	HTTP       HTTPTarget
+	Operations map[string]Operation
+}
+
+// GrpcTarget grpc server, and method descriptors are loaded from proto files in dir, or protoset file, or by
+// reflection of server.
+type GrpcTarget struct {
+	Address  string
+	ProtoDir string
+	Protoset string
+	Reflect  bool
+}
+
+// HTTPTarget http server.
+type HTTPTarget struct {
+	BaseURL string `mapstructure:"baseUrl"`
+}
+
+// Operation request of connection operation, and method, payload and header values are templates.
+type Operation struct {
+	Method  string
+	Payload string
+	Headers map[string]string
+}
+
+// RequestData data used in operation templates, and it's passed as input of connection operation.
+type RequestData struct {
+	Worker int
+	Seq    int64
+	Time   time.Time
+	// Data records of scenario feeders by feeder name, i.e. {{ .Data.users.name }}
+	Data map[string]interface{}
+}
+
+// NewConnection creates connection of type by target configs.
+func NewConnection(ctx context.Context, typ string, configs *TargetConfigs) (Connection, error) {
+	switch typ {
+	case TypeMock, "":
+		return &MockConnect{
+			IsRandom:   true,
+			Sleep:      100,
+			IsError:    false,
+			ErrPercent: 1,
+		}, nil
+	case TypeGrpc:
+		return NewGrpcConnect(ctx, configs)
+	case TypeHTTP:
+		return NewHTTPConnect(configs)
+	default:
+		return nil, fmt.Errorf("invalid connection type: %s", typ)
+	}
+}
+
+// ErrorCode returns code of failed request for stats: http status code, grpc status code, or "Error" for others.
+func ErrorCode(err error) string {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return strconv.Itoa(statusErr.StatusCode)
+	}
+	if st, ok := status.FromError(err); ok {
+		return st.Code().String()
+	}
+	return "Error"
+}
+
+// request rendered request of operation.
+type request struct {
+	method  string
+	payload string
+	headers map[string]string
+}
+
+// operationTemplates parsed templates of operation.
+type operationTemplates struct {
+	method  *template.Template
+	payload *template.Template
+	headers map[string]*template.Template
+}
+
+var templateFuncs = template.FuncMap{
+	// randInt returns random int in [min, max)
+	"randInt": func(min, max int) int {
+		if max <= min {
+			return min
+		}
+		return min + rand.Intn(max-min)
+	},
+	"randString": func(n int) string {
+		const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = letters[rand.Intn(len(letters))]
+		}
+		return string(b)
+	},
+}
+
+func newOperationTemplates(operations map[string]Operation) (map[string]*operationTemplates, error) {
+	ret := make(map[string]*operationTemplates, len(operations))
+	for name, op := range operations {
+		if len(op.Method) == 0 {
+			return nil, fmt.Errorf("method of operation [%s] is empty", name)
+		}
+		tmpls := &operationTemplates{
+			headers: make(map[string]*template.Template, len(op.Headers)),
+		}
+		var err error
+		if tmpls.method, err = newTemplate(name+".method", op.Method); err != nil {
+			return nil, err
+		}
+		if tmpls.payload, err = newTemplate(name+".payload", op.Payload); err != nil {
+			return nil, err
+		}
+		for key, value := range op.Headers {
+			if tmpls.headers[key], err = newTemplate(name+".headers."+key, value); err != nil {
+				return nil, err
+			}
+		}
+		ret[strings.ToLower(name)] = tmpls
+	}
+	return ret, nil
+}
+
+func newTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse template [%s] error: %v", name, err)
+	}
+	return tmpl, nil
+}
+
+// render renders request of operation by input, and input should be RequestData (or nil).
+func (t *operationTemplates) render(input interface{}) (*request, error) {
+	data, ok := input.(RequestData)
+	if !ok {
+		data = RequestData{Time: time.Now()}
+	}
+	execute := func(tmpl *template.Template) (string, error) {
+		buf := bytes.Buffer{}
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("execute template [%s] error: %v", tmpl.Name(), err)
+		}
+		return buf.String(), nil
+	}
+
+	var (
+		req = &request{headers: make(map[string]string, len(t.headers))}
+		err error
+	)
+	if req.method, err = execute(t.method); err != nil {
+		return nil, err
+	}
+	if req.payload, err = execute(t.payload); err != nil {
+		return nil, err
+	}
+	for key, tmpl := range t.headers {
+		if req.headers[key], err = execute(tmpl); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+func getTimeout(configs *TargetConfigs) time.Duration {
+	if configs.Timeout <= 0 {
+		return 3 * time.Second
+	}
+	return time.Duration(configs.Timeout) * time.Second
+}
@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"demo.grpc/grpc.impl/pb/greeter"
+)
+
+func TestRenderOperation(t *testing.T) {
+	tmpls, err := newOperationTemplates(map[string]Operation{
+		"Get": {
+			Method:  "GET /users/{{ .Worker }}-{{ .Seq }}",
+			Payload: `{"id": {{ randInt 5 6 }}}`,
+			Headers: map[string]string{"x-seq": "{{ .Seq }}"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := tmpls[OperationGet].render(RequestData{Worker: 1, Seq: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.method != "GET /users/1-7" || req.payload != `{"id": 5}` || req.headers["x-seq"] != "7" {
+		t.Errorf("unexpected request: %+v", req)
+	}
+
+	if _, err := newOperationTemplates(map[string]Operation{"get": {Method: "{{ .NotClosed"}}); err == nil {
+		t.Error("want error for invalid template")
+	}
+}
+
+func TestHTTPConnect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(r.Method + " " + r.URL.Path + " " + r.Header.Get("X-Env") + " " + string(body)))
+	}))
+	defer server.Close()
+
+	conn, err := NewHTTPConnect(&TargetConfigs{
+		HTTP: HTTPTarget{BaseURL: server.URL},
+		Operations: map[string]Operation{
+			"get":    {Method: "GET /users/{{ .Seq }}", Headers: map[string]string{"x-env": "test"}},
+			"create": {Method: "POST /users", Payload: `{"name":"foo"}`},
+			"update": {Method: "PUT /error"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	resp, err := conn.Get(RequestData{Seq: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "GET /users/3 test "; resp != want {
+		t.Errorf("want %q, got %q", want, resp)
+	}
+	if resp, err = conn.Create(RequestData{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := `POST /users  {"name":"foo"}`; resp != want {
+		t.Errorf("want %q, got %q", want, resp)
+	}
+
+	_, err = conn.Update(RequestData{})
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("want http status error 503, got %v", err)
+	}
+	if code := ErrorCode(err); code != "503" {
+		t.Errorf("want error code 503, got %s", code)
+	}
+}
+
+type testGreeterServer struct {
+	greeter.UnimplementedGreeterServer
+}
+
+func (testGreeterServer) SayHello(ctx context.Context, req *greeter.HelloRequest) (*greeter.HelloReply, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	env := ""
+	if values := md.Get("x-env"); len(values) > 0 {
+		env = values[0]
+	}
+	return &greeter.HelloReply{Content: "hello " + req.GetName() + " " + env}, nil
+}
+
+func TestGrpcConnect(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	greeter.RegisterGreeterServer(server, testGreeterServer{})
+	go server.Serve(lis)
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := NewGrpcConnect(ctx, &TargetConfigs{
+		Grpc: GrpcTarget{
+			Address:  lis.Addr().String(),
+			ProtoDir: "../../grpc.impl/proto/greeter",
+		},
+		Operations: map[string]Operation{
+			"get": {
+				Method:  "/greeter.Greeter/SayHello",
+				Payload: `{"name": "perf-{{ .Seq }}"}`,
+				Headers: map[string]string{"x-env": "test"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	resp, err := conn.Get(RequestData{Seq: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"content":"hello perf-1 test"}`; resp != want {
+		t.Errorf("want %s, got %s", want, resp)
+	}
+	if _, err := conn.Create(nil); err == nil {
+		t.Error("want error for undefined operation")
+	}
+}
@@ -1,12 +1,15 @@
 package cmd
 
 import (
+	"demo.grpc/perf/client"
 	"demo.grpc/perf/service/run"
 )
 
 // Configurations perf test run configs.
 type Configurations struct {
-	Env    string
+	Env string
+	// Type connection type: mock, grpc or http
 	Type   string
+	Target client.TargetConfigs
 	Runner run.Configs
 }
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"demo.grpc/perf/service/distributed"
+)
+
+var (
+	coordinatorPort string
+	agentsNum       int
+	coordinatorAddr string
+	agentName       string
+
+	coordinatorCmd = &cobra.Command{
+		Use:   "coordinator",
+		Short: "run coordinator of distributed perf test",
+		Long:  "run coordinator which sends perf test task to agents, and aggregates matrix data of agents to report",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(scenarioFile) > 0 {
+				configurations.Runner.Scenario = scenarioFile
+			}
+			if err := runCoordinator(); err != nil {
+				log.Error(err)
+			}
+		},
+	}
+
+	agentCmd = &cobra.Command{
+		Use:   "agent",
+		Short: "run agent of distributed perf test",
+		Long:  "run agent which connects to coordinator, and runs perf test task received from coordinator",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runAgent(); err != nil {
+				log.Error(err)
+			}
+		},
+	}
+)
+
+func init() {
+	coordinatorCmd.Flags().StringVar(&coordinatorPort, "port", "50060", "coordinator grpc port")
+	coordinatorCmd.Flags().IntVar(&agentsNum, "agents", 1, "number of agents to wait for")
+	coordinatorCmd.Flags().StringVarP(&scenarioFile, "scenario", "s", "", "scenario file which overrides runner.scenario")
+	rootCmd.AddCommand(coordinatorCmd)
+
+	hostname, _ := os.Hostname()
+	agentCmd.Flags().StringVar(&coordinatorAddr, "coordinator", "localhost:50060", "coordinator address")
+	agentCmd.Flags().StringVar(&agentName, "name", fmt.Sprintf("%s-%d", hostname, os.Getpid()), "agent name")
+	rootCmd.AddCommand(agentCmd)
+}
+
+func runCoordinator() error {
+	coordinator, err := distributed.NewCoordinator(&distributed.TaskConfigs{
+		Type:   configurations.Type,
+		Target: configurations.Target,
+		Runner: configurations.Runner,
+	}, agentsNum)
+	if err != nil {
+		return err
+	}
+	return coordinator.Run(signalContext(), coordinatorPort)
+}
+
+func runAgent() error {
+	return distributed.RunAgent(signalContext(), coordinatorAddr, agentName)
+}
+
+// signalContext returns context which is cancelled by SIGINT or SIGTERM.
+func signalContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		<-sig
+		log.Info("Cancelled by signal.")
+		cancel()
+	}()
+	return ctx
+}
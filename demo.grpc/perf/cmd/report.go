@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"demo.grpc/perf/service/report"
+)
+
+var (
+	threshold float64
+
+	reportCmd = &cobra.Command{
+		Use:   "report",
+		Short: "perf test reports",
+	}
+
+	compareCmd = &cobra.Command{
+		Use:   "compare <base.json> <current.json>",
+		Short: "compare perf test reports",
+		Long:  "compare current perf test report to base, and exit with code 1 if any metric regresses beyond threshold",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			regressed, err := compareReports(args[0], args[1])
+			if err != nil {
+				log.Error(err)
+				os.Exit(1)
+			}
+			if regressed {
+				log.Errorf("Perf regression exceeds threshold %.2f%%.", threshold)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	compareCmd.Flags().Float64Var(&threshold, "threshold", 10, "regression threshold percent of metrics")
+	reportCmd.AddCommand(compareCmd)
+	rootCmd.AddCommand(reportCmd)
+}
+
+func compareReports(basePath, curPath string) (bool, error) {
+	base, err := report.Load(basePath)
+	if err != nil {
+		return false, err
+	}
+	cur, err := report.Load(curPath)
+	if err != nil {
+		return false, err
+	}
+
+	c := report.Compare(base, cur, threshold)
+	fmt.Print(c)
+	return c.Regressed(), nil
+}
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"runtime"
 
 	"demo.grpc/perf/client"
@@ -15,6 +17,7 @@ import (
 
 var (
 	cfgFile        string
+	scenarioFile   string
 	configurations Configurations
 
 	runCmd = &cobra.Command{
@@ -22,6 +25,9 @@ var (
 		Short: "run api perf test",
 		Long:  "run api perf test with specified paralel number and time seconds",
 		Run: func(cmd *cobra.Command, args []string) {
+			if len(scenarioFile) > 0 {
+				configurations.Runner.Scenario = scenarioFile
+			}
 			c, err := json.Marshal(&configurations)
 			if err != nil {
 				log.Error(err)
@@ -43,6 +49,7 @@ var (
 
 func init() {
 	runCmd.Flags().StringVarP(&cfgFile, "config", "c", "perf.yml", "config file (default is ./perf.yml)")
+	runCmd.Flags().StringVarP(&scenarioFile, "scenario", "s", "", "scenario file which overrides runner.scenario")
 	initConfig()
 	rootCmd.AddCommand(runCmd)
 }
@@ -64,6 +71,10 @@ func initConfig() {
 }
 
 func isConfigsValid() error {
+	if len(configurations.Runner.Scenario) > 0 {
+		// stages of scenario are validated when loading
+		return nil
+	}
 	if configurations.Runner.RunTime <= 0 {
 		return fmt.Errorf("Config runTime [%d] cannot be <= 0", configurations.Runner.RunTime)
 	}
@@ -77,17 +88,20 @@ func isConfigsValid() error {
 }
 
 func runPerfTest() error {
-	conn := client.MockConnect{
-		IsRandom:   true,
-		Sleep:      100,
-		IsError:    false,
-		ErrPercent: 1,
+	conn, err := client.NewConnection(context.Background(), configurations.Type, &configurations.Target)
+	if err != nil {
+		return err
+	}
+	if closer, ok := conn.(io.Closer); ok {
+		defer closer.Close()
 	}
 
-	runner := run.NewRunner(&conn, &configurations.Runner)
+	runner := run.NewRunner(conn, &configurations.Runner)
 	if err := runner.Run(); err != nil {
 		return err
 	}
-	log.Infof("Mock api summary: total=%d, failed=%d", conn.Total, conn.Failed)
+	if mockConn, ok := conn.(*client.MockConnect); ok {
+		log.Infof("Mock api summary: total=%d, failed=%d", mockConn.Total, mockConn.Failed)
+	}
 	return nil
 }
@@ -1,14 +1,117 @@
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"demo.grpc/grpc.impl/pkg/application"
+	"demo.grpc/grpc.impl/pkg/protoc"
+)
 
 var (
+	grpcPort  string
+	httpPort  string
+	protoDir  string
+	mockDir   string
+	httpDelay int
+
 	serverCmd = &cobra.Command{
-		Use: "server",
+		Use:   "server",
+		Short: "run local test server",
+		Long:  "run local grpc mock server (grpc.impl) and http echo server as perf test targets",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runTestServer(); err != nil {
+				log.Error(err)
+			}
+		},
 	}
 )
 
 func init() {
-	// TODO:
+	serverCmd.Flags().StringVar(&grpcPort, "grpc-port", "50051", "grpc mock server port, and skip if empty")
+	serverCmd.Flags().StringVar(&httpPort, "http-port", "8081", "http echo server port, and skip if empty")
+	serverCmd.Flags().StringVar(&protoDir, "proto-dir", "../grpc.impl/proto", "proto files dir of grpc mock server")
+	serverCmd.Flags().StringVar(&mockDir, "mock-dir", "../grpc.impl/mocks", "mock rules dir of grpc mock server")
+	serverCmd.Flags().IntVar(&httpDelay, "http-delay", 50, "max random delay (ms) of http echo server")
 	rootCmd.AddCommand(serverCmd)
 }
+
+func runTestServer() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if len(grpcPort) > 0 {
+		dirs, err := protoc.GetAllProtoDirs(protoDir)
+		if err != nil {
+			return err
+		}
+		mDescs, err := protoc.LoadProtoFiles(dirs...)
+		if err != nil {
+			return err
+		}
+		application.InitProtoCoder(protoc.NewCoder(mDescs))
+		if err := application.RunGrpcServer(ctx, grpcPort, mockDir); err != nil {
+			return err
+		}
+	}
+
+	if len(httpPort) > 0 {
+		server := &http.Server{Addr: ":" + httpPort, Handler: newEchoHandler()}
+		go func() {
+			log.Infof("http echo server listen at: %s", httpPort)
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error(err)
+			}
+		}()
+		defer server.Close()
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	<-sig
+	log.Info("test server exit")
+	return nil
+}
+
+// newEchoHandler returns http handler which echoes request with random delay, and "/status/{code}" returns the
+// status code.
+func newEchoHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status/", func(w http.ResponseWriter, r *http.Request) {
+		code, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/status/"))
+		if err != nil || code < 100 || code > 599 {
+			http.Error(w, "invalid status code", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(code)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if httpDelay > 0 {
+			time.Sleep(time.Duration(rand.Intn(httpDelay)) * time.Millisecond)
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"body":   string(body),
+		})
+	})
+	return mux
+}
@@ -0,0 +1,139 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.21.2
+// source: agent.proto
+
+package agent
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// CoordinatorClient is the client API for Coordinator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CoordinatorClient interface {
+	// Connect agent registers and receives task, and sends matrix data deltas until task is finished.
+	Connect(ctx context.Context, opts ...grpc.CallOption) (Coordinator_ConnectClient, error)
+}
+
+type coordinatorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCoordinatorClient(cc grpc.ClientConnInterface) CoordinatorClient {
+	return &coordinatorClient{cc}
+}
+
+func (c *coordinatorClient) Connect(ctx context.Context, opts ...grpc.CallOption) (Coordinator_ConnectClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Coordinator_ServiceDesc.Streams[0], "/agent.Coordinator/Connect", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &coordinatorConnectClient{stream}
+	return x, nil
+}
+
+type Coordinator_ConnectClient interface {
+	Send(*AgentMessage) error
+	Recv() (*CoordinatorMessage, error)
+	grpc.ClientStream
+}
+
+type coordinatorConnectClient struct {
+	grpc.ClientStream
+}
+
+func (x *coordinatorConnectClient) Send(m *AgentMessage) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *coordinatorConnectClient) Recv() (*CoordinatorMessage, error) {
+	m := new(CoordinatorMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CoordinatorServer is the server API for Coordinator service.
+// All implementations must embed UnimplementedCoordinatorServer
+// for forward compatibility
+type CoordinatorServer interface {
+	// Connect agent registers and receives task, and sends matrix data deltas until task is finished.
+	Connect(Coordinator_ConnectServer) error
+	mustEmbedUnimplementedCoordinatorServer()
+}
+
+// UnimplementedCoordinatorServer must be embedded to have forward compatible implementations.
+type UnimplementedCoordinatorServer struct {
+}
+
+func (UnimplementedCoordinatorServer) Connect(Coordinator_ConnectServer) error {
+	return status.Errorf(codes.Unimplemented, "method Connect not implemented")
+}
+func (UnimplementedCoordinatorServer) mustEmbedUnimplementedCoordinatorServer() {}
+
+// UnsafeCoordinatorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CoordinatorServer will
+// result in compilation errors.
+type UnsafeCoordinatorServer interface {
+	mustEmbedUnimplementedCoordinatorServer()
+}
+
+func RegisterCoordinatorServer(s grpc.ServiceRegistrar, srv CoordinatorServer) {
+	s.RegisterService(&Coordinator_ServiceDesc, srv)
+}
+
+func _Coordinator_Connect_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CoordinatorServer).Connect(&coordinatorConnectServer{stream})
+}
+
+type Coordinator_ConnectServer interface {
+	Send(*CoordinatorMessage) error
+	Recv() (*AgentMessage, error)
+	grpc.ServerStream
+}
+
+type coordinatorConnectServer struct {
+	grpc.ServerStream
+}
+
+func (x *coordinatorConnectServer) Send(m *CoordinatorMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *coordinatorConnectServer) Recv() (*AgentMessage, error) {
+	m := new(AgentMessage)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Coordinator_ServiceDesc is the grpc.ServiceDesc for Coordinator service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Coordinator_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "agent.Coordinator",
+	HandlerType: (*CoordinatorServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Connect",
+			Handler:       _Coordinator_Connect_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "agent.proto",
+}
@@ -0,0 +1,178 @@
+package distributed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+
+	"demo.grpc/perf/client"
+	pb "demo.grpc/perf/pb/agent"
+	"demo.grpc/perf/service/run"
+	"demo.grpc/perf/service/utils"
+)
+
+const dialTimeout = 10 * time.Second
+
+// RunAgent connects to coordinator at address, and runs perf test task received from coordinator. Matrix data
+// synced by workers is sent to coordinator as deltas.
+func RunAgent(ctx context.Context, address, name string) error {
+	dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	conn, err := grpc.DialContext(dialCtx, address, grpc.WithInsecure(), grpc.WithBlock())
+	cancel()
+	if err != nil {
+		return fmt.Errorf("connect to coordinator [%s] error: %v", address, err)
+	}
+	defer conn.Close()
+
+	ctx, cancel = context.WithCancel(ctx)
+	defer cancel()
+	stream, err := pb.NewCoordinatorClient(conn).Connect(ctx)
+	if err != nil {
+		return err
+	}
+	register := &pb.Register{Name: name}
+	if err := stream.Send(&pb.AgentMessage{Body: &pb.AgentMessage_Register{Register: register}}); err != nil {
+		return err
+	}
+	log.Infof("Agent [%s] connected to coordinator %s, waiting for task.", name, address)
+
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	task := msg.GetTask()
+	if task == nil {
+		return fmt.Errorf("want task from coordinator, got: %v", msg)
+	}
+
+	dir, err := os.MkdirTemp("", "perf_agent_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	configs, err := newAgentConfigs(dir, name, task)
+	if err != nil {
+		return err
+	}
+	return runTask(ctx, stream, name, configs)
+}
+
+// newAgentConfigs returns configs of task, and scenario and feeder files are written to dir.
+func newAgentConfigs(dir, name string, task *pb.Task) (*TaskConfigs, error) {
+	configs := &TaskConfigs{}
+	if err := json.Unmarshal(task.Configs, configs); err != nil {
+		return nil, fmt.Errorf("unmarshal task configs error: %v", err)
+	}
+	path, err := writeTaskFiles(dir, task)
+	if err != nil {
+		return nil, err
+	}
+
+	configs.Runner.Scenario = path
+	// failure threshold is checked by coordinator globally
+	configs.Runner.FailedThreshold = math.MaxInt32
+	configs.Runner.ReportPath = fmt.Sprintf("perf_agent_%s_%s", name, utils.TimeFormatWithUnderline(time.Now()))
+	return configs, nil
+}
+
+func runTask(ctx context.Context, stream pb.Coordinator_ConnectClient, name string, configs *TaskConfigs) error {
+	conn, err := client.NewConnection(ctx, configs.Type, &configs.Target)
+	if err != nil {
+		return err
+	}
+	if closer, ok := conn.(io.Closer); ok {
+		defer closer.Close()
+	}
+	runner := run.NewRunner(conn, &configs.Runner)
+	deltas := &deltaBuffer{locker: &sync.Mutex{}, matrix: run.NewMatrixData()}
+	runner.OnSync(deltas.add)
+
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	recvDone := make(chan struct{})
+	go func() {
+		defer close(recvDone)
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				stop()
+				return
+			}
+			if s := msg.GetStop(); s != nil {
+				log.Warnf("Agent [%s] is stopped by coordinator: %s", name, s.Reason)
+				stop()
+			}
+		}
+	}()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- runner.RunContext(runCtx)
+	}()
+
+	tick := time.NewTicker(configs.Runner.GetSyncInterval())
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			if err := deltas.flush(stream); err != nil {
+				stop()
+				<-runErr
+				return fmt.Errorf("send matrix data to coordinator error: %v", err)
+			}
+		case err := <-runErr:
+			if flushErr := deltas.flush(stream); flushErr != nil {
+				return fmt.Errorf("send matrix data to coordinator error: %v", flushErr)
+			}
+			finished := &pb.Finished{}
+			if err != nil {
+				finished.Error = err.Error()
+			}
+			msg := &pb.AgentMessage{Body: &pb.AgentMessage_Finished{Finished: finished}}
+			if sendErr := stream.Send(msg); sendErr != nil {
+				return sendErr
+			}
+			stream.CloseSend()
+
+			// wait for coordinator to receive all messages
+			select {
+			case <-recvDone:
+			case <-time.After(dialTimeout):
+			}
+			log.Infof("Agent [%s] finished.", name)
+			return err
+		}
+	}
+}
+
+// deltaBuffer buffers matrix data synced by workers until it's sent to coordinator.
+type deltaBuffer struct {
+	locker *sync.Mutex
+	matrix run.MatrixData
+}
+
+func (b *deltaBuffer) add(data *run.MatrixData) {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	b.matrix.Merge(data)
+}
+
+func (b *deltaBuffer) flush(stream pb.Coordinator_ConnectClient) error {
+	b.locker.Lock()
+	if b.matrix.Total == 0 {
+		b.locker.Unlock()
+		return nil
+	}
+	delta := toMatrixDelta(&b.matrix)
+	b.matrix = run.NewMatrixData()
+	b.locker.Unlock()
+	return stream.Send(&pb.AgentMessage{Body: &pb.AgentMessage_Matrix{Matrix: delta}})
+}
@@ -0,0 +1,273 @@
+package distributed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "demo.grpc/perf/pb/agent"
+	"demo.grpc/perf/service/run"
+)
+
+// finishTimeout time to wait for agents to finish after scenario duration or stopped.
+const finishTimeout = 30 * time.Second
+
+// Coordinator coordinator of distributed perf test.
+type Coordinator struct {
+	pb.UnimplementedCoordinatorServer
+
+	configs  *TaskConfigs
+	agents   int
+	tasks    []*pb.Task
+	duration time.Duration
+
+	locker     *sync.Mutex
+	names      []string
+	matrix     run.MatrixData
+	startTime  time.Time
+	ready      chan struct{}
+	stopped    chan struct{}
+	stopReason string
+	finished   *sync.WaitGroup
+}
+
+// NewCoordinator creates coordinator which runs perf test of configs by agents, and rps and concurrency of scenario
+// are split to agents.
+func NewCoordinator(configs *TaskConfigs, agents int) (*Coordinator, error) {
+	if agents <= 0 {
+		return nil, fmt.Errorf("agents should be > 0")
+	}
+	scenario, err := run.NewScenario(&configs.Runner)
+	if err != nil {
+		return nil, err
+	}
+	files, err := readFeederFiles(scenario, configs.Runner.Scenario)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Coordinator{
+		configs:  configs,
+		agents:   agents,
+		tasks:    make([]*pb.Task, 0, agents),
+		locker:   &sync.Mutex{},
+		matrix:   run.NewMatrixData(),
+		ready:    make(chan struct{}),
+		stopped:  make(chan struct{}),
+		finished: &sync.WaitGroup{},
+	}
+	for _, stage := range scenario.Stages {
+		c.duration += time.Duration(stage.Duration) * time.Second
+	}
+	for i := 0; i < agents; i++ {
+		task, err := newTask(configs, newAgentScenario(scenario, agents, i), files)
+		if err != nil {
+			return nil, err
+		}
+		c.tasks = append(c.tasks, task)
+	}
+	c.finished.Add(agents)
+	return c, nil
+}
+
+func newTask(configs *TaskConfigs, scenario *run.Scenario, files map[string][]byte) (*pb.Task, error) {
+	b, err := json.Marshal(configs)
+	if err != nil {
+		return nil, fmt.Errorf("marshal task configs error: %v", err)
+	}
+	s, err := marshalScenario(scenario)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Task{Configs: b, Scenario: s, Files: files}, nil
+}
+
+// Run runs coordinator grpc server at port, and waits for agents to register and finish tasks, then writes report.
+func (c *Coordinator) Run(ctx context.Context, port string) error {
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return err
+	}
+	return c.Serve(ctx, lis)
+}
+
+// Serve runs coordinator grpc server by listener, and waits for agents to register and finish tasks, then writes
+// report.
+func (c *Coordinator) Serve(ctx context.Context, lis net.Listener) error {
+	server := grpc.NewServer()
+	pb.RegisterCoordinatorServer(server, c)
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			log.Error(err)
+		}
+	}()
+	defer server.Stop()
+	log.Infof("Coordinator listen at: %s, waiting for %d agents.", lis.Addr(), c.agents)
+
+	select {
+	case <-c.ready:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.finished.Wait()
+		close(done)
+	}()
+
+	runnerConfigs := &c.configs.Runner
+	syncTick := time.NewTicker(runnerConfigs.GetSyncInterval())
+	defer syncTick.Stop()
+	outTick := time.NewTicker(runnerConfigs.GetOutInterval())
+	defer outTick.Stop()
+	timeout := time.After(c.duration + finishTimeout)
+	for {
+		select {
+		case <-syncTick.C:
+			// failure threshold is checked globally
+			if failed := c.failed(); failed >= runnerConfigs.FailedThreshold {
+				log.Errorf("Failed cases %d, exceed threshold %d.", failed, runnerConfigs.FailedThreshold)
+				c.stop(fmt.Sprintf("failed cases %d exceed threshold %d", failed, runnerConfigs.FailedThreshold))
+				timeout = time.After(finishTimeout)
+			}
+		case <-outTick.C:
+			if err := c.writeReport(); err != nil {
+				log.Error(err)
+			}
+		case <-ctx.Done():
+			c.stop("coordinator is cancelled")
+			select {
+			case <-done:
+			case <-time.After(finishTimeout):
+			}
+			return c.writeReport()
+		case <-timeout:
+			log.Warn("Wait for agents to finish timeout.")
+			c.stop("timeout")
+			return c.writeReport()
+		case <-done:
+			log.Info("All agents finished.")
+			return c.writeReport()
+		}
+	}
+}
+
+// Connect registers agent, sends task to it, and receives matrix data deltas until task is finished.
+func (c *Coordinator) Connect(stream pb.Coordinator_ConnectServer) error {
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	register := msg.GetRegister()
+	if register == nil {
+		return status.Error(codes.InvalidArgument, "agent should register first")
+	}
+	idx, err := c.register(register.Name)
+	if err != nil {
+		return err
+	}
+	defer c.finished.Done()
+
+	select {
+	case <-c.ready:
+	case <-stream.Context().Done():
+		log.Warnf("Agent [%s] disconnected before task started.", register.Name)
+		return stream.Context().Err()
+	}
+	if err := stream.Send(&pb.CoordinatorMessage{Body: &pb.CoordinatorMessage_Task{Task: c.tasks[idx]}}); err != nil {
+		return err
+	}
+	log.Infof("Task is sent to agent [%s].", register.Name)
+
+	go func() {
+		select {
+		case <-c.stopped:
+			stop := &pb.Stop{Reason: c.stopReason}
+			if err := stream.Send(&pb.CoordinatorMessage{Body: &pb.CoordinatorMessage_Stop{Stop: stop}}); err != nil {
+				log.Warnf("Send stop to agent [%s] error: %v", register.Name, err)
+			}
+		case <-stream.Context().Done():
+		}
+	}()
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			log.Warnf("Agent [%s] disconnected: %v", register.Name, err)
+			return err
+		}
+		switch body := msg.Body.(type) {
+		case *pb.AgentMessage_Matrix:
+			c.merge(fromMatrixDelta(body.Matrix))
+		case *pb.AgentMessage_Finished:
+			if len(body.Finished.Error) > 0 {
+				log.Errorf("Agent [%s] finished with error: %s", register.Name, body.Finished.Error)
+			} else {
+				log.Infof("Agent [%s] finished.", register.Name)
+			}
+			return nil
+		}
+	}
+}
+
+func (c *Coordinator) register(name string) (int, error) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	if len(c.names) >= c.agents {
+		return 0, status.Errorf(codes.ResourceExhausted, "all %d agents are registered", c.agents)
+	}
+
+	idx := len(c.names)
+	c.names = append(c.names, name)
+	log.Infof("Agent [%s] registered (%d/%d).", name, len(c.names), c.agents)
+	if len(c.names) == c.agents {
+		c.startTime = time.Now()
+		close(c.ready)
+	}
+	return idx, nil
+}
+
+func (c *Coordinator) stop(reason string) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	select {
+	case <-c.stopped:
+	default:
+		c.stopReason = reason
+		close(c.stopped)
+	}
+}
+
+func (c *Coordinator) merge(data *run.MatrixData) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.matrix.Merge(data)
+}
+
+func (c *Coordinator) failed() int32 {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.matrix.Failed
+}
+
+func (c *Coordinator) writeReport() error {
+	c.locker.Lock()
+	path := run.GetReportPath(&c.configs.Runner, c.startTime)
+	rpt := c.matrix.Report(filepath.Base(path), c.startTime, time.Now())
+	c.locker.Unlock()
+	return run.WriteReport(rpt, path)
+}
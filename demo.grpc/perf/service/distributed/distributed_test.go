@@ -0,0 +1,145 @@
+package distributed
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"demo.grpc/perf/client"
+	"demo.grpc/perf/service/report"
+	"demo.grpc/perf/service/run"
+)
+
+func TestNewAgentScenario(t *testing.T) {
+	s := &run.Scenario{
+		Stages: []run.Stage{
+			{Name: "ramp-up", Duration: 10, RPS: 100, Concurrency: 4, Ramp: true},
+			{Name: "unlimited", Duration: 10, Concurrency: 1},
+		},
+		Feeders: []run.FeederConfigs{{Name: "users", Path: "data/users.csv"}},
+	}
+	for i, want := range []run.Stage{
+		{Name: "ramp-up", Duration: 10, RPS: 34, Concurrency: 2, Ramp: true},
+		{Name: "ramp-up", Duration: 10, RPS: 33, Concurrency: 1, Ramp: true},
+		{Name: "ramp-up", Duration: 10, RPS: 33, Concurrency: 1, Ramp: true},
+	} {
+		agentScenario := newAgentScenario(s, 3, i)
+		if agentScenario.Stages[0] != want {
+			t.Errorf("agent %d: want stage %+v, got %+v", i, want, agentScenario.Stages[0])
+		}
+		if stage := agentScenario.Stages[1]; stage.RPS != 0 || stage.Concurrency != 1 {
+			t.Errorf("agent %d: want unlimited stage with 1 worker, got %+v", i, stage)
+		}
+		if path := agentScenario.Feeders[0].Path; path != filepath.Join("feeders", "users.csv") {
+			t.Errorf("agent %d: unexpected feeder path %s", i, path)
+		}
+	}
+}
+
+// runDistributed runs coordinator and agents in process, and returns report of coordinator.
+func runDistributed(t *testing.T, configs *TaskConfigs, agents int) *report.Report {
+	// agents write local reports to current dir
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	configs.Runner.ReportPath = filepath.Join(dir, "perf_test")
+
+	coordinator, err := NewCoordinator(configs, agents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- coordinator.Serve(ctx, lis)
+	}()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < agents; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := RunAgent(ctx, lis.Addr().String(), name); err != nil {
+				t.Errorf("agent [%s] error: %v", name, err)
+			}
+		}(string(rune('a' + i)))
+	}
+	wg.Wait()
+	if err := <-serveErr; err != nil {
+		t.Fatal(err)
+	}
+
+	rpt, err := report.Load(configs.Runner.ReportPath + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rpt
+}
+
+func TestDistributedRun(t *testing.T) {
+	rpt := runDistributed(t, &TaskConfigs{
+		Type: client.TypeMock,
+		Runner: run.Configs{
+			Parallel:        4,
+			RunTime:         2,
+			Limit:           40,
+			SyncInterval:    1,
+			OutInterval:     5,
+			FailedThreshold: 1000,
+		},
+	}, 2)
+
+	// 20 rps of each agent, and burst of limiters
+	if rpt.Total < 60 || rpt.Total > 130 || rpt.Histogram.Total != rpt.Total {
+		t.Errorf("unexpected report: %s", rpt)
+	}
+}
+
+func TestDistributedFailedThreshold(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	rpt := runDistributed(t, &TaskConfigs{
+		Type: client.TypeHTTP,
+		Target: client.TargetConfigs{
+			HTTP:       client.HTTPTarget{BaseURL: server.URL},
+			Operations: map[string]client.Operation{"get": {Method: "GET /"}},
+		},
+		Runner: run.Configs{
+			Parallel:        2,
+			RunTime:         30,
+			Limit:           20,
+			SyncInterval:    1,
+			OutInterval:     5,
+			FailedThreshold: 10,
+		},
+	}, 2)
+
+	// agents are stopped by coordinator when failed requests of all agents exceed threshold
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("want agents stopped by failed threshold, but run %v", elapsed)
+	}
+	if rpt.Failed < 10 || rpt.Errors["500"] != rpt.Failed {
+		t.Errorf("unexpected report: %s, errors=%v", rpt, rpt.Errors)
+	}
+}
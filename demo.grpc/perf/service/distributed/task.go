@@ -0,0 +1,146 @@
+package distributed
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+
+	"demo.grpc/perf/client"
+	pb "demo.grpc/perf/pb/agent"
+	"demo.grpc/perf/service/report"
+	"demo.grpc/perf/service/run"
+)
+
+/*
+Distributed perf test: coordinator waits for agents to register, and sends task (configs and scenario) to each
+agent. Agents run workers locally and send matrix data deltas to coordinator, which aggregates them to report and
+stops all agents when failed requests exceed threshold.
+*/
+
+const scenarioFile = "scenario.yml"
+
+// TaskConfigs configs of perf test task which are sent to agents.
+type TaskConfigs struct {
+	Type   string
+	Target client.TargetConfigs
+	Runner run.Configs
+}
+
+// newAgentScenario returns scenario of agent i of n agents, and rps and concurrency of stages are split evenly.
+// Feeder paths are replaced by paths of feeder files sent to agent.
+func newAgentScenario(s *run.Scenario, n, i int) *run.Scenario {
+	ret := &run.Scenario{
+		Name:       s.Name,
+		Stages:     make([]run.Stage, 0, len(s.Stages)),
+		Operations: s.Operations,
+		Feeders:    make([]run.FeederConfigs, 0, len(s.Feeders)),
+	}
+	for _, stage := range s.Stages {
+		stage.RPS = splitInt(stage.RPS, n, i)
+		stage.Concurrency = splitInt(stage.Concurrency, n, i)
+		ret.Stages = append(ret.Stages, stage)
+	}
+	for _, feeder := range s.Feeders {
+		feeder.Path = feederFilePath(feeder)
+		ret.Feeders = append(ret.Feeders, feeder)
+	}
+	return ret
+}
+
+// splitInt returns part i of v split to n parts, and it's at least 1 if v > 0.
+func splitInt(v, n, i int) int {
+	if v == 0 {
+		return 0
+	}
+	ret := v / n
+	if i < v%n {
+		ret++
+	}
+	if ret < 1 {
+		ret = 1
+	}
+	return ret
+}
+
+func feederFilePath(feeder run.FeederConfigs) string {
+	return filepath.Join("feeders", feeder.Name+filepath.Ext(feeder.Path))
+}
+
+// readFeederFiles reads feeder files of scenario, and path of feeder is relative to dir of scenario file.
+func readFeederFiles(s *run.Scenario, scenarioPath string) (map[string][]byte, error) {
+	files := make(map[string][]byte, len(s.Feeders))
+	for _, feeder := range s.Feeders {
+		path := feeder.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(scenarioPath), path)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read file of feeder [%s] error: %v", feeder.Name, err)
+		}
+		files[feederFilePath(feeder)] = b
+	}
+	return files, nil
+}
+
+// writeTaskFiles writes scenario and feeder files of task to dir, and returns path of scenario file.
+func writeTaskFiles(dir string, task *pb.Task) (string, error) {
+	for name, b := range task.Files {
+		// files are kept in dir
+		path := filepath.Join(dir, filepath.Clean("/"+name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(path, b, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	path := filepath.Join(dir, scenarioFile)
+	if err := os.WriteFile(path, task.Scenario, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func marshalScenario(s *run.Scenario) ([]byte, error) {
+	b, err := yaml.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("marshal scenario error: %v", err)
+	}
+	return b, nil
+}
+
+func toMatrixDelta(m *run.MatrixData) *pb.MatrixDelta {
+	delta := &pb.MatrixDelta{
+		Total:      m.Total,
+		Failed:     m.Failed,
+		Errors:     m.Errors,
+		Throughput: make([]*pb.Point, 0, len(m.Throughput)),
+	}
+	if h := m.Histogram; h != nil {
+		delta.Histogram = &pb.Histogram{Counts: h.Counts, Total: h.Total, Sum: h.Sum, Min: h.Min, Max: h.Max}
+	}
+	for sec, point := range m.Throughput {
+		delta.Throughput = append(delta.Throughput, &pb.Point{Time: sec, Requests: point.Requests, Failed: point.Failed})
+	}
+	return delta
+}
+
+func fromMatrixDelta(delta *pb.MatrixDelta) *run.MatrixData {
+	m := run.NewMatrixData()
+	m.Total = delta.Total
+	m.Failed = delta.Failed
+	for code, count := range delta.Errors {
+		m.Errors[code] = count
+	}
+	if h := delta.Histogram; h != nil {
+		m.Histogram = &report.Histogram{Counts: h.Counts, Total: h.Total, Sum: h.Sum, Min: h.Min, Max: h.Max}
+	}
+	for _, point := range delta.Throughput {
+		m.Throughput[point.Time] = report.Point{Time: point.Time, Requests: point.Requests, Failed: point.Failed}
+	}
+	return &m
+}
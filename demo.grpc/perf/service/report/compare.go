@@ -0,0 +1,89 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Compare reports: the diff of each metric is the change percent of current report to base report, and it's a
+regression if the diff is worse than threshold percent.
+*/
+
+// Comparison result of comparing current report to base report.
+type Comparison struct {
+	Threshold float64       `json:"threshold"`
+	Items     []CompareItem `json:"items"`
+}
+
+// CompareItem compared metric.
+type CompareItem struct {
+	Metric     string  `json:"metric"`
+	Base       float64 `json:"base"`
+	Current    float64 `json:"current"`
+	Diff       float64 `json:"diff"`
+	Regression bool    `json:"regression"`
+}
+
+// Compare compares current report to base report, and threshold is percent, i.e. 10 for 10%.
+func Compare(base, current *Report, threshold float64) *Comparison {
+	c := &Comparison{Threshold: threshold}
+	add := func(metric string, baseValue, curValue float64, higherIsBetter bool) {
+		diff := diffPercent(baseValue, curValue)
+		regression := diff > threshold
+		if higherIsBetter {
+			regression = -diff > threshold
+		}
+		c.Items = append(c.Items, CompareItem{
+			Metric:     metric,
+			Base:       baseValue,
+			Current:    curValue,
+			Diff:       diff,
+			Regression: regression,
+		})
+	}
+
+	add("qps", base.QPS, current.QPS, true)
+	add("error_rate(%)", base.ErrorRate, current.ErrorRate, false)
+	add("mean(ms)", base.Latency.Mean, current.Latency.Mean, false)
+	add("p50(ms)", base.Latency.P50, current.Latency.P50, false)
+	add("p90(ms)", base.Latency.P90, current.Latency.P90, false)
+	add("p99(ms)", base.Latency.P99, current.Latency.P99, false)
+	add("p999(ms)", base.Latency.P999, current.Latency.P999, false)
+	return c
+}
+
+// Regressed returns true if any metric is regression.
+func (c *Comparison) Regressed() bool {
+	for _, item := range c.Items {
+		if item.Regression {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns comparison as text table.
+func (c *Comparison) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-16s%12s%12s%10s\n", "metric", "base", "current", "diff")
+	for _, item := range c.Items {
+		flag := ""
+		if item.Regression {
+			flag = "  REGRESSION"
+		}
+		fmt.Fprintf(&b, "%-16s%12.2f%12.2f%9.2f%%%s\n", item.Metric, item.Base, item.Current, item.Diff, flag)
+	}
+	return b.String()
+}
+
+// diffPercent returns change percent from base to current, and it's 100% if base is 0 and current is not.
+func diffPercent(base, current float64) float64 {
+	if base == 0 {
+		if current == 0 {
+			return 0
+		}
+		return 100
+	}
+	return round((current - base) * 100 / base)
+}
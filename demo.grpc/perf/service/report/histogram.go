@@ -0,0 +1,141 @@
+package report
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+/*
+HDR-style latency histogram.
+
+Values (microseconds) < 128 are recorded in linear buckets, and larger values are recorded in log-linear buckets
+(64 sub buckets per power of 2), so the relative error of percentile is < 1/64.
+*/
+
+const (
+	subBucketBits  = 7
+	subBucketCount = 1 << subBucketBits
+	subBucketHalf  = subBucketCount / 2
+)
+
+// Histogram latency histogram which can be merged, and all values are microseconds.
+type Histogram struct {
+	Counts []int64 `json:"counts"`
+	Total  int64   `json:"total"`
+	Sum    int64   `json:"sum"`
+	Min    int64   `json:"min"`
+	Max    int64   `json:"max"`
+}
+
+// NewHistogram returns an empty histogram.
+func NewHistogram() *Histogram {
+	return &Histogram{Counts: []int64{}}
+}
+
+// Record records a latency value.
+func (h *Histogram) Record(d time.Duration) {
+	v := d.Microseconds()
+	if v < 0 {
+		v = 0
+	}
+	idx := bucketIndex(v)
+	h.grow(idx + 1)
+	h.Counts[idx]++
+
+	if h.Total == 0 || v < h.Min {
+		h.Min = v
+	}
+	if v > h.Max {
+		h.Max = v
+	}
+	h.Total++
+	h.Sum += v
+}
+
+// Merge merges values of other histogram into h.
+func (h *Histogram) Merge(other *Histogram) {
+	if other == nil || other.Total == 0 {
+		return
+	}
+	h.grow(len(other.Counts))
+	for i, count := range other.Counts {
+		h.Counts[i] += count
+	}
+
+	if h.Total == 0 || other.Min < h.Min {
+		h.Min = other.Min
+	}
+	if other.Max > h.Max {
+		h.Max = other.Max
+	}
+	h.Total += other.Total
+	h.Sum += other.Sum
+}
+
+// Clone returns a copy of h.
+func (h *Histogram) Clone() *Histogram {
+	ret := *h
+	ret.Counts = append([]int64{}, h.Counts...)
+	return &ret
+}
+
+// Mean returns mean of values.
+func (h *Histogram) Mean() time.Duration {
+	if h.Total == 0 {
+		return 0
+	}
+	return time.Duration(h.Sum/h.Total) * time.Microsecond
+}
+
+// Percentile returns value at percentile p (0-100), i.e. 99.9 for p999.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.Total == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(p / 100 * float64(h.Total)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var count int64
+	for i, c := range h.Counts {
+		count += c
+		if count >= rank {
+			v := bucketValue(i)
+			if v > h.Max {
+				v = h.Max
+			}
+			if v < h.Min {
+				v = h.Min
+			}
+			return time.Duration(v) * time.Microsecond
+		}
+	}
+	return time.Duration(h.Max) * time.Microsecond
+}
+
+func (h *Histogram) grow(size int) {
+	if size > len(h.Counts) {
+		h.Counts = append(h.Counts, make([]int64, size-len(h.Counts))...)
+	}
+}
+
+// bucketIndex returns index of bucket which value is recorded in.
+func bucketIndex(v int64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - subBucketBits
+	return subBucketCount + (shift-1)*subBucketHalf + int(v>>uint(shift)) - subBucketHalf
+}
+
+// bucketValue returns the highest value of bucket.
+func bucketValue(idx int) int64 {
+	if idx < subBucketCount {
+		return int64(idx)
+	}
+	shift := uint((idx-subBucketCount)/subBucketHalf + 1)
+	sub := int64((idx-subBucketCount)%subBucketHalf + subBucketHalf)
+	return (sub+1)<<shift - 1
+}
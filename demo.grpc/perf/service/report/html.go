@@ -0,0 +1,144 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth  = 800
+	chartHeight = 200
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"formatTime": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04:05")
+	},
+	"chartPoints":  chartPoints,
+	"sortedErrors": sortedErrors,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Perf Test Report {{ .Name }}</title>
+<style>
+body { font-family: sans-serif; margin: 24px; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+th, td { border: 1px solid #ccc; padding: 4px 12px; text-align: right; }
+th { background: #f0f0f0; }
+svg { border: 1px solid #ccc; }
+</style>
+</head>
+<body>
+<h2>Perf Test Report {{ .Name }}</h2>
+<p>{{ formatTime .StartTime }} ~ {{ formatTime .EndTime }}</p>
+
+<h3>Summary</h3>
+<table>
+<tr><th>Total</th><th>Failed</th><th>Error Rate(%)</th><th>QPS</th></tr>
+<tr><td>{{ .Total }}</td><td>{{ .Failed }}</td><td>{{ printf "%.2f" .ErrorRate }}</td><td>{{ printf "%.2f" .QPS }}</td></tr>
+</table>
+
+<h3>Latency (ms)</h3>
+<table>
+<tr><th>Min</th><th>Mean</th><th>P50</th><th>P90</th><th>P99</th><th>P999</th><th>Max</th></tr>
+{{- with .Latency }}
+<tr><td>{{ printf "%.2f" .Min }}</td><td>{{ printf "%.2f" .Mean }}</td><td>{{ printf "%.2f" .P50 }}</td><td>{{ printf "%.2f" .P90 }}</td><td>{{ printf "%.2f" .P99 }}</td><td>{{ printf "%.2f" .P999 }}</td><td>{{ printf "%.2f" .Max }}</td></tr>
+{{- end }}
+</table>
+
+{{- if .Errors }}
+<h3>Errors</h3>
+<table>
+<tr><th>Code</th><th>Count</th></tr>
+{{- range sortedErrors .Errors }}
+<tr><td>{{ .Code }}</td><td>{{ .Count }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+
+<h3>Throughput (requests/s)</h3>
+<svg width="{{ .Width }}" height="{{ .Height }}" xmlns="http://www.w3.org/2000/svg">
+<polyline fill="none" stroke="#1f77b4" stroke-width="2" points="{{ chartPoints .Throughput false }}"/>
+<polyline fill="none" stroke="#d62728" stroke-width="2" points="{{ chartPoints .Throughput true }}"/>
+</svg>
+<table>
+<tr><th>Time</th><th>Requests</th><th>Failed</th></tr>
+{{- range .Throughput }}
+<tr><td>{{ .Time }}</td><td>{{ .Requests }}</td><td>{{ .Failed }}</td></tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes report to html summary file.
+func (r *Report) WriteHTML(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data := struct {
+		*Report
+		Width  int
+		Height int
+	}{r, chartWidth, chartHeight}
+	if err := htmlTemplate.Execute(f, data); err != nil {
+		return fmt.Errorf("write html report error: %v", err)
+	}
+	return nil
+}
+
+// chartPoints returns svg polyline points of requests (or failed) count of throughput.
+func chartPoints(points []Point, failed bool) string {
+	if len(points) == 0 {
+		return ""
+	}
+	var max int64 = 1
+	for _, p := range points {
+		if p.Requests > max {
+			max = p.Requests
+		}
+	}
+
+	items := make([]string, 0, len(points))
+	for i, p := range points {
+		value := p.Requests
+		if failed {
+			value = p.Failed
+		}
+		x := 0
+		if len(points) > 1 {
+			x = i * chartWidth / (len(points) - 1)
+		}
+		y := chartHeight - int(value*chartHeight/max)
+		items = append(items, fmt.Sprintf("%d,%d", x, y))
+	}
+	return strings.Join(items, " ")
+}
+
+type errorCount struct {
+	Code  string
+	Count int64
+}
+
+// sortedErrors returns error counts sorted by count desc.
+func sortedErrors(errors map[string]int64) []errorCount {
+	ret := make([]errorCount, 0, len(errors))
+	for code, count := range errors {
+		ret = append(ret, errorCount{code, count})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Count != ret[j].Count {
+			return ret[i].Count > ret[j].Count
+		}
+		return ret[i].Code < ret[j].Code
+	})
+	return ret
+}
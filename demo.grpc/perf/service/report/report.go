@@ -0,0 +1,136 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+/*
+Perf Test Report
+*/
+
+// Report perf test report, and it's written as json and html summary.
+type Report struct {
+	Name      string    `json:"name"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Total     int64     `json:"total"`
+	Failed    int64     `json:"failed"`
+	// QPS finished requests per second
+	QPS float64 `json:"qps"`
+	// ErrorRate percent of failed requests
+	ErrorRate float64 `json:"errorRate"`
+	Latency   Latency `json:"latency"`
+	// Errors failed requests count by error code (http status or grpc code)
+	Errors     map[string]int64 `json:"errors,omitempty"`
+	Throughput []Point          `json:"throughput"`
+	Histogram  *Histogram       `json:"histogram,omitempty"`
+}
+
+// Latency latency stats in milliseconds.
+type Latency struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	P999 float64 `json:"p999"`
+	Max  float64 `json:"max"`
+}
+
+// Point finished requests in the second of throughput.
+type Point struct {
+	Time     int64 `json:"time"`
+	Requests int64 `json:"requests"`
+	Failed   int64 `json:"failed"`
+}
+
+// NewLatency returns latency stats of histogram.
+func NewLatency(h *Histogram) Latency {
+	if h == nil {
+		return Latency{}
+	}
+	return Latency{
+		Min:  toMillis(time.Duration(h.Min) * time.Microsecond),
+		Mean: toMillis(h.Mean()),
+		P50:  toMillis(h.Percentile(50)),
+		P90:  toMillis(h.Percentile(90)),
+		P99:  toMillis(h.Percentile(99)),
+		P999: toMillis(h.Percentile(99.9)),
+		Max:  toMillis(time.Duration(h.Max) * time.Microsecond),
+	}
+}
+
+// NewThroughput returns throughput points sorted by time from points by unix second, and the missing seconds are
+// filled with zero.
+func NewThroughput(series map[int64]Point) []Point {
+	if len(series) == 0 {
+		return []Point{}
+	}
+	secs := make([]int64, 0, len(series))
+	for sec := range series {
+		secs = append(secs, sec)
+	}
+	sort.Slice(secs, func(i, j int) bool {
+		return secs[i] < secs[j]
+	})
+
+	start, end := secs[0], secs[len(secs)-1]
+	points := make([]Point, 0, end-start+1)
+	for sec := start; sec <= end; sec++ {
+		point := series[sec]
+		point.Time = sec
+		points = append(points, point)
+	}
+	return points
+}
+
+// Summarize calculates qps, error rate and latency stats of report.
+func (r *Report) Summarize() {
+	if secs := r.EndTime.Sub(r.StartTime).Seconds(); secs > 0 {
+		r.QPS = round(float64(r.Total) / secs)
+	}
+	if r.Total > 0 {
+		r.ErrorRate = round(float64(r.Failed) * 100 / float64(r.Total))
+	}
+	r.Latency = NewLatency(r.Histogram)
+}
+
+// String returns summary text of report.
+func (r *Report) String() string {
+	return fmt.Sprintf("total=%d, failed=%d, qps=%.2f, avg=%.2fms, p50=%.2fms, p90=%.2fms, p99=%.2fms, p999=%.2fms",
+		r.Total, r.Failed, r.QPS, r.Latency.Mean, r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.P999)
+}
+
+// WriteJSON writes report to json file.
+func (r *Report) WriteJSON(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal report error: %v", err)
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// Load loads report from json file.
+func Load(path string) (*Report, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, fmt.Errorf("unmarshal report [%s] error: %v", path, err)
+	}
+	return r, nil
+}
+
+func toMillis(d time.Duration) float64 {
+	return round(float64(d) / float64(time.Millisecond))
+}
+
+func round(f float64) float64 {
+	return float64(int64(f*1000+0.5)) / 1000
+}
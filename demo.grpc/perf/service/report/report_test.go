@@ -0,0 +1,106 @@
+package report
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistogramBucket(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 129, 255, 256, 1000, 123456, 60 * 1000 * 1000} {
+		value := bucketValue(bucketIndex(v))
+		if value < v {
+			t.Errorf("value %d: bucket value %d is less than value", v, value)
+		}
+		if float64(value-v) > float64(v)/64 {
+			t.Errorf("value %d: bucket value %d exceeds relative error", v, value)
+		}
+	}
+}
+
+func TestHistogramPercentile(t *testing.T) {
+	h1, h2 := NewHistogram(), NewHistogram()
+	for i := 1; i <= 1000; i++ {
+		h := h1
+		if i%2 == 0 {
+			h = h2
+		}
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	h1.Merge(h2)
+
+	if h1.Total != 1000 || h1.Min != 1000 || h1.Max != 1000*1000 {
+		t.Fatalf("unexpected merged histogram: total=%d, min=%d, max=%d", h1.Total, h1.Min, h1.Max)
+	}
+	for _, tc := range []struct {
+		p    float64
+		want time.Duration
+	}{
+		{50, 500 * time.Millisecond},
+		{90, 900 * time.Millisecond},
+		{99, 990 * time.Millisecond},
+		{99.9, 999 * time.Millisecond},
+		{100, 1000 * time.Millisecond},
+	} {
+		got := h1.Percentile(tc.p)
+		if math.Abs(float64(got-tc.want)) > float64(tc.want)/64 {
+			t.Errorf("p%v: want %v, got %v", tc.p, tc.want, got)
+		}
+	}
+	if mean := h1.Mean(); mean != 500500*time.Microsecond {
+		t.Errorf("want mean 500.5ms, got %v", mean)
+	}
+}
+
+func TestReportCompare(t *testing.T) {
+	newReport := func(rt time.Duration, failed int64) *Report {
+		h := NewHistogram()
+		for i := 0; i < 100; i++ {
+			h.Record(rt)
+		}
+		now := time.Now()
+		r := &Report{
+			StartTime:  now.Add(-10 * time.Second),
+			EndTime:    now,
+			Total:      100,
+			Failed:     failed,
+			Errors:     map[string]int64{"Unavailable": failed},
+			Throughput: NewThroughput(map[int64]Point{now.Unix() - 2: {Requests: 60}, now.Unix(): {Requests: 40}}),
+			Histogram:  h,
+		}
+		r.Summarize()
+		return r
+	}
+
+	base := newReport(10*time.Millisecond, 0)
+	if base.QPS != 10 || base.Latency.P99 != 10 || len(base.Throughput) != 3 {
+		t.Fatalf("unexpected report: %+v", base)
+	}
+
+	dir := t.TempDir()
+	if err := base.WriteHTML(filepath.Join(dir, "base.html")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "base.json")
+	if err := base.WriteJSON(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := Compare(loaded, newReport(10500*time.Microsecond, 0), 10); c.Regressed() {
+		t.Errorf("want no regression:\n%s", c)
+	}
+	c := Compare(loaded, newReport(20*time.Millisecond, 1), 10)
+	if !c.Regressed() {
+		t.Errorf("want regression:\n%s", c)
+	}
+	for _, item := range c.Items {
+		if item.Metric == "qps" && item.Regression {
+			t.Errorf("qps is not regression:\n%s", c)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// FeederConfigs data feeder of scenario, and records are loaded from csv (with header) or jsonl file.
+type FeederConfigs struct {
+	Name   string `yaml:"name"`
+	Path   string `yaml:"path"`
+	Random bool   `yaml:"random"`
+}
+
+// feeder provides records in order (circular) or randomly, and it's thread safe.
+type feeder struct {
+	name    string
+	records []map[string]interface{}
+	random  bool
+	idx     uint64
+}
+
+func newFeeder(dir string, configs FeederConfigs) (*feeder, error) {
+	if len(configs.Name) == 0 {
+		return nil, fmt.Errorf("name of feeder is empty")
+	}
+	path := configs.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	var (
+		records []map[string]interface{}
+		err     error
+	)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		records, err = loadCsvRecords(path)
+	case ".jsonl", ".json":
+		records, err = loadJsonlRecords(path)
+	default:
+		return nil, fmt.Errorf("feeder [%s]: invalid file type: %s", configs.Name, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("feeder [%s]: %v", configs.Name, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("feeder [%s]: no records in %s", configs.Name, path)
+	}
+
+	return &feeder{
+		name:    configs.Name,
+		records: records,
+		random:  configs.Random,
+	}, nil
+}
+
+func (f *feeder) next() map[string]interface{} {
+	if f.random {
+		return f.records[rand.Intn(len(f.records))]
+	}
+	idx := atomic.AddUint64(&f.idx, 1) - 1
+	return f.records[idx%uint64(len(f.records))]
+}
+
+// loadCsvRecords loads records of csv file, and the first line is header of field names.
+func loadCsvRecords(path string) ([]map[string]interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv error: %v", err)
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	header := lines[0]
+	records := make([]map[string]interface{}, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		record := make(map[string]interface{}, len(header))
+		for i, name := range header {
+			record[strings.TrimSpace(name)] = line[i]
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// loadJsonlRecords loads records of json lines file, and each line is a json object.
+func loadJsonlRecords(path string) ([]map[string]interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records := make([]map[string]interface{}, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 8*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		record := make(map[string]interface{})
+		if err := decoder.Decode(&record); err != nil {
+			return nil, fmt.Errorf("decode json of line %d error: %v", lineNo, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -3,17 +3,17 @@ package run
 import (
 	"context"
 	"fmt"
-	"math"
-	"sort"
-	"strconv"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
 
 	"demo.grpc/perf/client"
+	"demo.grpc/perf/service/report"
 	"demo.grpc/perf/service/utils"
 )
 
@@ -21,6 +21,9 @@ import (
 Perf Test Runner
 */
 
+// stageUpdateInterval interval to update rate limit of ramp stage
+const stageUpdateInterval = 100 * time.Millisecond
+
 // Configs perf test runner configs.
 type Configs struct {
 	Parallel        int
@@ -29,22 +32,113 @@ type Configs struct {
 	SyncInterval    int
 	OutInterval     int
 	FailedThreshold int32
-	ReportPath      string `json:"ReportPath,omitempty"`
+	// Operation operation of connection to run: get, create or update, and default get
+	Operation string `json:"Operation,omitempty"`
+	// ReportPath path of report files without extension, and report is written to .json and .html files
+	ReportPath string `json:"ReportPath,omitempty"`
+	// Scenario path of scenario file, and parallel, runTime, limit and operation are not used if it's set
+	Scenario string `json:"Scenario,omitempty"`
+}
+
+// GetSyncInterval returns interval to sync matrix data of workers, and default 1s.
+func (c *Configs) GetSyncInterval() time.Duration {
+	return getInterval(c.SyncInterval)
+}
+
+// GetOutInterval returns interval to output report, and default 1s.
+func (c *Configs) GetOutInterval() time.Duration {
+	return getInterval(c.OutInterval)
+}
+
+func getInterval(secs int) time.Duration {
+	if secs <= 0 {
+		return time.Second
+	}
+	return time.Duration(secs) * time.Second
 }
 
 // MatrixData perf test report matrix data.
 type MatrixData struct {
-	Total   int32
-	Failed  int32
-	Records []string
+	Total  int32
+	Failed int32
+	// Histogram latency histogram of requests
+	Histogram *report.Histogram
+	// Errors failed requests count by error code
+	Errors map[string]int32
+	// Throughput finished requests by unix second
+	Throughput map[int64]report.Point
+}
+
+// NewMatrixData returns an empty matrix data.
+func NewMatrixData() MatrixData {
+	return MatrixData{
+		Histogram:  report.NewHistogram(),
+		Errors:     map[string]int32{},
+		Throughput: map[int64]report.Point{},
+	}
+}
+
+// Record records response time and error of a request which is finished at end.
+func (m *MatrixData) Record(end time.Time, rt time.Duration, err error) {
+	m.Total++
+	m.Histogram.Record(rt)
+	point := m.Throughput[end.Unix()]
+	point.Requests++
+	if err != nil {
+		m.Failed++
+		m.Errors[client.ErrorCode(err)]++
+		point.Failed++
+	}
+	m.Throughput[end.Unix()] = point
+}
+
+// Merge merges other matrix data into m.
+func (m *MatrixData) Merge(other *MatrixData) {
+	m.Total += other.Total
+	m.Failed += other.Failed
+	m.Histogram.Merge(other.Histogram)
+	for code, count := range other.Errors {
+		m.Errors[code] += count
+	}
+	for sec, point := range other.Throughput {
+		p := m.Throughput[sec]
+		p.Requests += point.Requests
+		p.Failed += point.Failed
+		m.Throughput[sec] = p
+	}
+}
+
+// Report returns perf test report of matrix data from start to end.
+func (m *MatrixData) Report(name string, start, end time.Time) *report.Report {
+	rpt := &report.Report{
+		Name:       name,
+		StartTime:  start,
+		EndTime:    end,
+		Total:      int64(m.Total),
+		Failed:     int64(m.Failed),
+		Errors:     make(map[string]int64, len(m.Errors)),
+		Throughput: report.NewThroughput(m.Throughput),
+		Histogram:  m.Histogram.Clone(),
+	}
+	for code, count := range m.Errors {
+		rpt.Errors[code] = int64(count)
+	}
+	rpt.Summarize()
+	return rpt
 }
 
 // Runner runs api perf test cases parallel.
 type Runner struct {
-	Matrix  MatrixData
-	configs *Configs
-	connect client.Connection
-	locker  *sync.Mutex
+	Matrix    MatrixData
+	configs   *Configs
+	connect   client.Connection
+	locker    *sync.Mutex
+	startTime time.Time
+	scenario  *Scenario
+	// stage index of current stage, and concurrency of current stage
+	stage       int32
+	concurrency int32
+	syncHandler func(data *MatrixData)
 }
 
 // NewRunner returns a perf runner instance.
@@ -56,18 +150,41 @@ func NewRunner(conn client.Connection, configs *Configs) *Runner {
 	}
 }
 
+// OnSync sets handler which is called with matrix data synced by workers, and data should not be retained.
+func (r *Runner) OnSync(handler func(data *MatrixData)) {
+	r.syncHandler = handler
+}
+
 // Run runs perf test by multiple workers.
 func (r *Runner) Run() error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.configs.RunTime)*time.Second)
+	return r.RunContext(context.Background())
+}
+
+// RunContext runs perf test by multiple workers until scenario is finished or ctx is cancelled.
+func (r *Runner) RunContext(ctx context.Context) error {
+	scenario, err := r.getScenario()
+	if err != nil {
+		return err
+	}
+	r.scenario = scenario
+
+	ctx, cancel := context.WithTimeout(ctx, scenario.duration())
 	defer cancel()
 
+	r.startTime = time.Now()
+	r.Matrix = NewMatrixData()
+	r.stage = -1
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	r.updateStage(limiter, 0)
+	go r.runStages(ctx, limiter)
+
 	go func() {
-		tick := time.Tick(time.Duration(r.configs.SyncInterval) * time.Second)
+		tick := time.Tick(r.configs.GetSyncInterval())
 		for {
 			select {
 			case <-tick:
-				if r.Matrix.Failed >= r.configs.FailedThreshold {
-					log.Errorf("Failed cases %d, exceed threshold %d.", r.Matrix.Failed, r.configs.FailedThreshold)
+				if failed := r.failed(); failed >= r.configs.FailedThreshold {
+					log.Errorf("Failed cases %d, exceed threshold %d.", failed, r.configs.FailedThreshold)
 					cancel()
 					return
 				}
@@ -78,7 +195,7 @@ func (r *Runner) Run() error {
 	}()
 
 	go func() {
-		tick := time.Tick(time.Duration(r.configs.OutInterval) * time.Second)
+		tick := time.Tick(r.configs.GetOutInterval())
 		for {
 			select {
 			case <-tick:
@@ -91,12 +208,8 @@ func (r *Runner) Run() error {
 		}
 	}()
 
-	const recordTitle = "time\t\t\t\trt(ms)"
-	r.Matrix.Records = []string{recordTitle}
-
-	limiter := rate.NewLimiter(rate.Limit(r.configs.Limit), r.configs.Limit)
 	wg := sync.WaitGroup{}
-	for i := 0; i < r.configs.Parallel; i++ {
+	for i := 0; i < scenario.maxConcurrency(); i++ {
 		wg.Add(1)
 		w := worker{
 			id:     i,
@@ -110,64 +223,112 @@ func (r *Runner) Run() error {
 	return r.reportHandler()
 }
 
-// appendTS workers sync matrix rt data.
-func (r *Runner) appendTS(records []string) {
-	r.locker.Lock()
-	defer r.locker.Unlock()
-	r.Matrix.Records = append(r.Matrix.Records, records...)
+// getScenario loads scenario file in configs, or returns scenario of one stage by configs.
+func (r *Runner) getScenario() (*Scenario, error) {
+	scenario, err := NewScenario(r.configs)
+	if err != nil {
+		return nil, err
+	}
+
+	_, isCaller := r.connect.(client.Caller)
+	for _, op := range scenario.Operations {
+		switch op.Name {
+		case client.OperationGet, client.OperationCreate, client.OperationUpdate:
+		default:
+			if !isCaller {
+				return nil, fmt.Errorf("operation [%s] is not supported by connection", op.Name)
+			}
+		}
+	}
+	return scenario, nil
 }
 
-func (r *Runner) getReportPath() string {
-	if len(r.configs.ReportPath) == 0 {
-		r.configs.ReportPath = fmt.Sprintf("perf_test_%s.log", utils.TimeFormatWithUnderline(time.Now()))
+// runStages updates rate limit and concurrency of workers by stages of scenario.
+func (r *Runner) runStages(ctx context.Context, limiter *rate.Limiter) {
+	tick := time.NewTicker(stageUpdateInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			r.updateStage(limiter, time.Since(r.startTime))
+		case <-ctx.Done():
+			return
+		}
 	}
-	return r.configs.ReportPath
 }
 
-func (r *Runner) reportHandler() error {
-	log.Debug("Output runner matrix data and print summary.")
-	if err := r.writeMatrix(); err != nil {
-		return err
+func (r *Runner) updateStage(limiter *rate.Limiter, elapsed time.Duration) {
+	idx, limit := r.scenario.at(elapsed)
+	if limiter.Limit() != limit {
+		burst := 1
+		if limit != rate.Inf && int(limit) > 1 {
+			burst = int(limit)
+		}
+		limiter.SetLimit(limit)
+		limiter.SetBurst(burst)
+	}
+
+	if atomic.LoadInt32(&r.stage) != int32(idx) {
+		stage := r.scenario.Stages[idx]
+		log.Infof("Stage [%s] start: duration=%ds, rps=%d, concurrency=%d.", stage.Name, stage.Duration, stage.RPS,
+			stage.Concurrency)
+		atomic.StoreInt32(&r.concurrency, int32(stage.Concurrency))
+		atomic.StoreInt32(&r.stage, int32(idx))
 	}
-	return r.printSummary()
 }
 
-func (r *Runner) writeMatrix() error {
+// Report returns perf test report of synced matrix data.
+func (r *Runner) Report() *report.Report {
 	r.locker.Lock()
 	defer r.locker.Unlock()
-
-	_, err := utils.AppendTextToFile(r.getReportPath(), strings.Join(r.Matrix.Records, "\n"))
-	r.Matrix.Records = []string{""}
-	return err
+	return r.Matrix.Report(filepath.Base(r.getReportPath()), r.startTime, time.Now())
 }
 
-func (r *Runner) printSummary() error {
-	lines, err := utils.ReadFileLines(r.getReportPath())
-	if err != nil {
-		return err
+// mergeMatrix workers sync matrix data.
+func (r *Runner) mergeMatrix(data *MatrixData) {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	r.Matrix.Merge(data)
+	if r.syncHandler != nil {
+		r.syncHandler(data)
 	}
+}
 
-	sum := 0
-	rts := make([]int, 0, len(lines)-1)
-	for _, line := range lines[1:] {
-		tmp := strings.Split(line, "\t")[1]
-		rt, err := strconv.Atoi(strings.TrimRight(tmp, "\n"))
-		if err != nil {
-			return err
-		}
-		rts = append(rts, rt)
-		sum += rt
+func (r *Runner) failed() int32 {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	return r.Matrix.Failed
+}
+
+func (r *Runner) getReportPath() string {
+	return GetReportPath(r.configs, r.startTime)
+}
+
+// GetReportPath returns report path (without extension) of configs, and it's set to perf_test_<start time> if empty.
+func GetReportPath(configs *Configs, start time.Time) string {
+	if len(configs.ReportPath) == 0 {
+		configs.ReportPath = fmt.Sprintf("perf_test_%s", utils.TimeFormatWithUnderline(start))
 	}
+	return strings.TrimSuffix(configs.ReportPath, filepath.Ext(configs.ReportPath))
+}
 
-	avg := float32(sum) / float32(len(rts))
+func (r *Runner) reportHandler() error {
+	log.Debug("Output runner report and print summary.")
+	return WriteReport(r.Report(), r.getReportPath())
+}
 
-	sort.Slice(rts, func(i, j int) bool {
-		return rts[i] < rts[j]
-	})
-	log.Debugf("Sorted rts: %v", rts)
-	line90 := rts[int(math.Round(float64(len(rts))*0.9)-1)]
-	line99 := rts[int(math.Round(float64(len(rts))*0.99)-1)]
+// WriteReport writes report to json and html files of path (without extension), and prints summary.
+func WriteReport(rpt *report.Report, path string) error {
+	if err := rpt.WriteJSON(path + ".json"); err != nil {
+		return err
+	}
+	if err := rpt.WriteHTML(path + ".html"); err != nil {
+		return err
+	}
 
-	log.Infof("Summary: total=%d, failed=%d, avg=%.2fms, line90=%d, line99=%d", r.Matrix.Total, r.Matrix.Failed, avg, line90, line99)
+	log.Infof("Summary: %s", rpt)
+	if len(rpt.Errors) > 0 {
+		log.Infof("Errors: %v", rpt.Errors)
+	}
 	return nil
 }
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
 	"demo.grpc/perf/client"
+	"demo.grpc/perf/service/report"
 )
 
 func TestTimer(t *testing.T) {
@@ -97,6 +99,7 @@ func TestPerfRun(t *testing.T) {
 		SyncInterval:    5,
 		OutInterval:     10,
 		FailedThreshold: 10,
+		ReportPath:      filepath.Join(t.TempDir(), "perf_test"),
 	}
 
 	runner := Runner{
@@ -109,4 +112,13 @@ func TestPerfRun(t *testing.T) {
 		t.Fatal(err)
 	}
 	t.Logf("mock api invoked: total=%d, failed=%d\n", conn.Total, conn.Failed)
+
+	rpt, err := report.Load(configs.ReportPath + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rpt.Total == 0 || rpt.Histogram.Total != rpt.Total || rpt.Errors["Error"] != rpt.Failed {
+		t.Errorf("invalid report: total=%d, failed=%d, errors=%v", rpt.Total, rpt.Failed, rpt.Errors)
+	}
+	t.Logf("report summary: %s", rpt)
 }
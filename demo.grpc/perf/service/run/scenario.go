@@ -0,0 +1,225 @@
+package run
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+	"gopkg.in/yaml.v2"
+
+	"demo.grpc/perf/client"
+)
+
+/*
+Perf test scenario file:
+
+name: "greeter"
+stages:
+  - name: "ramp-up"
+    duration: 10 # seconds
+    rps: 100 # target rps, and 0 for no rate limit (driven by concurrency only)
+    concurrency: 4 # workers
+    ramp: true # rps ramps linearly from the rps of previous stage (or 0) to target
+  - name: "steady"
+    duration: 30
+    rps: 100
+    concurrency: 4
+operations: # operations run by weight
+  - name: "get"
+    weight: 8
+  - name: "sayHello" # any operation declared in target operations
+    weight: 2
+feeders: # records of feeders are used in operation templates, i.e. {{ .Data.users.name }}
+  - name: "users"
+    path: "data/users.csv" # csv with header, or jsonl
+    random: false # records are read in order (circular), or randomly
+*/
+
+// Scenario perf test scenario which runs by stages.
+type Scenario struct {
+	Name       string              `yaml:"name"`
+	Stages     []Stage             `yaml:"stages"`
+	Operations []WeightedOperation `yaml:"operations"`
+	Feeders    []FeederConfigs     `yaml:"feeders"`
+
+	feeders     []*feeder
+	totalWeight int
+}
+
+// Stage stage of scenario, with target rps or concurrency.
+type Stage struct {
+	Name        string `yaml:"name"`
+	Duration    int    `yaml:"duration"`
+	RPS         int    `yaml:"rps"`
+	Concurrency int    `yaml:"concurrency"`
+	Ramp        bool   `yaml:"ramp"`
+}
+
+// WeightedOperation operation of connection with weight.
+type WeightedOperation struct {
+	Name   string `yaml:"name"`
+	Weight int    `yaml:"weight"`
+}
+
+// LoadScenario loads scenario from yaml (or json) file, and feeders are loaded relative to dir of the file.
+func LoadScenario(path string) (*Scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Scenario{}
+	if err := yaml.UnmarshalStrict(b, s); err != nil {
+		return nil, fmt.Errorf("unmarshal scenario [%s] error: %v", path, err)
+	}
+	if err := s.init(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("invalid scenario [%s]: %v", path, err)
+	}
+	return s, nil
+}
+
+// NewScenario loads scenario file of runner configs, or returns scenario of one stage by runner configs if it's not
+// set.
+func NewScenario(configs *Configs) (*Scenario, error) {
+	if len(configs.Scenario) > 0 {
+		return LoadScenario(configs.Scenario)
+	}
+	return newDefaultScenario(configs), nil
+}
+
+// newDefaultScenario returns scenario of one stage by runner configs.
+func newDefaultScenario(configs *Configs) *Scenario {
+	operation := configs.Operation
+	if len(operation) == 0 {
+		operation = client.OperationGet
+	}
+	s := &Scenario{
+		Name: "default",
+		Stages: []Stage{
+			{
+				Name:        "default",
+				Duration:    configs.RunTime,
+				RPS:         configs.Limit,
+				Concurrency: configs.Parallel,
+			},
+		},
+		Operations: []WeightedOperation{{Name: operation, Weight: 1}},
+	}
+	s.totalWeight = 1
+	return s
+}
+
+func (s *Scenario) init(dir string) error {
+	if len(s.Stages) == 0 {
+		return fmt.Errorf("no stages")
+	}
+	for i, stage := range s.Stages {
+		if stage.Duration <= 0 || stage.RPS < 0 || stage.Concurrency <= 0 {
+			return fmt.Errorf("stage [%d]: duration and concurrency should be > 0, and rps should be >= 0", i)
+		}
+	}
+
+	if len(s.Operations) == 0 {
+		return fmt.Errorf("no operations")
+	}
+	for i, op := range s.Operations {
+		if len(op.Name) == 0 || op.Weight <= 0 {
+			return fmt.Errorf("operation [%d]: name is empty or weight <= 0", i)
+		}
+		s.Operations[i].Name = strings.ToLower(op.Name)
+		s.totalWeight += op.Weight
+	}
+
+	names := make(map[string]struct{}, len(s.Feeders))
+	for _, cfg := range s.Feeders {
+		if _, ok := names[cfg.Name]; ok {
+			return fmt.Errorf("duplicated feeder: %s", cfg.Name)
+		}
+		names[cfg.Name] = struct{}{}
+		f, err := newFeeder(dir, cfg)
+		if err != nil {
+			return err
+		}
+		s.feeders = append(s.feeders, f)
+	}
+	return nil
+}
+
+// duration returns total duration of stages.
+func (s *Scenario) duration() time.Duration {
+	var secs int
+	for _, stage := range s.Stages {
+		secs += stage.Duration
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// maxConcurrency returns max concurrency of stages, and it's the number of workers.
+func (s *Scenario) maxConcurrency() int {
+	ret := 0
+	for _, stage := range s.Stages {
+		if stage.Concurrency > ret {
+			ret = stage.Concurrency
+		}
+	}
+	return ret
+}
+
+// at returns index of stage and rate limit at elapsed time of scenario, and the last stage is returned if elapsed
+// exceeds duration of scenario.
+func (s *Scenario) at(elapsed time.Duration) (int, rate.Limit) {
+	idx, start := 0, time.Duration(0)
+	for ; idx < len(s.Stages)-1; idx++ {
+		end := start + time.Duration(s.Stages[idx].Duration)*time.Second
+		if elapsed < end {
+			break
+		}
+		start = end
+	}
+
+	stage := s.Stages[idx]
+	if stage.RPS == 0 {
+		return idx, rate.Inf
+	}
+	if !stage.Ramp {
+		return idx, rate.Limit(stage.RPS)
+	}
+
+	from := 0
+	if idx > 0 {
+		from = s.Stages[idx-1].RPS
+	}
+	progress := math.Min(float64(elapsed-start)/float64(time.Duration(stage.Duration)*time.Second), 1)
+	return idx, rate.Limit(float64(from) + float64(stage.RPS-from)*progress)
+}
+
+// nextOperation returns operation by weight randomly.
+func (s *Scenario) nextOperation() string {
+	if len(s.Operations) == 1 {
+		return s.Operations[0].Name
+	}
+	n := rand.Intn(s.totalWeight)
+	for _, op := range s.Operations {
+		if n < op.Weight {
+			return op.Name
+		}
+		n -= op.Weight
+	}
+	return s.Operations[len(s.Operations)-1].Name
+}
+
+// nextData returns next records of feeders.
+func (s *Scenario) nextData() map[string]interface{} {
+	if len(s.feeders) == 0 {
+		return nil
+	}
+	data := make(map[string]interface{}, len(s.feeders))
+	for _, f := range s.feeders {
+		data[f.name] = f.next()
+	}
+	return data
+}
@@ -0,0 +1,137 @@
+package run
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"demo.grpc/perf/client"
+)
+
+func TestLoadScenario(t *testing.T) {
+	s, err := LoadScenario("../../scenarios/greeter.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.duration() != 45*time.Second || s.maxConcurrency() != 8 || s.totalWeight != 10 {
+		t.Fatalf("unexpected scenario: duration=%v, concurrency=%d, weight=%d", s.duration(), s.maxConcurrency(),
+			s.totalWeight)
+	}
+	if op := s.Operations[1].Name; op != "sayhellotouser" {
+		t.Errorf("want lower case operation name, got %s", op)
+	}
+
+	// users are fed in order, and deposits randomly
+	for i, want := range []string{"alice", "bob", "carol", "alice"} {
+		data := s.nextData()
+		user := data["users"].(map[string]interface{})
+		if user["name"] != want {
+			t.Errorf("record %d: want user %s, got %v", i, want, user["name"])
+		}
+		if _, ok := data["deposits"].(map[string]interface{})["amount"]; !ok {
+			t.Errorf("record %d: want deposit amount, got %v", i, data["deposits"])
+		}
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		counts[s.nextOperation()]++
+	}
+	if counts["get"] < 500 || counts["get"] > 700 || counts["deposit"] == 0 {
+		t.Errorf("unexpected operations mix: %v", counts)
+	}
+}
+
+func TestLoadInvalidScenario(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"no_stages.yml":      "operations: [{name: get, weight: 1}]",
+		"invalid_stage.yml":  "stages: [{duration: 0, concurrency: 1}]\noperations: [{name: get, weight: 1}]",
+		"unknown_field.yml":  "stages: [{duration: 1, concurrency: 1, qps: 10}]\noperations: [{name: get, weight: 1}]",
+		"invalid_feeder.yml": "stages: [{duration: 1, concurrency: 1}]\noperations: [{name: get, weight: 1}]\nfeeders: [{name: a, path: a.txt}]",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadScenario(path); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
+
+func TestScenarioAt(t *testing.T) {
+	s := &Scenario{
+		Stages: []Stage{
+			{Name: "ramp-up", Duration: 10, RPS: 100, Concurrency: 2, Ramp: true},
+			{Name: "steady", Duration: 10, RPS: 100, Concurrency: 2},
+			{Name: "unlimited", Duration: 5, Concurrency: 4},
+			{Name: "ramp-down", Duration: 10, RPS: 10, Concurrency: 2, Ramp: true},
+		},
+	}
+	for _, tc := range []struct {
+		elapsed time.Duration
+		idx     int
+		limit   rate.Limit
+	}{
+		{0, 0, 0},
+		{5 * time.Second, 0, 50},
+		{12 * time.Second, 1, 100},
+		{20 * time.Second, 2, rate.Inf},
+		{30 * time.Second, 3, 5},
+		{time.Minute, 3, 10},
+	} {
+		idx, limit := s.at(tc.elapsed)
+		if idx != tc.idx || limit != tc.limit {
+			t.Errorf("at %v: want stage %d limit %v, got stage %d limit %v", tc.elapsed, tc.idx, tc.limit, idx, limit)
+		}
+	}
+}
+
+func TestScenarioRun(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scenario.yml")
+	text := `
+stages:
+  - {name: "ramp-up", duration: 2, rps: 50, concurrency: 2, ramp: true}
+  - {name: "steady", duration: 2, rps: 50, concurrency: 4}
+operations:
+  - {name: "get", weight: 1}
+  - {name: "update", weight: 1}
+`
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conn := &client.MockConnect{Sleep: 10}
+	runner := Runner{
+		locker:  &sync.Mutex{},
+		connect: conn,
+		configs: &Configs{
+			SyncInterval:    1,
+			OutInterval:     5,
+			FailedThreshold: 10,
+			ReportPath:      filepath.Join(dir, "perf_test"),
+			Scenario:        path,
+		},
+	}
+	if err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	// 50 (ramp-up) + 100 (steady) requests, and burst of limiter
+	if total := runner.Matrix.Total; total < 100 || total > 200 {
+		t.Errorf("want about 150 requests, got %d", total)
+	}
+
+	// operations except get, create and update are not supported by mock connection
+	if err := os.WriteFile(path, []byte("stages: [{duration: 1, concurrency: 1}]\noperations: [{name: sayHello, weight: 1}]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.Run(); err == nil {
+		t.Error("want error for unsupported operation")
+	}
+}
@@ -10,13 +10,16 @@ import (
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
 
-	"demo.grpc/perf/service/utils"
+	"demo.grpc/perf/client"
 )
 
 /*
 Perf Test Worker
 */
 
+// maxLimiterWait max time to wait for rate limiter before checking stage again
+const maxLimiterWait = 100 * time.Millisecond
+
 type worker struct {
 	id     int
 	ctx    context.Context
@@ -31,56 +34,102 @@ func (w *worker) exec(wg *sync.WaitGroup, limiter *rate.Limiter) {
 	}()
 
 	log.Debugf("[%d]: Worker start.", w.id)
-	w.matrix.Records = []string{}
-
-	go func() {
-		tick := time.Tick(time.Duration(w.runner.configs.SyncInterval) * time.Second)
-		for {
-			select {
-			case <-tick:
-				w.syncMatrixData()
-			case <-w.ctx.Done():
-				return
-			}
-		}
-	}()
+	w.matrix = NewMatrixData()
 
+	// matrix data is synced in loop of worker, as it's not thread safe
+	tick := time.NewTicker(w.runner.configs.GetSyncInterval())
+	defer tick.Stop()
+
+	var seq int64
 	for {
 		select {
 		case <-w.ctx.Done():
 			log.Infof("[%d]: Worker cancelled.", w.id)
 			return
+		case <-tick.C:
+			w.syncMatrixData()
 		default:
 		}
 
-		if err := limiter.Wait(w.ctx); err != nil {
-			log.Warn(err)
-			return
+		if !w.isActive() {
+			w.sleep(maxLimiterWait)
+			continue
+		}
+		if !w.wait(limiter) {
+			continue
 		}
 		start := time.Now()
-		results, err := w.runner.connect.Get("data") // api test
-		w.matrix.Total++
+		seq++
+		scenario := w.runner.scenario
+		input := client.RequestData{Worker: w.id, Seq: seq, Time: start, Data: scenario.nextData()}
+		results, err := w.call(scenario.nextOperation(), input) // api test
 		if err != nil {
-			w.matrix.Failed++
 			log.Error(err)
 		}
 		log.Debug("Response result: " + results)
 
 		now := time.Now()
-		record := fmt.Sprintf("%s\t%d", utils.TimeFormat(now), (now.Sub(start).Milliseconds()))
-		w.matrix.Records = append(w.matrix.Records, record)
+		w.matrix.Record(now, now.Sub(start), err)
 	}
 }
 
-func (w *worker) syncMatrixData() {
-	// log.Debugf("[%d]: Sync worker matrix data: %+v", w.id, w.matrix)
-	log.Infof("[%d]: Sync worker matrix data: total=%d, length=%d", w.id, w.matrix.Total, len(w.matrix.Records))
+// isActive returns true if worker is active in concurrency of current stage.
+func (w *worker) isActive() bool {
+	return int32(w.id) < atomic.LoadInt32(&w.runner.concurrency)
+}
 
-	atomic.AddInt32(&w.runner.Matrix.Total, w.matrix.Total)
-	atomic.AddInt32(&w.runner.Matrix.Failed, w.matrix.Failed)
-	w.runner.appendTS(w.matrix.Records)
+// wait waits for rate limiter, and returns false if it's not reserved in maxLimiterWait, as rate limit may be
+// changed by stages of scenario.
+func (w *worker) wait(limiter *rate.Limiter) bool {
+	r := limiter.Reserve()
+	delay := r.Delay()
+	if !r.OK() || delay > maxLimiterWait {
+		r.Cancel()
+		w.sleep(maxLimiterWait)
+		return false
+	}
+	if !w.sleep(delay) {
+		r.Cancel()
+		return false
+	}
+	return true
+}
 
-	w.matrix = MatrixData{
-		Records: []string{},
+// sleep returns false if worker is cancelled while sleeping.
+func (w *worker) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-w.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
 	}
 }
+
+// call runs operation of connection.
+func (w *worker) call(operation string, input client.RequestData) (string, error) {
+	conn := w.runner.connect
+	switch operation {
+	case client.OperationGet:
+		return conn.Get(input)
+	case client.OperationCreate:
+		return conn.Create(input)
+	case client.OperationUpdate:
+		return conn.Update(input)
+	default:
+		if caller, ok := conn.(client.Caller); ok {
+			return caller.Call(operation, input)
+		}
+		return "", fmt.Errorf("operation [%s] is not supported by connection", operation)
+	}
+}
+
+func (w *worker) syncMatrixData() {
+	log.Infof("[%d]: Sync worker matrix data: total=%d, failed=%d", w.id, w.matrix.Total, w.matrix.Failed)
+	w.runner.mergeMatrix(&w.matrix)
+	w.matrix = NewMatrixData()
+}
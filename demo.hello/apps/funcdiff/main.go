@@ -1,8 +1,12 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"os"
+
+	"demo.hello/apps/funcdiff/pkg"
 )
 
 var (
@@ -11,7 +15,20 @@ var (
 	dstPath string
 )
 
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "merge" {
+		if err := runMerge(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "merge error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	flag.BoolVar(&help, "h", false, "help.")
 	flag.StringVar(&srcPath, "s", "", "source: go file path to diff.")
 	flag.StringVar(&dstPath, "d", "", "target: go file path to diff.")
@@ -19,8 +36,61 @@ func main() {
 	flag.Parse()
 	if help {
 		flag.Usage()
+		fmt.Println("\nSub commands:\n  merge\tmerge .cov files of src and dst commits, run \"funcdiff merge -h\" for usage.")
 		return
 	}
 
 	fmt.Println("Func Diff")
 }
+
+// runMerge runs: funcdiff merge --repo path --src-commit c1 --dst-commit c2 --src-cov src.cov --dst-cov dst.cov -o merged.cov
+func runMerge(args []string) error {
+	var (
+		opts   pkg.CovMergeOptions
+		output string
+	)
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	fs.StringVar(&opts.RepoPath, "repo", ".", "git repo path.")
+	fs.StringVar(&opts.Branch, "branch", "", "pull branch of repo before merge, skip pull if empty.")
+	fs.StringVar(&opts.SrcCommit, "src-commit", "", "commit of src .cov file.")
+	fs.StringVar(&opts.DstCommit, "dst-commit", "", "commit of dst .cov file.")
+	fs.StringVar(&opts.SrcCovPath, "src-cov", "", "src .cov file path.")
+	fs.StringVar(&opts.DstCovPath, "dst-cov", "", "dst .cov file path.")
+	fs.StringVar(&opts.OutPath, "o", "merged.cov", "output merged .cov file path.")
+	fs.StringVar(&opts.Strategy, "strategy", pkg.MergeStrategyFunc, "merge strategy of changed files: func or line.")
+	fs.StringVar(&output, "output", outputTable, "summary output format: table or json.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	for _, item := range [][2]string{
+		{"src-commit", opts.SrcCommit},
+		{"dst-commit", opts.DstCommit},
+		{"src-cov", opts.SrcCovPath},
+		{"dst-cov", opts.DstCovPath},
+	} {
+		if len(item[1]) == 0 {
+			fs.Usage()
+			return fmt.Errorf("flag --%s is required", item[0])
+		}
+	}
+	if output != outputTable && output != outputJSON {
+		return fmt.Errorf("invalid output format: %s", output)
+	}
+
+	result, err := pkg.MergeCovFilesByCommits(opts)
+	if err != nil {
+		return err
+	}
+
+	if output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	if err := result.Write(os.Stdout); err != nil {
+		return err
+	}
+	fmt.Println("\nmerged cov file:", opts.OutPath)
+	return nil
+}
@@ -323,13 +323,27 @@ func GetFuncInfos(filePath string, src []byte) ([]*FuncInfo, error) {
 	if err != nil {
 		return nil, err
 	}
+	return walkFuncInfos(fset, root, rPath), nil
+}
+
+// GetFuncInfosWithPath returns all funcs info of go source, and the given path (i.e. import path of file
+// in profile) is used as func path instead of the one resolved by "go list".
+func GetFuncInfosWithPath(path string, src []byte) ([]*FuncInfo, error) {
+	fset := token.NewFileSet()
+	root, err := getASTRoot(fset, "", src)
+	if err != nil {
+		return nil, err
+	}
+	return walkFuncInfos(fset, root, path), nil
+}
 
+func walkFuncInfos(fset *token.FileSet, root *ast.File, path string) []*FuncInfo {
 	visit := &funcVisit{
 		fset: fset,
-		path: rPath,
+		path: path,
 	}
 	ast.Walk(visit, root)
-	return visit.funcInfos, nil
+	return visit.funcInfos
 }
 
 //
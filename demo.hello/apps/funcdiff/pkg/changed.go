@@ -0,0 +1,154 @@
+package pkg
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+
+	gocpkg "demo.hello/gocplugin/pkg"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+//
+// Changed funcs between commits.
+//
+
+// ChangedFunc func which is added or changed between commits.
+type ChangedFunc struct {
+	// File file path (relative to repo root) in dst commit
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Package string `json:"package"`
+	// Recv receiver type name (without pointer) of method
+	Recv string `json:"recv,omitempty"`
+	Name string `json:"name"`
+	Diff string `json:"diff"`
+	// From src location of moved or renamed func
+	From string `json:"from,omitempty"`
+}
+
+// GetChangedFuncsByCommits returns funcs (including test funcs) which are added or changed between src and dst
+// commits. Removed funcs are not included as they are not in dst code, and funcs which are moved or renamed
+// without changes are skipped.
+func GetChangedFuncsByCommits(repoPath, srcCommit, dstCommit string) ([]ChangedFunc, error) {
+	repo := gocpkg.NewGitRepo(repoPath)
+	src, err := repo.GetCommit(srcCommit)
+	if err != nil {
+		return nil, fmt.Errorf("GetChangedFuncsByCommits get src commit error: %w", err)
+	}
+	dst, err := repo.GetCommit(dstCommit)
+	if err != nil {
+		return nil, fmt.Errorf("GetChangedFuncsByCommits get dst commit error: %w", err)
+	}
+
+	funcs, err := getChangedFuncsBetweenCommits(src, dst)
+	if err != nil {
+		return nil, fmt.Errorf("GetChangedFuncsByCommits error: %w", err)
+	}
+	return funcs, nil
+}
+
+// VerifyHeadAtCommit returns error if head of repo is not at the commit, as go packages of working tree are analysed
+// for changed funcs of the commit.
+func VerifyHeadAtCommit(repoPath, commit string) error {
+	ok, err := gocpkg.NewGitRepo(repoPath).IsHeadAtCommit(commit)
+	if err != nil {
+		return fmt.Errorf("VerifyHeadAtCommit error: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("head of repo [%s] is not at commit [%s], checkout it first", repoPath, commit)
+	}
+	return nil
+}
+
+func getChangedFuncsBetweenCommits(srcCommit, dstCommit *object.Commit) ([]ChangedFunc, error) {
+	diffs, err := getDiffFilesBetweenCommits(srcCommit, dstCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	diffEntries := make(DiffEntries, 0, len(diffs)*8)
+	// dst file => package name
+	packages := make(map[string]string, len(diffs))
+	for _, diff := range diffs {
+		if !strings.HasSuffix(diff.SrcName, ".go") && !strings.HasSuffix(diff.DstName, ".go") {
+			continue
+		}
+
+		var srcFuncInfos, dstFuncInfos []*FuncInfo
+		if len(diff.SrcName) > 0 {
+			if srcFuncInfos, err = getFuncInfosOfCommitFile(srcCommit, diff.SrcName, diff.SrcName); err != nil {
+				return nil, err
+			}
+		}
+		if len(diff.DstName) > 0 {
+			if dstFuncInfos, err = getFuncInfosOfCommitFile(dstCommit, diff.DstName, diff.DstName); err != nil {
+				return nil, err
+			}
+			if packages[diff.DstName], err = getPackageNameOfCommitFile(dstCommit, diff.DstName); err != nil {
+				return nil, err
+			}
+		}
+		diffEntries = append(diffEntries, funcDiffForFuncInfos(srcFuncInfos, dstFuncInfos)...)
+	}
+
+	funcs := make([]ChangedFunc, 0, len(diffEntries))
+	for _, entry := range matchMovedFuncs(diffEntries) {
+		switch entry.Result {
+		case diffTypeSame, diffTypeRemove:
+			continue
+		case diffTypeMoved, diffTypeRenamed:
+			if diffFuncSrc(entry.SrcFuncProfileEntry.FuncInfo, entry.DstFuncProfileEntry.FuncInfo) == resultSame {
+				continue
+			}
+		}
+
+		info := entry.DstFuncProfileEntry.FuncInfo
+		recv, name := splitFuncName(info.Name)
+		funcs = append(funcs, ChangedFunc{
+			File:    info.Path,
+			Line:    info.StartLine,
+			Package: packages[info.Path],
+			Recv:    recv,
+			Name:    name,
+			Diff:    entry.Result,
+			From:    entry.SrcLocation,
+		})
+	}
+	sort.Slice(funcs, func(i, j int) bool {
+		fi, fj := funcs[i], funcs[j]
+		return fi.File < fj.File || fi.File == fj.File && fi.Line < fj.Line
+	})
+	return funcs, nil
+}
+
+func getPackageNameOfCommitFile(commit *object.Commit, relPath string) (string, error) {
+	f, err := commit.File(relPath)
+	if err != nil {
+		return "", fmt.Errorf("get file [%s] of commit [%s] error: %w", relPath, commit.Hash.String()[:8], err)
+	}
+	content, err := f.Contents()
+	if err != nil {
+		return "", fmt.Errorf("read file [%s] error: %w", relPath, err)
+	}
+	root, err := parser.ParseFile(token.NewFileSet(), "", content, parser.PackageClauseOnly)
+	if err != nil {
+		return "", fmt.Errorf("parse package of file [%s] error: %w", relPath, err)
+	}
+	return root.Name.Name, nil
+}
+
+// splitFuncName returns receiver type (without pointer) and name of func, i.e. "(p *person) hello" => "person", "hello".
+func splitFuncName(name string) (string, string) {
+	key := getFuncKey(name)
+	if !strings.HasPrefix(key, "(") {
+		return "", name
+	}
+	idx := strings.Index(key, ") ")
+	if idx == -1 {
+		return "", name
+	}
+	return strings.TrimPrefix(key[1:idx], "*"), key[idx+2:]
+}
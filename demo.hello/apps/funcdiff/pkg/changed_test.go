@@ -0,0 +1,52 @@
+package pkg
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGetChangedFuncsByCommits(t *testing.T) {
+	dstFiles := make(map[string]string, len(testMergeDstFiles)+1)
+	for name, content := range testMergeDstFiles {
+		dstFiles[name] = content
+	}
+	dstFiles["person.go"] = `package main
+
+type person struct {
+	name string
+}
+
+func (p *person) hello() string {
+	return "hello " + p.name
+}
+`
+	repoDir := filepath.Join(t.TempDir(), "repo")
+	srcHash, dstHash := newTestMergeRepo(t, repoDir, dstFiles)
+
+	funcs, err := GetChangedFuncsByCommits(repoDir, srcHash.String(), dstHash.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fnSame, fnRenamed (file renamed) are not changed, and fnDel is removed
+	want := []ChangedFunc{
+		{File: "main.go", Line: 5, Package: "main", Name: "fnAdd", Diff: diffTypeAdd},
+		{File: "main.go", Line: 16, Package: "main", Name: "fnChange", Diff: diffTypeChange},
+		{File: "person.go", Line: 7, Package: "main", Recv: "person", Name: "hello", Diff: diffTypeAdd},
+	}
+	if len(funcs) != len(want) {
+		t.Fatalf("want %d changed funcs, got: %+v", len(want), funcs)
+	}
+	for i := range want {
+		if funcs[i] != want[i] {
+			t.Fatalf("want changed func %+v, got %+v", want[i], funcs[i])
+		}
+	}
+
+	if err := VerifyHeadAtCommit(repoDir, dstHash.String()[:10]); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyHeadAtCommit(repoDir, srcHash.String()); err == nil {
+		t.Fatal("want error when head is not at commit")
+	}
+}
@@ -229,15 +229,12 @@ func buildProfileLine(fpath string, block ProfileBlock) string {
 
 func linkProfileBlocksToFunc(entry *FuncProfileEntry, profile *Profile) {
 	linkedBlocks := make([]ProfileBlock, 0, 16)
-	funcInfo := entry.FuncInfo
+	if profile == nil {
+		entry.ProfileBlocks = linkedBlocks
+		return
+	}
 	for i := 0; i < len(profile.Blocks); i++ {
-		block := profile.Blocks[i]
-		if (block.StartLine > funcInfo.EndLine) ||
-			(block.StartLine == funcInfo.EndLine && block.StartCol > funcInfo.EndCol) {
-			continue
-		}
-		if (block.StartLine > funcInfo.StartLine) ||
-			((block.StartLine == funcInfo.StartLine) && (block.StartCol > funcInfo.StartCol)) {
+		if isBlockInFunc(profile.Blocks[i], entry.FuncInfo) {
 			profile.Blocks[i].isLink = true
 			linkedBlocks = append(linkedBlocks, profile.Blocks[i])
 		}
@@ -245,6 +242,15 @@ func linkProfileBlocksToFunc(entry *FuncProfileEntry, profile *Profile) {
 	entry.ProfileBlocks = linkedBlocks
 }
 
+func isBlockInFunc(block ProfileBlock, funcInfo *FuncInfo) bool {
+	if (block.StartLine > funcInfo.EndLine) ||
+		(block.StartLine == funcInfo.EndLine && block.StartCol > funcInfo.EndCol) {
+		return false
+	}
+	return (block.StartLine > funcInfo.StartLine) ||
+		((block.StartLine == funcInfo.StartLine) && (block.StartCol > funcInfo.StartCol))
+}
+
 /* Merge Func Cov Entries */
 
 // mergeProfiles: 1.diff func; 2.link profile blocks to func; 3.merge profiles
@@ -264,6 +270,24 @@ func mergeProfiles(srcPath, dstPath string, srcFnProfile, dstFnProfile map[strin
 	}
 
 	// 2.link profile blocks to func
+	if err := linkProfileBlocksToDiffEntries(diffEntries, srcFnProfile, dstFnProfile); err != nil {
+		return "", nil, err
+	}
+
+	log.Println("Diff entries:")
+	for _, entry := range diffEntries {
+		fmt.Println(prettySprintDiffEntry(entry))
+	}
+
+	// 3.merge profiles
+	mergedBlocks, err := mergeLinkedProfileBlocks(diffEntries, dstFnProfile[dstFilePath])
+	if err != nil {
+		return "", nil, err
+	}
+	return dstFilePath, mergedBlocks, nil
+}
+
+func linkProfileBlocksToDiffEntries(diffEntries []*DiffEntry, srcFnProfile, dstFnProfile map[string]*Profile) error {
 	for _, entry := range diffEntries {
 		if entry.Result == diffTypeAdd {
 			fpath := entry.DstFuncProfileEntry.FuncInfo.Path
@@ -273,7 +297,8 @@ func mergeProfiles(srcPath, dstPath string, srcFnProfile, dstFnProfile map[strin
 			fpath := entry.SrcFuncProfileEntry.FuncInfo.Path
 			profile := srcFnProfile[fpath]
 			linkProfileBlocksToFunc(entry.SrcFuncProfileEntry, profile)
-		} else if entry.Result == diffTypeSame || entry.Result == diffTypeChange {
+		} else if entry.Result == diffTypeSame || entry.Result == diffTypeChange ||
+			entry.Result == diffTypeMoved || entry.Result == diffTypeRenamed {
 			// src
 			fpath := entry.SrcFuncProfileEntry.FuncInfo.Path
 			srcProfile := srcFnProfile[fpath]
@@ -283,31 +308,31 @@ func mergeProfiles(srcPath, dstPath string, srcFnProfile, dstFnProfile map[strin
 			dstProfile := dstFnProfile[fpath]
 			linkProfileBlocksToFunc(entry.DstFuncProfileEntry, dstProfile)
 		} else {
-			return "", nil, fmt.Errorf("invalid entry diff result")
+			return fmt.Errorf("invalid entry diff result")
 		}
 	}
+	return nil
+}
 
-	log.Println("Diff entries:")
-	for _, entry := range diffEntries {
-		fmt.Println(prettySprintDiffEntry(entry))
-	}
-
+// mergeLinkedProfileBlocks merges blocks of diff entries, and unlinked blocks of dst profile (i.e. blocks
+// out of funcs) are kept.
+func mergeLinkedProfileBlocks(diffEntries []*DiffEntry, dstProfile *Profile) ([]ProfileBlock, error) {
 	unLinkedBlocks := make([]ProfileBlock, 0, 8)
-	for _, block := range dstFnProfile[dstFilePath].Blocks {
-		if !block.isLink {
-			unLinkedBlocks = append(unLinkedBlocks, block)
+	if dstProfile != nil {
+		for _, block := range dstProfile.Blocks {
+			if !block.isLink {
+				unLinkedBlocks = append(unLinkedBlocks, block)
+			}
 		}
 	}
 
-	// 3.merge profiles
 	mergedBlocks, err := mergeProfileForDiffEntries(diffEntries)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 	mergedBlocks = append(mergedBlocks, unLinkedBlocks...)
 	sort.Sort(blocksByStartPos(mergedBlocks))
-
-	return dstFilePath, mergedBlocks, nil
+	return mergedBlocks, nil
 }
 
 func mergeProfileForDiffEntries(diffEntries []*DiffEntry) ([]ProfileBlock, error) {
@@ -315,9 +340,28 @@ func mergeProfileForDiffEntries(diffEntries []*DiffEntry) ([]ProfileBlock, error
 	for _, diffEntry := range diffEntries {
 		if diffEntry.Result == diffTypeAdd || diffEntry.Result == diffTypeChange {
 			retBlocks = append(retBlocks, diffEntry.DstFuncProfileEntry.ProfileBlocks...)
+		} else if diffEntry.Result == diffTypeMoved || diffEntry.Result == diffTypeRenamed {
+			// merge blocks only if func body is not changed
+			srcBlocks := diffEntry.SrcFuncProfileEntry.ProfileBlocks
+			dstBlocks := diffEntry.DstFuncProfileEntry.ProfileBlocks
+			if diffFuncSrc(diffEntry.SrcFuncProfileEntry.FuncInfo, diffEntry.DstFuncProfileEntry.FuncInfo) == resultSame &&
+				len(srcBlocks) == len(dstBlocks) {
+				blocks, err := mergeProfileBlocks(srcBlocks, dstBlocks)
+				if err != nil {
+					return nil, err
+				}
+				retBlocks = append(retBlocks, blocks...)
+			} else {
+				retBlocks = append(retBlocks, dstBlocks...)
+			}
 		} else if diffEntry.Result == diffTypeSame {
 			srcBlocks := diffEntry.SrcFuncProfileEntry.ProfileBlocks
 			dstBlocks := diffEntry.DstFuncProfileEntry.ProfileBlocks
+			if len(srcBlocks) == 0 {
+				// no src cover data to merge
+				retBlocks = append(retBlocks, dstBlocks...)
+				continue
+			}
 			blocks, err := mergeProfileBlocks(srcBlocks, dstBlocks)
 			if err != nil {
 				return nil, err
@@ -15,10 +15,12 @@ const (
 	resultDiff = "diff"
 	resultSame = "same"
 
-	diffTypeAdd    = "add"
-	diffTypeChange = "change"
-	diffTypeRemove = "remove"
-	diffTypeSame   = "same"
+	diffTypeAdd     = "add"
+	diffTypeChange  = "change"
+	diffTypeRemove  = "remove"
+	diffTypeSame    = "same"
+	diffTypeMoved   = "moved"
+	diffTypeRenamed = "renamed"
 )
 
 var diffResultAndTypeMap = map[string]string{
@@ -37,6 +39,10 @@ type DiffEntry struct {
 	SrcFuncProfileEntry *FuncProfileEntry `json:"src_func_profile_entry,omitempty"`
 	DstFuncProfileEntry *FuncProfileEntry `json:"dst_func_profile_entry,omitempty"`
 	Result              string            `json:"result"`
+	// SrcLocation, DstLocation and Similarity are only set for moved or renamed func.
+	SrcLocation string  `json:"src_location,omitempty"`
+	DstLocation string  `json:"dst_location,omitempty"`
+	Similarity  float64 `json:"similarity,omitempty"`
 }
 
 // funcDiff compares func between src and dst .go files, and return diff, same
@@ -106,7 +112,11 @@ func funcDiffForGoFiles(srcPath, dstPath string) (DiffEntries, error) {
 	if err != nil {
 		return nil, err
 	}
+	return funcDiffForFuncInfos(srcFuncInfos, dstFuncInfos), nil
+}
 
+// funcDiffForFuncInfos compares funcs bewteen src and dst func infos of a go file.
+func funcDiffForFuncInfos(srcFuncInfos, dstFuncInfos []*FuncInfo) DiffEntries {
 	// 交集
 	sameFuncInfos := getSameFuncInfos(srcFuncInfos, dstFuncInfos)
 
@@ -139,7 +149,7 @@ func funcDiffForGoFiles(srcPath, dstPath string) (DiffEntries, error) {
 	}
 
 	sort.Sort(DiffEntries(retDiffEntries))
-	return retDiffEntries, nil
+	return retDiffEntries
 }
 
 func getSameFuncInfos(srcFuncInfos, dstFuncInfos []*FuncInfo) map[string]*FuncInfo {
@@ -173,6 +183,165 @@ func getDiffEntries(funcInfos []*FuncInfo, baseFuncInfos map[string]*FuncInfo, d
 	return retDiffEntries
 }
 
+//
+// Match moved and renamed funcs across files.
+//
+
+const (
+	// renamedFuncMinSimilarity min body similarity of removed and added funcs to be matched as renamed.
+	renamedFuncMinSimilarity = 0.8
+	// renamedFuncMinLines min body lines of func to be matched as renamed, to skip trivial funcs like getters.
+	renamedFuncMinLines = 4
+)
+
+// matchMovedFuncs pairs removed and added funcs of diff entries (across changed files): first by receiver and
+// name as moved, and then by normalized body similarity as renamed. Unmatched entries are kept.
+func matchMovedFuncs(entries DiffEntries) DiffEntries {
+	removed := make([]*DiffEntry, 0, 8)
+	added := make([]*DiffEntry, 0, 8)
+	retEntries := make([]*DiffEntry, 0, len(entries))
+	for _, entry := range entries {
+		switch entry.Result {
+		case diffTypeRemove:
+			removed = append(removed, entry)
+		case diffTypeAdd:
+			added = append(added, entry)
+		default:
+			retEntries = append(retEntries, entry)
+		}
+	}
+	if len(removed) == 0 || len(added) == 0 {
+		return entries
+	}
+
+	matched := make(map[*DiffEntry]struct{}, len(removed)+len(added))
+	addMatched := func(src, dst *DiffEntry, diffType string, similarity float64) {
+		srcInfo := src.SrcFuncProfileEntry.FuncInfo
+		dstInfo := dst.DstFuncProfileEntry.FuncInfo
+		retEntries = append(retEntries, &DiffEntry{
+			SrcFuncProfileEntry: src.SrcFuncProfileEntry,
+			DstFuncProfileEntry: dst.DstFuncProfileEntry,
+			Result:              diffType,
+			SrcLocation:         fmt.Sprintf("%s:%d", srcInfo.Path, srcInfo.StartLine),
+			DstLocation:         fmt.Sprintf("%s:%d", dstInfo.Path, dstInfo.StartLine),
+			Similarity:          similarity,
+		})
+		matched[src] = struct{}{}
+		matched[dst] = struct{}{}
+	}
+
+	// moved: same receiver and name
+	addedByKey := make(map[string][]*DiffEntry, len(added))
+	for _, entry := range added {
+		key := getFuncKey(entry.DstFuncProfileEntry.FuncInfo.Name)
+		addedByKey[key] = append(addedByKey[key], entry)
+	}
+	for _, src := range removed {
+		key := getFuncKey(src.SrcFuncProfileEntry.FuncInfo.Name)
+		if dsts := addedByKey[key]; len(dsts) > 0 {
+			similarity := getFuncSimilarity(src.SrcFuncProfileEntry.FuncInfo, dsts[0].DstFuncProfileEntry.FuncInfo)
+			addMatched(src, dsts[0], diffTypeMoved, similarity)
+			addedByKey[key] = dsts[1:]
+		}
+	}
+
+	// renamed: most similar body
+	type candidate struct {
+		src, dst   *DiffEntry
+		similarity float64
+	}
+	candidates := make([]candidate, 0, 8)
+	for _, src := range removed {
+		if _, ok := matched[src]; ok {
+			continue
+		}
+		for _, dst := range added {
+			if _, ok := matched[dst]; ok {
+				continue
+			}
+			similarity := getFuncSimilarity(src.SrcFuncProfileEntry.FuncInfo, dst.DstFuncProfileEntry.FuncInfo)
+			if similarity >= renamedFuncMinSimilarity {
+				candidates = append(candidates, candidate{src: src, dst: dst, similarity: similarity})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].similarity > candidates[j].similarity
+	})
+	for _, c := range candidates {
+		_, srcOk := matched[c.src]
+		_, dstOk := matched[c.dst]
+		if !srcOk && !dstOk {
+			addMatched(c.src, c.dst, diffTypeRenamed, c.similarity)
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.Result != diffTypeRemove && entry.Result != diffTypeAdd {
+			continue
+		}
+		if _, ok := matched[entry]; !ok {
+			retEntries = append(retEntries, entry)
+		}
+	}
+	sort.Stable(DiffEntries(retEntries))
+	return retEntries
+}
+
+// getFuncKey returns func name with receiver type only, i.e. "(p *person) hello" => "(*person) hello".
+func getFuncKey(name string) string {
+	if !strings.HasPrefix(name, "(") {
+		return name
+	}
+	idx := strings.Index(name, ") ")
+	if idx == -1 {
+		return name
+	}
+	fields := strings.Fields(name[1:idx])
+	if len(fields) == 0 {
+		return name
+	}
+	return fmt.Sprintf("(%s) %s", fields[len(fields)-1], name[idx+2:])
+}
+
+// getFuncSimilarity returns similarity of normalized func bodies by lines LCS, and returns 0 for trivial funcs.
+func getFuncSimilarity(srcFuncInfo, dstFuncInfo *FuncInfo) float64 {
+	srcLines := getNormalizedLines(srcFuncInfo.Source)
+	dstLines := getNormalizedLines(dstFuncInfo.Source)
+	if len(srcLines) < renamedFuncMinLines || len(dstLines) < renamedFuncMinLines {
+		return 0
+	}
+
+	// lcs[i][j]: lcs length of srcLines[i:] and dstLines[j:]
+	lcs := make([][]int, len(srcLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(dstLines)+1)
+	}
+	for i := len(srcLines) - 1; i >= 0; i-- {
+		for j := len(dstLines) - 1; j >= 0; j-- {
+			if srcLines[i] == dstLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] > lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	return float64(2*lcs[0][0]) / float64(len(srcLines)+len(dstLines))
+}
+
+func getNormalizedLines(src string) []string {
+	lines := strings.Split(deleteEmptyLinesInText([]byte(src)), "\n")
+	retLines := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			retLines = append(retLines, line)
+		}
+	}
+	return retLines
+}
+
 //
 // Pretty print.
 //
@@ -44,3 +44,81 @@ func TestFuncDiffForGoFiles(t *testing.T) {
 		fmt.Println(prettySprintDiffEntry(entry) + "\n")
 	}
 }
+
+func TestMatchMovedFuncs(t *testing.T) {
+	srcA := []byte(`package demo
+
+func (p *person) hello() string {
+	return "hello " + p.name
+}
+
+func sum(nums []int) int {
+	total := 0
+	for _, n := range nums {
+		total += n
+	}
+	return total
+}
+`)
+	srcB := []byte(`package demo
+
+func noop() {}
+`)
+	dstA := []byte(`package demo
+
+func total(nums []int) int {
+	total := 0
+	for _, n := range nums {
+		total += n
+	}
+	return total
+}
+`)
+	dstB := []byte(`package demo
+
+func (s *person) hello() string {
+	return "hello " + s.name
+}
+
+func noop2() {}
+`)
+
+	getFuncInfos := func(path string, src []byte) []*FuncInfo {
+		infos, err := GetFuncInfosWithPath(path, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return infos
+	}
+	entries := funcDiffForFuncInfos(getFuncInfos("demo/a.go", srcA), getFuncInfos("demo/a.go", dstA))
+	entries = append(entries, funcDiffForFuncInfos(getFuncInfos("demo/b.go", srcB), getFuncInfos("demo/b.go", dstB))...)
+	entries = matchMovedFuncs(entries)
+
+	results := make(map[string]*DiffEntry, len(entries))
+	for _, entry := range entries {
+		fmt.Println(prettySprintDiffEntry(entry))
+		if entry.DstFuncProfileEntry != nil {
+			results[entry.DstFuncProfileEntry.FuncInfo.Name] = entry
+		} else {
+			results[entry.SrcFuncProfileEntry.FuncInfo.Name] = entry
+		}
+	}
+
+	for name, want := range map[string]string{
+		"(s *person) hello": diffTypeMoved,
+		"total":             diffTypeRenamed,
+		"noop":              diffTypeRemove,
+		"noop2":             diffTypeAdd,
+	} {
+		entry, ok := results[name]
+		if !ok || entry.Result != want {
+			t.Fatalf("func %s: want diff %s, got: %+v", name, want, entry)
+		}
+	}
+	if entry := results["(s *person) hello"]; entry.SrcLocation != "demo/a.go:3" || entry.DstLocation != "demo/b.go:3" {
+		t.Fatalf("want moved from demo/a.go:3 to demo/b.go:3, got: %s, %s", entry.SrcLocation, entry.DstLocation)
+	}
+	if entry := results["total"]; entry.Similarity != 1 {
+		t.Fatalf("want renamed func similarity 1, got %.2f", entry.Similarity)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	pkg "demo.hello/gocplugin/pkg"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
 const (
@@ -22,6 +24,7 @@ type FileDiffEntry struct {
 	DType   string `json:"type"`
 	SrcName string `json:"src_name"`
 	DstName string `json:"dst_name"`
+	patch   fdiff.FilePatch
 }
 
 // getDiffFilesByCommits: 1.syncs and checkouts to branch; 2.returns diff files bewtween commits of branch.
@@ -43,6 +46,11 @@ func getDiffFilesByCommits(repoPath, branch string, srcHash, dstHash string) ([]
 	if err != nil {
 		return nil, err
 	}
+	return getDiffFilesBetweenCommits(srcCommit, dstCommit)
+}
+
+// getDiffFilesBetweenCommits returns diff files bewteen src and dst commits, and renamed files are detected.
+func getDiffFilesBetweenCommits(srcCommit, dstCommit *object.Commit) ([]FileDiffEntry, error) {
 	patch, err := srcCommit.Patch(dstCommit)
 	if err != nil {
 		return nil, err
@@ -52,7 +60,9 @@ func getDiffFilesByCommits(repoPath, branch string, srcHash, dstHash string) ([]
 	diffs := make([]FileDiffEntry, 0, len(patches))
 	for _, fpatch := range patches {
 		from, to := fpatch.Files()
-		entry := FileDiffEntry{}
+		entry := FileDiffEntry{
+			patch: fpatch,
+		}
 		if from == nil && to != nil {
 			entry.DType = fileDiffTypeAdd
 			entry.DstName = to.Path()
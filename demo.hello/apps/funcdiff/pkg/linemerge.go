@@ -0,0 +1,241 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+//
+// Merge profiles by line diff.
+//
+
+// lineDiff src to dst lines mapping of a changed file.
+type lineDiff struct {
+	dstToSrc map[int]int
+	// deletedLines src lines which are deleted
+	deletedLines map[int]struct{}
+	// addedLines dst lines which are added
+	addedLines map[int]struct{}
+}
+
+func newLineDiff(patch fdiff.FilePatch) *lineDiff {
+	d := &lineDiff{
+		dstToSrc:     make(map[int]int),
+		deletedLines: make(map[int]struct{}),
+		addedLines:   make(map[int]struct{}),
+	}
+	if patch == nil {
+		return d
+	}
+
+	srcLine, dstLine := 1, 1
+	for _, chunk := range patch.Chunks() {
+		count := countLines(chunk.Content())
+		for i := 0; i < count; i++ {
+			switch chunk.Type() {
+			case fdiff.Equal:
+				d.dstToSrc[dstLine] = srcLine
+				srcLine++
+				dstLine++
+			case fdiff.Delete:
+				d.deletedLines[srcLine] = struct{}{}
+				srcLine++
+			case fdiff.Add:
+				d.addedLines[dstLine] = struct{}{}
+				dstLine++
+			}
+		}
+	}
+	return d
+}
+
+// mapDstBlock returns src start and end lines of dst block if all lines of block are not changed.
+func (d *lineDiff) mapDstBlock(block ProfileBlock) (int, int, bool) {
+	srcStart, ok := d.dstToSrc[block.StartLine]
+	if !ok {
+		return 0, 0, false
+	}
+	for line := block.StartLine + 1; line <= block.EndLine; line++ {
+		if src, ok := d.dstToSrc[line]; !ok || src != srcStart+line-block.StartLine {
+			return 0, 0, false
+		}
+	}
+	return srcStart, srcStart + block.EndLine - block.StartLine, true
+}
+
+// isSrcBlockDeleted returns true if any line of src block is deleted, since closing lines of a deleted block
+// (i.e. "}") may be mapped to other lines by git diff.
+func (d *lineDiff) isSrcBlockDeleted(block ProfileBlock) bool {
+	return isAnyLineIn(d.deletedLines, block.StartLine, block.EndLine)
+}
+
+func (d *lineDiff) isDstBlockAdded(block ProfileBlock) bool {
+	return isAnyLineIn(d.addedLines, block.StartLine, block.EndLine)
+}
+
+func isAnyLineIn(lines map[int]struct{}, start, end int) bool {
+	for line := start; line <= end; line++ {
+		if _, ok := lines[line]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func countLines(content string) int {
+	if len(content) == 0 {
+		return 0
+	}
+	count := strings.Count(content, "\n")
+	if !strings.HasSuffix(content, "\n") {
+		count++
+	}
+	return count
+}
+
+// lineDiffFile changed file with line diff and source lines.
+type lineDiffFile struct {
+	changedFile
+	lineDiff *lineDiff
+	srcLines []string
+	dstLines []string
+}
+
+// mergeChangedFilesByLine merges profiles of changed files by lines mapping, and returns merged profiles of dst files.
+// Blocks of unchanged lines are merged with mapped src blocks, and blocks of added lines are merged with src blocks
+// of deleted lines which have the same statements and source (i.e. func moved to another file).
+func (m *commitsCovMerger) mergeChangedFilesByLine(files []changedFile) ([]*Profile, error) {
+	diffFiles := make([]*lineDiffFile, 0, len(files))
+	for _, f := range files {
+		diffFile := &lineDiffFile{
+			changedFile: f,
+			lineDiff:    newLineDiff(f.diff.patch),
+		}
+		var err error
+		if len(f.srcFile) > 0 {
+			if diffFile.srcLines, err = getCommitFileLines(m.srcCommit, f.diff.SrcName); err != nil {
+				return nil, err
+			}
+		}
+		if len(f.dstFile) > 0 {
+			if diffFile.dstLines, err = getCommitFileLines(m.dstCommit, f.diff.DstName); err != nil {
+				return nil, err
+			}
+		}
+		diffFiles = append(diffFiles, diffFile)
+	}
+
+	movedBlocks := m.getDeletedSrcBlocks(diffFiles)
+	profiles := make([]*Profile, 0, len(diffFiles))
+	for _, f := range diffFiles {
+		if dstProfile, ok := m.dstFnProfile[f.dstFile]; ok {
+			profiles = append(profiles, &Profile{
+				FileName: f.dstFile,
+				Mode:     dstProfile.Mode,
+				Blocks:   m.mergeBlocksByLine(f, dstProfile, movedBlocks),
+			})
+		}
+	}
+	return profiles, nil
+}
+
+// getDeletedSrcBlocks returns src blocks of deleted lines by block source.
+func (m *commitsCovMerger) getDeletedSrcBlocks(files []*lineDiffFile) map[string][]ProfileBlock {
+	blocks := make(map[string][]ProfileBlock)
+	for _, f := range files {
+		srcProfile, ok := m.srcFnProfile[f.srcFile]
+		if !ok {
+			continue
+		}
+		for _, block := range srcProfile.Blocks {
+			if !f.lineDiff.isSrcBlockDeleted(block) {
+				continue
+			}
+			if key := getBlockKey(f.srcLines, block); len(key) > 0 {
+				blocks[key] = append(blocks[key], block)
+			}
+		}
+	}
+	return blocks
+}
+
+func (m *commitsCovMerger) mergeBlocksByLine(f *lineDiffFile, dstProfile *Profile, movedBlocks map[string][]ProfileBlock) []ProfileBlock {
+	srcBlocks := make(map[string]ProfileBlock)
+	if srcProfile, ok := m.srcFnProfile[f.srcFile]; ok {
+		for _, block := range srcProfile.Blocks {
+			srcBlocks[getBlockPosition(block.StartLine, block.EndLine, block)] = block
+		}
+	}
+
+	mergedBlocks := make([]ProfileBlock, 0, len(dstProfile.Blocks))
+	for _, block := range dstProfile.Blocks {
+		if srcStart, srcEnd, ok := f.lineDiff.mapDstBlock(block); ok {
+			if src, ok := srcBlocks[getBlockPosition(srcStart, srcEnd, block)]; ok && src.NumStmt == block.NumStmt {
+				block.Count = mergeBlockCount(src.Count, block.Count)
+			}
+		} else if f.lineDiff.isDstBlockAdded(block) {
+			key := getBlockKey(f.dstLines, block)
+			if moved := movedBlocks[key]; len(moved) > 0 {
+				block.Count = mergeBlockCount(moved[0].Count, block.Count)
+				movedBlocks[key] = moved[1:]
+				m.result.MovedBlocks++
+			}
+		}
+		block.isLink = false
+		mergedBlocks = append(mergedBlocks, block)
+	}
+	sort.Sort(blocksByStartPos(mergedBlocks))
+	return mergedBlocks
+}
+
+func mergeBlockCount(srcCount, dstCount int) int {
+	if srcCount > dstCount {
+		return srcCount
+	}
+	return dstCount
+}
+
+func getBlockPosition(startLine, endLine int, block ProfileBlock) string {
+	return fmt.Sprintf("%d.%d,%d.%d", startLine, block.StartCol, endLine, block.EndCol)
+}
+
+// getBlockKey returns key of block by statements number and trimmed source, and returns empty if source is not found.
+func getBlockKey(lines []string, block ProfileBlock) string {
+	if block.StartLine < 1 || block.EndLine > len(lines) || block.StartLine > block.EndLine {
+		return ""
+	}
+
+	texts := make([]string, 0, block.EndLine-block.StartLine+1)
+	for lineNum := block.StartLine; lineNum <= block.EndLine; lineNum++ {
+		line := lines[lineNum-1]
+		if lineNum == block.EndLine && block.EndCol-1 <= len(line) {
+			line = line[:block.EndCol-1]
+		}
+		if lineNum == block.StartLine && block.StartCol-1 <= len(line) {
+			line = line[block.StartCol-1:]
+		}
+		if text := strings.TrimSpace(line); len(text) > 0 {
+			texts = append(texts, text)
+		}
+	}
+	if len(texts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d|%s", block.NumStmt, strings.Join(texts, "\n"))
+}
+
+func getCommitFileLines(commit *object.Commit, relPath string) ([]string, error) {
+	f, err := commit.File(relPath)
+	if err != nil {
+		return nil, fmt.Errorf("get file [%s] of commit [%s] error: %w", relPath, commit.Hash.String()[:8], err)
+	}
+	lines, err := f.Lines()
+	if err != nil {
+		return nil, fmt.Errorf("read lines of file [%s] error: %w", relPath, err)
+	}
+	return lines, nil
+}
@@ -0,0 +1,539 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	gocpkg "demo.hello/gocplugin/pkg"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/mod/modfile"
+)
+
+//
+// Merge .cov files of diff commits.
+//
+
+const (
+	// MergeStrategyFunc merges profiles of changed files by funcs diff.
+	MergeStrategyFunc = "func"
+	// MergeStrategyLine merges profiles of changed files by src to dst lines mapping of git diff, and blocks out
+	// of funcs and blocks moved between files are merged too.
+	MergeStrategyLine = "line"
+)
+
+// CovMergeOptions .
+type CovMergeOptions struct {
+	RepoPath string
+	// Branch pulls branch before merge if set.
+	Branch     string
+	SrcCommit  string
+	DstCommit  string
+	SrcCovPath string
+	DstCovPath string
+	OutPath    string
+	// Strategy merge strategy of changed files: func (default) or line.
+	Strategy string
+}
+
+// CoverStat covered and total statements.
+type CoverStat struct {
+	Covered int `json:"covered"`
+	Total   int `json:"total"`
+}
+
+// Percent .
+func (s CoverStat) Percent() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Covered) / float64(s.Total) * 100
+}
+
+func (s *CoverStat) add(blocks []ProfileBlock) {
+	for _, block := range blocks {
+		s.Total += block.NumStmt
+		if block.Count > 0 {
+			s.Covered += block.NumStmt
+		}
+	}
+}
+
+func newCoverStat(blocks []ProfileBlock) *CoverStat {
+	stat := &CoverStat{}
+	stat.add(blocks)
+	return stat
+}
+
+// FuncMergeSummary merge result of a func, src is nil for added func, and dst and merged are nil for removed func.
+type FuncMergeSummary struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Func string `json:"func"`
+	Diff string `json:"diff"`
+	// From src location of moved or renamed func
+	From   string     `json:"from,omitempty"`
+	Src    *CoverStat `json:"src,omitempty"`
+	Dst    *CoverStat `json:"dst,omitempty"`
+	Merged *CoverStat `json:"merged,omitempty"`
+}
+
+// CovMergeResult .
+type CovMergeResult struct {
+	Strategy string             `json:"strategy"`
+	Files    []FileDiffEntry    `json:"files"`
+	Funcs    []FuncMergeSummary `json:"funcs"`
+	Src      CoverStat          `json:"src"`
+	Dst      CoverStat          `json:"dst"`
+	Merged   CoverStat          `json:"merged"`
+	// MovedBlocks number of blocks merged from moved code, only for line strategy
+	MovedBlocks int `json:"moved_blocks,omitempty"`
+}
+
+// Write writes per func summary as table.
+func (r *CovMergeResult) Write(w io.Writer) error {
+	formatStat := func(stat *CoverStat) string {
+		if stat == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f%%", stat.Percent())
+	}
+
+	tabber := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+	fmt.Fprintln(tabber, "FILE\tFUNC\tDIFF\tSRC\tDST\tMERGED")
+	for _, f := range r.Funcs {
+		fmt.Fprintf(tabber, "%s:%d:\t%s\t%s\t%s\t%s\t%s\n",
+			f.File, f.Line, f.Func, f.Diff, formatStat(f.Src), formatStat(f.Dst), formatStat(f.Merged))
+	}
+	fmt.Fprintf(tabber, "total:\t(statements)\t\t%s\t%s\t%s\n", formatStat(&r.Src), formatStat(&r.Dst), formatStat(&r.Merged))
+	if r.MovedBlocks > 0 {
+		fmt.Fprintf(tabber, "moved:\t(blocks)\t\t\t\t%d\n", r.MovedBlocks)
+	}
+	return tabber.Flush()
+}
+
+// MergeCovFilesByCommits merges src .cov file into dst .cov file base on diff files between src and dst commits:
+// changed files are merged by funcs diff or lines mapping (by strategy), and unchanged files are merged by blocks.
+func MergeCovFilesByCommits(opts CovMergeOptions) (*CovMergeResult, error) {
+	repo := gocpkg.NewGitRepo(opts.RepoPath)
+	if len(opts.Branch) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if _, err := repo.Pull(ctx, opts.Branch); err != nil {
+			return nil, fmt.Errorf("MergeCovFilesByCommits pull branch error: %w", err)
+		}
+	}
+
+	srcCommit, err := repo.GetCommit(opts.SrcCommit)
+	if err != nil {
+		return nil, fmt.Errorf("MergeCovFilesByCommits get src commit error: %w", err)
+	}
+	dstCommit, err := repo.GetCommit(opts.DstCommit)
+	if err != nil {
+		return nil, fmt.Errorf("MergeCovFilesByCommits get dst commit error: %w", err)
+	}
+
+	srcFnProfile, err := parseCovFile(opts.SrcCovPath)
+	if err != nil {
+		return nil, fmt.Errorf("MergeCovFilesByCommits parse src cov file error: %w", err)
+	}
+	dstFnProfile, err := parseCovFile(opts.DstCovPath)
+	if err != nil {
+		return nil, fmt.Errorf("MergeCovFilesByCommits parse dst cov file error: %w", err)
+	}
+
+	if len(opts.Strategy) == 0 {
+		opts.Strategy = MergeStrategyFunc
+	}
+	if opts.Strategy != MergeStrategyFunc && opts.Strategy != MergeStrategyLine {
+		return nil, fmt.Errorf("MergeCovFilesByCommits invalid strategy: %s", opts.Strategy)
+	}
+
+	merger, err := newCommitsCovMerger(opts.Strategy, srcCommit, dstCommit, srcFnProfile, dstFnProfile)
+	if err != nil {
+		return nil, fmt.Errorf("MergeCovFilesByCommits error: %w", err)
+	}
+	profiles, err := merger.merge()
+	if err != nil {
+		return nil, fmt.Errorf("MergeCovFilesByCommits error: %w", err)
+	}
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("MergeCovFilesByCommits error: merged profiles is empty")
+	}
+	if err := writeProfilesToCovFile(opts.OutPath, profiles); err != nil {
+		return nil, fmt.Errorf("MergeCovFilesByCommits write cov file error: %w", err)
+	}
+	return merger.result, nil
+}
+
+type commitsCovMerger struct {
+	strategy     string
+	srcCommit    *object.Commit
+	dstCommit    *object.Commit
+	srcModules   commitModules
+	dstModules   commitModules
+	srcFnProfile map[string]*Profile
+	dstFnProfile map[string]*Profile
+	result       *CovMergeResult
+}
+
+func newCommitsCovMerger(strategy string, srcCommit, dstCommit *object.Commit, srcFnProfile, dstFnProfile map[string]*Profile) (*commitsCovMerger, error) {
+	srcModules, err := getCommitModules(srcCommit)
+	if err != nil {
+		return nil, err
+	}
+	dstModules, err := getCommitModules(dstCommit)
+	if err != nil {
+		return nil, err
+	}
+	return &commitsCovMerger{
+		strategy:     strategy,
+		srcCommit:    srcCommit,
+		dstCommit:    dstCommit,
+		srcModules:   srcModules,
+		dstModules:   dstModules,
+		srcFnProfile: srcFnProfile,
+		dstFnProfile: dstFnProfile,
+		result: &CovMergeResult{
+			Strategy: strategy,
+		},
+	}, nil
+}
+
+// merge returns merged profiles sorted by file name.
+func (m *commitsCovMerger) merge() ([]*Profile, error) {
+	diffs, err := getDiffFilesBetweenCommits(m.srcCommit, m.dstCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	mergedProfiles := make(map[string]*Profile, len(m.dstFnProfile))
+	// files (import path) in src and dst profiles which are changed between commits
+	changedSrcFiles := make(map[string]struct{})
+	changedDstFiles := make(map[string]struct{})
+	changedFiles := make([]changedFile, 0, len(diffs))
+	for _, diff := range diffs {
+		if !isGoSrcFile(diff.SrcName) && !isGoSrcFile(diff.DstName) {
+			continue
+		}
+		m.result.Files = append(m.result.Files, diff)
+
+		f := changedFile{diff: diff}
+		if len(diff.SrcName) > 0 {
+			f.srcFile = m.srcModules.importPath(diff.SrcName)
+			changedSrcFiles[f.srcFile] = struct{}{}
+		}
+		if len(diff.DstName) > 0 {
+			f.dstFile = m.dstModules.importPath(diff.DstName)
+			changedDstFiles[f.dstFile] = struct{}{}
+		}
+		changedFiles = append(changedFiles, f)
+	}
+
+	diffEntries, err := m.getChangedFilesDiffEntries(changedFiles)
+	if err != nil {
+		return nil, err
+	}
+	var changedProfiles []*Profile
+	if m.strategy == MergeStrategyLine {
+		changedProfiles, err = m.mergeChangedFilesByLine(changedFiles)
+	} else {
+		changedProfiles, err = m.mergeChangedFilesByFunc(changedFiles, diffEntries)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, profile := range changedProfiles {
+		mergedProfiles[profile.FileName] = profile
+	}
+	m.addDiffEntrySummaries(diffEntries, mergedProfiles)
+
+	for _, file := range m.getUnchangedFiles(changedSrcFiles, changedDstFiles) {
+		mergedProfiles[file] = m.mergeUnchangedFile(file)
+	}
+
+	profiles := make([]*Profile, 0, len(mergedProfiles))
+	for _, profile := range mergedProfiles {
+		profiles = append(profiles, profile)
+		m.result.Merged.add(profile.Blocks)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].FileName < profiles[j].FileName
+	})
+
+	for _, profile := range m.srcFnProfile {
+		m.result.Src.add(profile.Blocks)
+	}
+	for _, profile := range m.dstFnProfile {
+		m.result.Dst.add(profile.Blocks)
+	}
+	sort.SliceStable(m.result.Funcs, func(i, j int) bool {
+		fi, fj := m.result.Funcs[i], m.result.Funcs[j]
+		return fi.File < fj.File || fi.File == fj.File && fi.Line < fj.Line
+	})
+	return profiles, nil
+}
+
+// changedFile changed go file between commits, and src or dst file (import path) is empty if file is added or deleted.
+type changedFile struct {
+	diff    FileDiffEntry
+	srcFile string
+	dstFile string
+}
+
+// mergeChangedFilesByFunc merges profiles of changed files by funcs diff, and returns merged profiles of dst files.
+func (m *commitsCovMerger) mergeChangedFilesByFunc(files []changedFile, diffEntries DiffEntries) ([]*Profile, error) {
+	entriesByFile := make(map[string]DiffEntries, len(files))
+	for _, entry := range diffEntries {
+		if entry.DstFuncProfileEntry != nil {
+			file := entry.DstFuncProfileEntry.FuncInfo.Path
+			entriesByFile[file] = append(entriesByFile[file], entry)
+		}
+	}
+
+	profiles := make([]*Profile, 0, len(files))
+	for _, f := range files {
+		dstProfile, ok := m.dstFnProfile[f.dstFile]
+		if !ok {
+			continue
+		}
+		mergedBlocks, err := mergeLinkedProfileBlocks(entriesByFile[f.dstFile], dstProfile)
+		if err != nil {
+			return nil, fmt.Errorf("merge file [%s] error: %w", f.dstFile, err)
+		}
+		profiles = append(profiles, &Profile{
+			FileName: f.dstFile,
+			Mode:     dstProfile.Mode,
+			Blocks:   mergedBlocks,
+		})
+	}
+	return profiles, nil
+}
+
+// getChangedFilesDiffEntries diffs funcs of all changed files, and matches funcs which are moved or renamed
+// across files.
+func (m *commitsCovMerger) getChangedFilesDiffEntries(files []changedFile) (DiffEntries, error) {
+	diffEntries := make(DiffEntries, 0, len(files)*8)
+	for _, f := range files {
+		entries, err := m.getFuncDiffEntries(f)
+		if err != nil {
+			return nil, fmt.Errorf("diff funcs of file [%s] error: %w", f.dstFile, err)
+		}
+		diffEntries = append(diffEntries, entries...)
+	}
+	return matchMovedFuncs(diffEntries), nil
+}
+
+// getFuncDiffEntries diffs funcs of changed file, and links profile blocks to funcs.
+func (m *commitsCovMerger) getFuncDiffEntries(f changedFile) (DiffEntries, error) {
+	var srcFuncInfos, dstFuncInfos []*FuncInfo
+	var err error
+	if len(f.srcFile) > 0 {
+		if srcFuncInfos, err = getFuncInfosOfCommitFile(m.srcCommit, f.diff.SrcName, f.srcFile); err != nil {
+			return nil, err
+		}
+	}
+	if len(f.dstFile) > 0 {
+		if dstFuncInfos, err = getFuncInfosOfCommitFile(m.dstCommit, f.diff.DstName, f.dstFile); err != nil {
+			return nil, err
+		}
+	}
+
+	diffEntries := funcDiffForFuncInfos(srcFuncInfos, dstFuncInfos)
+	if err := linkProfileBlocksToDiffEntries(diffEntries, m.srcFnProfile, m.dstFnProfile); err != nil {
+		return nil, err
+	}
+	return diffEntries, nil
+}
+
+func (m *commitsCovMerger) addDiffEntrySummaries(diffEntries DiffEntries, mergedProfiles map[string]*Profile) {
+	for _, entry := range diffEntries {
+		summary := FuncMergeSummary{
+			Diff: entry.Result,
+			From: entry.SrcLocation,
+		}
+		if entry.SrcFuncProfileEntry != nil {
+			info := entry.SrcFuncProfileEntry.FuncInfo
+			summary.File, summary.Line, summary.Func = info.Path, info.StartLine, info.Name
+			summary.Src = newCoverStat(entry.SrcFuncProfileEntry.ProfileBlocks)
+		}
+		if entry.DstFuncProfileEntry != nil {
+			info := entry.DstFuncProfileEntry.FuncInfo
+			summary.File, summary.Line, summary.Func = info.Path, info.StartLine, info.Name
+			summary.Dst = newCoverStat(entry.DstFuncProfileEntry.ProfileBlocks)
+			if profile, ok := mergedProfiles[info.Path]; ok {
+				summary.Merged = newCoverStat(getBlocksInFunc(profile.Blocks, info))
+			}
+		}
+		m.result.Funcs = append(m.result.Funcs, summary)
+	}
+}
+
+// getUnchangedFiles returns files in src or dst profile which are not changed between commits.
+func (m *commitsCovMerger) getUnchangedFiles(changedSrcFiles, changedDstFiles map[string]struct{}) []string {
+	set := make(map[string]struct{}, len(m.dstFnProfile))
+	for _, fnProfile := range [2]map[string]*Profile{m.srcFnProfile, m.dstFnProfile} {
+		for file := range fnProfile {
+			_, isSrcChanged := changedSrcFiles[file]
+			_, isDstChanged := changedDstFiles[file]
+			if !isSrcChanged && !isDstChanged {
+				set[file] = struct{}{}
+			}
+		}
+	}
+
+	files := make([]string, 0, len(set))
+	for file := range set {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// mergeUnchangedFile merges blocks of src and dst profiles for unchanged file, and blocks of src profile are
+// carried over if file is not in dst profile.
+func (m *commitsCovMerger) mergeUnchangedFile(file string) *Profile {
+	srcProfile, hasSrc := m.srcFnProfile[file]
+	dstProfile, hasDst := m.dstFnProfile[file]
+
+	var profile *Profile
+	switch {
+	case hasSrc && hasDst:
+		blocks, err := mergeProfileBlocks(srcProfile.Blocks, dstProfile.Blocks)
+		if err != nil {
+			log.Printf("merge blocks of unchanged file [%s] error: %v, and dst profile is kept", file, err)
+			blocks = dstProfile.Blocks
+		}
+		profile = &Profile{FileName: file, Mode: dstProfile.Mode, Blocks: blocks}
+	case hasSrc:
+		profile = srcProfile
+	default:
+		profile = dstProfile
+	}
+
+	relPath, ok := m.dstModules.relPath(file)
+	if !ok {
+		return profile
+	}
+	funcInfos, err := getFuncInfosOfCommitFile(m.dstCommit, relPath, file)
+	if err != nil {
+		log.Printf("get funcs of unchanged file [%s] error: %v", file, err)
+		return profile
+	}
+	for _, info := range funcInfos {
+		summary := FuncMergeSummary{
+			File:   file,
+			Line:   info.StartLine,
+			Func:   info.Name,
+			Diff:   diffTypeSame,
+			Merged: newCoverStat(getBlocksInFunc(profile.Blocks, info)),
+		}
+		if hasSrc {
+			summary.Src = newCoverStat(getBlocksInFunc(srcProfile.Blocks, info))
+		}
+		if hasDst {
+			summary.Dst = newCoverStat(getBlocksInFunc(dstProfile.Blocks, info))
+		}
+		m.result.Funcs = append(m.result.Funcs, summary)
+	}
+	return profile
+}
+
+func getFuncInfosOfCommitFile(commit *object.Commit, relPath, importPath string) ([]*FuncInfo, error) {
+	f, err := commit.File(relPath)
+	if err != nil {
+		return nil, fmt.Errorf("get file [%s] of commit [%s] error: %w", relPath, commit.Hash.String()[:8], err)
+	}
+	content, err := f.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("read file [%s] error: %w", relPath, err)
+	}
+	return GetFuncInfosWithPath(importPath, []byte(content))
+}
+
+func getBlocksInFunc(blocks []ProfileBlock, funcInfo *FuncInfo) []ProfileBlock {
+	retBlocks := make([]ProfileBlock, 0, 8)
+	for _, block := range blocks {
+		if isBlockInFunc(block, funcInfo) {
+			retBlocks = append(retBlocks, block)
+		}
+	}
+	return retBlocks
+}
+
+func isGoSrcFile(name string) bool {
+	return strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go")
+}
+
+//
+// Go modules of commit.
+//
+
+// commitModules go modules in tree of a commit: module dir (relative to repo root) -> module path.
+type commitModules map[string]string
+
+func getCommitModules(commit *object.Commit) (commitModules, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("getCommitModules get tree error: %w", err)
+	}
+
+	modules := make(commitModules)
+	if err := tree.Files().ForEach(func(f *object.File) error {
+		if path.Base(f.Name) != "go.mod" || strings.HasPrefix(f.Name, "vendor/") || strings.Contains(f.Name, "/vendor/") {
+			return nil
+		}
+		content, err := f.Contents()
+		if err != nil {
+			return err
+		}
+		if modPath := modfile.ModulePath([]byte(content)); len(modPath) > 0 {
+			modules[path.Dir(f.Name)] = modPath
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("getCommitModules iterator files error: %w", err)
+	}
+	return modules, nil
+}
+
+// importPath returns import path of file by the nearest module, and relative path is returned if no module found.
+func (m commitModules) importPath(relPath string) string {
+	prefixLen, modPath := -1, ""
+	for modDir, p := range m {
+		l := 0
+		if modDir != "." {
+			if !strings.HasPrefix(relPath, modDir+"/") {
+				continue
+			}
+			l = len(modDir) + 1
+		}
+		if l > prefixLen {
+			prefixLen, modPath = l, p
+		}
+	}
+	if prefixLen == -1 {
+		return relPath
+	}
+	return path.Join(modPath, relPath[prefixLen:])
+}
+
+// relPath returns relative path of file in repo by the longest matched module path.
+func (m commitModules) relPath(importPath string) (string, bool) {
+	dir, modPath := "", ""
+	for modDir, p := range m {
+		if strings.HasPrefix(importPath, p+"/") && len(p) > len(modPath) {
+			dir, modPath = modDir, p
+		}
+	}
+	if len(modPath) == 0 {
+		return "", false
+	}
+	return path.Join(dir, strings.TrimPrefix(importPath, modPath+"/")), true
+}
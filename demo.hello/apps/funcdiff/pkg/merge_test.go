@@ -0,0 +1,289 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+var testMergeSrcFiles = map[string]string{
+	"go.mod": "module example.com/demo\n\ngo 1.16\n",
+	"main.go": `package main
+
+import "fmt"
+
+func fnSame(ok bool) int {
+	if ok {
+		return 1
+	}
+	return 0
+}
+
+func fnChange() {
+	fmt.Println("v1")
+}
+
+func fnDel() {
+	fmt.Println("del")
+}
+`,
+	"old.go": `package main
+
+func fnRenamed(n int) int {
+	if n > 0 {
+		return n
+	}
+	return -n
+}
+`,
+	"util.go": `package main
+
+func fnUtil(ok bool) int {
+	if ok {
+		return 1
+	}
+	return 0
+}
+`,
+}
+
+var testMergeDstFiles = map[string]string{
+	"main.go": `package main
+
+import "fmt"
+
+func fnAdd() {
+	fmt.Println("add")
+}
+
+func fnSame(ok bool) int {
+	if ok {
+		return 1
+	}
+	return 0
+}
+
+func fnChange() {
+	fmt.Println("v2")
+}
+`,
+	"renamed.go": testMergeSrcFiles["old.go"],
+}
+
+const testMergeSrcCov = `mode: count
+example.com/demo/main.go:5.26,6.8 1 1
+example.com/demo/main.go:6.8,8.3 1 0
+example.com/demo/main.go:9.2,9.10 1 1
+example.com/demo/main.go:12.17,14.2 1 1
+example.com/demo/main.go:16.14,18.2 1 1
+example.com/demo/old.go:3.28,4.11 1 1
+example.com/demo/old.go:4.11,6.3 1 1
+example.com/demo/old.go:7.2,7.11 1 0
+example.com/demo/util.go:3.26,4.8 1 1
+example.com/demo/util.go:4.8,6.3 1 1
+example.com/demo/util.go:7.2,7.10 1 0
+`
+
+const testMergeDstCov = `mode: count
+example.com/demo/main.go:5.14,7.2 1 1
+example.com/demo/main.go:9.26,10.8 1 0
+example.com/demo/main.go:10.8,12.3 1 1
+example.com/demo/main.go:13.2,13.10 1 0
+example.com/demo/main.go:16.17,18.2 1 0
+example.com/demo/renamed.go:3.28,4.11 1 1
+example.com/demo/renamed.go:4.11,6.3 1 0
+example.com/demo/renamed.go:7.2,7.11 1 0
+example.com/demo/util.go:3.26,4.8 1 1
+example.com/demo/util.go:4.8,6.3 1 0
+example.com/demo/util.go:7.2,7.10 1 1
+`
+
+const testMergedCov = `mode: count
+example.com/demo/main.go:5.14,7.2 1 1
+example.com/demo/main.go:9.26,10.8 1 1
+example.com/demo/main.go:10.8,12.3 1 1
+example.com/demo/main.go:13.2,13.10 1 1
+example.com/demo/main.go:16.17,18.2 1 0
+example.com/demo/renamed.go:3.28,4.11 1 1
+example.com/demo/renamed.go:4.11,6.3 1 1
+example.com/demo/renamed.go:7.2,7.11 1 0
+example.com/demo/util.go:3.26,4.8 1 1
+example.com/demo/util.go:4.8,6.3 1 1
+example.com/demo/util.go:7.2,7.10 1 1`
+
+// newTestMergeRepo creates repo with src and dst commits, and returns commit hashes.
+func newTestMergeRepo(t *testing.T, repoDir string, dstFiles map[string]string) (plumbing.Hash, plumbing.Hash) {
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit := func(files map[string]string, removed []string) plumbing.Hash {
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := wt.Add(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, name := range removed {
+			if _, err := wt.Remove(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		hash, err := wt.Commit("test commit", &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	return commit(testMergeSrcFiles, nil), commit(dstFiles, []string{"old.go"})
+}
+
+// runTestMerge merges test cov files by strategy, and returns merge result and merged cov file content.
+func runTestMerge(t *testing.T, strategy string, dstFiles map[string]string, dstCov string) (*CovMergeResult, string) {
+	tmpDir := t.TempDir()
+	repoDir := filepath.Join(tmpDir, "repo")
+	srcHash, dstHash := newTestMergeRepo(t, repoDir, dstFiles)
+
+	opts := CovMergeOptions{
+		RepoPath:   repoDir,
+		SrcCommit:  srcHash.String()[:8],
+		DstCommit:  dstHash.String()[:8],
+		SrcCovPath: filepath.Join(tmpDir, "src.cov"),
+		DstCovPath: filepath.Join(tmpDir, "dst.cov"),
+		OutPath:    filepath.Join(tmpDir, "merged.cov"),
+		Strategy:   strategy,
+	}
+	for path, content := range map[string]string{
+		opts.SrcCovPath: testMergeSrcCov,
+		opts.DstCovPath: dstCov,
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := MergeCovFilesByCommits(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := result.Write(os.Stdout); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(opts.OutPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result, strings.TrimSpace(string(got))
+}
+
+func TestMergeCovFilesByCommits(t *testing.T) {
+	result, got := runTestMerge(t, MergeStrategyFunc, testMergeDstFiles, testMergeDstCov)
+
+	// main.go is updated, old.go is renamed, and util.go is unchanged
+	if len(result.Files) != 2 {
+		t.Fatalf("want 2 changed go files, got: %+v", result.Files)
+	}
+	if got != testMergedCov {
+		t.Fatalf("merged cov file:\nwant:\n%s\ngot:\n%s", testMergedCov, got)
+	}
+
+	diffs := make(map[string]string, len(result.Funcs))
+	for _, f := range result.Funcs {
+		diffs[f.Func] = f.Diff
+	}
+	for name, diff := range map[string]string{
+		"fnSame":    diffTypeSame,
+		"fnAdd":     diffTypeAdd,
+		"fnChange":  diffTypeChange,
+		"fnDel":     diffTypeRemove,
+		"fnRenamed": diffTypeSame,
+		"fnUtil":    diffTypeSame,
+	} {
+		if diffs[name] != diff {
+			t.Fatalf("func %s: want diff %s, got %s", name, diff, diffs[name])
+		}
+	}
+	if result.Merged.Covered != 9 || result.Merged.Total != 11 {
+		t.Fatalf("want merged cover 9/11, got %d/%d", result.Merged.Covered, result.Merged.Total)
+	}
+}
+
+func TestMergeCovFilesByLines(t *testing.T) {
+	result, got := runTestMerge(t, MergeStrategyLine, testMergeDstFiles, testMergeDstCov)
+	if got != testMergedCov {
+		t.Fatalf("merged cov file:\nwant:\n%s\ngot:\n%s", testMergedCov, got)
+	}
+	if result.Strategy != MergeStrategyLine || result.MovedBlocks != 0 {
+		t.Fatalf("want line strategy without moved blocks, got: %s, %d", result.Strategy, result.MovedBlocks)
+	}
+
+	// fnDel is moved from main.go to moved.go
+	dstFiles := make(map[string]string, len(testMergeDstFiles)+1)
+	for name, content := range testMergeDstFiles {
+		dstFiles[name] = content
+	}
+	dstFiles["moved.go"] = `package main
+
+import "fmt"
+
+func fnDel() {
+	fmt.Println("del")
+}
+`
+	dstCov := testMergeDstCov + "example.com/demo/moved.go:5.14,7.2 1 0\n"
+
+	result, got = runTestMerge(t, MergeStrategyLine, dstFiles, dstCov)
+	if result.MovedBlocks != 1 {
+		t.Fatalf("want 1 moved block, got %d", result.MovedBlocks)
+	}
+	if !strings.Contains(got, "example.com/demo/moved.go:5.14,7.2 1 1") {
+		t.Fatalf("want coverage of moved block merged, got:\n%s", got)
+	}
+
+	// moved func is matched across files by func strategy
+	result, got = runTestMerge(t, MergeStrategyFunc, dstFiles, dstCov)
+	if !strings.Contains(got, "example.com/demo/moved.go:5.14,7.2 1 1") {
+		t.Fatalf("want coverage of moved func merged, got:\n%s", got)
+	}
+	for _, f := range result.Funcs {
+		if f.Func == "fnDel" && (f.Diff != diffTypeMoved || f.From != "example.com/demo/main.go:16") {
+			t.Fatalf("want fnDel moved from main.go, got: %+v", f)
+		}
+	}
+}
+
+func TestCommitModulesImportPath(t *testing.T) {
+	modules := commitModules{
+		".":         "example.com/mono",
+		"libs/util": "example.com/mono/util",
+	}
+	for relPath, want := range map[string]string{
+		"main.go":              "example.com/mono/main.go",
+		"libs/util/str.go":     "example.com/mono/util/str.go",
+		"libs/utility/str.go":  "example.com/mono/libs/utility/str.go",
+		"cmd/server/server.go": "example.com/mono/cmd/server/server.go",
+	} {
+		got := modules.importPath(relPath)
+		if got != want {
+			t.Fatalf("import path of %s: want %s, got %s", relPath, want, got)
+		}
+		if rel, ok := modules.relPath(got); !ok || rel != relPath {
+			t.Fatalf("rel path of %s: want %s, got %s", got, relPath, rel)
+		}
+	}
+}
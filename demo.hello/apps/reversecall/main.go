@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"go/build"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
 
+	funcdiff "demo.hello/apps/funcdiff/pkg"
 	"demo.hello/apps/reversecall/pkg"
 )
 
@@ -18,8 +21,14 @@ Refer:
 
 Usage:
 diff commits -> diff files -> diff funcs -> reverse call chain
+
+reversecall impact: outputs impacted entry points (http handlers, grpc methods, tests) of changed funcs between commits.
 */
 
+const cacheDirUsage = "Cache dir of caller relations, and analysis is skipped if no package source changed. " +
+	"Only func bodies of changed packages are re-analysed for static algo, and the whole program is re-analysed for other algos. " +
+	"Skip cache if empty."
+
 var (
 	fullPackage string
 	goFilePath  string
@@ -30,6 +39,12 @@ var (
 	include string
 	ignore  string
 
+	algo        string
+	graphFormat string
+	graphOut    string
+	fullGraph   bool
+	cacheDir    string
+
 	help bool
 )
 
@@ -41,6 +56,13 @@ func getItemsFromString(input string) []string {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "impact" {
+		if err := runImpact(os.Args[2:]); err != nil {
+			log.Fatalln("impact error:", err)
+		}
+		return
+	}
+
 	flag.StringVar(&fullPackage, "fullpackage", "", "Full package import path.")
 	flag.StringVar(&goFilePath, "gofile", "", "Go file path.")
 	flag.StringVar(&packageName, "package", "", "Package name.")
@@ -50,18 +72,25 @@ func main() {
 	flag.StringVar(&include, "include", "", "Include packages with matched prefix.")
 	flag.StringVar(&ignore, "ignore", "", "Ignore packages with matched prefix.")
 
+	flag.StringVar(&algo, "algo", pkg.AlgoPointer, "Call graph algorithm: pointer, static, cha, rta or vta.")
+	flag.StringVar(&graphFormat, "format", "", "Export reverse call chains as graph: dot, mermaid or json. Skip export if empty.")
+	flag.StringVar(&graphOut, "graph-out", "", "Graph output file path, and print to stdout if empty.")
+	flag.StringVar(&cacheDir, "cache-dir", "", cacheDirUsage)
+	flag.BoolVar(&fullGraph, "full", false, "Export the whole call graph (filtered by include and ignore) instead of reverse call chains.")
+
 	flag.BoolVar(&help, "help", false, "Help.")
 
 	flag.Parse()
 	if help {
 		flag.Usage()
+		fmt.Println("\nSub commands:\n  impact\toutput impacted entry points of changed funcs between commits, run \"reversecall impact -h\" for usage.")
 		return
 	}
 
-	args := []string{fullPackage}
-	a, err := pkg.RunAnalysis(&build.Default, false, args)
-	if err != nil {
-		panic(fmt.Sprintln("analysis error:", err))
+	switch graphFormat {
+	case "", pkg.FormatDOT, pkg.FormatMermaid, pkg.FormatJSON:
+	default:
+		panic(fmt.Sprintln("invalid graph format:", graphFormat))
 	}
 
 	opts := &pkg.RenderOpts{
@@ -76,10 +105,12 @@ func main() {
 	}
 	log.Println("render options:", string(b))
 
-	callMap, err := a.Render(fullPackage, opts)
+	args := []string{fullPackage}
+	result, err := pkg.RunAnalysisWithCache(&build.Default, false, args, algo, opts, cacheDir)
 	if err != nil {
-		panic(fmt.Sprintln("render process error:", err))
+		panic(fmt.Sprintln("analysis error:", err))
 	}
+	callMap := result.CallMap
 
 	tree := &pkg.MWTree{}
 	param := pkg.MWTreeBuildParam{
@@ -101,4 +132,116 @@ func main() {
 		}
 		log.Printf("反向调用链:%s", strings.Join(funcs, "<-"))
 	}
+
+	if len(graphFormat) > 0 {
+		graph := tree.ToCallGraph()
+		graph.Algo = algo
+		if fullGraph {
+			graph = pkg.NewCallGraphFromCallMap(algo, callMap)
+		}
+		if err := writeGraph(graph, graphFormat, graphOut); err != nil {
+			panic(fmt.Sprintln("write graph error:", err))
+		}
+	}
+}
+
+func writeGraph(graph *pkg.CallGraph, format, path string) error {
+	if len(path) == 0 {
+		return graph.Write(os.Stdout, format)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return graph.Write(f, format)
+}
+
+// runImpact runs: reversecall impact -repo path -src-commit c1 -dst-commit c2 -fullpackage demo.hello/echoserver
+func runImpact(args []string) error {
+	var (
+		repoPath  string
+		srcCommit string
+		dstCommit string
+		packages  string
+		tests     bool
+		output    string
+	)
+	fs := flag.NewFlagSet("impact", flag.ExitOnError)
+	fs.StringVar(&repoPath, "repo", ".", "git repo path.")
+	fs.StringVar(&srcCommit, "src-commit", "", "src (base) commit.")
+	fs.StringVar(&dstCommit, "dst-commit", "", "dst commit.")
+	fs.StringVar(&packages, "fullpackage", "", "Full package import paths to analysis, separated by comma.")
+	fs.BoolVar(&tests, "tests", true, "Whether include test funcs in analysis.")
+	fs.StringVar(&algo, "algo", pkg.AlgoPointer, "Call graph algorithm: pointer, static, cha, rta or vta.")
+	fs.StringVar(&cacheDir, "cache-dir", "", cacheDirUsage)
+	fs.BoolVar(&nointer, "nointer", false, "Whether include internal (private) functions.")
+	fs.StringVar(&include, "include", "", "Include packages with matched prefix.")
+	fs.StringVar(&ignore, "ignore", "", "Ignore packages with matched prefix.")
+	fs.StringVar(&output, "o", "", "output json file path, and print to stdout if empty.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	for _, item := range [][2]string{
+		{"src-commit", srcCommit},
+		{"dst-commit", dstCommit},
+		{"fullpackage", packages},
+	} {
+		if len(item[1]) == 0 {
+			fs.Usage()
+			return fmt.Errorf("flag -%s is required", item[0])
+		}
+	}
+
+	// working tree is analysed, and it should be same as dst commit so that changed funcs match call map
+	if err := funcdiff.VerifyHeadAtCommit(repoPath, dstCommit); err != nil {
+		return err
+	}
+	changedFuncs, err := funcdiff.GetChangedFuncsByCommits(repoPath, srcCommit, dstCommit)
+	if err != nil {
+		return err
+	}
+	log.Printf("%d changed funcs", len(changedFuncs))
+
+	// empty report if no changed funcs
+	report := pkg.GetImpacts(nil, nil, nil)
+	if len(changedFuncs) > 0 {
+		opts := &pkg.RenderOpts{
+			Nointer: nointer,
+			Nostd:   true,
+			Include: getItemsFromString(include),
+			Ignore:  getItemsFromString(ignore),
+		}
+		result, err := pkg.RunAnalysisWithCache(&build.Default, tests, getItemsFromString(packages), algo, opts, cacheDir)
+		if err != nil {
+			return fmt.Errorf("analysis error: %w", err)
+		}
+
+		params := make([]pkg.MWTreeBuildParam, 0, len(changedFuncs))
+		for _, fn := range changedFuncs {
+			name := fn.Name
+			if len(fn.Recv) > 0 {
+				name = fn.Recv + "@" + fn.Name
+			}
+			params = append(params, pkg.MWTreeBuildParam{
+				GoFilePath: filepath.Join(repoPath, fn.File),
+				PkgName:    fn.Package,
+				FnName:     name,
+			})
+		}
+		report = pkg.GetImpacts(params, result.CallMap, result.EntryPoints)
+	}
+	report.SrcCommit = srcCommit
+	report.DstCommit = dstCommit
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("report marshal error: %w", err)
+	}
+	if len(output) == 0 {
+		fmt.Println(string(b))
+		return nil
+	}
+	return os.WriteFile(output, b, 0644)
 }
@@ -0,0 +1,336 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+/*
+调用关系缓存
+packages source hash (and whether test files included) => cached caller relations and entry points of each package
+*/
+
+// AnalysisResult 分析结果, 包括函数调用关系和入口函数
+type AnalysisResult struct {
+	CallMap     map[string]CallerRelation
+	EntryPoints map[string]EntryPoint
+}
+
+// callMapCacheVersion version of cache format, and it's increased when cached results are incompatible.
+const callMapCacheVersion = 3
+
+// callMapCache cached analysis results grouped by package path.
+type callMapCache struct {
+	Algo string `json:"algo"`
+	// Packages package path => source
+	Packages    map[string]packageSource             `json:"packages"`
+	Relations   map[string]map[string]CallerRelation `json:"relations"`
+	EntryPoints map[string]map[string]EntryPoint     `json:"entry_points"`
+}
+
+func newCallMapCache(algo string) *callMapCache {
+	return &callMapCache{
+		Algo:        algo,
+		Packages:    make(map[string]packageSource),
+		Relations:   make(map[string]map[string]CallerRelation),
+		EntryPoints: make(map[string]map[string]EntryPoint),
+	}
+}
+
+// RunAnalysisWithCache runs analysis and returns call map and entry points, and results are cached in dir by
+// source hash of packages. If no package is changed, cached results are returned without analysis. If some
+// packages are changed, only func bodies of changed packages are analysed for static algo, and the whole program
+// is analysed for other algos as call edges of them depend on the whole program.
+func RunAnalysisWithCache(buildCtx *build.Context, tests bool, args []string, algo string, opts *RenderOpts, cacheDir string) (*AnalysisResult, error) {
+	if len(cacheDir) == 0 {
+		return analysisRunner(buildCtx, tests, args, algo, opts, nil)
+	}
+
+	pkgs, err := getPackageSources(buildCtx, args, tests)
+	if err != nil {
+		return nil, fmt.Errorf("RunAnalysisWithCache get package sources error: %w", err)
+	}
+	cachePath, err := getCacheFilePath(cacheDir, tests, args, algo, opts)
+	if err != nil {
+		return nil, fmt.Errorf("RunAnalysisWithCache error: %w", err)
+	}
+	cache, err := loadCallMapCache(cachePath)
+	if err != nil {
+		log.Printf("load cache [%s] error: %v, and rebuild it", cachePath, err)
+		cache = newCallMapCache(algo)
+	}
+
+	changed := make([]string, 0)
+	for path, src := range pkgs {
+		if cached, ok := cache.Packages[path]; !ok || cached.Hash != src.Hash || cached.Tests != src.Tests {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	removed := make([]string, 0)
+	for path := range cache.Packages {
+		if _, ok := pkgs[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	log.Printf("cached packages: %d, changed: %d, removed: %d", len(cache.Packages), len(changed), len(removed))
+	if len(changed) == 0 && len(removed) == 0 {
+		return cache.toResult(), nil
+	}
+
+	var result *AnalysisResult
+	if algo == AlgoStatic && len(cache.Packages) > 0 {
+		// static call edges only depend on source of caller package, and the same args are loaded so that test
+		// files are only included for args packages
+		log.Printf("analysis changed packages: %v", changed)
+		if len(changed) > 0 {
+			bodyPkgs := make(map[string]struct{}, len(changed))
+			for _, path := range changed {
+				bodyPkgs[path] = struct{}{}
+			}
+			if result, err = analysisRunner(buildCtx, tests, args, algo, opts, bodyPkgs); err != nil {
+				return nil, fmt.Errorf("RunAnalysisWithCache error: %w", err)
+			}
+		}
+		for _, path := range append(changed, removed...) {
+			delete(cache.Packages, path)
+			delete(cache.Relations, path)
+			delete(cache.EntryPoints, path)
+		}
+	} else {
+		if result, err = analysisRunner(buildCtx, tests, args, algo, opts, nil); err != nil {
+			return nil, fmt.Errorf("RunAnalysisWithCache error: %w", err)
+		}
+		cache = newCallMapCache(algo)
+	}
+
+	if result != nil {
+		if algo == AlgoStatic {
+			cache.addResult(result, pkgs, changed)
+		} else {
+			cache.addResult(result, pkgs, nil)
+		}
+	}
+	for path, src := range pkgs {
+		cache.Packages[path] = src
+	}
+	if err := cache.save(cachePath); err != nil {
+		log.Printf("save cache [%s] error: %v", cachePath, err)
+	}
+	return cache.toResult(), nil
+}
+
+// analysisRunner runs analysis for results, and it's replaced in test.
+var analysisRunner = runAnalysisForResult
+
+func runAnalysisForResult(buildCtx *build.Context, tests bool, args []string, algo string, opts *RenderOpts, bodyPkgs map[string]struct{}) (*AnalysisResult, error) {
+	a, err := runAnalysisWithAlgo(buildCtx, tests, args, algo, bodyPkgs)
+	if err != nil {
+		return nil, err
+	}
+	callMap, err := a.Render(args[0], opts)
+	if err != nil {
+		return nil, err
+	}
+	return &AnalysisResult{
+		CallMap:     callMap,
+		EntryPoints: a.GetEntryPoints(),
+	}, nil
+}
+
+// addResult adds analysis results grouped by package of caller file, and only results of the given packages are
+// added if paths is not nil (for static algo, results of unchanged dependencies are skipped).
+func (c *callMapCache) addResult(result *AnalysisResult, pkgs map[string]packageSource, paths []string) {
+	dirs := make(map[string]string, len(pkgs))
+	for path, src := range pkgs {
+		dirs[src.Dir] = path
+	}
+	included := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		included[path] = struct{}{}
+	}
+	getPackage := func(file string) (string, bool) {
+		path := dirs[filepath.Dir(file)]
+		if _, ok := included[path]; ok || paths == nil {
+			return path, true
+		}
+		return "", false
+	}
+
+	for name, relation := range result.CallMap {
+		if path, ok := getPackage(relation.Caller.File); ok {
+			if _, ok := c.Relations[path]; !ok {
+				c.Relations[path] = make(map[string]CallerRelation)
+			}
+			c.Relations[path][name] = relation
+		}
+	}
+	for key, entry := range result.EntryPoints {
+		if path, ok := getPackage(entry.File); ok {
+			if _, ok := c.EntryPoints[path]; !ok {
+				c.EntryPoints[path] = make(map[string]EntryPoint)
+			}
+			c.EntryPoints[path][key] = entry
+		}
+	}
+}
+
+func (c *callMapCache) toResult() *AnalysisResult {
+	result := &AnalysisResult{
+		CallMap:     make(map[string]CallerRelation),
+		EntryPoints: make(map[string]EntryPoint),
+	}
+	for _, relations := range c.Relations {
+		for name, relation := range relations {
+			result.CallMap[name] = relation
+		}
+	}
+	for _, entryPoints := range c.EntryPoints {
+		for key, entry := range entryPoints {
+			result.EntryPoints[key] = entry
+		}
+	}
+	return result
+}
+
+func (c *callMapCache) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	// write to temp file and rename, to avoid broken cache file
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func loadCallMapCache(path string) (*callMapCache, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cache := &callMapCache{}
+	if err := json.Unmarshal(b, cache); err != nil {
+		return nil, err
+	}
+	if cache.Packages == nil || cache.Relations == nil || cache.EntryPoints == nil {
+		return nil, fmt.Errorf("invalid cache file")
+	}
+	return cache, nil
+}
+
+// getCacheFilePath returns cache file path by analysis args, algo and render options.
+func getCacheFilePath(cacheDir string, tests bool, args []string, algo string, opts *RenderOpts) (string, error) {
+	b, err := json.Marshal(struct {
+		Version int
+		Tests   bool
+		Args    []string
+		Algo    string
+		Opts    *RenderOpts
+	}{callMapCacheVersion, tests, args, algo, opts})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return filepath.Join(cacheDir, fmt.Sprintf("callmap_%s_%s.json", algo, hex.EncodeToString(sum[:8]))), nil
+}
+
+//
+// Package source hash.
+//
+
+type packageSource struct {
+	Dir  string `json:"dir"`
+	Hash string `json:"hash"`
+	// Tests whether test files are included in hash, and it's true only for args packages when analysis with tests
+	Tests bool `json:"tests"`
+}
+
+// getPackageSources returns source dir and hash of packages (not in GOROOT) imported by args recursively, and test
+// files of args packages are included if tests is true.
+func getPackageSources(buildCtx *build.Context, args []string, tests bool) (map[string]packageSource, error) {
+	srcDir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	roots := make(map[string]struct{}, len(args))
+	for _, arg := range args {
+		roots[arg] = struct{}{}
+	}
+
+	pkgs := make(map[string]packageSource)
+	var visit func(path string) error
+	visit = func(path string) error {
+		if _, ok := pkgs[path]; ok || path == "C" || path == "unsafe" {
+			return nil
+		}
+		p, err := buildCtx.Import(path, srcDir, 0)
+		if err != nil {
+			return fmt.Errorf("import package [%s] error: %w", path, err)
+		}
+		if p.Goroot {
+			return nil
+		}
+
+		// root package may be imported by another root, so test inclusion is decided by path instead of visit order
+		_, isRoot := roots[path]
+		withTests := tests && isRoot
+		files := append(append([]string{}, p.GoFiles...), p.CgoFiles...)
+		imports := p.Imports
+		if withTests {
+			files = append(append(files, p.TestGoFiles...), p.XTestGoFiles...)
+			imports = append(append(append([]string{}, imports...), p.TestImports...), p.XTestImports...)
+		}
+		hash, err := hashFiles(p.Dir, files)
+		if err != nil {
+			return err
+		}
+		pkgs[path] = packageSource{Dir: p.Dir, Hash: hash, Tests: withTests}
+
+		for _, imp := range imports {
+			if err := visit(imp); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, arg := range args {
+		if err := visit(arg); err != nil {
+			return nil, err
+		}
+	}
+	return pkgs, nil
+}
+
+func hashFiles(dir string, files []string) (string, error) {
+	sort.Strings(files)
+	h := sha256.New()
+	for _, name := range files {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\n", name)
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
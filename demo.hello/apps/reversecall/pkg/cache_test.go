@@ -0,0 +1,150 @@
+package pkg
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRunAnalysisWithCache(t *testing.T) {
+	t.Setenv("GO111MODULE", "off")
+	gopath := t.TempDir()
+	appDir := filepath.Join(gopath, "src/example.com/app")
+	libDir := filepath.Join(appDir, "lib")
+	writeFile := func(path, content string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(filepath.Join(appDir, "main.go"), "package main\n\nimport \"example.com/app/lib\"\n\nfunc main() { lib.Hello() }\n")
+	writeFile(filepath.Join(libDir, "lib.go"), "package lib\n\nfunc Hello() { world() }\n\nfunc world() {}\n")
+
+	buildCtx := build.Default
+	buildCtx.GOPATH = gopath
+	args := []string{"example.com/app"}
+
+	var analysedPkgs []map[string]struct{}
+	analysisRunner = func(buildCtx *build.Context, tests bool, analysisArgs []string, algo string, opts *RenderOpts, bodyPkgs map[string]struct{}) (*AnalysisResult, error) {
+		if !reflect.DeepEqual(analysisArgs, args) {
+			t.Fatalf("want analysis args %v, got %v", args, analysisArgs)
+		}
+		analysedPkgs = append(analysedPkgs, bodyPkgs)
+		result := &AnalysisResult{
+			CallMap:     make(map[string]CallerRelation),
+			EntryPoints: make(map[string]EntryPoint),
+		}
+		result.CallMap["main.main"] = CallerRelation{
+			Caller:  FuncDesc{File: filepath.Join(appDir, "main.go"), Package: "main", Name: "main"},
+			Callees: []FuncDesc{{File: filepath.Join(libDir, "lib.go"), Package: "lib", Name: "Hello"}},
+		}
+		result.CallMap["lib.Hello"] = CallerRelation{
+			Caller:  FuncDesc{File: filepath.Join(libDir, "lib.go"), Package: "lib", Name: "Hello"},
+			Callees: []FuncDesc{{File: filepath.Join(libDir, "lib.go"), Package: "lib", Name: "world"}},
+		}
+		return result, nil
+	}
+	defer func() {
+		analysisRunner = runAnalysisForResult
+	}()
+
+	cacheDir := t.TempDir()
+	run := func() *AnalysisResult {
+		result, err := RunAnalysisWithCache(&buildCtx, false, args, AlgoStatic, &RenderOpts{Nostd: true}, cacheDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.CallMap) != 2 {
+			t.Fatalf("want 2 caller relations, got: %+v", result.CallMap)
+		}
+		return result
+	}
+
+	// build cache, and then load from cache
+	run()
+	run()
+	// only changed package is analysed
+	writeFile(filepath.Join(libDir, "lib.go"), "package lib\n\nfunc Hello() { world() }\n\nfunc world() { println() }\n")
+	run()
+	want := []map[string]struct{}{nil, {"example.com/app/lib": {}}}
+	if !reflect.DeepEqual(analysedPkgs, want) {
+		t.Fatalf("want analysed packages %v, got %v", want, analysedPkgs)
+	}
+}
+
+func TestRunAnalysisWithCacheOfStaticAlgo(t *testing.T) {
+	t.Setenv("GO111MODULE", "off")
+	gopath := t.TempDir()
+	writeFile := func(path, content string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// fake std "testing" package, so that the real std lib is not analysed
+	goroot := t.TempDir()
+	writeFile(filepath.Join(goroot, "src/testing/testing.go"), "package testing\n\ntype T struct{}\n")
+
+	appDir := filepath.Join(gopath, "src/example.com/app")
+	libDir := filepath.Join(appDir, "lib")
+	writeFile(filepath.Join(appDir, "main.go"), "package main\n\nimport \"example.com/app/lib\"\n\nfunc main() { run() }\n\nfunc run() { lib.Hello() }\n")
+	writeFile(filepath.Join(appDir, "main_test.go"), "package main\n\nimport \"testing\"\n\nfunc TestRun(t *testing.T) { run() }\n")
+	writeFile(filepath.Join(libDir, "lib.go"), "package lib\n\nfunc Hello() { world() }\n\nfunc world() {}\n")
+	writeFile(filepath.Join(libDir, "lib_test.go"), "package lib\n\nimport \"testing\"\n\nfunc TestHello(t *testing.T) { Hello() }\n")
+
+	buildCtx := build.Default
+	buildCtx.GOPATH = gopath
+	buildCtx.GOROOT = goroot
+	args := []string{"example.com/app"}
+	opts := &RenderOpts{Nostd: true}
+	cacheDir := t.TempDir()
+
+	run := func(cacheDir string) *AnalysisResult {
+		result, err := RunAnalysisWithCache(&buildCtx, true, args, AlgoStatic, opts, cacheDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// tests of dependency package are not included
+		if _, ok := result.EntryPoints["example.com/app.TestRun"]; !ok {
+			t.Fatalf("want entry point TestRun, got: %+v", result.EntryPoints)
+		}
+		for key, relation := range result.CallMap {
+			if relation.Caller.Name == "TestHello" {
+				t.Fatalf("want no relations of dependency tests, got: %s", key)
+			}
+		}
+		for key := range result.EntryPoints {
+			if key == "example.com/app/lib.TestHello" {
+				t.Fatalf("want no entry points of dependency tests, got: %s", key)
+			}
+		}
+		return result
+	}
+
+	run(cacheDir)
+	// changed dependency package is analysed without tests, and result is same as analysis without cache
+	writeFile(filepath.Join(libDir, "lib.go"), "package lib\n\nfunc Hello() { world2() }\n\nfunc world2() {}\n")
+	if got, want := run(cacheDir), run(""); !reflect.DeepEqual(got, want) {
+		t.Fatalf("want result:\n%+v\ngot:\n%+v", want, got)
+	}
+
+	cachePath, err := getCacheFilePath(cacheDir, true, args, AlgoStatic, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := loadCallMapCache(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, tests := range map[string]bool{"example.com/app": true, "example.com/app/lib": false} {
+		if src := cache.Packages[path]; src.Tests != tests {
+			t.Errorf("want package %s cached with tests %v, got %v", path, tests, src.Tests)
+		}
+	}
+}
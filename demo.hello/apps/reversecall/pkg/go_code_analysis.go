@@ -4,8 +4,14 @@ import (
 	"fmt"
 	"go/build"
 	"log"
+	"strings"
 	"time"
 
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/callgraph/static"
+	"golang.org/x/tools/go/callgraph/vta"
 	"golang.org/x/tools/go/loader"
 	"golang.org/x/tools/go/pointer"
 	"golang.org/x/tools/go/ssa"
@@ -55,19 +61,63 @@ go/pointer: Package pointer implements Andersen's analysis, an inclusion-based p
 （指针分析是一类特殊的数据流问题，它是其它静态程序分析的基础。算法最终建立各节点间的指向关系。）
 */
 
+// Call graph algorithms.
+const (
+	// AlgoPointer Andersen's pointer analysis, most precise but slowest
+	AlgoPointer = "pointer"
+	// AlgoStatic static calls only
+	AlgoStatic = "static"
+	// AlgoCHA class hierarchy analysis
+	AlgoCHA = "cha"
+	// AlgoRTA rapid type analysis
+	AlgoRTA = "rta"
+	// AlgoVTA variable type analysis, more precise than cha and rta
+	AlgoVTA = "vta"
+)
+
 // Analysis includes analysis data and results.
 type Analysis struct {
-	prog   *ssa.Program
-	conf   loader.Config
-	pkgs   []*ssa.Package
-	mains  []*ssa.Package
-	result *pointer.Result
+	algo      string
+	prog      *ssa.Program
+	conf      loader.Config
+	pkgs      []*ssa.Package
+	mains     []*ssa.Package
+	callGraph *callgraph.Graph
 }
 
-// RunAnalysis runs go package analysis.
+// RunAnalysis runs go package analysis by pointer analysis.
 func RunAnalysis(buildCtx *build.Context, tests bool, args []string) (*Analysis, error) {
+	return RunAnalysisWithAlgo(buildCtx, tests, args, AlgoPointer)
+}
+
+// RunAnalysisWithAlgo runs go package analysis, and builds call graph by the given algorithm.
+func RunAnalysisWithAlgo(buildCtx *build.Context, tests bool, args []string, algo string) (*Analysis, error) {
+	return runAnalysisWithAlgo(buildCtx, tests, args, algo, nil)
+}
+
+// runAnalysisWithAlgo runs go package analysis, and only func bodies of the given packages are type-checked and
+// built if bodyPkgs is not nil. It's for static algo only, as call edges of other algos depend on the whole program.
+func runAnalysisWithAlgo(buildCtx *build.Context, tests bool, args []string, algo string, bodyPkgs map[string]struct{}) (*Analysis, error) {
+	switch algo {
+	case AlgoPointer, AlgoStatic, AlgoCHA, AlgoRTA, AlgoVTA:
+	default:
+		return nil, fmt.Errorf("invalid algo: %s", algo)
+	}
+	if bodyPkgs != nil && algo != AlgoStatic {
+		return nil, fmt.Errorf("algo %s requires func bodies of all packages", algo)
+	}
+
+	// external test package "x_test" belongs to package "x"
+	hasBody := func(path string) bool {
+		_, ok := bodyPkgs[strings.TrimSuffix(path, "_test")]
+		return ok || bodyPkgs == nil
+	}
+
 	t0 := time.Now()
 	conf := loader.Config{Build: buildCtx}
+	if bodyPkgs != nil {
+		conf.TypeCheckFuncBodies = hasBody
+	}
 	_, err := conf.FromArgs(args, tests)
 	if err != nil {
 		return nil, fmt.Errorf("invalid args: %v", args)
@@ -81,18 +131,34 @@ func RunAnalysis(buildCtx *build.Context, tests bool, args []string) (*Analysis,
 
 	t0 = time.Now()
 	prog := ssautil.CreateProgram(load, 0)
-	prog.Build()
 	pkgs := prog.AllPackages()
+	if bodyPkgs == nil {
+		prog.Build()
+	} else {
+		for _, pkg := range pkgs {
+			if hasBody(pkg.Pkg.Path()) {
+				pkg.Build()
+			}
+		}
+	}
 
 	var mains []*ssa.Package
 	if tests {
 		for _, pkg := range pkgs {
-			if main := prog.CreateTestMainPackage(pkg); main != nil {
+			main, err := createTestMainPackage(prog, pkg)
+			if err != nil {
+				return nil, fmt.Errorf("create test main package error: %w", err)
+			}
+			if main != nil {
 				mains = append(mains, main)
 			}
 		}
 		if mains == nil {
-			log.Fatalln("no tests")
+			// static, cha and vta algo don't require main packages
+			if algo == AlgoPointer || algo == AlgoRTA {
+				return nil, fmt.Errorf("no tests")
+			}
+			log.Printf("no tests")
 		}
 	} else {
 		mains = append(mains, ssautil.MainPackages(pkgs)...)
@@ -103,25 +169,53 @@ func RunAnalysis(buildCtx *build.Context, tests bool, args []string) (*Analysis,
 	log.Printf("building.. %d packages (%d main) took: %v", len(pkgs), len(mains), time.Since(t0))
 
 	t0 = time.Now()
-	ptrcfg := &pointer.Config{
-		Mains:          mains,
-		BuildCallGraph: true,
-	}
-	result, err := pointer.Analyze(ptrcfg)
+	cg, err := buildCallGraph(algo, prog, mains)
 	if err != nil {
-		log.Fatalln("analyze failed:", err)
+		return nil, err
 	}
-	log.Printf("analysis took: %v", time.Since(t0))
+	log.Printf("analysis by %s took: %v", algo, time.Since(t0))
 
 	return &Analysis{
-		prog:   prog,
-		conf:   conf,
-		pkgs:   pkgs,
-		mains:  mains,
-		result: result,
+		algo:      algo,
+		prog:      prog,
+		conf:      conf,
+		pkgs:      pkgs,
+		mains:     mains,
+		callGraph: cg,
 	}, nil
 }
 
+func buildCallGraph(algo string, prog *ssa.Program, mains []*ssa.Package) (*callgraph.Graph, error) {
+	switch algo {
+	case AlgoStatic:
+		return static.CallGraph(prog), nil
+	case AlgoCHA:
+		return cha.CallGraph(prog), nil
+	case AlgoVTA:
+		// vta refines the initial call graph built by cha
+		return vta.CallGraph(ssautil.AllFunctions(prog), cha.CallGraph(prog)), nil
+	case AlgoRTA:
+		roots := make([]*ssa.Function, 0, len(mains)*2)
+		for _, main := range mains {
+			roots = append(roots, main.Func("init"), main.Func("main"))
+		}
+		if len(roots) == 0 {
+			return nil, fmt.Errorf("algo rta requires main packages")
+		}
+		return rta.Analyze(roots, true).CallGraph, nil
+	default:
+		ptrcfg := &pointer.Config{
+			Mains:          mains,
+			BuildCallGraph: true,
+		}
+		result, err := pointer.Analyze(ptrcfg)
+		if err != nil {
+			return nil, fmt.Errorf("pointer analysis error: %w", err)
+		}
+		return result.CallGraph, nil
+	}
+}
+
 // RenderOpts go package analysis render options.
 type RenderOpts struct {
 	Nointer bool
@@ -136,5 +230,5 @@ func (a *Analysis) Render(project string, opts *RenderOpts) (map[string]CallerRe
 	log.Printf("no std packages: %v", opts.Nostd)
 	log.Printf("%d include prefixes: %v", len(opts.Include), opts.Include)
 	log.Printf("%d ignore prefixes: %v", len(opts.Ignore), opts.Ignore)
-	return printOutput(a.prog, a.result.CallGraph, opts)
+	return printOutput(a.prog, a.callGraph, opts)
 }
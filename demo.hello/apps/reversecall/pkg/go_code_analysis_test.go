@@ -81,3 +81,57 @@ func TestAnalysisReverseCallGraphy(t *testing.T) {
 		}
 	}
 }
+
+// newFixtureBuildContext returns build context of GOPATH mode for packages in "testdata/src".
+func newFixtureBuildContext(t *testing.T) *build.Context {
+	t.Setenv("GO111MODULE", "off")
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := build.Default
+	ctx.GOPATH = gopath
+	return &ctx
+}
+
+func TestRunAnalysisWithAlgo(t *testing.T) {
+	buildCtx := newFixtureBuildContext(t)
+	for _, tc := range []struct {
+		algo    string
+		dynamic bool
+	}{
+		{algo: AlgoStatic},
+		{algo: AlgoCHA, dynamic: true},
+		{algo: AlgoRTA, dynamic: true},
+		{algo: AlgoVTA, dynamic: true},
+		{algo: AlgoPointer, dynamic: true},
+	} {
+		a, err := RunAnalysisWithAlgo(buildCtx, true, []string{"fixture/lib"}, tc.algo)
+		if err != nil {
+			t.Fatalf("algo %s: %v", tc.algo, err)
+		}
+		if len(a.mains) != 1 {
+			t.Fatalf("algo %s: want 1 test main package, got %d", tc.algo, len(a.mains))
+		}
+
+		// dynamic call of interface method
+		found := false
+		for fn, node := range a.callGraph.Nodes {
+			if fn == nil || fn.String() != "fixture/lib.Hello" {
+				continue
+			}
+			for _, edge := range node.Out {
+				if edge.Callee.Func.String() == "(fixture/lib.En).Greet" {
+					found = true
+				}
+			}
+		}
+		if found != tc.dynamic {
+			t.Errorf("algo %s: want edge Hello->En.Greet %v, got %v", tc.algo, tc.dynamic, found)
+		}
+	}
+
+	if _, err := RunAnalysisWithAlgo(buildCtx, false, []string{"fixture/lib"}, "unknown"); err == nil {
+		t.Fatal("want invalid algo error")
+	}
+}
@@ -0,0 +1,171 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+/*
+调用图导出
+call graph => Graphviz DOT, Mermaid, JSON
+*/
+
+// Graph output formats.
+const (
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+	FormatJSON    = "json"
+)
+
+// CallGraphNode 调用图节点
+type CallGraphNode struct {
+	ID      string `json:"id"` // package.func
+	File    string `json:"file"`
+	Package string `json:"package"`
+	Name    string `json:"name"`
+}
+
+// CallGraphEdge 调用图的边: caller -> callee
+type CallGraphEdge struct {
+	Caller string `json:"caller"`
+	Callee string `json:"callee"`
+}
+
+// CallGraph 调用图文档
+type CallGraph struct {
+	Algo  string          `json:"algo,omitempty"`
+	Nodes []CallGraphNode `json:"nodes"`
+	Edges []CallGraphEdge `json:"edges"`
+
+	nodeSet map[string]struct{}
+	edgeSet map[CallGraphEdge]struct{}
+}
+
+// NewCallGraph .
+func NewCallGraph(algo string) *CallGraph {
+	return &CallGraph{
+		Algo:    algo,
+		Nodes:   make([]CallGraphNode, 0),
+		Edges:   make([]CallGraphEdge, 0),
+		nodeSet: make(map[string]struct{}),
+		edgeSet: make(map[CallGraphEdge]struct{}),
+	}
+}
+
+// AddEdge adds caller -> callee edge and nodes, and duplicated nodes and edges are skipped.
+func (g *CallGraph) AddEdge(caller, callee FuncDesc) {
+	callerID := g.addNode(caller)
+	calleeID := g.addNode(callee)
+	edge := CallGraphEdge{Caller: callerID, Callee: calleeID}
+	if _, ok := g.edgeSet[edge]; !ok {
+		g.edgeSet[edge] = struct{}{}
+		g.Edges = append(g.Edges, edge)
+	}
+}
+
+func (g *CallGraph) addNode(fn FuncDesc) string {
+	id := fmt.Sprintf("%s.%s", fn.Package, fn.Name)
+	if _, ok := g.nodeSet[id]; !ok {
+		g.nodeSet[id] = struct{}{}
+		g.Nodes = append(g.Nodes, CallGraphNode{
+			ID:      id,
+			File:    fn.File,
+			Package: fn.Package,
+			Name:    fn.Name,
+		})
+	}
+	return id
+}
+
+// Sort sorts nodes and edges by id, to make output stable.
+func (g *CallGraph) Sort() {
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].ID < g.Nodes[j].ID
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		ei, ej := g.Edges[i], g.Edges[j]
+		return ei.Caller < ej.Caller || ei.Caller == ej.Caller && ei.Callee < ej.Callee
+	})
+}
+
+// Write writes call graph in the given format.
+func (g *CallGraph) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatDOT:
+		return g.writeDOT(w)
+	case FormatMermaid:
+		return g.writeMermaid(w)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(g)
+	default:
+		return fmt.Errorf("invalid graph format: %s", format)
+	}
+}
+
+func (g *CallGraph) writeDOT(w io.Writer) error {
+	lines := make([]string, 0, len(g.Nodes)+len(g.Edges)+4)
+	lines = append(lines, "digraph callgraph {", "  rankdir=LR;", "  node [shape=box];")
+	for _, node := range g.Nodes {
+		lines = append(lines, fmt.Sprintf("  %q;", node.ID))
+	}
+	for _, edge := range g.Edges {
+		lines = append(lines, fmt.Sprintf("  %q -> %q;", edge.Caller, edge.Callee))
+	}
+	lines = append(lines, "}")
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+func (g *CallGraph) writeMermaid(w io.Writer) error {
+	// mermaid node id should be simple, and func name is used as label
+	ids := make(map[string]string, len(g.Nodes))
+	lines := make([]string, 0, len(g.Nodes)+len(g.Edges)+1)
+	lines = append(lines, "graph LR")
+	for i, node := range g.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+		label := strings.ReplaceAll(node.ID, `"`, "#quot;")
+		lines = append(lines, fmt.Sprintf(`  %s["%s"]`, ids[node.ID], label))
+	}
+	for _, edge := range g.Edges {
+		lines = append(lines, fmt.Sprintf("  %s --> %s", ids[edge.Caller], ids[edge.Callee]))
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// NewCallGraphFromCallMap returns call graph of caller relations (filtered by render options).
+func NewCallGraphFromCallMap(algo string, callMap map[string]CallerRelation) *CallGraph {
+	graph := NewCallGraph(algo)
+	for _, relation := range callMap {
+		for _, callee := range relation.Callees {
+			graph.AddEdge(relation.Caller, callee)
+		}
+	}
+	graph.Sort()
+	return graph
+}
+
+// ToCallGraph returns call graph of reverse call tree, edges are from caller (leaf node) to callee.
+func (tree *MWTree) ToCallGraph() *CallGraph {
+	graph := NewCallGraph("")
+	if tree.Head == nil {
+		return graph
+	}
+
+	graph.addNode(tree.Head.Value)
+	nodeList := []*MWTNode{tree.Head}
+	for len(nodeList) > 0 {
+		curNode := nodeList[0]
+		nodeList = nodeList[1:]
+		for _, node := range curNode.LeafNodes {
+			graph.AddEdge(node.Value, curNode.Value)
+			nodeList = append(nodeList, node)
+		}
+	}
+	return graph
+}
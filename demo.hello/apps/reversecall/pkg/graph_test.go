@@ -0,0 +1,64 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newTestReverseCallTree() *MWTree {
+	newRelation := func(caller string, callees ...string) CallerRelation {
+		relation := CallerRelation{Caller: FuncDesc{Package: "example", Name: caller}}
+		for _, callee := range callees {
+			relation.Callees = append(relation.Callees, FuncDesc{Package: "example", Name: callee})
+		}
+		return relation
+	}
+	callMap := map[string]CallerRelation{
+		"main.main":      {Caller: FuncDesc{Package: "main", Name: "main"}, Callees: []FuncDesc{{Package: "example", Name: "Test3"}}},
+		"example.Test3":  newRelation("Test3", "test3b"),
+		"example.Test3c": newRelation("Test3c", "test3b"),
+	}
+	tree := &MWTree{}
+	tree.BuildReverseCallTreeFromCallMap(MWTreeBuildParam{PkgName: "example", FnName: "test3b"}, callMap)
+	return tree
+}
+
+func TestReverseCallTreeToCallGraph(t *testing.T) {
+	graph := newTestReverseCallTree().ToCallGraph()
+	graph.Sort()
+	if len(graph.Nodes) != 4 || len(graph.Edges) != 3 {
+		t.Fatalf("want 4 nodes and 3 edges, got: %+v", graph)
+	}
+
+	for format, want := range map[string]string{
+		FormatDOT:     `"example.Test3" -> "example.test3b";`,
+		FormatMermaid: "n3[\"main.main\"]\n  n0 --> n2",
+	} {
+		buf := &bytes.Buffer{}
+		if err := graph.Write(buf, format); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("%s:\n%s", format, buf.String())
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("%s: want contains %s, got:\n%s", format, want, buf.String())
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := graph.Write(buf, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	got := &CallGraph{}
+	if err := json.Unmarshal(buf.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Edges) != 3 || got.Edges[2] != (CallGraphEdge{Caller: "main.main", Callee: "example.Test3"}) {
+		t.Fatalf("want json graph edges, got: %+v", got.Edges)
+	}
+
+	if err := graph.Write(buf, "svg"); err == nil {
+		t.Fatal("want error for invalid format")
+	}
+}
@@ -0,0 +1,245 @@
+package pkg
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"log"
+	"sort"
+	"strings"
+)
+
+/*
+影响面分析
+changed funcs -> reverse call chains -> impacted entry points (http handlers, grpc methods, tests)
+*/
+
+// Entry point kinds.
+const (
+	EntryKindHTTP = "http"
+	EntryKindGrpc = "grpc"
+	EntryKindTest = "test"
+)
+
+// EntryPoint 入口函数定义
+type EntryPoint struct {
+	Kind    string `json:"kind"`
+	Key     string `json:"key"` // pkgpath.func
+	File    string `json:"file"`
+	PkgPath string `json:"pkg_path"`
+	Package string `json:"package"`
+	Name    string `json:"name"`
+}
+
+// ImpactedTests impacted tests of a package, and run by: go test {pkg_path} -run {pattern}
+type ImpactedTests struct {
+	PkgPath string   `json:"pkg_path"`
+	Tests   []string `json:"tests"`
+	Pattern string   `json:"pattern"`
+}
+
+// FuncImpact 变更函数的影响面
+type FuncImpact struct {
+	Func        string   `json:"func"` // package.func
+	File        string   `json:"file"`
+	Chains      int      `json:"chains"`
+	EntryPoints []string `json:"entry_points"`
+}
+
+// ImpactReport 变更函数的影响面报告
+type ImpactReport struct {
+	SrcCommit    string       `json:"src_commit,omitempty"`
+	DstCommit    string       `json:"dst_commit,omitempty"`
+	Funcs        []FuncImpact `json:"funcs"`
+	HTTPHandlers []EntryPoint `json:"http_handlers"`
+	GrpcMethods  []EntryPoint `json:"grpc_methods"`
+	Tests        []EntryPoint `json:"tests"`
+	// TestPackages impacted tests grouped by package, to run impacted tests only
+	TestPackages []ImpactedTests `json:"test_packages"`
+}
+
+// GetEntryPoints returns entry points (http handlers, grpc methods and tests) in call graph by "pkgpath.func".
+func (a *Analysis) GetEntryPoints() map[string]EntryPoint {
+	entryPoints := make(map[string]EntryPoint)
+	for fn, node := range a.callGraph.Nodes {
+		if fn == nil || fn.Pkg == nil || fn.Synthetic != "" {
+			continue
+		}
+		kind := getEntryPointKind(fn.Name(), fn.Signature)
+		if len(kind) == 0 {
+			continue
+		}
+
+		items := strings.Split(getFuncNameOfNode(node), ".")
+		if len(items) < 2 {
+			continue
+		}
+		entry := EntryPoint{
+			Kind:    kind,
+			File:    a.prog.Fset.Position(fn.Pos()).Filename,
+			PkgPath: fn.Pkg.Pkg.Path(),
+			Package: fn.Pkg.Pkg.Name(),
+			Name:    items[1],
+		}
+		entry.Key = fmt.Sprintf("%s.%s", entry.PkgPath, entry.Name)
+		entryPoints[entry.Key] = entry
+	}
+	log.Printf("found %d entry points", len(entryPoints))
+	return entryPoints
+}
+
+// getEntryPointKind returns kind of entry point by func name and signature, and returns empty if it's not entry point.
+func getEntryPointKind(name string, sig *types.Signature) string {
+	params := sig.Params()
+	results := sig.Results()
+	switch {
+	case sig.Recv() == nil && strings.HasPrefix(name, "Test") && params.Len() == 1 &&
+		typeString(params.At(0).Type()) == "*testing.T":
+		return EntryKindTest
+	case isHTTPHandler(params):
+		return EntryKindHTTP
+	case sig.Recv() != nil && token.IsExported(name) && isGrpcMethod(params, results):
+		return EntryKindGrpc
+	}
+	return ""
+}
+
+var httpContextTypes = map[string]struct{}{
+	"*github.com/gin-gonic/gin.Context":   {},
+	"github.com/labstack/echo.Context":    {},
+	"github.com/labstack/echo/v4.Context": {},
+}
+
+// isHTTPHandler returns true for func like http.HandlerFunc, gin.HandlerFunc and echo.HandlerFunc.
+func isHTTPHandler(params *types.Tuple) bool {
+	if params.Len() == 2 {
+		return typeString(params.At(0).Type()) == "net/http.ResponseWriter" &&
+			typeString(params.At(1).Type()) == "*net/http.Request"
+	}
+	if params.Len() == 1 {
+		_, ok := httpContextTypes[typeString(params.At(0).Type())]
+		return ok
+	}
+	return false
+}
+
+// isGrpcMethod returns true for method of grpc server:
+// unary: (ctx context.Context, req *Request) (*Response, error)
+// stream: (req *Request, stream Service_MethodServer) error, or (stream Service_MethodServer) error
+func isGrpcMethod(params, results *types.Tuple) bool {
+	if results.Len() == 2 && params.Len() == 2 {
+		return typeString(params.At(0).Type()) == "context.Context" &&
+			hasMethods(params.At(1).Type(), "ProtoMessage") &&
+			hasMethods(results.At(0).Type(), "ProtoMessage") &&
+			typeString(results.At(1).Type()) == "error"
+	}
+	if results.Len() == 1 && params.Len() > 0 && params.Len() <= 2 {
+		stream := params.At(params.Len() - 1).Type()
+		if params.Len() == 2 && !hasMethods(params.At(0).Type(), "ProtoMessage") {
+			return false
+		}
+		return hasMethods(stream, "SendMsg", "RecvMsg", "Context") &&
+			typeString(results.At(0).Type()) == "error"
+	}
+	return false
+}
+
+func hasMethods(t types.Type, names ...string) bool {
+	methods := types.NewMethodSet(t)
+	for _, name := range names {
+		if methods.Lookup(nil, name) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func typeString(t types.Type) string {
+	return types.TypeString(t, nil)
+}
+
+// GetImpacts builds reverse call tree for each changed func, and returns entry points in reverse call chains.
+func GetImpacts(params []MWTreeBuildParam, callMap map[string]CallerRelation, entryPoints map[string]EntryPoint) *ImpactReport {
+	report := &ImpactReport{
+		Funcs:        make([]FuncImpact, 0, len(params)),
+		HTTPHandlers: make([]EntryPoint, 0),
+		GrpcMethods:  make([]EntryPoint, 0),
+		Tests:        make([]EntryPoint, 0),
+		TestPackages: make([]ImpactedTests, 0),
+	}
+
+	// func in reverse call chain is matched to entry point by package dir, since package name is not unique
+	funcEntryPoints := make(map[string]EntryPoint, len(entryPoints))
+	for _, entry := range entryPoints {
+		funcEntryPoints[getFuncID(FuncDesc{entry.File, entry.Package, entry.Name})] = entry
+	}
+
+	impacted := make(map[string]EntryPoint)
+	for _, param := range params {
+		tree := &MWTree{}
+		tree.BuildReverseCallTreeFromCallMap(param, callMap)
+		relations := tree.GetReverseCallRelations()
+
+		funcImpact := FuncImpact{
+			Func:        tree.Head.Key,
+			File:        param.GoFilePath,
+			Chains:      len(relations),
+			EntryPoints: make([]string, 0),
+		}
+		keys := make(map[string]struct{})
+		for _, relation := range relations {
+			for _, fn := range relation.Callees {
+				entry, ok := funcEntryPoints[getFuncID(fn)]
+				if !ok {
+					continue
+				}
+				if _, ok := keys[entry.Key]; !ok {
+					keys[entry.Key] = struct{}{}
+					funcImpact.EntryPoints = append(funcImpact.EntryPoints, entry.Key)
+				}
+				impacted[entry.Key] = entry
+			}
+		}
+		sort.Strings(funcImpact.EntryPoints)
+		report.Funcs = append(report.Funcs, funcImpact)
+	}
+
+	keys := make([]string, 0, len(impacted))
+	for key := range impacted {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		entry := impacted[key]
+		switch entry.Kind {
+		case EntryKindHTTP:
+			report.HTTPHandlers = append(report.HTTPHandlers, entry)
+		case EntryKindGrpc:
+			report.GrpcMethods = append(report.GrpcMethods, entry)
+		case EntryKindTest:
+			report.Tests = append(report.Tests, entry)
+		}
+	}
+	report.TestPackages = groupTestsByPackage(report.Tests)
+	return report
+}
+
+// groupTestsByPackage groups sorted tests by package, and pattern for "go test -run" is set for each package.
+func groupTestsByPackage(tests []EntryPoint) []ImpactedTests {
+	groups := make([]ImpactedTests, 0)
+	indexes := make(map[string]int)
+	for _, entry := range tests {
+		idx, ok := indexes[entry.PkgPath]
+		if !ok {
+			idx = len(groups)
+			indexes[entry.PkgPath] = idx
+			groups = append(groups, ImpactedTests{PkgPath: entry.PkgPath})
+		}
+		groups[idx].Tests = append(groups[idx].Tests, entry.Name)
+	}
+	for idx := range groups {
+		groups[idx].Pattern = fmt.Sprintf("^(%s)$", strings.Join(groups[idx].Tests, "|"))
+	}
+	return groups
+}
@@ -0,0 +1,167 @@
+package pkg
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+type testImporter map[string]*types.Package
+
+func (i testImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := i[path]; ok {
+		return pkg, nil
+	}
+	return nil, fmt.Errorf("package %s not found", path)
+}
+
+func checkTestPackage(t *testing.T, importer testImporter, path, src string) *types.Package {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path+".go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importer}
+	pkg, err := conf.Check(path, fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	importer[path] = pkg
+	return pkg
+}
+
+func TestGetEntryPointKind(t *testing.T) {
+	importer := testImporter{}
+	checkTestPackage(t, importer, "context", "package context\n\ntype Context interface{ Done() <-chan struct{} }\n")
+	checkTestPackage(t, importer, "testing", "package testing\n\ntype T struct{}\n")
+	checkTestPackage(t, importer, "net/http", `package http
+
+type ResponseWriter interface{ Write([]byte) (int, error) }
+
+type Request struct{}
+`)
+	pkg := checkTestPackage(t, importer, "demo", `package demo
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+type HelloRequest struct{}
+
+func (*HelloRequest) ProtoMessage() {}
+
+type HelloReply struct{}
+
+func (*HelloReply) ProtoMessage() {}
+
+type Greeter_StreamServer interface {
+	SendMsg(m interface{}) error
+	RecvMsg(m interface{}) error
+	Context() context.Context
+}
+
+type server struct{}
+
+func (s *server) SayHello(ctx context.Context, req *HelloRequest) (*HelloReply, error) { return nil, nil }
+
+func (s *server) Stream(req *HelloRequest, stream Greeter_StreamServer) error { return nil }
+
+func (s *server) sayHello(ctx context.Context, req *HelloRequest) (*HelloReply, error) { return nil, nil }
+
+func Index(w http.ResponseWriter, r *http.Request) {}
+
+func TestHello(t *testing.T) {}
+
+func Hello(name string) string { return name }
+`)
+
+	srvType := pkg.Scope().Lookup("server").Type()
+	methods := types.NewMethodSet(types.NewPointer(srvType))
+	signatures := make(map[string]*types.Signature)
+	for i := 0; i < methods.Len(); i++ {
+		fn := methods.At(i).Obj().(*types.Func)
+		signatures[fn.Name()] = fn.Type().(*types.Signature)
+	}
+	for _, name := range []string{"Index", "TestHello", "Hello"} {
+		signatures[name] = pkg.Scope().Lookup(name).Type().(*types.Signature)
+	}
+
+	for name, want := range map[string]string{
+		"SayHello":  EntryKindGrpc,
+		"Stream":    EntryKindGrpc,
+		"sayHello":  "",
+		"Index":     EntryKindHTTP,
+		"TestHello": EntryKindTest,
+		"Hello":     "",
+	} {
+		if got := getEntryPointKind(name, signatures[name]); got != want {
+			t.Fatalf("func %s: want kind [%s], got [%s]", name, want, got)
+		}
+	}
+}
+
+func TestGetImpacts(t *testing.T) {
+	newFunc := func(pkgDir, name string) FuncDesc {
+		return FuncDesc{File: pkgDir + "/example.go", Package: "example", Name: name}
+	}
+	newRelation := func(caller FuncDesc, callees ...FuncDesc) CallerRelation {
+		return CallerRelation{Caller: caller, Callees: callees}
+	}
+	newEntry := func(kind, pkgDir, name string) EntryPoint {
+		pkgPath := "demo" + pkgDir
+		return EntryPoint{
+			Kind: kind, Key: pkgPath + "." + name, File: pkgDir + "/example.go",
+			PkgPath: pkgPath, Package: "example", Name: name,
+		}
+	}
+
+	// /a: TestA -> Index -> util -> helper, TestB -> helper, recursive: loopA <-> loopB -> helper
+	// /b: TestA -> helper of /a, and TestB -> helper of /b (not changed)
+	helper := newFunc("/a", "helper")
+	callMap := map[string]CallerRelation{
+		"demo/a.TestA": newRelation(newFunc("/a", "TestA"), newFunc("/a", "Index")),
+		"demo/a.Index": newRelation(newFunc("/a", "Index"), newFunc("/a", "util")),
+		"demo/a.util":  newRelation(newFunc("/a", "util"), helper),
+		"demo/a.TestB": newRelation(newFunc("/a", "TestB"), helper),
+		"demo/a.loopA": newRelation(newFunc("/a", "loopA"), newFunc("/a", "loopB")),
+		"demo/a.loopB": newRelation(newFunc("/a", "loopB"), newFunc("/a", "loopA"), helper),
+		"demo/b.TestA": newRelation(newFunc("/b", "TestA"), helper),
+		"demo/b.TestB": newRelation(newFunc("/b", "TestB"), newFunc("/b", "helper")),
+	}
+	entryPoints := make(map[string]EntryPoint)
+	for _, entry := range []EntryPoint{
+		newEntry(EntryKindTest, "/a", "TestA"),
+		newEntry(EntryKindTest, "/a", "TestB"),
+		newEntry(EntryKindHTTP, "/a", "Index"),
+		newEntry(EntryKindTest, "/a", "TestC"),
+		newEntry(EntryKindTest, "/b", "TestA"),
+		newEntry(EntryKindTest, "/b", "TestB"),
+	} {
+		entryPoints[entry.Key] = entry
+	}
+
+	params := []MWTreeBuildParam{
+		{GoFilePath: "/a/example.go", PkgName: "example", FnName: "helper"},
+		{GoFilePath: "/a/example.go", PkgName: "example", FnName: "TestC"},
+	}
+	report := GetImpacts(params, callMap, entryPoints)
+	if len(report.Funcs) != 2 || len(report.Funcs[0].EntryPoints) != 4 {
+		t.Fatalf("want 4 entry points of helper, got: %+v", report.Funcs)
+	}
+	if len(report.HTTPHandlers) != 1 || report.HTTPHandlers[0].Name != "Index" {
+		t.Fatalf("want impacted http handler Index, got: %+v", report.HTTPHandlers)
+	}
+
+	want := []ImpactedTests{
+		{PkgPath: "demo/a", Tests: []string{"TestA", "TestB", "TestC"}, Pattern: "^(TestA|TestB|TestC)$"},
+		{PkgPath: "demo/b", Tests: []string{"TestA"}, Pattern: "^(TestA)$"},
+	}
+	if fmt.Sprint(report.TestPackages) != fmt.Sprint(want) {
+		t.Fatalf("want impacted tests %+v, got %+v", want, report.TestPackages)
+	}
+}
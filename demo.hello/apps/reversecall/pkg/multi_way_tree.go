@@ -3,6 +3,7 @@ package pkg
 import (
 	"fmt"
 	"log"
+	"path/filepath"
 )
 
 // MWTNode multi way tree node.
@@ -27,14 +28,20 @@ type MWTreeBuildParam struct {
 
 // BuildReverseCallTreeFromCallMap creates reverse call multi way tree from caller relationship.
 func (tree *MWTree) BuildReverseCallTreeFromCallMap(param MWTreeBuildParam, callMap map[string]CallerRelation) {
+	// file paths of call map are absolute
+	goFilePath := param.GoFilePath
+	if path, err := filepath.Abs(goFilePath); err == nil && len(goFilePath) > 0 {
+		goFilePath = path
+	}
 	tree.Head = &MWTNode{
 		Key:       fmt.Sprintf("%s.%s", param.PkgName, param.FnName),
-		Value:     FuncDesc{param.GoFilePath, param.PkgName, param.FnName},
+		Value:     FuncDesc{goFilePath, param.PkgName, param.FnName},
 		LeafNodes: make([]*MWTNode, 0),
 	}
 
 	nodeMap := make(map[string]struct{}) // mark whether node has been handled
-	nodeList := make([]*MWTNode, 1)      // a queue
+	nodeMap[getFuncID(tree.Head.Value)] = struct{}{}
+	nodeList := make([]*MWTNode, 1) // a queue
 	nodeList[0] = tree.Head
 
 	for {
@@ -46,11 +53,12 @@ func (tree *MWTree) BuildReverseCallTreeFromCallMap(param MWTreeBuildParam, call
 		log.Printf("current node %+v", curNode)
 		for callerName, callRelation := range callMap {
 			for _, callee := range callRelation.Callees {
-				if curNode.Key == fmt.Sprintf("%s.%s", callee.Package, callee.Name) {
+				if getFuncID(curNode.Value) == getFuncID(callee) {
 					log.Printf("found caller:%s -> callee:%s", callerName, callee)
 
 					key := fmt.Sprintf("%s.%s", callRelation.Caller.Package, callRelation.Caller.Name)
-					if _, ok := nodeMap[key]; !ok {
+					id := getFuncID(callRelation.Caller)
+					if _, ok := nodeMap[id]; !ok {
 						newNode := &MWTNode{
 							Key:       key,
 							Value:     FuncDesc{callRelation.Caller.File, callRelation.Caller.Package, callRelation.Caller.Name},
@@ -59,9 +67,8 @@ func (tree *MWTree) BuildReverseCallTreeFromCallMap(param MWTreeBuildParam, call
 						curNode.N++
 						curNode.LeafNodes = append(curNode.LeafNodes, newNode)
 						nodeList = append(nodeList, newNode)
-					} else {
-						// node has been handled
-						nodeMap[key] = struct{}{}
+						// mark node as handled to avoid endless loop for recursive calls
+						nodeMap[id] = struct{}{}
 					}
 				}
 			}
@@ -73,6 +80,12 @@ func (tree *MWTree) BuildReverseCallTreeFromCallMap(param MWTreeBuildParam, call
 	}
 }
 
+// getFuncID returns id of func as "dir:package.func", and func is matched by package dir since package name is
+// not unique.
+func getFuncID(fn FuncDesc) string {
+	return fmt.Sprintf("%s:%s.%s", filepath.Dir(fn.File), fn.Package, fn.Name)
+}
+
 // GetReverseCallRelations returns reverse call relations of multi way tree.
 func (tree *MWTree) GetReverseCallRelations() []ReverseCallRelation {
 	// golbal vars "relation" and "relationList" for depthTraversal recursion
@@ -1,6 +1,7 @@
 package pkg
 
 import (
+	"fmt"
 	"go/build"
 	"log"
 	"strings"
@@ -18,10 +19,8 @@ func inStd(node *callgraph.Node) bool {
 	return pkg.Goroot
 }
 
-func printOutput(prog *ssa.Program, cg *callgraph.Graph, opts *RenderOpts) (map[string]CallerRelation, error) {
-	callMap := make(map[string]CallerRelation, 0)
-	cg.DeleteSyntheticNodes()
-
+// newEdgeFilter returns filter of call graph edges by render options, and returns true if edge is included.
+func newEdgeFilter(prog *ssa.Program, opts *RenderOpts) func(edge *callgraph.Edge) bool {
 	var isFocused = func(edge *callgraph.Edge) bool {
 		caller := edge.Caller
 		callee := edge.Callee
@@ -79,51 +78,64 @@ func printOutput(prog *ssa.Program, cg *callgraph.Graph, opts *RenderOpts) (map[
 		return false
 	}
 
-	count := 0
-	var onGraphVisitEdges = func(edge *callgraph.Edge) error {
-		count++
-
+	return func(edge *callgraph.Edge) bool {
 		caller := edge.Caller
 		callee := edge.Callee
 
-		callerPos := prog.Fset.Position(caller.Func.Pos())
-		callerFile := callerPos.Filename
-
-		calleePos := prog.Fset.Position(callee.Func.Pos())
-		calleeFile := calleePos.Filename
-
+		callerFile := prog.Fset.Position(caller.Func.Pos()).Filename
+		calleeFile := prog.Fset.Position(callee.Func.Pos()).Filename
 		if strings.Contains(callerFile, "vendor") || strings.Contains(calleeFile, "vendor") {
-			return nil
+			return false
 		}
-		// omit synthetic calls
-		if isSynthetic(edge) {
-			return nil
+		// omit synthetic calls, and funcs without package (i.e. wrappers by static and cha algo)
+		if isSynthetic(edge) || callee.Func.Pkg == nil {
+			return false
 		}
 		// omit std
 		if opts.Nostd && (inStd(caller) || inStd(callee)) {
-			return nil
+			return false
 		}
 		// omit inter
 		if opts.Nointer && isInter(edge) {
-			return nil
+			return false
 		}
 
 		// focus specific pkg
 		if len(opts.Focus) > 0 && !isFocused(edge) {
-			return nil
+			return false
 		}
 
 		// include path prefixes
 		if len(opts.Include) > 0 && (!inIncludes(caller) || !inIncludes(callee)) {
 			// log.Printf("NOT in include: %s -> %s", caller, callee)
-			return nil
+			return false
 		}
 		// ignore path prefixes
 		if len(opts.Ignore) > 0 && (inIgnores(caller) || inIgnores(callee)) {
 			// log.Printf("IS ignored: %s -> %s", caller, callee)
+			return false
+		}
+		return true
+	}
+}
+
+func printOutput(prog *ssa.Program, cg *callgraph.Graph, opts *RenderOpts) (map[string]CallerRelation, error) {
+	callMap := make(map[string]CallerRelation, 0)
+	cg.DeleteSyntheticNodes()
+	isIncluded := newEdgeFilter(prog, opts)
+
+	count := 0
+	var onGraphVisitEdges = func(edge *callgraph.Edge) error {
+		count++
+		if !isIncluded(edge) {
 			return nil
 		}
 
+		caller := edge.Caller
+		callee := edge.Callee
+		callerFile := prog.Fset.Position(caller.Func.Pos()).Filename
+		calleeFile := prog.Fset.Position(callee.Func.Pos()).Filename
+
 		// var buf bytes.Buffer
 		// data, _ := json.MarshalIndent(caller.Func, "", " ")
 		// log.Printf("call node: %s -> %s\n %v", caller, callee, string(data))
@@ -132,12 +144,8 @@ func printOutput(prog *ssa.Program, cg *callgraph.Graph, opts *RenderOpts) (map[
 
 		callerPkg := caller.Func.Pkg.Pkg.Name()
 		calleePkg := callee.Func.Pkg.Pkg.Name()
-		callerName := strings.Split(caller.String(), "/")[len(strings.Split(caller.String(), "/"))-1]
-		calleeName := strings.Split(callee.String(), "/")[len(strings.Split(callee.String(), "/"))-1]
-
-		// 针对 go func(){} 的情况，处理 $ (比如 Test3c$1)
-		callerName = strings.Split(callerName, "$")[0]
-		calleeName = strings.Split(calleeName, "$")[0]
+		callerName := getFuncNameOfNode(caller)
+		calleeName := getFuncNameOfNode(callee)
 
 		// 防止递归
 		if callerName == calleeName {
@@ -145,20 +153,15 @@ func printOutput(prog *ssa.Program, cg *callgraph.Graph, opts *RenderOpts) (map[
 			return nil
 		}
 
-		// 注意类的方法, 表现形式不一样: (demo.hello/apps/calltrace/test/example.XYZ).print
 		// callerList和calleeList的第一个元素是package, 第二个元素是function (包括类的function): ["example", "XYZ@print"]
-		if strings.Contains(callerName, ").") {
-			callerName = strings.Replace(callerName, ").", "@", -1)
-		}
 		callerList := strings.Split(callerName, ".")
-		if strings.Contains(calleeName, ").") {
-			calleeName = strings.Replace(calleeName, ").", "@", -1)
-		}
 		calleeList := strings.Split(calleeName, ".")
 
-		if v, ok := callMap[callerName]; ok {
+		// package name is not unique, and caller is keyed by package path
+		callerKey := fmt.Sprintf("%s.%s", caller.Func.Pkg.Pkg.Path(), callerList[1])
+		if v, ok := callMap[callerKey]; ok {
 			for _, c := range v.Callees {
-				if c.Package == calleeList[0] && c.Name == calleeList[1] {
+				if c.File == calleeFile && c.Package == calleeList[0] && c.Name == calleeList[1] {
 					log.Printf("duplicated call node: %s -> %s", caller, callee)
 					return nil
 				}
@@ -168,9 +171,9 @@ func printOutput(prog *ssa.Program, cg *callgraph.Graph, opts *RenderOpts) (map[
 				calleePkg,
 				calleeList[1]})
 			v.Callees = list
-			callMap[callerName] = v
+			callMap[callerKey] = v
 		} else {
-			callMap[callerName] = CallerRelation{
+			callMap[callerKey] = CallerRelation{
 				Caller: FuncDesc{
 					callerFile,
 					callerPkg,
@@ -192,3 +195,15 @@ func printOutput(prog *ssa.Program, cg *callgraph.Graph, opts *RenderOpts) (map[
 	log.Printf("%d/%d edges", len(callMap), count)
 	return callMap, nil
 }
+
+// getFuncNameOfNode returns func name of call node as "package.func".
+func getFuncNameOfNode(node *callgraph.Node) string {
+	name := strings.Split(node.String(), "/")[len(strings.Split(node.String(), "/"))-1]
+	// 针对 go func(){} 的情况，处理 $ (比如 Test3c$1)
+	name = strings.Split(name, "$")[0]
+	// 注意类的方法, 表现形式不一样: (demo.hello/apps/calltrace/test/example.XYZ).print => example.XYZ@print
+	if strings.Contains(name, ").") {
+		name = strings.Replace(name, ").", "@", -1)
+	}
+	return name
+}
@@ -0,0 +1,19 @@
+package lib
+
+// Greeter .
+type Greeter interface {
+	Greet() string
+}
+
+// En .
+type En struct{}
+
+// Greet .
+func (En) Greet() string {
+	return "hello"
+}
+
+// Hello .
+func Hello(g Greeter) string {
+	return g.Greet()
+}
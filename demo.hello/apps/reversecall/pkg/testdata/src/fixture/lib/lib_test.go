@@ -0,0 +1,7 @@
+package lib
+
+// Fixture tests do not import "testing", so that std lib is not loaded in analysis.
+
+func ExampleHello() {
+	Hello(En{})
+}
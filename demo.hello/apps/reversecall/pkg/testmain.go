@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+/*
+ssa.Program.CreateTestMainPackage is removed from golang.org/x/tools, and a simplified "testmain" package is created here.
+It only calls all test funcs (Test, Benchmark, Fuzz, Example and TestMain) from main, which is enough to be roots of call graph.
+*/
+
+// createTestMainPackage creates a synthetic "main" package which calls test funcs of the given package, and returns nil if no tests.
+func createTestMainPackage(prog *ssa.Program, pkg *ssa.Package) (*ssa.Package, error) {
+	calls := findTestCalls(prog, pkg)
+	if len(calls) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "package main")
+	if prog.ImportedPackage("testing") != nil {
+		fmt.Fprintln(&buf, `import "testing"`)
+	}
+	fmt.Fprintf(&buf, "import p %q\n", pkg.Pkg.Path())
+	fmt.Fprintln(&buf, "func main() {")
+	for _, call := range calls {
+		fmt.Fprintf(&buf, "\t%s\n", call)
+	}
+	fmt.Fprintln(&buf, "}")
+
+	path := pkg.Pkg.Path() + "$testmain"
+	f, err := parser.ParseFile(prog.Fset, path+".go", &buf, 0)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s error: %w", path, err)
+	}
+	conf := types.Config{
+		DisableUnusedImportCheck: true,
+		Importer:                 testMainImporter{pkg: pkg},
+	}
+	files := []*ast.File{f}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Instances:  make(map[*ast.Ident]types.Instance),
+		Scopes:     make(map[ast.Node]*types.Scope),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	testmainPkg, err := conf.Check(path, prog.Fset, files, info)
+	if err != nil {
+		return nil, fmt.Errorf("type-check %s error: %w", path, err)
+	}
+
+	testmain := prog.CreatePackage(testmainPkg, files, info, false)
+	testmain.Build()
+	testmain.Func("main").Synthetic = "test main function"
+	return testmain, nil
+}
+
+// findTestCalls returns call statements of test funcs defined in "_test.go" files of the given package.
+func findTestCalls(prog *ssa.Program, pkg *ssa.Package) []string {
+	testingPkg := prog.ImportedPackage("testing")
+	argOf := func(typeName string) string {
+		if testingPkg == nil {
+			return ""
+		}
+		if t, ok := testingPkg.Members[typeName].(*ssa.Type); ok {
+			return types.NewPointer(t.Type()).String()
+		}
+		return ""
+	}
+	// test func prefix => arg type
+	prefixes := map[string]string{"Example": ""}
+	for prefix, typeName := range map[string]string{"Test": "T", "Benchmark": "B", "Fuzz": "F"} {
+		if argType := argOf(typeName); len(argType) > 0 {
+			prefixes[prefix] = argType
+		}
+	}
+
+	calls := make([]string, 0)
+	for name, mem := range pkg.Members {
+		fn, ok := mem.(*ssa.Function)
+		if !ok || !strings.HasSuffix(prog.Fset.Position(fn.Pos()).Filename, "_test.go") {
+			continue
+		}
+
+		params := fn.Signature.Params()
+		if fn.Signature.Results().Len() > 0 || params.Len() > 1 {
+			continue
+		}
+		argType := ""
+		if params.Len() == 1 {
+			argType = params.At(0).Type().String()
+		}
+
+		if name == "TestMain" {
+			if len(argType) > 0 && argType == argOf("M") {
+				calls = append(calls, "p.TestMain(new(testing.M))")
+			}
+			continue
+		}
+		for prefix, wantArgType := range prefixes {
+			if !isTestFunc(name, prefix) || argType != wantArgType {
+				continue
+			}
+			if len(argType) == 0 {
+				calls = append(calls, fmt.Sprintf("p.%s()", name))
+			} else {
+				calls = append(calls, fmt.Sprintf("p.%s(new(%s))", name, strings.TrimPrefix(argType, "*")))
+			}
+			break
+		}
+	}
+	sort.Strings(calls)
+	return calls
+}
+
+// isTestFunc refer to isTest() in $GOROOT/src/cmd/go/internal/load/test.go
+func isTestFunc(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+	return ast.IsExported(name[len(prefix):])
+}
+
+// testMainImporter imports packages from loaded program, and package under test may be non-importable.
+type testMainImporter struct {
+	pkg *ssa.Package
+}
+
+func (imp testMainImporter) Import(path string) (*types.Package, error) {
+	if path == imp.pkg.Pkg.Path() {
+		return imp.pkg.Pkg, nil
+	}
+	if p := imp.pkg.Prog.ImportedPackage(path); p != nil {
+		return p.Pkg, nil
+	}
+	return nil, fmt.Errorf("package not found: %s", path)
+}
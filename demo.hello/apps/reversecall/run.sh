@@ -25,6 +25,33 @@ function run_analysis_for_echo() {
     rm ${go_project}/reversecall
 }
 
+function run_graph_for_example() {
+    local go_project="${HOME}/Workspaces/zj_repos/zj_go2_project/demo.hello/apps/reversecall/pkg/test"
+    go build .
+    mv reversecall ${go_project}
+    cd ${go_project}
+    # export reverse call chains by diff algorithms to compare precision
+    for algo in pointer static cha rta vta; do
+      ./reversecall -fullpackage=demo.hello/apps/reversecall/pkg/test -gofile=${go_project}/example/test3.go \
+        -package=example -func=test3b -nointer -algo=${algo} -format=mermaid -graph-out=/tmp/test3b_${algo}.mmd
+    done
+    # export the whole call graph as dot, and render by: dot -Tsvg /tmp/callgraph.dot -o /tmp/callgraph.svg
+    ./reversecall -fullpackage=demo.hello/apps/reversecall/pkg/test -include=demo.hello -algo=cha -format=dot -full \
+      -graph-out=/tmp/callgraph.dot
+    rm ${go_project}/reversecall
+}
+
+function run_impact_for_echo() {
+    local go_project="${HOME}/Workspaces/zj_repos/zj_go2_project/demo.hello/echoserver"
+    go build .
+    mv reversecall ${go_project}
+    cd ${go_project}
+    # outputs impacted http handlers, grpc methods and tests, and run impacted tests only by "pattern" of each "test_packages" in CI
+    ./reversecall impact -repo=${go_project}/.. -src-commit=${SRC_COMMIT} -dst-commit=${DST_COMMIT} \
+      -fullpackage=demo.hello/echoserver -algo=static -cache-dir=/tmp/reversecall_cache -o impact.json
+    rm ${go_project}/reversecall
+}
+
 #help
 
 run_analysis_for_example
@@ -1,5 +1,10 @@
 package main
 
+import "os"
+
 func main() {
+	if len(os.Getenv("S3_HOST")) == 0 {
+		panic("Env variables is not set")
+	}
 	// TODO:
 }
@@ -34,14 +34,23 @@ type CephMgmt struct {
 	client    *s3.S3
 }
 
-// New .
+// New creates ceph client, and credentials are from env S3_ACCESS_KEY and S3_SECRET_KEY.
 func New(host, bucket string) *CephMgmt {
+	return newCephMgmt(host, bucket, credentials.NewCredentials(&MyProvider{}))
+}
+
+// NewWithCredentials creates ceph client with the given access key and secret key.
+func NewWithCredentials(host, bucket, accessKey, secretKey string) *CephMgmt {
+	return newCephMgmt(host, bucket, credentials.NewStaticCredentials(accessKey, secretKey, ""))
+}
+
+func newCephMgmt(host, bucket string, creds *credentials.Credentials) *CephMgmt {
 	sess := session.Must(session.NewSessionWithOptions(session.Options{
 		Config: aws.Config{
 			Region:           aws.String("default"),
 			Endpoint:         aws.String(host),
 			S3ForcePathStyle: aws.Bool(true),
-			Credentials:      credentials.NewCredentials(&MyProvider{}),
+			Credentials:      creds,
 		},
 	}))
 	return &CephMgmt{
@@ -16,8 +16,4 @@ func init() {
 	bucket = os.Getenv("S3_BUCKET")
 	accessKey = os.Getenv("S3_ACCESS_KEY")
 	secretKey = os.Getenv("S3_SECRET_KEY")
-
-	if len(host) == 0 {
-		panic("Env variables is not set")
-	}
 }
@@ -13,7 +13,10 @@ import (
 
 	"demo.hello/gocplugin/pkg"
 	"demo.hello/gocplugin/pkg/handler"
+	k8spkg "demo.hello/k8s/client/pkg"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/client-go/kubernetes"
 )
 
 var (
@@ -38,12 +41,18 @@ func main() {
 	if err := pkg.InitConfig(rootDir); err != nil {
 		log.Fatalf("Load config error: %v", err)
 	}
+	if err := pkg.InitNotifyDispatcher(); err != nil {
+		log.Fatalf("Init notify dispatcher error: %v", err)
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 
 	r := initRouter()
 	switch pkg.AppConfig.RunMode {
 	case pkg.RunModeReport:
+		if err := pkg.InitSrvCoverTotalMetrics(pkg.NewCoverHistoryStore()); err != nil {
+			log.Println("Init cover total metrics error:", err)
+		}
 		pkg.InitSrvCoverSyncTasksPool()
 		defer pkg.CloseSrvCoverSyncTasksPool()
 		runRptServer(ctx, r)
@@ -74,6 +83,7 @@ func initRouter() *gin.Engine {
 
 	r.GET("/", handler.IndexHandler)
 	r.GET("/ping", handler.PingHandler)
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	r.Static("/static/report", pkg.AppConfig.PublicDir)
 
@@ -106,6 +116,10 @@ func setupRptServerRouter(r *gin.Engine) {
 	cover.POST("raw", handler.FetchSrvRawCoverHandler)
 	cover.POST("clear", handler.ClearSrvCoverHandler)
 	cover.POST("sync", handler.SyncSrvCoverHandler)
+	cover.POST("gate", handler.CoverGateHandler)
+	cover.GET("jobs", handler.ListSyncJobsHandler)
+	cover.GET("jobs/:id", handler.GetSyncJobHandler)
+	cover.POST("retention", handler.CoverRetentionHandler)
 
 	report := r.Group("/cover/report")
 	report.POST("list", handler.ListSrvCoverReportsHandler)
@@ -115,6 +129,13 @@ func setupRptServerRouter(r *gin.Engine) {
 func runRptScheduleTask(ctx context.Context) {
 	scheduler := pkg.NewScheduler()
 	scheduler.SyncRegisterSrvsCoverReportTask(ctx, 4*time.Hour)
+	if pkg.AppConfig.Retention.KeepLastCommits > 0 {
+		// invalid archive config fails at startup
+		if _, err := pkg.NewCoverRetention(pkg.NewCoverHistoryStore(), pkg.AppConfig.Retention); err != nil {
+			log.Fatalf("Init cover retention error: %v", err)
+		}
+		scheduler.RemoveExpiredSrvCoverFilesTask(ctx, 24*time.Hour)
+	}
 }
 
 //
@@ -138,6 +159,27 @@ func setupWatcherRouter(r *gin.Engine) {
 
 func runWatcherScheduleTask(ctx context.Context) {
 	scheduler := pkg.NewScheduler()
-	scheduler.RemoveUnhealthSrvTask(ctx, time.Hour)
+	if pkg.AppConfig.Discovery.Mode == pkg.DiscoveryModeK8s {
+		runK8sPodDiscovery(ctx)
+	} else {
+		scheduler.RemoveUnhealthSrvTask(ctx, time.Hour)
+	}
 	scheduler.SyncSrvsRawCoverTask(ctx, time.Hour)
 }
+
+func runK8sPodDiscovery(ctx context.Context) {
+	var (
+		client *kubernetes.Clientset
+		err    error
+	)
+	cfg := pkg.AppConfig.Discovery
+	if len(cfg.KubeConfig) > 0 {
+		client, err = k8spkg.CreateK8sClientLocal(cfg.KubeConfig)
+	} else {
+		client, err = k8spkg.CreateK8sClient()
+	}
+	if err != nil {
+		log.Fatalln("Create k8s client error:", err)
+	}
+	pkg.RunK8sPodDiscovery(ctx, client, cfg.Namespace)
+}
@@ -27,6 +27,10 @@ const (
 	CoverRptTypeFunc = "func"
 	// CoverRptTypeHTML .
 	CoverRptTypeHTML = "html"
+	// CoverRptTypeDiff .
+	CoverRptTypeDiff = "diff"
+	// CoverRptTypeModule .
+	CoverRptTypeModule = "module"
 
 	// RunModeReport .
 	RunModeReport = "report"
@@ -37,8 +41,10 @@ const (
 var (
 	// AppConfig .
 	AppConfig GocPluginConfig
-	// ModuleToRepoMap .
+	// ModuleToRepoMap app name to url of service's own repo.
 	ModuleToRepoMap map[string]string
+	// AppRepoSourcesMap app name to all repos of service, and the first one is service's own repo.
+	AppRepoSourcesMap map[string][]RepoSource
 )
 
 // GocPluginConfig .
@@ -47,9 +53,29 @@ type GocPluginConfig struct {
 	IsDebug          bool   `json:"is_debug"`
 	RootDir          string `json:"root"`
 	PublicDir        string
-	GocCenterIngHost string `json:"goc_center_ing_host"`
-	GocCenterSvcHost string `json:"goc_center_svc_host"`
-	PodMonitorHost   string `json:"pod_monitor_host"`
+	GocCenterIngHost string          `json:"goc_center_ing_host"`
+	GocCenterSvcHost string          `json:"goc_center_svc_host"`
+	PodMonitorHost   string          `json:"pod_monitor_host"`
+	Store            StoreConfig     `json:"store"`
+	DiffCoverBaseRef string          `json:"diff_cover_base_ref"`
+	Gate             GateRules       `json:"gate"`
+	Notify           NotifyConfig    `json:"notify"`
+	Discovery        DiscoveryConfig `json:"discovery"`
+	Retention        RetentionConfig `json:"retention"`
+}
+
+// StoreConfig config for cover history store, type: sqlite (default), mysql, postgres or memory.
+type StoreConfig struct {
+	Type string `json:"type"`
+	DSN  string `json:"dsn"`
+}
+
+// DiscoveryConfig service discovery config of watcher, mode: "" (goc register and pod monitor) or k8s.
+// KubeConfig is used when run outside k8s cluster.
+type DiscoveryConfig struct {
+	Mode       string `json:"mode"`
+	Namespace  string `json:"namespace"`
+	KubeConfig string `json:"kube_config"`
 }
 
 // InitConfig .
@@ -71,7 +97,20 @@ func InitConfig(rootDir string) error {
 	return err
 }
 
-// LoadModuleToRepoMap .
+// RepoSource a git repo of service.
+// Dir: checkout dir name in app dir for repos other than service's own repo, default repo name. Service's own repo
+// is always checked out to "repo", and dir is not allowed.
+// Branch: checkout branch for repos other than service's own repo, default "master".
+// ModuleDirs: go module dirs relative to repo root, and modules are found by go.mod if empty.
+type RepoSource struct {
+	URL        string   `json:"url"`
+	Dir        string   `json:"dir"`
+	Branch     string   `json:"branch"`
+	ModuleDirs []string `json:"module_dirs"`
+}
+
+// LoadModuleToRepoMap loads app to repos map, and value is repo url, or repo sources for monorepo
+// and service with multiple repos.
 func LoadModuleToRepoMap() error {
 	const mapFile = "module_repo_map.json"
 	b, err := os.ReadFile(filepath.Join(AppConfig.RootDir, mapFile))
@@ -79,12 +118,47 @@ func LoadModuleToRepoMap() error {
 		return fmt.Errorf("LoadModuleToRepoMap error: %w", err)
 	}
 
-	if err := json.Unmarshal(b, &ModuleToRepoMap); err != nil {
+	repoMap, sourcesMap, err := parseModuleToRepoMap(b)
+	if err != nil {
 		return fmt.Errorf("LoadModuleToRepoMap error: %w", err)
 	}
+	ModuleToRepoMap = repoMap
+	AppRepoSourcesMap = sourcesMap
 	return nil
 }
 
+func parseModuleToRepoMap(b []byte) (map[string]string, map[string][]RepoSource, error) {
+	rawMap := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &rawMap); err != nil {
+		return nil, nil, err
+	}
+
+	repoMap := make(map[string]string, len(rawMap))
+	sourcesMap := make(map[string][]RepoSource, len(rawMap))
+	for app, raw := range rawMap {
+		var url string
+		if err := json.Unmarshal(raw, &url); err == nil {
+			repoMap[app] = url
+			sourcesMap[app] = []RepoSource{{URL: url}}
+			continue
+		}
+
+		var sources []RepoSource
+		if err := json.Unmarshal(raw, &sources); err != nil {
+			return nil, nil, fmt.Errorf("invalid repo of app [%s]: %w", app, err)
+		}
+		if len(sources) == 0 {
+			return nil, nil, fmt.Errorf("empty repo of app [%s]", app)
+		}
+		if len(sources[0].Dir) > 0 {
+			return nil, nil, fmt.Errorf("dir of service's own repo is not supported, app [%s]", app)
+		}
+		repoMap[app] = sources[0].URL
+		sourcesMap[app] = sources
+	}
+	return repoMap, sourcesMap, nil
+}
+
 // GetModuleCoverDataDir .
 func GetModuleCoverDataDir(appName string) string {
 	return filepath.Join(AppConfig.RootDir, appName, ReportCoverDataDirName)
@@ -0,0 +1,291 @@
+package coverage
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestMergeCovFiles(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "merged.cov")
+	if err := MergeCovFiles([]string{"testdata/replica1.cov", "testdata/replica2.cov"}, outPath); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := ParseCovFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 1 {
+		t.Fatalf("want 1 profile, got %d", len(profiles))
+	}
+
+	counts := make([]string, 0, len(profiles[0].Blocks))
+	for _, b := range profiles[0].Blocks {
+		counts = append(counts, fmt.Sprint(b.Count))
+	}
+	if got := strings.Join(counts, ","); got != "2,1,1,3,3,0" {
+		t.Fatalf("want merged counts 2,1,1,3,3,0, got %s", got)
+	}
+}
+
+func TestMergeProfilesSetMode(t *testing.T) {
+	newProfile := func(count int) []*cover.Profile {
+		return []*cover.Profile{
+			{
+				FileName: "example.com/demo/calc.go",
+				Mode:     ModeSet,
+				Blocks:   []cover.ProfileBlock{{StartLine: 4, StartCol: 21, EndLine: 5, EndCol: 11, NumStmt: 1, Count: count}},
+			},
+		}
+	}
+
+	merged, err := MergeProfiles(newProfile(1), newProfile(1), newProfile(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := merged[0].Blocks[0].Count; count != 1 {
+		t.Fatalf("want set mode count 1, got %d", count)
+	}
+}
+
+func TestMergeProfilesModeNotMatch(t *testing.T) {
+	countProfiles, err := ParseCovFile("testdata/replica1.cov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setProfiles, err := ParseCovFile("testdata/set.cov")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = MergeProfiles(countProfiles, setProfiles)
+	fmt.Println("error:", err)
+	if err == nil {
+		t.Fatal("want inconsistent profile mode error")
+	}
+}
+
+func TestCreateFuncReport(t *testing.T) {
+	profiles, err := ParseCovFile("testdata/replica1.cov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := NewModuleFileResolver("testdata/demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := CreateFuncReport(profiles, resolver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Funcs) != 2 {
+		t.Fatalf("want 2 funcs, got %d", len(report.Funcs))
+	}
+	if report.PercentText() != "50.0" {
+		t.Fatalf("want total 50.0, got %s", report.PercentText())
+	}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(buf.String())
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "example.com/demo/calc.go:4: Abs 100.0%" {
+		t.Fatalf("unexpected func line: %s", lines[0])
+	}
+	if summary := lines[len(lines)-1]; !strings.HasSuffix(summary, "\t50.0%") {
+		t.Fatalf("unexpected summary line: %s", summary)
+	}
+}
+
+func TestModuleFileResolverNotInModule(t *testing.T) {
+	resolver, err := NewModuleFileResolver("testdata/demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolver("example.com/other/calc.go"); err == nil {
+		t.Fatal("want ErrFileNotResolved")
+	}
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	profiles, err := ParseCovFile("testdata/replica2.cov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := NewModuleFileResolver("testdata/demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHTMLReport(&buf, profiles, resolver); err != nil {
+		t.Fatal(err)
+	}
+	html := buf.String()
+	for _, want := range []string{"example.com/demo/calc.go (33.3%)", `<span class="cov0" title="0">`, `<span class="cov10" title="3">`} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("html report does not contain: %s", want)
+		}
+	}
+}
+
+func TestCreateDiffCoverReport(t *testing.T) {
+	profiles, err := ParseCovFile("testdata/replica1.cov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := NewModuleFileResolver("testdata/demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changedLines := map[string][]int{
+		"calc.go":  {3, 5, 6, 7, 10, 13, 14, 15, 20},
+		"other.go": {1, 2},
+	}
+	report, err := CreateDiffCoverReport(profiles, resolver, "testdata/demo", changedLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Files) != 1 {
+		t.Fatalf("want 1 diff file, got %d", len(report.Files))
+	}
+	file := report.Files[0]
+	if fmt.Sprint(file.CoveredLines) != "[5 6]" || fmt.Sprint(file.UncoveredLines) != "[13 14]" {
+		t.Fatalf("unexpected diff lines: covered=%v, uncovered=%v", file.CoveredLines, file.UncoveredLines)
+	}
+	if report.PercentText() != "50.0" {
+		t.Fatalf("want diff cover 50.0, got %s", report.PercentText())
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDiffCoverHTMLReport(&buf, report, resolver); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<pre class="uncovered"><span class="num">13</span>`) {
+		t.Fatal("html report does not contain uncovered line 13")
+	}
+}
+
+func TestDiffCoverForFileSharedLine(t *testing.T) {
+	src := []byte("package demo\n\nfunc Abs(x int) int {\n\tif x < 0 {\n\t\t// negative\n\t\treturn -x\n\t}\n\treturn x\n}\n")
+	profile := &cover.Profile{
+		FileName: "example.com/demo/abs.go",
+		Mode:     "count",
+		Blocks: []cover.ProfileBlock{
+			{StartLine: 3, StartCol: 21, EndLine: 4, EndCol: 11, NumStmt: 1, Count: 0},
+			{StartLine: 4, StartCol: 11, EndLine: 7, EndCol: 3, NumStmt: 1, Count: 1},
+			{StartLine: 8, StartCol: 2, EndLine: 8, EndCol: 10, NumStmt: 1, Count: 0},
+		},
+	}
+	// line 4 is counted by block of "if x < 0", and comment and brace lines are skipped
+	file := diffCoverForFile(profile, []int{4, 5, 6, 7, 8}, src)
+	if fmt.Sprint(file.CoveredLines) != "[6]" || fmt.Sprint(file.UncoveredLines) != "[4 8]" {
+		t.Fatalf("unexpected diff lines: covered=%v, uncovered=%v", file.CoveredLines, file.UncoveredLines)
+	}
+}
+
+func TestGetPackageCovers(t *testing.T) {
+	profiles := []*cover.Profile{
+		{
+			FileName: "example.com/demo/calc.go",
+			Mode:     ModeCount,
+			Blocks:   []cover.ProfileBlock{{NumStmt: 2, Count: 1}, {NumStmt: 2, Count: 0}},
+		},
+		{
+			FileName: "example.com/demo/util/str.go",
+			Mode:     ModeCount,
+			Blocks:   []cover.ProfileBlock{{NumStmt: 3, Count: 1}},
+		},
+		{
+			FileName: "example.com/demo/main.go",
+			Mode:     ModeCount,
+			Blocks:   []cover.ProfileBlock{{NumStmt: 4, Count: 0}},
+		},
+	}
+
+	pkgs := GetPackageCovers(profiles)
+	if len(pkgs) != 2 {
+		t.Fatalf("want 2 packages, got %d", len(pkgs))
+	}
+	if pkgs[0].Package != "example.com/demo" || pkgs[0].Covered != 2 || pkgs[0].Total != 8 {
+		t.Fatalf("unexpected package cover: %+v", pkgs[0])
+	}
+	if pkgs[1].Percent() != 100 {
+		t.Fatalf("want package util cover 100, got %.1f", pkgs[1].Percent())
+	}
+}
+
+func TestMultiModuleFileResolver(t *testing.T) {
+	dirs, err := FindModuleDirs("testdata/mono")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 2 {
+		t.Fatalf("want 2 module dirs (vendor skipped), got %v", dirs)
+	}
+
+	resolver, err := NewMultiModuleFileResolver(append(dirs, "testdata/demo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for fileName, want := range map[string]string{
+		"example.com/mono/cmd/main.go":       "testdata/mono/cmd/main.go",
+		"example.com/mono/libs/util/str.go":  "testdata/mono/libs/util/str.go",
+		"example.com/demo/calc.go":           "testdata/demo/calc.go",
+		"example.com/mono/libs/utils/str.go": "testdata/mono/libs/utils/str.go",
+	} {
+		path, err := resolver(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != filepath.FromSlash(want) {
+			t.Fatalf("file [%s]: want %s, got %s", fileName, want, path)
+		}
+	}
+	if _, err := resolver("example.com/other/calc.go"); err == nil {
+		t.Fatal("want ErrFileNotResolved")
+	}
+}
+
+func TestGetModuleCovers(t *testing.T) {
+	profiles := []*cover.Profile{
+		{
+			FileName: "example.com/mono/cmd/main.go",
+			Mode:     ModeCount,
+			Blocks:   []cover.ProfileBlock{{NumStmt: 2, Count: 1}, {NumStmt: 2, Count: 0}},
+		},
+		{
+			FileName: "example.com/mono/libs/util/str.go",
+			Mode:     ModeCount,
+			Blocks:   []cover.ProfileBlock{{NumStmt: 3, Count: 1}},
+		},
+		{
+			FileName: "example.com/other/main.go",
+			Mode:     ModeCount,
+			Blocks:   []cover.ProfileBlock{{NumStmt: 4, Count: 0}},
+		},
+	}
+
+	mods := GetModuleCovers(profiles, []string{"example.com/mono", "example.com/mono/libs/util"})
+	if len(mods) != 3 {
+		t.Fatalf("want 3 modules, got %d", len(mods))
+	}
+	if mods[0].Module != "example.com/mono" || mods[0].Covered != 2 || mods[0].Total != 4 {
+		t.Fatalf("unexpected module cover: %+v", mods[0])
+	}
+	if mods[1].Module != "example.com/mono/libs/util" || mods[1].Percent() != 100 {
+		t.Fatalf("unexpected module cover: %+v", mods[1])
+	}
+	if mods[2].Module != ModuleOthers || mods[2].Total != 4 {
+		t.Fatalf("unexpected module cover: %+v", mods[2])
+	}
+}
@@ -0,0 +1,282 @@
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// DiffCoverFile incremental cover result of a changed file.
+type DiffCoverFile struct {
+	FileName       string `json:"file_name"`
+	CoveredLines   []int  `json:"covered_lines"`
+	UncoveredLines []int  `json:"uncovered_lines"`
+	Covered        int    `json:"covered"`
+	Total          int    `json:"total"`
+}
+
+// Percent .
+func (f DiffCoverFile) Percent() float64 {
+	return percent(int64(f.Covered), int64(f.Total))
+}
+
+// DiffCoverReport incremental cover result of changed lines between base ref and commit.
+// Only changed lines which have code in profile blocks are counted, and lines of comments or braces are skipped.
+type DiffCoverReport struct {
+	BaseRef string          `json:"base_ref"`
+	Commit  string          `json:"commit"`
+	Files   []DiffCoverFile `json:"files"`
+	Covered int             `json:"covered"`
+	Total   int             `json:"total"`
+}
+
+// Percent .
+func (r *DiffCoverReport) Percent() float64 {
+	return percent(int64(r.Covered), int64(r.Total))
+}
+
+// PercentText .
+func (r *DiffCoverReport) PercentText() string {
+	return fmt.Sprintf("%.1f", r.Percent())
+}
+
+// CreateDiffCoverReport intersects changed lines with profile blocks. changedLines is map of
+// file path relative to repoDir (slash separated) to changed line numbers.
+func CreateDiffCoverReport(profiles []*cover.Profile, resolver FileResolver, repoDir string, changedLines map[string][]int) (*DiffCoverReport, error) {
+	report := &DiffCoverReport{
+		Files: make([]DiffCoverFile, 0, len(changedLines)),
+	}
+	for _, profile := range profiles {
+		path, err := resolver(profile.FileName)
+		if err != nil {
+			return nil, fmt.Errorf("CreateDiffCoverReport error: %w", err)
+		}
+		relPath, err := filepath.Rel(repoDir, path)
+		if err != nil {
+			return nil, fmt.Errorf("CreateDiffCoverReport error: %w", err)
+		}
+
+		lines, ok := changedLines[filepath.ToSlash(relPath)]
+		if !ok {
+			continue
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("CreateDiffCoverReport read source file error: %w", err)
+		}
+		file := diffCoverForFile(profile, lines, src)
+		if file.Total == 0 {
+			continue
+		}
+		report.Files = append(report.Files, file)
+		report.Covered += file.Covered
+		report.Total += file.Total
+	}
+
+	sort.Slice(report.Files, func(i, j int) bool {
+		return report.Files[i].FileName < report.Files[j].FileName
+	})
+	return report, nil
+}
+
+// diffCoverForFile counts changed lines by profile block which contains the first code token of line, so a line
+// shared by two blocks (i.e. "if x < 0 {") is counted once by the block of its statement.
+func diffCoverForFile(profile *cover.Profile, lines []int, src []byte) DiffCoverFile {
+	file := DiffCoverFile{
+		FileName:       profile.FileName,
+		CoveredLines:   make([]int, 0, len(lines)),
+		UncoveredLines: make([]int, 0, len(lines)),
+	}
+	codeColumns := getCodeColumnsOfLines(src)
+	for _, line := range lines {
+		col, ok := codeColumns[line]
+		if !ok {
+			continue
+		}
+		isInBlock, isCovered := false, false
+		for _, b := range profile.Blocks {
+			if b.NumStmt == 0 || !isInProfileBlock(b, line, col) {
+				continue
+			}
+			isInBlock = true
+			isCovered = b.Count > 0
+			break
+		}
+
+		if !isInBlock {
+			continue
+		}
+		if isCovered {
+			file.CoveredLines = append(file.CoveredLines, line)
+		} else {
+			file.UncoveredLines = append(file.UncoveredLines, line)
+		}
+	}
+
+	file.Covered = len(file.CoveredLines)
+	file.Total = len(file.CoveredLines) + len(file.UncoveredLines)
+	return file
+}
+
+func isInProfileBlock(b cover.ProfileBlock, line, col int) bool {
+	if line < b.StartLine || line > b.EndLine {
+		return false
+	}
+	if line == b.StartLine && col < b.StartCol {
+		return false
+	}
+	if line == b.EndLine && col >= b.EndCol {
+		return false
+	}
+	return true
+}
+
+// getCodeColumnsOfLines returns column of the first code token for each line of go source, and lines which only
+// have comments, braces or parens are not included.
+func getCodeColumnsOfLines(src []byte) map[int]int {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, 0)
+
+	columns := make(map[int]int)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return columns
+		}
+		switch tok {
+		case token.LBRACE, token.RBRACE, token.LPAREN, token.RPAREN, token.COMMA:
+			continue
+		case token.SEMICOLON:
+			if lit == "\n" {
+				continue
+			}
+		}
+		position := file.Position(pos)
+		if _, ok := columns[position.Line]; !ok {
+			columns[position.Line] = position.Column
+		}
+	}
+}
+
+type diffHTMLLine struct {
+	Number int
+	Class  string
+	Text   string
+}
+
+type diffHTMLFile struct {
+	DiffCoverFile
+	Coverage float64
+	Lines    []diffHTMLLine
+}
+
+type diffHTMLTemplateData struct {
+	*DiffCoverReport
+	Coverage float64
+	Files    []diffHTMLFile
+}
+
+// WriteDiffCoverHTMLReport writes html report of changed lines with cover status.
+func WriteDiffCoverHTMLReport(w io.Writer, report *DiffCoverReport, resolver FileResolver) error {
+	data := diffHTMLTemplateData{
+		DiffCoverReport: report,
+		Coverage:        report.Percent(),
+		Files:           make([]diffHTMLFile, 0, len(report.Files)),
+	}
+	for _, file := range report.Files {
+		path, err := resolver(file.FileName)
+		if err != nil {
+			return fmt.Errorf("WriteDiffCoverHTMLReport error: %w", err)
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("WriteDiffCoverHTMLReport read source file error: %w", err)
+		}
+
+		lineClasses := make(map[int]string, file.Total)
+		for _, line := range file.CoveredLines {
+			lineClasses[line] = "covered"
+		}
+		for _, line := range file.UncoveredLines {
+			lineClasses[line] = "uncovered"
+		}
+
+		htmlFile := diffHTMLFile{
+			DiffCoverFile: file,
+			Coverage:      file.Percent(),
+		}
+		s := bufio.NewScanner(bytes.NewReader(src))
+		for number := 1; s.Scan(); number++ {
+			htmlFile.Lines = append(htmlFile.Lines, diffHTMLLine{
+				Number: number,
+				Class:  lineClasses[number],
+				Text:   strings.ReplaceAll(s.Text(), "\t", "    "),
+			})
+		}
+		data.Files = append(data.Files, htmlFile)
+	}
+
+	if err := diffHTMLTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("WriteDiffCoverHTMLReport execute template error: %w", err)
+	}
+	return nil
+}
+
+// CreateDiffCoverHTMLReportFile .
+func CreateDiffCoverHTMLReportFile(outFilePath string, report *DiffCoverReport, resolver FileResolver) error {
+	f, err := os.Create(outFilePath)
+	if err != nil {
+		return fmt.Errorf("CreateDiffCoverHTMLReportFile create file error: %w", err)
+	}
+	defer f.Close()
+
+	buf := bufio.NewWriter(f)
+	if err := WriteDiffCoverHTMLReport(buf, report, resolver); err != nil {
+		return fmt.Errorf("CreateDiffCoverHTMLReportFile error: %w", err)
+	}
+	return buf.Flush()
+}
+
+var diffHTMLTemplate = template.Must(template.New("diff").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta http-equiv="Content-Type" content="text/html; charset=utf-8">
+<title>Goc Plugin Diff Cover Report</title>
+<style>
+body { font-family: Menlo, monospace; }
+table.summary { border-collapse: collapse; margin-bottom: 20px; }
+table.summary td, table.summary th { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+pre { margin: 0; }
+.src { border: 1px solid #ccc; margin-bottom: 20px; }
+.num { color: #999; display: inline-block; width: 50px; text-align: right; margin-right: 10px; }
+.covered { background: #dfd; }
+.uncovered { background: #fdd; }
+</style>
+</head>
+<body>
+<h2>Diff Cover Report</h2>
+<p>Compare: {{.BaseRef}}...{{.Commit}}</p>
+<p>Total: {{.Covered}}/{{.Total}} lines ({{printf "%.1f" .Coverage}}%)</p>
+<table class="summary">
+<tr><th>File</th><th>Covered</th><th>Total</th><th>Cover</th><th>Missing Lines</th></tr>
+{{range .Files}}<tr><td><a href="#{{.FileName}}">{{.FileName}}</a></td><td>{{.Covered}}</td><td>{{.Total}}</td><td>{{printf "%.1f" .Coverage}}%</td><td>{{range $i, $l := .UncoveredLines}}{{if $i}},{{end}}{{$l}}{{end}}</td></tr>
+{{end}}</table>
+{{range .Files}}<h3 id="{{.FileName}}">{{.FileName}}</h3>
+<div class="src">
+{{range .Lines}}<pre class="{{.Class}}"><span class="num">{{.Number}}</span>{{.Text}}</pre>
+{{end}}</div>
+{{end}}</body>
+</html>
+`))
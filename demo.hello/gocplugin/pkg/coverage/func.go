@@ -0,0 +1,149 @@
+package coverage
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"text/tabwriter"
+
+	"golang.org/x/tools/cover"
+)
+
+// FuncCover cover result of a func, same as "go tool cover -func".
+type FuncCover struct {
+	FileName  string `json:"file_name"`
+	FuncName  string `json:"func_name"`
+	StartLine int    `json:"start_line"`
+	Covered   int64  `json:"covered"`
+	Total     int64  `json:"total"`
+}
+
+// Percent .
+func (f FuncCover) Percent() float64 {
+	return percent(f.Covered, f.Total)
+}
+
+// FuncReport .
+type FuncReport struct {
+	Funcs   []FuncCover `json:"funcs"`
+	Covered int64       `json:"covered"`
+	Total   int64       `json:"total"`
+}
+
+// Percent returns total cover percentage of statements in funcs.
+func (r *FuncReport) Percent() float64 {
+	return percent(r.Covered, r.Total)
+}
+
+// PercentText returns total cover in format "37.9", which is saved as cover total.
+func (r *FuncReport) PercentText() string {
+	return fmt.Sprintf("%.1f", r.Percent())
+}
+
+// Write writes report in "go tool cover -func" output format.
+func (r *FuncReport) Write(w io.Writer) error {
+	tabber := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+	for _, f := range r.Funcs {
+		fmt.Fprintf(tabber, "%s:%d:\t%s\t%.1f%%\n", f.FileName, f.StartLine, f.FuncName, f.Percent())
+	}
+	fmt.Fprintf(tabber, "total:\t(statements)\t%.1f%%\n", r.Percent())
+	return tabber.Flush()
+}
+
+// CreateFuncReport creates func cover report for profiles, the source files are read by resolver.
+func CreateFuncReport(profiles []*cover.Profile, resolver FileResolver) (*FuncReport, error) {
+	report := &FuncReport{
+		Funcs: make([]FuncCover, 0, len(profiles)*4),
+	}
+	for _, profile := range profiles {
+		path, err := resolver(profile.FileName)
+		if err != nil {
+			return nil, fmt.Errorf("CreateFuncReport error: %w", err)
+		}
+		extents, err := findFuncs(path)
+		if err != nil {
+			return nil, fmt.Errorf("CreateFuncReport error: %w", err)
+		}
+
+		for _, extent := range extents {
+			covered, total := extent.coverage(profile)
+			report.Funcs = append(report.Funcs, FuncCover{
+				FileName:  profile.FileName,
+				FuncName:  extent.name,
+				StartLine: extent.startLine,
+				Covered:   covered,
+				Total:     total,
+			})
+			report.Covered += covered
+			report.Total += total
+		}
+	}
+	return report, nil
+}
+
+// funcExtent describes a function's extent in the source by file and position.
+type funcExtent struct {
+	name      string
+	startLine int
+	startCol  int
+	endLine   int
+	endCol    int
+}
+
+func findFuncs(path string) ([]*funcExtent, error) {
+	fset := token.NewFileSet()
+	parsedFile, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("findFuncs parse file error: %w", err)
+	}
+
+	extents := make([]*funcExtent, 0, 16)
+	ast.Inspect(parsedFile, func(node ast.Node) bool {
+		fn, ok := node.(*ast.FuncDecl)
+		if !ok {
+			return true
+		}
+		if fn.Body == nil {
+			return false
+		}
+		start := fset.Position(fn.Pos())
+		end := fset.Position(fn.End())
+		extents = append(extents, &funcExtent{
+			name:      fn.Name.Name,
+			startLine: start.Line,
+			startCol:  start.Column,
+			endLine:   end.Line,
+			endCol:    end.Column,
+		})
+		return false
+	})
+	return extents, nil
+}
+
+// coverage returns the fraction of the statements in the function that were covered, as a numerator and denominator.
+func (f *funcExtent) coverage(profile *cover.Profile) (int64, int64) {
+	var covered, total int64
+	// the blocks are sorted, so we can stop counting as soon as we reach the end of the relevant block
+	for _, b := range profile.Blocks {
+		if b.StartLine > f.endLine || (b.StartLine == f.endLine && b.StartCol >= f.endCol) {
+			break
+		}
+		if b.EndLine < f.startLine || (b.EndLine == f.startLine && b.EndCol <= f.startCol) {
+			continue
+		}
+		total += int64(b.NumStmt)
+		if b.Count > 0 {
+			covered += int64(b.NumStmt)
+		}
+	}
+	return covered, total
+}
+
+func percent(covered, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100.0 * float64(covered) / float64(total)
+}
@@ -0,0 +1,198 @@
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+
+	"golang.org/x/tools/cover"
+)
+
+type htmlFile struct {
+	Name     string
+	Body     template.HTML
+	Coverage float64
+}
+
+type htmlTemplateData struct {
+	Set   bool
+	Files []htmlFile
+}
+
+// WriteHTMLReport writes html cover report like "go tool cover -html" for profiles.
+func WriteHTMLReport(w io.Writer, profiles []*cover.Profile, resolver FileResolver) error {
+	data := htmlTemplateData{
+		Files: make([]htmlFile, 0, len(profiles)),
+	}
+	for _, profile := range profiles {
+		if profile.Mode == ModeSet {
+			data.Set = true
+		}
+		path, err := resolver(profile.FileName)
+		if err != nil {
+			return fmt.Errorf("WriteHTMLReport error: %w", err)
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("WriteHTMLReport read source file error: %w", err)
+		}
+
+		var buf bytes.Buffer
+		if err := htmlGen(&buf, src, profile.Boundaries(src)); err != nil {
+			return fmt.Errorf("WriteHTMLReport error: %w", err)
+		}
+		data.Files = append(data.Files, htmlFile{
+			Name:     profile.FileName,
+			Body:     template.HTML(buf.String()),
+			Coverage: profileCoverage(profile),
+		})
+	}
+
+	if err := htmlTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("WriteHTMLReport execute template error: %w", err)
+	}
+	return nil
+}
+
+// CreateHTMLReportFile .
+func CreateHTMLReportFile(outFilePath string, profiles []*cover.Profile, resolver FileResolver) error {
+	f, err := os.Create(outFilePath)
+	if err != nil {
+		return fmt.Errorf("CreateHTMLReportFile create file error: %w", err)
+	}
+	defer f.Close()
+
+	buf := bufio.NewWriter(f)
+	if err := WriteHTMLReport(buf, profiles, resolver); err != nil {
+		return fmt.Errorf("CreateHTMLReportFile error: %w", err)
+	}
+	return buf.Flush()
+}
+
+// profileCoverage returns percentage of covered statements of a profile.
+func profileCoverage(profile *cover.Profile) float64 {
+	var covered, total int64
+	for _, b := range profile.Blocks {
+		total += int64(b.NumStmt)
+		if b.Count > 0 {
+			covered += int64(b.NumStmt)
+		}
+	}
+	return percent(covered, total)
+}
+
+// htmlGen generates an HTML coverage report with the provided source and boundaries.
+func htmlGen(w io.Writer, src []byte, boundaries []cover.Boundary) error {
+	dst := bufio.NewWriter(w)
+	for i := range src {
+		for len(boundaries) > 0 && boundaries[0].Offset == i {
+			b := boundaries[0]
+			if b.Start {
+				n := 0
+				if b.Count > 0 {
+					n = int(math.Floor(b.Norm*9)) + 1
+				}
+				fmt.Fprintf(dst, `<span class="cov%v" title="%v">`, n, b.Count)
+			} else {
+				dst.WriteString("</span>")
+			}
+			boundaries = boundaries[1:]
+		}
+		switch b := src[i]; b {
+		case '>':
+			dst.WriteString("&gt;")
+		case '<':
+			dst.WriteString("&lt;")
+		case '&':
+			dst.WriteString("&amp;")
+		case '\t':
+			dst.WriteString("        ")
+		default:
+			dst.WriteByte(b)
+		}
+	}
+	return dst.Flush()
+}
+
+var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta http-equiv="Content-Type" content="text/html; charset=utf-8">
+<title>Goc Plugin Cover Report</title>
+<style>
+body { background: black; color: rgb(80, 80, 80); }
+body, pre, #legend span { font-family: Menlo, monospace; font-weight: bold; }
+#topbar { background: black; position: fixed; top: 0; left: 0; right: 0; height: 42px; border-bottom: 1px solid rgb(80, 80, 80); }
+#content { margin-top: 50px; }
+#nav, #legend { float: left; margin-left: 10px; }
+#legend { margin-top: 12px; }
+#nav { margin-top: 10px; }
+#legend span { margin: 0 5px; }
+.cov0 { color: rgb(192, 0, 0) }
+.cov1 { color: rgb(128, 128, 128) }
+.cov2 { color: rgb(116, 140, 131) }
+.cov3 { color: rgb(104, 152, 134) }
+.cov4 { color: rgb(92, 164, 137) }
+.cov5 { color: rgb(80, 176, 140) }
+.cov6 { color: rgb(68, 188, 143) }
+.cov7 { color: rgb(56, 200, 146) }
+.cov8 { color: rgb(44, 212, 149) }
+.cov9 { color: rgb(32, 224, 152) }
+.cov10 { color: rgb(20, 236, 155) }
+</style>
+</head>
+<body>
+<div id="topbar">
+<div id="nav">
+<select id="files">
+{{range $i, $f := .Files}}<option value="file{{$i}}">{{$f.Name}} ({{printf "%.1f" $f.Coverage}}%)</option>
+{{end}}</select>
+</div>
+<div id="legend">
+<span>not tracked</span>
+{{if .Set}}<span class="cov0">not covered</span>
+<span class="cov8">covered</span>
+{{else}}<span class="cov0">no coverage</span>
+<span class="cov1">low coverage</span>
+<span class="cov2">*</span>
+<span class="cov3">*</span>
+<span class="cov4">*</span>
+<span class="cov5">*</span>
+<span class="cov6">*</span>
+<span class="cov7">*</span>
+<span class="cov8">*</span>
+<span class="cov9">*</span>
+<span class="cov10">high coverage</span>
+{{end}}</div>
+</div>
+<div id="content">
+{{range $i, $f := .Files}}<pre class="file" id="file{{$i}}" style="display: none">{{$f.Body}}</pre>
+{{end}}</div>
+</body>
+<script>
+(function() {
+	var files = document.getElementById('files');
+	var visible;
+	files.addEventListener('change', onChange, false);
+	function select(part) {
+		if (visible) visible.style.display = 'none';
+		visible = document.getElementById(part);
+		if (!visible) return;
+		files.value = part;
+		visible.style.display = 'block';
+		location.hash = part;
+	}
+	function onChange() {
+		select(files.value);
+		window.scrollTo(0, 0);
+	}
+	if (location.hash != "") select(location.hash.substr(1));
+	if (!visible) select("file0");
+})();
+</script>
+</html>
+`))
@@ -0,0 +1,65 @@
+package coverage
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// ModuleOthers module name for files which are not in given modules.
+const ModuleOthers = "others"
+
+// ModuleCover statements cover result of a go module.
+type ModuleCover struct {
+	Module  string `json:"module"`
+	Covered int64  `json:"covered"`
+	Total   int64  `json:"total"`
+}
+
+// Percent .
+func (m ModuleCover) Percent() float64 {
+	return percent(m.Covered, m.Total)
+}
+
+// GetModuleCovers returns statements cover results group by module, and a file belongs to the module
+// with longest matched path.
+func GetModuleCovers(profiles []*cover.Profile, modulePaths []string) []ModuleCover {
+	sortedPaths := make([]string, len(modulePaths))
+	copy(sortedPaths, modulePaths)
+	sort.Slice(sortedPaths, func(i, j int) bool {
+		return len(sortedPaths[i]) > len(sortedPaths[j])
+	})
+
+	modules := make(map[string]*ModuleCover, len(modulePaths))
+	for _, profile := range profiles {
+		modName := ModuleOthers
+		for _, modPath := range sortedPaths {
+			if strings.HasPrefix(profile.FileName, modPath+"/") {
+				modName = modPath
+				break
+			}
+		}
+
+		mod, ok := modules[modName]
+		if !ok {
+			mod = &ModuleCover{Module: modName}
+			modules[modName] = mod
+		}
+		for _, b := range profile.Blocks {
+			mod.Total += int64(b.NumStmt)
+			if b.Count > 0 {
+				mod.Covered += int64(b.NumStmt)
+			}
+		}
+	}
+
+	retModules := make([]ModuleCover, 0, len(modules))
+	for _, mod := range modules {
+		retModules = append(retModules, *mod)
+	}
+	sort.Slice(retModules, func(i, j int) bool {
+		return retModules[i].Module < retModules[j].Module
+	})
+	return retModules
+}
@@ -0,0 +1,48 @@
+package coverage
+
+import (
+	"path"
+	"sort"
+
+	"golang.org/x/tools/cover"
+)
+
+// PackageCover statements cover result of a package.
+type PackageCover struct {
+	Package string `json:"package"`
+	Covered int64  `json:"covered"`
+	Total   int64  `json:"total"`
+}
+
+// Percent .
+func (p PackageCover) Percent() float64 {
+	return percent(p.Covered, p.Total)
+}
+
+// GetPackageCovers returns statements cover results group by package (dir of file name in profile).
+func GetPackageCovers(profiles []*cover.Profile) []PackageCover {
+	pkgs := make(map[string]*PackageCover, len(profiles))
+	for _, profile := range profiles {
+		pkgName := path.Dir(profile.FileName)
+		pkg, ok := pkgs[pkgName]
+		if !ok {
+			pkg = &PackageCover{Package: pkgName}
+			pkgs[pkgName] = pkg
+		}
+		for _, b := range profile.Blocks {
+			pkg.Total += int64(b.NumStmt)
+			if b.Count > 0 {
+				pkg.Covered += int64(b.NumStmt)
+			}
+		}
+	}
+
+	retPkgs := make([]PackageCover, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		retPkgs = append(retPkgs, *pkg)
+	}
+	sort.Slice(retPkgs, func(i, j int) bool {
+		return retPkgs[i].Package < retPkgs[j].Package
+	})
+	return retPkgs
+}
@@ -0,0 +1,176 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"golang.org/x/tools/cover"
+)
+
+const (
+	// ModeSet .
+	ModeSet = "set"
+	// ModeCount .
+	ModeCount = "count"
+	// ModeAtomic .
+	ModeAtomic = "atomic"
+)
+
+// ParseCovFile parses .cov file and returns a profile for each source file, sorted by file name.
+func ParseCovFile(covFilePath string) ([]*cover.Profile, error) {
+	profiles, err := cover.ParseProfiles(covFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("ParseCovFile error: %w", err)
+	}
+	return profiles, nil
+}
+
+// MergeCovFiles merges .cov files (i.e. from service replicas) into one file.
+func MergeCovFiles(covFilePaths []string, outFilePath string) error {
+	profilesList := make([][]*cover.Profile, 0, len(covFilePaths))
+	for _, path := range covFilePaths {
+		profiles, err := ParseCovFile(path)
+		if err != nil {
+			return fmt.Errorf("MergeCovFiles error: %w", err)
+		}
+		profilesList = append(profilesList, profiles)
+	}
+
+	merged, err := MergeProfiles(profilesList...)
+	if err != nil {
+		return fmt.Errorf("MergeCovFiles error: %w", err)
+	}
+	if err := WriteCovFile(outFilePath, merged); err != nil {
+		return fmt.Errorf("MergeCovFiles error: %w", err)
+	}
+	return nil
+}
+
+// MergeProfiles merges profiles with same mode. For "set" mode, block count is 1 if covered in any profile,
+// and for "count" and "atomic" mode, block counts are summed.
+func MergeProfiles(profilesList ...[]*cover.Profile) ([]*cover.Profile, error) {
+	mode := ""
+	files := make(map[string]*cover.Profile, 16)
+	for _, profiles := range profilesList {
+		for _, profile := range profiles {
+			if len(mode) == 0 {
+				mode = profile.Mode
+			}
+			if profile.Mode != mode {
+				return nil, fmt.Errorf("MergeProfiles inconsistent profile mode: %s and %s", mode, profile.Mode)
+			}
+
+			merged, ok := files[profile.FileName]
+			if !ok {
+				merged = &cover.Profile{
+					FileName: profile.FileName,
+					Mode:     mode,
+					Blocks:   make([]cover.ProfileBlock, 0, len(profile.Blocks)),
+				}
+				files[profile.FileName] = merged
+			}
+			merged.Blocks = append(merged.Blocks, profile.Blocks...)
+		}
+	}
+
+	retProfiles := make([]*cover.Profile, 0, len(files))
+	for _, profile := range files {
+		blocks, err := mergeBlocksByPos(mode, profile.Blocks)
+		if err != nil {
+			return nil, fmt.Errorf("MergeProfiles file [%s] error: %w", profile.FileName, err)
+		}
+		profile.Blocks = blocks
+		retProfiles = append(retProfiles, profile)
+	}
+	sort.Slice(retProfiles, func(i, j int) bool {
+		return retProfiles[i].FileName < retProfiles[j].FileName
+	})
+	return retProfiles, nil
+}
+
+func mergeBlocksByPos(mode string, blocks []cover.ProfileBlock) ([]cover.ProfileBlock, error) {
+	if len(blocks) == 0 {
+		return blocks, nil
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return isBlockBefore(blocks[i], blocks[j])
+	})
+	j := 1
+	for i := 1; i < len(blocks); i++ {
+		cur := blocks[i]
+		prev := blocks[j-1]
+		if isSameBlockPos(cur, prev) {
+			if cur.NumStmt != prev.NumStmt {
+				return nil, fmt.Errorf("inconsistent NumStmt for block: %+v", cur)
+			}
+			blocks[j-1].Count = mergeCount(mode, prev.Count, cur.Count)
+			continue
+		}
+		blocks[j] = cur
+		j++
+	}
+	return blocks[:j], nil
+}
+
+func mergeCount(mode string, src, dst int) int {
+	if mode == ModeSet {
+		if src > 0 || dst > 0 {
+			return 1
+		}
+		return 0
+	}
+	return src + dst
+}
+
+func isBlockBefore(src, dst cover.ProfileBlock) bool {
+	if src.StartLine != dst.StartLine {
+		return src.StartLine < dst.StartLine
+	}
+	if src.StartCol != dst.StartCol {
+		return src.StartCol < dst.StartCol
+	}
+	if src.EndLine != dst.EndLine {
+		return src.EndLine < dst.EndLine
+	}
+	return src.EndCol < dst.EndCol
+}
+
+func isSameBlockPos(src, dst cover.ProfileBlock) bool {
+	return src.StartLine == dst.StartLine && src.StartCol == dst.StartCol &&
+		src.EndLine == dst.EndLine && src.EndCol == dst.EndCol
+}
+
+// WriteCovFile .
+func WriteCovFile(covFilePath string, profiles []*cover.Profile) error {
+	f, err := os.Create(covFilePath)
+	if err != nil {
+		return fmt.Errorf("WriteCovFile create file error: %w", err)
+	}
+	defer f.Close()
+
+	if err := WriteProfiles(f, profiles); err != nil {
+		return fmt.Errorf("WriteCovFile error: %w", err)
+	}
+	return nil
+}
+
+// WriteProfiles writes profiles in go cover profile format.
+func WriteProfiles(w io.Writer, profiles []*cover.Profile) error {
+	if len(profiles) == 0 {
+		return fmt.Errorf("WriteProfiles error: profiles is empty")
+	}
+
+	buf := bufio.NewWriter(w)
+	fmt.Fprintf(buf, "mode: %s\n", profiles[0].Mode)
+	for _, profile := range profiles {
+		for _, b := range profile.Blocks {
+			fmt.Fprintf(buf, "%s:%d.%d,%d.%d %d %d\n",
+				profile.FileName, b.StartLine, b.StartCol, b.EndLine, b.EndCol, b.NumStmt, b.Count)
+		}
+	}
+	return buf.Flush()
+}
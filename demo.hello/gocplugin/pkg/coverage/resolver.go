@@ -0,0 +1,114 @@
+package coverage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+var (
+	// ErrFileNotResolved .
+	ErrFileNotResolved = errors.New("ErrFileNotResolved")
+)
+
+// FileResolver resolves file name in profile (i.e. "module/pkg/file.go") to local file path.
+type FileResolver func(fileName string) (string, error)
+
+// NewModuleFileResolver returns a resolver for files of the go module in moduleDir.
+func NewModuleFileResolver(moduleDir string) (FileResolver, error) {
+	modPath, err := GetModulePath(moduleDir)
+	if err != nil {
+		return nil, fmt.Errorf("NewModuleFileResolver error: %w", err)
+	}
+
+	prefix := modPath + "/"
+	return func(fileName string) (string, error) {
+		if filepath.IsAbs(fileName) {
+			return fileName, nil
+		}
+		if !strings.HasPrefix(fileName, prefix) {
+			return "", fmt.Errorf("file [%s] is not in module [%s]: %w", fileName, modPath, ErrFileNotResolved)
+		}
+		relPath := filepath.FromSlash(strings.TrimPrefix(fileName, prefix))
+		return filepath.Join(moduleDir, relPath), nil
+	}, nil
+}
+
+// NewMultiModuleFileResolver returns a resolver for files of go modules in moduleDirs (i.e. modules in monorepo
+// or multiple repos), and a file is resolved by the module with longest matched path for nested modules.
+func NewMultiModuleFileResolver(moduleDirs []string) (FileResolver, error) {
+	resolvers := make([]moduleResolver, 0, len(moduleDirs))
+	for _, dir := range moduleDirs {
+		modPath, err := GetModulePath(dir)
+		if err != nil {
+			return nil, fmt.Errorf("NewMultiModuleFileResolver error: %w", err)
+		}
+		resolvers = append(resolvers, moduleResolver{modPath: modPath, dir: dir})
+	}
+	sort.Slice(resolvers, func(i, j int) bool {
+		return len(resolvers[i].modPath) > len(resolvers[j].modPath)
+	})
+
+	return func(fileName string) (string, error) {
+		if filepath.IsAbs(fileName) {
+			return fileName, nil
+		}
+		for _, r := range resolvers {
+			if strings.HasPrefix(fileName, r.modPath+"/") {
+				relPath := filepath.FromSlash(strings.TrimPrefix(fileName, r.modPath+"/"))
+				return filepath.Join(r.dir, relPath), nil
+			}
+		}
+		return "", fmt.Errorf("file [%s] is not in modules: %w", fileName, ErrFileNotResolved)
+	}, nil
+}
+
+type moduleResolver struct {
+	modPath string
+	dir     string
+}
+
+// FindModuleDirs returns dirs which contain go.mod under rootDir, and vendor, testdata and hidden dirs are skipped.
+func FindModuleDirs(rootDir string) ([]string, error) {
+	dirs := make([]string, 0, 4)
+	err := filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != rootDir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == "go.mod" {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("FindModuleDirs error: %w", err)
+	}
+	return dirs, nil
+}
+
+// GetModulePath returns module path from go.mod in moduleDir.
+func GetModulePath(moduleDir string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(moduleDir, "go.mod"))
+	if err != nil {
+		return "", fmt.Errorf("GetModulePath read go.mod error: %w", err)
+	}
+
+	modPath := modfile.ModulePath(b)
+	if len(modPath) == 0 {
+		return "", fmt.Errorf("GetModulePath module path not found in: %s", moduleDir)
+	}
+	return modPath, nil
+}
@@ -0,0 +1,17 @@
+package demo
+
+// Abs .
+func Abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Max .
+func Max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
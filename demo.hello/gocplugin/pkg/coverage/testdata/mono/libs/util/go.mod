@@ -0,0 +1,3 @@
+module example.com/mono/libs/util
+
+go 1.16
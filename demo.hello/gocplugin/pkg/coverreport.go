@@ -0,0 +1,96 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"demo.hello/gocplugin/pkg/coverage"
+	"demo.hello/utils"
+)
+
+// CreateCoverFuncReport creates ".func" report next to cov file, and returns cover total.
+// Files in profile are resolved from go modules in moduleDirs.
+func CreateCoverFuncReport(moduleDirs []string, covFilePath string) (string, error) {
+	report, err := createCoverFuncReport(moduleDirs, covFilePath)
+	if err != nil {
+		return "", fmt.Errorf("CreateCoverFuncReport error: %w", err)
+	}
+
+	outFilePath := FormatFilePathWithNewExt(covFilePath, CoverRptTypeFunc)
+	f, err := os.Create(outFilePath)
+	if err != nil {
+		return "", fmt.Errorf("CreateCoverFuncReport create file error: %w", err)
+	}
+	defer f.Close()
+
+	if err := report.Write(f); err != nil {
+		return "", fmt.Errorf("CreateCoverFuncReport write report error: %w", err)
+	}
+	return report.PercentText(), nil
+}
+
+func createCoverFuncReport(moduleDirs []string, covFilePath string) (*coverage.FuncReport, error) {
+	profiles, err := coverage.ParseCovFile(covFilePath)
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := coverage.NewMultiModuleFileResolver(moduleDirs)
+	if err != nil {
+		return nil, err
+	}
+	return coverage.CreateFuncReport(profiles, resolver)
+}
+
+// CreateCoverHTMLReport creates ".html" report in public dir of module, and returns report file path.
+func CreateCoverHTMLReport(moduleDirs []string, moduleName, covFilePath string) (string, error) {
+	outDirPath := filepath.Join(AppConfig.PublicDir, moduleName)
+	if err := utils.MakeDir(outDirPath); err != nil && !errors.Is(err, os.ErrExist) {
+		return "", fmt.Errorf("CreateCoverHTMLReport make public dir error: %w", err)
+	}
+	outFileName := filepath.Base(FormatFilePathWithNewExt(covFilePath, CoverRptTypeHTML))
+	outFilePath := filepath.Join(outDirPath, outFileName)
+
+	profiles, err := coverage.ParseCovFile(covFilePath)
+	if err != nil {
+		return "", fmt.Errorf("CreateCoverHTMLReport error: %w", err)
+	}
+	resolver, err := coverage.NewMultiModuleFileResolver(moduleDirs)
+	if err != nil {
+		return "", fmt.Errorf("CreateCoverHTMLReport error: %w", err)
+	}
+	if err := coverage.CreateHTMLReportFile(outFilePath, profiles, resolver); err != nil {
+		return "", fmt.Errorf("CreateCoverHTMLReport error: %w", err)
+	}
+	return outFilePath, nil
+}
+
+// CreateCoverModuleReport creates ".module" json report next to cov file, which contains cover results
+// group by go module.
+func CreateCoverModuleReport(modulePaths []string, covFilePath string) ([]coverage.ModuleCover, error) {
+	profiles, err := coverage.ParseCovFile(covFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("CreateCoverModuleReport error: %w", err)
+	}
+
+	modules := coverage.GetModuleCovers(profiles, modulePaths)
+	b, err := json.Marshal(modules)
+	if err != nil {
+		return nil, fmt.Errorf("CreateCoverModuleReport json marshal error: %w", err)
+	}
+	outFilePath := FormatFilePathWithNewExt(covFilePath, CoverRptTypeModule)
+	if err := utils.CreateFile(outFilePath, b); err != nil {
+		return nil, fmt.Errorf("CreateCoverModuleReport error: %w", err)
+	}
+	return modules, nil
+}
+
+// MergeSrvCovers merges cov files of service replicas.
+func MergeSrvCovers(covFilePaths []string, mergeFilePath string) error {
+	if err := coverage.MergeCovFiles(covFilePaths, mergeFilePath); err != nil {
+		return fmt.Errorf("MergeSrvCovers error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateCoverReports(t *testing.T) {
+	tmpDir := t.TempDir()
+	AppConfig.PublicDir = filepath.Join(tmpDir, "public/report")
+
+	const testDataDir = "coverage/testdata"
+	covFilePath := filepath.Join(tmpDir, "staging_th_apa_echoserver_master_845820727e_20220420_154057.cov")
+	replicas := []string{filepath.Join(testDataDir, "replica1.cov"), filepath.Join(testDataDir, "replica2.cov")}
+	if err := MergeSrvCovers(replicas, covFilePath); err != nil {
+		t.Fatal(err)
+	}
+
+	moduleDirs := []string{filepath.Join(testDataDir, "demo")}
+	total, err := CreateCoverFuncReport(moduleDirs, covFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println("cover total:", total)
+	if total != "83.3" {
+		t.Fatalf("want cover total 83.3, got %s", total)
+	}
+	if _, err := os.Stat(FormatFilePathWithNewExt(covFilePath, CoverRptTypeFunc)); err != nil {
+		t.Fatal(err)
+	}
+
+	htmlPath, err := CreateCoverHTMLReport(moduleDirs, "apa_echoserver", covFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println("html report:", htmlPath)
+	if _, err := os.Stat(htmlPath); err != nil {
+		t.Fatal(err)
+	}
+
+	modules, err := CreateCoverModuleReport([]string{"example.com/demo"}, covFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modules) != 1 || modules[0].Covered != 5 || modules[0].Total != 6 {
+		t.Fatalf("want module cover 5/6, got %+v", modules)
+	}
+	if _, err := os.Stat(FormatFilePathWithNewExt(covFilePath, CoverRptTypeModule)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseModuleToRepoMap(t *testing.T) {
+	b := []byte(`{
+  "apa_echoserver": "https://github.com/zhengjin/echoserver.git",
+  "apa_gateway": [
+    {"url": "https://github.com/zhengjin/mono.git", "module_dirs": ["services/gateway", "libs/common"]},
+    {"url": "https://github.com/zhengjin/proto.git", "branch": "release"}
+  ]
+}`)
+	repoMap, sourcesMap, err := parseModuleToRepoMap(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repoMap["apa_echoserver"] != "https://github.com/zhengjin/echoserver.git" || len(sourcesMap["apa_echoserver"]) != 1 {
+		t.Fatalf("unexpected repo of apa_echoserver: %s, %+v", repoMap["apa_echoserver"], sourcesMap["apa_echoserver"])
+	}
+	if repoMap["apa_gateway"] != "https://github.com/zhengjin/mono.git" {
+		t.Fatalf("unexpected repo of apa_gateway: %s", repoMap["apa_gateway"])
+	}
+	if sources := sourcesMap["apa_gateway"]; len(sources) != 2 || len(sources[0].ModuleDirs) != 2 || sources[1].Branch != "release" {
+		t.Fatalf("unexpected repo sources of apa_gateway: %+v", sources)
+	}
+
+	if _, _, err := parseModuleToRepoMap([]byte(`{"apa_gateway": []}`)); err == nil {
+		t.Fatal("want error for empty repo sources")
+	}
+	if _, _, err := parseModuleToRepoMap([]byte(`{"apa_gateway": [{"url": "https://github.com/zhengjin/mono.git", "dir": "mono"}]}`)); err == nil {
+		t.Fatal("want error for dir of service's own repo")
+	}
+}
@@ -0,0 +1,99 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"demo.hello/gocplugin/pkg/coverage"
+	"demo.hello/utils"
+)
+
+const (
+	defaultDiffCoverBaseRef = "master"
+	diffCoverFileSuffix     = "_diff"
+)
+
+// SrvDiffCoverResult .
+type SrvDiffCoverResult struct {
+	Report         *coverage.DiffCoverReport
+	JSONReportPath string
+	HTMLReportPath string
+}
+
+// CreateSrvDiffCoverReport creates incremental cover report of service commit compared with base ref,
+// and saves report as json (in cover data dir) and html (in public dir).
+func CreateSrvDiffCoverReport(srvName, covFilePath, baseRef string) (SrvDiffCoverResult, error) {
+	if len(baseRef) == 0 {
+		baseRef = getDiffCoverBaseRef()
+	}
+
+	meta := GetSrvMetaFromName(srvName)
+	repos, err := CheckoutSrvRepos(srvName)
+	if err != nil {
+		return SrvDiffCoverResult{}, fmt.Errorf("CreateSrvDiffCoverReport error: %w", err)
+	}
+
+	report, resolver, err := createDiffCoverReport(repos, covFilePath, baseRef, meta.GitCommit)
+	if err != nil {
+		return SrvDiffCoverResult{}, fmt.Errorf("CreateSrvDiffCoverReport error: %w", err)
+	}
+
+	result := SrvDiffCoverResult{
+		Report:         report,
+		JSONReportPath: getFilePathWithoutExt(covFilePath) + diffCoverFileSuffix + ".json",
+	}
+	b, err := json.Marshal(report)
+	if err != nil {
+		return SrvDiffCoverResult{}, fmt.Errorf("CreateSrvDiffCoverReport json marshal error: %w", err)
+	}
+	if err := utils.CreateFile(result.JSONReportPath, b); err != nil {
+		return SrvDiffCoverResult{}, fmt.Errorf("CreateSrvDiffCoverReport error: %w", err)
+	}
+
+	outDirPath := filepath.Join(AppConfig.PublicDir, meta.AppName)
+	if err := utils.MakeDir(outDirPath); err != nil && !errors.Is(err, os.ErrExist) {
+		return SrvDiffCoverResult{}, fmt.Errorf("CreateSrvDiffCoverReport make public dir error: %w", err)
+	}
+	htmlFileName := filepath.Base(getFilePathWithoutExt(covFilePath)) + diffCoverFileSuffix + ".html"
+	result.HTMLReportPath = filepath.Join(outDirPath, htmlFileName)
+	if err := coverage.CreateDiffCoverHTMLReportFile(result.HTMLReportPath, report, resolver); err != nil {
+		return SrvDiffCoverResult{}, fmt.Errorf("CreateSrvDiffCoverReport error: %w", err)
+	}
+	return result, nil
+}
+
+// createDiffCoverReport creates diff report for changed lines in service's own repo.
+func createDiffCoverReport(repos SrvRepos, covFilePath, baseRef, commit string) (*coverage.DiffCoverReport, coverage.FileResolver, error) {
+	repo := NewGitRepo(repos.RepoDir)
+	changedLines, err := repo.GetChangedLines(baseRef, commit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	profiles, err := coverage.ParseCovFile(covFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	resolver, err := repos.FileResolver()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report, err := coverage.CreateDiffCoverReport(profiles, resolver, repos.RepoDir, changedLines)
+	if err != nil {
+		return nil, nil, err
+	}
+	report.BaseRef = baseRef
+	report.Commit = commit
+	return report, resolver, nil
+}
+
+func getDiffCoverBaseRef() string {
+	if len(AppConfig.DiffCoverBaseRef) > 0 {
+		return AppConfig.DiffCoverBaseRef
+	}
+	return defaultDiffCoverBaseRef
+}
@@ -0,0 +1,129 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const testDiffCalcSrc = `package demo
+
+// Abs .
+func Abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+`
+
+const testDiffCalcSrcChanged = `package demo
+
+// Abs .
+func Abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Max .
+func Max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+`
+
+func initTestDiffRepo(t *testing.T) (string, string) {
+	repoDir := t.TempDir()
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit := func(files map[string]string) plumbing.Hash {
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Add(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		hash, err := w.Commit("test", &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+
+	base := commit(map[string]string{
+		"go.mod":  "module example.com/demo\n\ngo 1.16\n",
+		"calc.go": testDiffCalcSrc,
+	})
+	if err := repo.Storer.SetReference(plumbing.NewHashReference("refs/heads/base", base)); err != nil {
+		t.Fatal(err)
+	}
+	head := commit(map[string]string{
+		"calc.go": testDiffCalcSrcChanged,
+	})
+	return repoDir, head.String()[:10]
+}
+
+func TestGetChangedLines(t *testing.T) {
+	repoDir, head := initTestDiffRepo(t)
+	repo := NewGitRepo(repoDir)
+	changedLines, err := repo.GetChangedLines("base", head)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fmt.Printf("changed lines: %v\n", changedLines)
+	if got := fmt.Sprint(changedLines["calc.go"]); got != "[10 11 12 13 14 15 16 17]" {
+		t.Fatalf("unexpected changed lines: %s", got)
+	}
+}
+
+func TestCreateDiffCoverReport(t *testing.T) {
+	repoDir, head := initTestDiffRepo(t)
+	covFilePath := filepath.Join(t.TempDir(), "profile.cov")
+	profile := `mode: count
+example.com/demo/calc.go:4.21,5.11 1 1
+example.com/demo/calc.go:5.11,7.3 1 0
+example.com/demo/calc.go:8.2,8.10 1 1
+example.com/demo/calc.go:12.24,13.11 1 2
+example.com/demo/calc.go:13.11,15.3 1 0
+example.com/demo/calc.go:16.2,16.10 1 2
+`
+	if err := os.WriteFile(covFilePath, []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repos := SrvRepos{
+		RepoDir:    repoDir,
+		ModuleDirs: []string{repoDir},
+	}
+	report, _, err := createDiffCoverReport(repos, covFilePath, "base", head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("diff cover: %s, files: %+v\n", report.PercentText(), report.Files)
+	// only code lines 13, 14 and 16 are counted, and comment, func declare and brace lines are skipped
+	if report.Covered != 2 || report.Total != 3 {
+		t.Fatalf("want diff cover 2/3, got %d/%d", report.Covered, report.Total)
+	}
+}
@@ -0,0 +1,265 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"demo.hello/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// DiscoveryModeK8s .
+	DiscoveryModeK8s = "k8s"
+
+	// AnnotationGocSrvName goc service name of pod, i.e. "staging_th_apa_goc_echoserver_master_845820727e".
+	AnnotationGocSrvName = "goc-plugin/service-name"
+	// AnnotationGocAgentPort port of goc agent in pod.
+	AnnotationGocAgentPort = "goc-plugin/agent-port"
+
+	discoveryMaxRetries = 5
+)
+
+// SrvRegistry registers service address to goc.
+type SrvRegistry interface {
+	RegisterService(ctx context.Context, service, addr string) (string, error)
+	DeleteRegisterServiceByAddr(ctx context.Context, addr string) (string, error)
+}
+
+// FinalCoverFetchFunc fetches cover of service address before it's removed.
+type FinalCoverFetchFunc func(srvName, addr string) error
+
+type registeredSrv struct {
+	srvName string
+	addr    string
+}
+
+// PodDiscovery watches pods with goc annotations, and registers / unregisters them in goc.
+// It replaces pre-stop hook (/watcher/cover/hook/sync) and pod monitor in watcher mode.
+type PodDiscovery struct {
+	registry        SrvRegistry
+	fetchCover      FinalCoverFetchFunc
+	podLister       corelisters.PodLister
+	podListerSynced cache.InformerSynced
+	queue           workqueue.RateLimitingInterface
+	// registered services by pod key "namespace/name".
+	registered map[string]registeredSrv
+	lock       *sync.Mutex
+}
+
+// NewPodDiscovery .
+func NewPodDiscovery(registry SrvRegistry, fetchCover FinalCoverFetchFunc, podInformer coreinformers.PodInformer) *PodDiscovery {
+	d := &PodDiscovery{
+		registry:        registry,
+		fetchCover:      fetchCover,
+		podLister:       podInformer.Lister(),
+		podListerSynced: podInformer.Informer().HasSynced,
+		queue:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "goc-pod-discovery"),
+		registered:      make(map[string]registeredSrv),
+		lock:            &sync.Mutex{},
+	}
+
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    d.enqueue,
+		UpdateFunc: func(old, new interface{}) { d.enqueue(new) },
+		DeleteFunc: d.enqueue,
+	})
+	return d
+}
+
+// RunK8sPodDiscovery creates informer by client, and runs discovery until ctx done.
+func RunK8sPodDiscovery(ctx context.Context, client clientset.Interface, namespace string) {
+	const resync = 10 * time.Minute
+	factory := informers.NewSharedInformerFactoryWithOptions(client, resync, informers.WithNamespace(namespace))
+	d := NewPodDiscovery(NewGocAPI(), fetchAndSaveFinalSrvCover, factory.Core().V1().Pods())
+	factory.Start(ctx.Done())
+	go d.Run(ctx)
+}
+
+// Run .
+func (d *PodDiscovery) Run(ctx context.Context) {
+	defer func() {
+		d.queue.ShutDown()
+		log.Println("goc pod discovery shutdown")
+	}()
+
+	if !cache.WaitForNamedCacheSync("goc-pod-discovery", ctx.Done(), d.podListerSynced) {
+		log.Println("goc pod discovery cache is not sync, and exit")
+		return
+	}
+
+	log.Println("goc pod discovery start")
+	go d.work(ctx)
+	<-ctx.Done()
+}
+
+func (d *PodDiscovery) work(ctx context.Context) {
+	for d.processNextItem(ctx) {
+	}
+}
+
+func (d *PodDiscovery) processNextItem(ctx context.Context) bool {
+	key, quit := d.queue.Get()
+	if quit {
+		return false
+	}
+	defer d.queue.Done(key)
+
+	if err := d.syncHandler(ctx, key.(string)); err != nil {
+		log.Println("goc pod discovery sync error:", err)
+		if d.queue.NumRequeues(key) < discoveryMaxRetries {
+			d.queue.AddRateLimited(key)
+			return true
+		}
+		log.Printf("exceed max retries [%d], and forget pod key: %s", discoveryMaxRetries, key)
+	}
+	d.queue.Forget(key)
+	return true
+}
+
+func (d *PodDiscovery) syncHandler(ctx context.Context, key string) error {
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return fmt.Errorf("syncHandler split key error: %w", err)
+	}
+
+	pod, err := d.podLister.Pods(ns).Get(name)
+	if errors.IsNotFound(err) {
+		return d.unregister(ctx, key)
+	}
+	if err != nil {
+		return fmt.Errorf("syncHandler get pod error: %w", err)
+	}
+
+	// pod which lost goc annotation or became not ready is unregistered if it's registered before
+	srv, ok := getGocSrvOfPod(pod)
+	if !ok || isPodTerminating(pod) || !isPodReady(pod) {
+		return d.unregister(ctx, key)
+	}
+	return d.register(ctx, key, srv)
+}
+
+// register registers service of pod, and previous address is unregistered if service or address changed.
+func (d *PodDiscovery) register(ctx context.Context, key string, srv registeredSrv) error {
+	d.lock.Lock()
+	pre, ok := d.registered[key]
+	d.lock.Unlock()
+	if ok && pre == srv {
+		return nil
+	}
+	if ok {
+		if err := d.unregister(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	localCtx, cancel := context.WithTimeout(ctx, ShortWait)
+	defer cancel()
+	if _, err := d.registry.RegisterService(localCtx, srv.srvName, srv.addr); err != nil {
+		return fmt.Errorf("register service [%s] error: %w", srv.srvName, err)
+	}
+	log.Printf("Register service to goc: srv=%s, addr=%s", srv.srvName, srv.addr)
+
+	d.lock.Lock()
+	d.registered[key] = srv
+	d.lock.Unlock()
+	return nil
+}
+
+// unregister fetches final cover of service, and removes it from goc.
+func (d *PodDiscovery) unregister(ctx context.Context, key string) error {
+	d.lock.Lock()
+	srv, ok := d.registered[key]
+	d.lock.Unlock()
+	if !ok {
+		return nil
+	}
+
+	if err := d.fetchCover(srv.srvName, srv.addr); err != nil {
+		// pod may be already stopped, and goc returns error
+		log.Printf("Fetch final cover of service [%s] error: %v", srv.srvName, err)
+	}
+
+	localCtx, cancel := context.WithTimeout(ctx, ShortWait)
+	defer cancel()
+	if _, err := d.registry.DeleteRegisterServiceByAddr(localCtx, srv.addr); err != nil {
+		return fmt.Errorf("unregister service [%s] error: %w", srv.srvName, err)
+	}
+	log.Printf("Remove service from goc: srv=%s, addr=%s", srv.srvName, srv.addr)
+
+	d.lock.Lock()
+	delete(d.registered, key)
+	d.lock.Unlock()
+	return nil
+}
+
+func (d *PodDiscovery) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Printf("couldn't get key for object %#v: %v", obj, err)
+		return
+	}
+	d.queue.Add(key)
+}
+
+func getGocSrvOfPod(pod *corev1.Pod) (registeredSrv, bool) {
+	srvName, ok := pod.Annotations[AnnotationGocSrvName]
+	if !ok || len(srvName) == 0 {
+		return registeredSrv{}, false
+	}
+	port, ok := pod.Annotations[AnnotationGocAgentPort]
+	if !ok || len(port) == 0 || len(pod.Status.PodIP) == 0 {
+		return registeredSrv{}, false
+	}
+	return registeredSrv{
+		srvName: srvName,
+		addr:    "http://" + net.JoinHostPort(pod.Status.PodIP, port),
+	}, true
+}
+
+func isPodTerminating(pod *corev1.Pod) bool {
+	return pod.DeletionTimestamp != nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// fetchAndSaveFinalSrvCover saves cover of terminating pod to watcher data dir.
+func fetchAndSaveFinalSrvCover(srvName, addr string) error {
+	savedDir := filepath.Join(GetSrvModuleDir(srvName), WatcherCoverDataDirName)
+	if !utils.IsDirExist(savedDir) {
+		if err := utils.MakeDir(savedDir); err != nil {
+			return fmt.Errorf("fetchAndSaveFinalSrvCover error: %w", err)
+		}
+	}
+
+	param := SyncSrvCoverParam{
+		SrvName:   srvName,
+		Addresses: []string{addr},
+	}
+	if _, err := FetchAndSaveSrvCoverByAddr(savedDir, param); err != nil {
+		return fmt.Errorf("fetchAndSaveFinalSrvCover error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package pkg
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type mockSrvRegistry struct {
+	lock       sync.Mutex
+	registered map[string]string
+	fetched    []string
+}
+
+func (r *mockSrvRegistry) RegisterService(ctx context.Context, service, addr string) (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.registered[addr] = service
+	return "ok", nil
+}
+
+func (r *mockSrvRegistry) DeleteRegisterServiceByAddr(ctx context.Context, addr string) (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.registered, addr)
+	return "ok", nil
+}
+
+func (r *mockSrvRegistry) fetchCover(srvName, addr string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.fetched = append(r.fetched, addr)
+	return nil
+}
+
+func (r *mockSrvRegistry) get(addr string) (string, int, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	srvName, ok := r.registered[addr]
+	return srvName, len(r.fetched), ok
+}
+
+func TestPodDiscovery(t *testing.T) {
+	const (
+		ns      = "goc-test"
+		srvName = "staging_th_apa_goc_echoserver_master_845820727e"
+		addr    = "http://10.0.0.8:7777"
+	)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ns,
+			Name:      "echoserver-0",
+			Annotations: map[string]string{
+				AnnotationGocSrvName:   srvName,
+				AnnotationGocAgentPort: "7777",
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			PodIP: "10.0.0.8",
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+	noGocPod := pod.DeepCopy()
+	noGocPod.Name = "nginx-0"
+	noGocPod.Annotations = nil
+	noGocPod.Status.PodIP = "10.0.0.9"
+
+	client := fake.NewSimpleClientset(pod, noGocPod)
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithNamespace(ns))
+	registry := &mockSrvRegistry{registered: make(map[string]string)}
+	d := NewPodDiscovery(registry, registry.fetchCover, factory.Core().V1().Pods())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	factory.Start(ctx.Done())
+	go d.Run(ctx)
+
+	waitFor := func(desc string, cond func() bool) {
+		for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(20 * time.Millisecond) {
+			if cond() {
+				return
+			}
+		}
+		t.Fatal("wait timeout:", desc)
+	}
+
+	waitFor("pod registered", func() bool {
+		name, _, ok := registry.get(addr)
+		return ok && name == srvName
+	})
+	if _, _, ok := registry.get("http://10.0.0.9:7777"); ok {
+		t.Fatal("pod without goc annotation should not be registered")
+	}
+
+	// pod address changed, and previous address is unregistered
+	pod.Annotations[AnnotationGocAgentPort] = "7778"
+	if _, err := client.CoreV1().Pods(ns).Update(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	newAddr := "http://10.0.0.8:7778"
+	waitFor("pod address changed", func() bool {
+		_, _, preOK := registry.get(addr)
+		name, _, ok := registry.get(newAddr)
+		return !preOK && ok && name == srvName
+	})
+
+	// pod not ready
+	pod.Status.Conditions[0].Status = corev1.ConditionFalse
+	if _, err := client.CoreV1().Pods(ns).Update(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("not ready pod unregistered", func() bool {
+		_, _, ok := registry.get(newAddr)
+		return !ok
+	})
+
+	// pod ready again, then lost goc annotation
+	pod.Status.Conditions[0].Status = corev1.ConditionTrue
+	if _, err := client.CoreV1().Pods(ns).Update(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("pod registered again", func() bool {
+		_, _, ok := registry.get(newAddr)
+		return ok
+	})
+	delete(pod.Annotations, AnnotationGocSrvName)
+	if _, err := client.CoreV1().Pods(ns).Update(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("pod without goc annotation unregistered", func() bool {
+		_, _, ok := registry.get(newAddr)
+		return !ok
+	})
+	pod.Annotations[AnnotationGocSrvName] = srvName
+	pod.Annotations[AnnotationGocAgentPort] = "7777"
+	if _, err := client.CoreV1().Pods(ns).Update(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("pod registered again", func() bool {
+		_, _, ok := registry.get(addr)
+		return ok
+	})
+	_, fetchedBefore, _ := registry.get(addr)
+
+	// pod terminating
+	now := metav1.Now()
+	pod.DeletionTimestamp = &now
+	if _, err := client.CoreV1().Pods(ns).Update(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("pod unregistered", func() bool {
+		_, fetched, ok := registry.get(addr)
+		return !ok && fetched == fetchedBefore+1
+	})
+
+	// pod deleted after unregistered, no more fetch
+	if err := client.CoreV1().Pods(ns).Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if _, fetched, _ := registry.get(addr); fetched != fetchedBefore+1 {
+		t.Fatalf("want final cover fetched once, got %d", fetched-fetchedBefore)
+	}
+}
@@ -0,0 +1,234 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"demo.hello/gocplugin/pkg/coverage"
+)
+
+const (
+	// GateRuleMinTotal .
+	GateRuleMinTotal = "min_total"
+	// GateRuleMinDiffTotal .
+	GateRuleMinDiffTotal = "min_diff_total"
+	// GateRuleNoRegression .
+	GateRuleNoRegression = "no_regression"
+	// GateRulePackageMinTotal .
+	GateRulePackageMinTotal = "package_min_total"
+
+	gateHistoryLimit = 20
+)
+
+// GateRules rules for cover quality gate, a rule is skipped if it's not set.
+// Key of PackageMinTotals is package path, and it matches sub packages if ends with "/...". A key which matches
+// no package fails the gate.
+type GateRules struct {
+	MinTotal            float64            `json:"min_total"`
+	MinDiffTotal        float64            `json:"min_diff_total"`
+	NoRegression        bool               `json:"no_regression"`
+	RegressionTolerance float64            `json:"regression_tolerance"`
+	PackageMinTotals    map[string]float64 `json:"package_min_totals"`
+}
+
+// GateReason result of a gate rule.
+type GateReason struct {
+	Rule    string `json:"rule"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message"`
+}
+
+// GateVerdict .
+type GateVerdict struct {
+	SrvName string       `json:"srv_name"`
+	Commit  string       `json:"commit"`
+	Passed  bool         `json:"passed"`
+	Reasons []GateReason `json:"reasons"`
+}
+
+// String returns verdict as notify message.
+func (v GateVerdict) String() string {
+	result := "PASSED"
+	if !v.Passed {
+		result = "FAILED"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Cover gate %s: srv=%s, commit=%s", result, v.SrvName, v.Commit)
+	for _, reason := range v.Reasons {
+		mark := "pass"
+		if !reason.Passed {
+			mark = "fail"
+		}
+		fmt.Fprintf(&b, "\n- [%s] %s: %s", mark, reason.Rule, reason.Message)
+	}
+	return b.String()
+}
+
+// gateInput cover results which gate rules are evaluated against.
+type gateInput struct {
+	total         float64
+	prevCommit    string
+	prevTotal     float64
+	hasPrev       bool
+	diffReport    *coverage.DiffCoverReport
+	packageCovers []coverage.PackageCover
+}
+
+// EvaluateSrvCoverGate evaluates latest cover row of service against gate rules.
+func EvaluateSrvCoverGate(srvName string, rules GateRules, baseRef string) (GateVerdict, error) {
+	input, err := getSrvGateInput(srvName, rules, baseRef)
+	if err != nil {
+		return GateVerdict{}, fmt.Errorf("EvaluateSrvCoverGate error: %w", err)
+	}
+
+	verdict := evaluateGate(input, rules)
+	verdict.SrvName = srvName
+	verdict.Commit = GetSrvMetaFromName(srvName).GitCommit
+	return verdict, nil
+}
+
+func getSrvGateInput(srvName string, rules GateRules, baseRef string) (gateInput, error) {
+	meta := GetSrvMetaFromName(srvName)
+	store := NewCoverHistoryStore()
+	row, err := store.GetLatestSrvCoverRow(meta)
+	if err != nil {
+		return gateInput{}, err
+	}
+
+	input := gateInput{}
+	if input.total, err = parseCoverTotal(row.CoverTotal.String); err != nil {
+		return gateInput{}, err
+	}
+
+	if rules.NoRegression {
+		rows, err := store.GetLimitedHistorySrvCoverRows(meta, gateHistoryLimit)
+		if err != nil {
+			return gateInput{}, err
+		}
+		for _, r := range rows {
+			if r.GitCommit == meta.GitCommit {
+				continue
+			}
+			if input.prevTotal, err = parseCoverTotal(r.CoverTotal.String); err != nil {
+				return gateInput{}, err
+			}
+			input.prevCommit = r.GitCommit
+			input.hasPrev = true
+			break
+		}
+	}
+
+	if rules.MinDiffTotal <= 0 && len(rules.PackageMinTotals) == 0 {
+		return input, nil
+	}
+	if row.CovFilePath == CovFilePathNullValue {
+		return gateInput{}, errors.New("cov file of latest cover row is null")
+	}
+
+	if rules.MinDiffTotal > 0 {
+		result, err := CreateSrvDiffCoverReport(srvName, row.CovFilePath, baseRef)
+		if err != nil {
+			return gateInput{}, err
+		}
+		input.diffReport = result.Report
+	}
+
+	if len(rules.PackageMinTotals) > 0 {
+		profiles, err := coverage.ParseCovFile(row.CovFilePath)
+		if err != nil {
+			return gateInput{}, err
+		}
+		input.packageCovers = coverage.GetPackageCovers(profiles)
+	}
+	return input, nil
+}
+
+func evaluateGate(input gateInput, rules GateRules) GateVerdict {
+	verdict := GateVerdict{
+		Passed:  true,
+		Reasons: make([]GateReason, 0, 4),
+	}
+	addReason := func(rule string, passed bool, format string, a ...interface{}) {
+		verdict.Reasons = append(verdict.Reasons, GateReason{
+			Rule:    rule,
+			Passed:  passed,
+			Message: fmt.Sprintf(format, a...),
+		})
+		if !passed {
+			verdict.Passed = false
+		}
+	}
+
+	if rules.MinTotal > 0 {
+		addReason(GateRuleMinTotal, input.total >= rules.MinTotal,
+			"cover total %.2f, expect >= %.2f", input.total, rules.MinTotal)
+	}
+
+	if rules.MinDiffTotal > 0 && input.diffReport != nil {
+		if input.diffReport.Total == 0 {
+			addReason(GateRuleMinDiffTotal, true, "no changed lines to cover")
+		} else {
+			diffTotal := input.diffReport.Percent()
+			addReason(GateRuleMinDiffTotal, diffTotal >= rules.MinDiffTotal,
+				"diff cover total %.2f (%d/%d lines), expect >= %.2f",
+				diffTotal, input.diffReport.Covered, input.diffReport.Total, rules.MinDiffTotal)
+		}
+	}
+
+	if rules.NoRegression {
+		if !input.hasPrev {
+			addReason(GateRuleNoRegression, true, "no previous commit to compare")
+		} else {
+			addReason(GateRuleNoRegression, input.total+rules.RegressionTolerance >= input.prevTotal,
+				"cover total %.2f, previous commit [%s] cover total %.2f, tolerance %.2f",
+				input.total, input.prevCommit, input.prevTotal, rules.RegressionTolerance)
+		}
+	}
+
+	patterns := make([]string, 0, len(rules.PackageMinTotals))
+	for pattern := range rules.PackageMinTotals {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		minTotal := rules.PackageMinTotals[pattern]
+		matched := false
+		for _, pkgCover := range input.packageCovers {
+			if !isPackageMatched(pattern, pkgCover.Package) {
+				continue
+			}
+			matched = true
+			addReason(GateRulePackageMinTotal, pkgCover.Percent() >= minTotal,
+				"package [%s] cover total %.2f, expect >= %.2f", pkgCover.Package, pkgCover.Percent(), minTotal)
+		}
+		if !matched {
+			// typo or renamed package should not pass gate silently
+			addReason(GateRulePackageMinTotal, false, "no package matched [%s]", pattern)
+		}
+	}
+	return verdict
+}
+
+func isPackageMatched(pattern, pkg string) bool {
+	const suffix = "/..."
+	if strings.HasSuffix(pattern, suffix) {
+		prefix := strings.TrimSuffix(pattern, suffix)
+		return pkg == prefix || strings.HasPrefix(pkg, prefix+"/")
+	}
+	return pkg == pattern
+}
+
+func parseCoverTotal(total string) (float64, error) {
+	if len(total) == 0 {
+		return 0, nil
+	}
+	value, err := strconv.ParseFloat(total, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parseCoverTotal error: %w", err)
+	}
+	return value, nil
+}
@@ -0,0 +1,134 @@
+package pkg
+
+import (
+	"fmt"
+	"testing"
+
+	"demo.hello/gocplugin/pkg/coverage"
+)
+
+func TestEvaluateGate(t *testing.T) {
+	input := gateInput{
+		total:      55.5,
+		prevCommit: "518e0a570c",
+		prevTotal:  60.0,
+		hasPrev:    true,
+		diffReport: &coverage.DiffCoverReport{Covered: 8, Total: 10},
+		packageCovers: []coverage.PackageCover{
+			{Package: "example.com/demo", Covered: 5, Total: 10},
+			{Package: "example.com/demo/util", Covered: 9, Total: 10},
+		},
+	}
+	rules := GateRules{
+		MinTotal:            50,
+		MinDiffTotal:        80,
+		NoRegression:        true,
+		RegressionTolerance: 1,
+		PackageMinTotals: map[string]float64{
+			"example.com/demo/util/...": 90,
+		},
+	}
+
+	verdict := evaluateGate(input, rules)
+	fmt.Println(verdict.String())
+	if verdict.Passed {
+		t.Fatal("want verdict failed by no_regression")
+	}
+
+	results := make(map[string]bool, len(verdict.Reasons))
+	for _, reason := range verdict.Reasons {
+		results[reason.Rule] = reason.Passed
+	}
+	want := map[string]bool{
+		GateRuleMinTotal:        true,
+		GateRuleMinDiffTotal:    true,
+		GateRuleNoRegression:    false,
+		GateRulePackageMinTotal: true,
+	}
+	for rule, passed := range want {
+		if results[rule] != passed {
+			t.Fatalf("rule [%s]: want passed=%v, got %v", rule, passed, results[rule])
+		}
+	}
+}
+
+func TestEvaluateGateNoPackageMatched(t *testing.T) {
+	input := gateInput{
+		total: 80,
+		packageCovers: []coverage.PackageCover{
+			{Package: "example.com/demo/util", Covered: 9, Total: 10},
+		},
+	}
+	rules := GateRules{
+		PackageMinTotals: map[string]float64{
+			"example.com/demo/utils/...": 50,
+			"example.com/demo/util":      50,
+			"example.com/demo/api":       50,
+		},
+	}
+
+	verdict := evaluateGate(input, rules)
+	fmt.Println(verdict.String())
+	if verdict.Passed {
+		t.Fatal("want verdict failed by unmatched package pattern")
+	}
+
+	want := []string{
+		"no package matched [example.com/demo/api]",
+		"package [example.com/demo/util] cover total 90.00, expect >= 50.00",
+		"no package matched [example.com/demo/utils/...]",
+	}
+	if len(verdict.Reasons) != len(want) {
+		t.Fatalf("want %d reasons, got %d", len(want), len(verdict.Reasons))
+	}
+	for i, reason := range verdict.Reasons {
+		if reason.Message != want[i] {
+			t.Fatalf("reason %d: want [%s], got [%s]", i, want[i], reason.Message)
+		}
+	}
+}
+
+func TestIsPackageMatched(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		pkg     string
+		want    bool
+	}{
+		{"example.com/demo", "example.com/demo", true},
+		{"example.com/demo", "example.com/demo/util", false},
+		{"example.com/demo/...", "example.com/demo/util", true},
+		{"example.com/demo/...", "example.com/demo", true},
+		{"example.com/demo/...", "example.com/demox", false},
+	} {
+		if got := isPackageMatched(c.pattern, c.pkg); got != c.want {
+			t.Fatalf("isPackageMatched(%s, %s): want %v, got %v", c.pattern, c.pkg, c.want, got)
+		}
+	}
+}
+
+func TestEvaluateSrvCoverGateWithMemStore(t *testing.T) {
+	SetCoverHistoryStore(NewMemCoverHistoryStore())
+	store := NewCoverHistoryStore()
+	rows := []GocSrvCoverModel{
+		newTestSrvCoverRow("staging_th_apa_echoserver_master_518e0a570c", "60.00"),
+		newTestSrvCoverRow("staging_th_apa_echoserver_master_845820727e", "62.50"),
+	}
+	for _, row := range rows {
+		if err := store.InsertSrvCoverRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules := GateRules{
+		MinTotal:     60,
+		NoRegression: true,
+	}
+	verdict, err := EvaluateSrvCoverGate("staging_th_apa_echoserver_master_845820727e", rules, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(verdict.String())
+	if !verdict.Passed || len(verdict.Reasons) != 2 {
+		t.Fatalf("want verdict passed with 2 reasons, got: %+v", verdict)
+	}
+}
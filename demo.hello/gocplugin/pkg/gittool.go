@@ -11,6 +11,7 @@ import (
 
 	git "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/storage"
@@ -220,6 +221,85 @@ func (r *GitRepo) GetCommit(hash string) (*object.Commit, error) {
 	return object.GetCommit(r.repo.Storer, plumbing.NewHash(fullHash))
 }
 
+// GetChangedLines returns added and modified lines (file path -> line numbers) in commit compared with base ref.
+// Same as "git diff base_ref...commit", the merge base of base ref and commit is used as start point.
+func (r *GitRepo) GetChangedLines(baseRef, commitID string) (map[string][]int, error) {
+	baseCommit, err := r.getRefCommit(baseRef)
+	if err != nil {
+		return nil, fmt.Errorf("GetChangedLines error: %w", err)
+	}
+	commit, err := r.GetCommit(commitID)
+	if err != nil {
+		return nil, fmt.Errorf("GetChangedLines error: %w", err)
+	}
+
+	bases, err := baseCommit.MergeBase(commit)
+	if err != nil {
+		return nil, fmt.Errorf("GetChangedLines get merge base error: %w", err)
+	}
+	if len(bases) > 0 {
+		baseCommit = bases[0]
+	}
+
+	patch, err := baseCommit.Patch(commit)
+	if err != nil {
+		return nil, fmt.Errorf("GetChangedLines get patch error: %w", err)
+	}
+
+	changedLines := make(map[string][]int, len(patch.FilePatches()))
+	for _, filePatch := range patch.FilePatches() {
+		_, to := filePatch.Files()
+		if to == nil || filePatch.IsBinary() {
+			continue
+		}
+
+		lines := make([]int, 0, 16)
+		lineNum := 1
+		for _, chunk := range filePatch.Chunks() {
+			count := getLinesCount(chunk.Content())
+			switch chunk.Type() {
+			case diff.Equal:
+				lineNum += count
+			case diff.Add:
+				for i := 0; i < count; i++ {
+					lines = append(lines, lineNum)
+					lineNum++
+				}
+			}
+		}
+		if len(lines) > 0 {
+			changedLines[to.Path()] = lines
+		}
+	}
+	return changedLines, nil
+}
+
+// getRefCommit returns commit of local branch, remote branch or commit id.
+func (r *GitRepo) getRefCommit(ref string) (*object.Commit, error) {
+	commitID, err := r.GetBranchCommit(ref)
+	if errors.Is(err, git.ErrBranchNotFound) {
+		_, commitID, err = r.getRemoteBranch(ref)
+	}
+	if errors.Is(err, git.ErrBranchNotFound) {
+		commitID, err = r.getFullCommitID(ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getRefCommit error: %w", err)
+	}
+	return object.GetCommit(r.repo.Storer, plumbing.NewHash(commitID))
+}
+
+func getLinesCount(content string) int {
+	if len(content) == 0 {
+		return 0
+	}
+	count := strings.Count(content, "\n")
+	if !strings.HasSuffix(content, "\n") {
+		count++
+	}
+	return count
+}
+
 // GetBranchCommit .
 func (r *GitRepo) GetBranchCommit(branch string) (string, error) {
 	_, commitID, err := r.getBranch(branch)
@@ -284,6 +364,44 @@ func (r *GitRepo) getRemoteBranch(branch string) (string, string, error) {
 	return name, commitID, err
 }
 
+// GetTaggedCommits returns short commit ids (10 chars) to tag name, for tags which match any of glob patterns.
+func (r *GitRepo) GetTaggedCommits(patterns []string) (map[string]string, error) {
+	tags, err := r.repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("GetTaggedCommits get repo tags error: %w", err)
+	}
+
+	commits := make(map[string]string)
+	if err := tags.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		if !isMatchAnyPattern(name, patterns) {
+			return nil
+		}
+
+		hash := ref.Hash()
+		// annotated tag points to tag object, and lightweight tag points to commit directly
+		if tag, err := r.repo.TagObject(hash); err == nil {
+			hash = tag.Target
+		} else if !errors.Is(err, plumbing.ErrObjectNotFound) {
+			return err
+		}
+		commits[hash.String()[:10]] = name
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("GetTaggedCommits iterator tags error: %w", err)
+	}
+	return commits, nil
+}
+
+func isMatchAnyPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, err := filepath.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *GitRepo) getFullCommitID(shortCommitID string) (string, error) {
 	objects, err := r.repo.CommitObjects()
 	if err != nil {
@@ -306,6 +424,19 @@ func (r *GitRepo) getFullCommitID(shortCommitID string) (string, error) {
 	return commitID, nil
 }
 
+// IsHeadAtCommit returns true if head of repo is at the given (short) commit.
+func (r *GitRepo) IsHeadAtCommit(commitID string) (bool, error) {
+	fullCommitID, err := r.getFullCommitID(commitID)
+	if err != nil {
+		return false, fmt.Errorf("IsHeadAtCommit error: %w", err)
+	}
+	ref, err := r.repo.Head()
+	if err != nil {
+		return false, fmt.Errorf("IsHeadAtCommit get repo head ref error: %w", err)
+	}
+	return ref.Hash().String() == fullCommitID, nil
+}
+
 func (r *GitRepo) getRepoHeadCommitShortID() (string, error) {
 	ref, err := r.repo.Head()
 	if err != nil {
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"demo.hello/gocplugin/pkg"
+	"github.com/gin-gonic/gin"
+)
+
+type coverGateReq struct {
+	SrvName  string         `json:"srv_name" binding:"required"`
+	BaseRef  string         `json:"base_ref"`
+	Rules    *pkg.GateRules `json:"rules"`
+	IsNotify bool           `json:"is_notify"`
+}
+
+// CoverGateHandler evaluates latest service cover against gate rules, and returns pass/fail verdict.
+// Rules in request override the default rules in config.
+func CoverGateHandler(c *gin.Context) {
+	var req coverGateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Println("CoverGateHandler error:", err)
+		sendErrorResp(c, http.StatusBadRequest, errMsgJSONBind)
+		return
+	}
+
+	rules := pkg.AppConfig.Gate
+	if req.Rules != nil {
+		rules = *req.Rules
+	}
+
+	verdict, err := pkg.EvaluateSrvCoverGate(req.SrvName, rules, req.BaseRef)
+	if err != nil {
+		log.Println("CoverGateHandler error:", err)
+		if errors.Is(err, pkg.ErrSrvCoverLatestRowNotFound) {
+			sendErrorResp(c, http.StatusBadRequest, "Service cover is not exist in db.")
+		} else {
+			sendErrorResp(c, http.StatusInternalServerError, "Evaluate cover gate failed.")
+		}
+		return
+	}
+
+	if req.IsNotify {
+		notify := pkg.NewNotifyDispatcher()
+		notify.MustNotify(pkg.NewNotifyMessage(pkg.NotifyEventGateResult, req.SrvName, verdict.String()))
+	}
+	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "data": verdict})
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"demo.hello/gocplugin/pkg"
+	"github.com/gin-gonic/gin"
+)
+
+type respSyncJobItem struct {
+	ID          uint   `json:"id"`
+	SrvName     string `json:"srv_name"`
+	Commit      string `json:"commit"`
+	Status      string `json:"status"`
+	Attempts    int    `json:"attempts"`
+	MaxAttempts int    `json:"max_attempts"`
+	CoverTotal  string `json:"cover_total"`
+	ErrMsg      string `json:"error"`
+	CreatedAt   string `json:"created_at"`
+	StartedAt   string `json:"started_at"`
+	FinishedAt  string `json:"finished_at"`
+	Owner       string `json:"owner"`
+}
+
+func newRespSyncJobItem(job pkg.SyncJobModel) respSyncJobItem {
+	return respSyncJobItem{
+		ID:          job.ID,
+		SrvName:     job.SrvName,
+		Commit:      job.GitCommit,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		CoverTotal:  job.CoverTotal,
+		ErrMsg:      job.ErrMsg,
+		CreatedAt:   pkg.GetSimpleDatetime(job.CreatedAt),
+		StartedAt:   formatNullTime(job.StartedAt),
+		FinishedAt:  formatNullTime(job.FinishedAt),
+		Owner:       job.Owner,
+	}
+}
+
+func formatNullTime(t sql.NullTime) string {
+	if !t.Valid {
+		return emptyDate
+	}
+	return pkg.GetSimpleDatetime(t.Time)
+}
+
+// GetSyncJobHandler .
+func GetSyncJobHandler(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		sendErrorResp(c, http.StatusBadRequest, "Invalid parameter: id="+c.Param("id"))
+		return
+	}
+
+	store := pkg.NewSyncJobStore()
+	job, err := store.GetJob(uint(id))
+	if err != nil {
+		log.Println("GetSyncJobHandler error:", err)
+		if errors.Is(err, pkg.ErrSyncJobNotFound) {
+			sendErrorResp(c, http.StatusNotFound, "Sync job is not exist.")
+		} else {
+			sendErrorResp(c, http.StatusInternalServerError, "Get sync job failed.")
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "data": newRespSyncJobItem(job)})
+}
+
+// ListSyncJobsHandler lists jobs by query "srv_name", "status" and "limit".
+func ListSyncJobsHandler(c *gin.Context) {
+	const defaultLimit = 20
+	filter := pkg.SyncJobFilter{
+		SrvName: c.Query("srv_name"),
+		Status:  c.Query("status"),
+		Limit:   defaultLimit,
+	}
+	if limit := c.Query("limit"); len(limit) > 0 {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 {
+			sendErrorResp(c, http.StatusBadRequest, "Invalid parameter: limit="+limit)
+			return
+		}
+		filter.Limit = value
+	}
+
+	store := pkg.NewSyncJobStore()
+	jobs, err := store.ListJobs(filter)
+	if err != nil {
+		log.Println("ListSyncJobsHandler error:", err)
+		sendErrorResp(c, http.StatusInternalServerError, "List sync jobs failed.")
+		return
+	}
+
+	items := make([]respSyncJobItem, 0, len(jobs))
+	for _, job := range jobs {
+		items = append(items, newRespSyncJobItem(job))
+	}
+	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "count": len(items), "data": items})
+}
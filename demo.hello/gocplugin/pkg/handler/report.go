@@ -108,9 +108,9 @@ func GetLatestSrvCoverTotalHandler(c *gin.Context) {
 const emptyDate = "null"
 
 func getLatestSrvCoverTotalInDB(srvName string) (string, string, error) {
-	dbInstance := pkg.NewGocSrvCoverDBInstance()
+	store := pkg.NewCoverHistoryStore()
 	meta := pkg.GetSrvMetaFromName(srvName)
-	row, err := dbInstance.GetLatestSrvCoverRow(meta)
+	row, err := store.GetLatestSrvCoverRow(meta)
 	if err != nil {
 		if errors.Is(err, pkg.ErrSrvCoverLatestRowNotFound) {
 			return pkg.ZeroCoverTotal, emptyDate, nil
@@ -146,9 +146,9 @@ func GetHistorySrvCoverTotalsHandler(c *gin.Context) {
 	}
 
 	const defaultLimit = 10
-	dbInstance := pkg.NewGocSrvCoverDBInstance()
+	store := pkg.NewCoverHistoryStore()
 	meta := pkg.GetSrvMetaFromName(param.SrvName)
-	rows, err := dbInstance.GetLimitedHistorySrvCoverRows(meta, defaultLimit)
+	rows, err := store.GetLimitedHistorySrvCoverRows(meta, defaultLimit)
 	if err != nil {
 		log.Println("GetHistorySrvCoverTotalsHandler error:", err)
 		respErrMsg := "Get history service cover in db failed."
@@ -254,7 +254,7 @@ func ClearSrvCoverHandler(c *gin.Context) {
 	tasksState := pkg.NewSrvCoverSyncTasksState()
 	tasksState.Delete(param.SrvName)
 
-	db := pkg.NewGocSrvCoverDBInstance()
+	store := pkg.NewCoverHistoryStore()
 	meta := pkg.GetSrvMetaFromName(param.SrvName)
 	meta.Addrs = strings.Join(param.Addresses, ",")
 	row := pkg.GocSrvCoverModel{
@@ -266,12 +266,13 @@ func ClearSrvCoverHandler(c *gin.Context) {
 			Valid:  true,
 		},
 	}
-	if err := db.AddLatestSrvCoverRow(row); err != nil {
+	if err := store.AddLatestSrvCoverRow(row); err != nil {
 		log.Println("ClearSrvCoverHandler error:", err)
 		respErrMsg := "Add latest service cover row failed."
 		sendErrorResp(c, http.StatusInternalServerError, respErrMsg)
 		return
 	}
+	pkg.ObserveSrvCoverTotal(param.SrvName, pkg.ZeroCoverTotal)
 
 	sendSuccessResp(c, "Cover clear success.")
 }
@@ -329,7 +330,7 @@ func SyncSrvCoverHandler(c *gin.Context) {
 		}
 	}
 
-	retCh := pkg.SubmitSrvCoverSyncTask(pkg.SyncSrvCoverParam{
+	jobID, retCh := pkg.SubmitSrvCoverSyncTask(pkg.SyncSrvCoverParam{
 		SrvName:   req.SrvName,
 		Addresses: addrs,
 	})
@@ -348,8 +349,11 @@ func SyncSrvCoverHandler(c *gin.Context) {
 		}
 		close(retCh)
 	case <-time.After(pkg.LongWait):
-		respMsg := "Sync service cover task submitted and running."
-		sendSuccessResp(c, respMsg)
+		c.JSON(http.StatusOK, gin.H{
+			"code":    http.StatusOK,
+			"message": "Sync service cover task submitted and running.",
+			"job_id":  jobID,
+		})
 	}
 }
 
@@ -411,9 +415,10 @@ type getSrvFuncCoverRptReq struct {
 	pkg.SyncSrvCoverParam
 	RptName string `json:"rpt_name"`
 	RptType string `json:"rpt_type"`
+	BaseRef string `json:"base_ref"`
 }
 
-// GetSrvCoverReportHandler returns cov or func cover report. (html report is get from static.)
+// GetSrvCoverReportHandler returns cov, func, module or diff cover report. (html report is get from static.)
 func GetSrvCoverReportHandler(c *gin.Context) {
 	var req getSrvFuncCoverRptReq
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -422,26 +427,32 @@ func GetSrvCoverReportHandler(c *gin.Context) {
 		return
 	}
 
-	if req.RptType != pkg.CoverRptTypeFunc && req.RptType != pkg.CoverRptTypeRaw {
+	if !isValidSrvCoverRptType(req.RptType) {
 		respErrMsg := fmt.Sprintf("Invalid parameter: rpt_type=%s", req.RptType)
 		sendErrorResp(c, http.StatusBadRequest, respErrMsg)
 		return
 	}
 
+	// diff report is created from cov file
+	ext := req.RptType
+	if req.RptType == pkg.CoverRptTypeDiff {
+		ext = pkg.CoverRptTypeRaw
+	}
+
 	var (
 		filePath string
 		err      error
 	)
 	meta := pkg.GetSrvMetaFromName(req.SrvName)
 	if len(req.RptName) > 0 {
-		suffix := "." + req.RptType
+		suffix := "." + ext
 		if !strings.HasSuffix(req.RptName, suffix) {
 			req.RptName = req.RptName + suffix
 		}
 		filePath = filepath.Join(pkg.GetModuleCoverDataDir(meta.AppName), req.RptName)
 	} else {
 		// if rpt_name not set, get from latest row in db
-		filePath, err = getLatestSrvCoverRptPath(meta, req.RptType)
+		filePath, err = getLatestSrvCoverRptPath(meta, ext)
 		if err != nil {
 			log.Println("GetSrvCoverReportHandler error:", err)
 			if errors.Is(err, pkg.ErrSrvCoverLatestRowNotFound) {
@@ -453,6 +464,11 @@ func GetSrvCoverReportHandler(c *gin.Context) {
 		}
 	}
 
+	if req.RptType == pkg.CoverRptTypeDiff {
+		sendSrvDiffCoverReport(c, req.SrvName, filePath, req.BaseRef)
+		return
+	}
+
 	b, err := utils.ReadFile(filePath)
 	if err != nil {
 		log.Println("GetSrvCoverReportHandler error:", err)
@@ -462,9 +478,40 @@ func GetSrvCoverReportHandler(c *gin.Context) {
 	sendBytes(c, b)
 }
 
+func isValidSrvCoverRptType(rptType string) bool {
+	switch rptType {
+	case pkg.CoverRptTypeRaw, pkg.CoverRptTypeFunc, pkg.CoverRptTypeModule, pkg.CoverRptTypeDiff:
+		return true
+	default:
+		return false
+	}
+}
+
+func sendSrvDiffCoverReport(c *gin.Context, srvName, covFilePath, baseRef string) {
+	if !utils.IsExist(covFilePath) {
+		sendErrorResp(c, http.StatusBadRequest, "Cov file is not exist.")
+		return
+	}
+
+	result, err := pkg.CreateSrvDiffCoverReport(srvName, covFilePath, baseRef)
+	if err != nil {
+		log.Println("GetSrvCoverReportHandler error:", err)
+		sendErrorResp(c, http.StatusInternalServerError, "Create diff cover report failed.")
+		return
+	}
+
+	meta := pkg.GetSrvMetaFromName(srvName)
+	c.JSON(http.StatusOK, gin.H{
+		"code":        http.StatusOK,
+		"cover_total": result.Report.PercentText(),
+		"html_report": filepath.Join("/static/report", meta.AppName, filepath.Base(result.HTMLReportPath)),
+		"data":        result.Report,
+	})
+}
+
 func getLatestSrvCoverRptPath(meta pkg.SrvCoverMeta, ext string) (string, error) {
-	dbInstance := pkg.NewGocSrvCoverDBInstance()
-	row, err := dbInstance.GetLatestSrvCoverRow(meta)
+	store := pkg.NewCoverHistoryStore()
+	row, err := store.GetLatestSrvCoverRow(meta)
 	if err != nil {
 		return "", fmt.Errorf("getLatestSrvCoverRow error: %w", err)
 	}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"demo.hello/gocplugin/pkg"
+	"github.com/gin-gonic/gin"
+)
+
+type coverRetentionReq struct {
+	DryRun *bool                `json:"dry_run"`
+	Policy *pkg.RetentionConfig `json:"policy"`
+}
+
+// CoverRetentionHandler applies retention policy on history cov files, and it's dry run by default which only lists
+// the planned actions. Policy in request overrides the default policy in config.
+func CoverRetentionHandler(c *gin.Context) {
+	var req coverRetentionReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Println("CoverRetentionHandler error:", err)
+		sendErrorResp(c, http.StatusBadRequest, errMsgJSONBind)
+		return
+	}
+
+	dryRun := true
+	if req.DryRun != nil {
+		dryRun = *req.DryRun
+	}
+	policy := pkg.AppConfig.Retention
+	if req.Policy != nil {
+		policy = *req.Policy
+	}
+	if policy.KeepLastCommits <= 0 {
+		sendErrorResp(c, http.StatusBadRequest, "Retention policy is not set: keep_last_commits should be greater than 0.")
+		return
+	}
+
+	retention, err := pkg.NewCoverRetention(pkg.NewCoverHistoryStore(), policy)
+	if err != nil {
+		log.Println("CoverRetentionHandler error:", err)
+		sendErrorResp(c, http.StatusBadRequest, "Invalid retention policy: archive host, bucket and credentials should be set.")
+		return
+	}
+	result, err := retention.Run(dryRun)
+	if err != nil {
+		log.Println("CoverRetentionHandler error:", err)
+		sendErrorResp(c, http.StatusInternalServerError, "Apply cover retention policy failed.")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "count": len(result.Items), "data": result})
+}
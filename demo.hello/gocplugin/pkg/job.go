@@ -0,0 +1,152 @@
+package pkg
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// JobStatusPending .
+	JobStatusPending = "pending"
+	// JobStatusRunning .
+	JobStatusRunning = "running"
+	// JobStatusSucceeded .
+	JobStatusSucceeded = "succeeded"
+	// JobStatusFailed .
+	JobStatusFailed = "failed"
+)
+
+// ErrSyncJobNotFound .
+var ErrSyncJobNotFound = errors.New("ErrSyncJobNotFound")
+
+// SyncJobModel srv cover sync job, and only one pending or running job exists for a service commit.
+// ActiveKey is unique for pending or running jobs, and it's null for finished jobs.
+// Owner is the report instance which runs the job, and it refreshes HeartbeatAt of its unfinished jobs. Unfinished
+// jobs whose heartbeat is expired are claimed by other instances.
+type SyncJobModel struct {
+	gorm.Model
+	SrvName     string `gorm:"index"`
+	GitCommit   string
+	ActiveKey   *string `gorm:"uniqueIndex"`
+	Addresses   string
+	Status      string `gorm:"index"`
+	Attempts    int
+	MaxAttempts int
+	CoverTotal  string
+	ErrMsg      string
+	StartedAt   sql.NullTime
+	FinishedAt  sql.NullTime
+	Owner       string `gorm:"index"`
+	HeartbeatAt sql.NullTime
+}
+
+// TableName .
+func (SyncJobModel) TableName() string {
+	return "goc_cover_sync_job"
+}
+
+// BeforeSave sets active key by job status.
+func (job *SyncJobModel) BeforeSave(tx *gorm.DB) error {
+	if job.IsFinished() {
+		job.ActiveKey = nil
+	} else {
+		key := job.getActiveKey()
+		job.ActiveKey = &key
+	}
+	return nil
+}
+
+func (job SyncJobModel) getActiveKey() string {
+	return job.SrvName + "@" + job.GitCommit
+}
+
+// IsFinished .
+func (job SyncJobModel) IsFinished() bool {
+	return job.Status == JobStatusSucceeded || job.Status == JobStatusFailed
+}
+
+// GetSyncSrvCoverParam .
+func (job SyncJobModel) GetSyncSrvCoverParam() SyncSrvCoverParam {
+	param := SyncSrvCoverParam{
+		SrvName: job.SrvName,
+	}
+	if len(job.Addresses) > 0 {
+		param.Addresses = strings.Split(job.Addresses, ",")
+	}
+	return param
+}
+
+// SyncJobFilter empty fields match all.
+type SyncJobFilter struct {
+	SrvName string
+	Status  string
+	Limit   int
+}
+
+// SyncJobStore stores srv cover sync jobs.
+type SyncJobStore interface {
+	// CreateOrGetActiveJob returns the pending or running job of same service and commit if exists,
+	// otherwise creates a new one. The bool result is true if job is created.
+	CreateOrGetActiveJob(job SyncJobModel) (SyncJobModel, bool, error)
+	GetJob(id uint) (SyncJobModel, error)
+	// ListJobs returns jobs order by id desc.
+	ListJobs(filter SyncJobFilter) ([]SyncJobModel, error)
+	ListUnfinishedJobs() ([]SyncJobModel, error)
+	UpdateJob(job SyncJobModel) error
+	// RefreshJobsHeartbeat updates heartbeat of unfinished jobs of owner.
+	RefreshJobsHeartbeat(owner string, at time.Time) error
+	// ClaimExpiredJobs sets owner of unfinished jobs whose heartbeat is before expiredAt, and resets them to pending.
+	// A job is claimed by only one of concurrent claims.
+	ClaimExpiredJobs(owner string, expiredAt, now time.Time) ([]SyncJobModel, error)
+}
+
+var activeJobStatuses = []string{JobStatusPending, JobStatusRunning}
+
+func (job SyncJobModel) isHeartbeatExpired(expiredAt time.Time) bool {
+	return !job.HeartbeatAt.Valid || job.HeartbeatAt.Time.Before(expiredAt)
+}
+
+var (
+	syncJobStore     SyncJobStore
+	syncJobStoreOnce sync.Once
+)
+
+// NewSyncJobStore returns global job store which shares db with cover history store.
+func NewSyncJobStore() SyncJobStore {
+	syncJobStoreOnce.Do(func() {
+		store, err := OpenSyncJobStore(NewCoverHistoryStore())
+		if err != nil {
+			panic("NewSyncJobStore error: " + err.Error())
+		}
+		syncJobStore = store
+	})
+	return syncJobStore
+}
+
+// SetSyncJobStore replaces global job store, it should be invoked before job queue init.
+func SetSyncJobStore(store SyncJobStore) {
+	syncJobStoreOnce.Do(func() {})
+	syncJobStore = store
+}
+
+// OpenSyncJobStore .
+func OpenSyncJobStore(coverStore CoverHistoryStore) (SyncJobStore, error) {
+	switch store := coverStore.(type) {
+	case *GormCoverHistoryStore:
+		jobStore, err := NewGormSyncJobStore(store.db)
+		if err != nil {
+			return nil, fmt.Errorf("OpenSyncJobStore error: %w", err)
+		}
+		return jobStore, nil
+	case *MemCoverHistoryStore:
+		return NewMemSyncJobStore(), nil
+	default:
+		return nil, fmt.Errorf("OpenSyncJobStore unsupported cover store type: %T", coverStore)
+	}
+}
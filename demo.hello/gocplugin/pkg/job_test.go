@@ -0,0 +1,194 @@
+package pkg
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+)
+
+func TestGormSyncJobStore(t *testing.T) {
+	coverStore, err := NewGormCoverHistoryStore(sqlite.Open(filepath.Join(t.TempDir(), "sqlite.db")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenSyncJobStore(coverStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := SyncJobModel{
+		SrvName:     "staging_th_apa_goc_echoserver_master_845820727e",
+		GitCommit:   "845820727e",
+		Addresses:   "http://127.0.0.1:51007,http://127.0.0.1:51025",
+		Status:      JobStatusPending,
+		MaxAttempts: 3,
+	}
+	job1, isCreated, err := store.CreateOrGetActiveJob(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isCreated {
+		t.Fatal("want job created")
+	}
+	if addrs := job1.GetSyncSrvCoverParam().Addresses; len(addrs) != 2 {
+		t.Fatalf("want 2 addresses, got %v", addrs)
+	}
+
+	job2, isCreated, err := store.CreateOrGetActiveJob(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isCreated || job2.ID != job1.ID {
+		t.Fatalf("want active job %d reused, got job %d", job1.ID, job2.ID)
+	}
+
+	job1.Status = JobStatusSucceeded
+	job1.CoverTotal = "50.00"
+	if err := store.UpdateJob(job1); err != nil {
+		t.Fatal(err)
+	}
+	job3, isCreated, err := store.CreateOrGetActiveJob(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isCreated || job3.ID == job1.ID {
+		t.Fatal("want new job created after active job finished")
+	}
+
+	jobs, err := store.ListJobs(SyncJobFilter{SrvName: job.SrvName, Status: JobStatusSucceeded})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].CoverTotal != "50.00" {
+		t.Fatalf("want 1 succeeded job, got %+v", jobs)
+	}
+
+	jobs, err = store.ListUnfinishedJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != job3.ID {
+		t.Fatalf("want 1 unfinished job %d, got %+v", job3.ID, jobs)
+	}
+
+	if _, err := store.GetJob(100); !errors.Is(err, ErrSyncJobNotFound) {
+		t.Fatalf("want ErrSyncJobNotFound, got %v", err)
+	}
+}
+
+func TestGormSyncJobStoreConcurrentCreate(t *testing.T) {
+	coverStore, err := NewGormCoverHistoryStore(sqlite.Open(filepath.Join(t.TempDir(), "sqlite.db")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenSyncJobStore(coverStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := SyncJobModel{
+		SrvName:   "staging_th_apa_goc_echoserver_master_845820727e",
+		GitCommit: "845820727e",
+		Status:    JobStatusPending,
+	}
+	// create active job without select, and duplicated active job is rejected by unique index
+	if result := store.(*GormSyncJobStore).db.Create(&job); result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	dup := job
+	dup.ID = 0
+	if result := store.(*GormSyncJobStore).db.Create(&dup); result.Error == nil {
+		t.Fatal("want duplicated active key error")
+	}
+
+	// concurrent creates of a new service commit
+	const num = 5
+	type createResult struct {
+		id        uint
+		isCreated bool
+	}
+	results := make(chan createResult, num)
+	wg := sync.WaitGroup{}
+	for i := 0; i < num; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			created, isCreated, err := store.CreateOrGetActiveJob(SyncJobModel{
+				SrvName:   "staging_th_apa_goc_echoserver_master_518e0a570c",
+				GitCommit: "518e0a570c",
+				Status:    JobStatusPending,
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results <- createResult{id: created.ID, isCreated: isCreated}
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	ids := make(map[uint]struct{}, 1)
+	createdCount := 0
+	for ret := range results {
+		ids[ret.id] = struct{}{}
+		if ret.isCreated {
+			createdCount++
+		}
+	}
+	if len(ids) != 1 || createdCount != 1 {
+		t.Fatalf("want 1 job created, got ids %v and %d created", ids, createdCount)
+	}
+}
+
+func TestGormSyncJobStoreClaimExpiredJobs(t *testing.T) {
+	coverStore, err := NewGormCoverHistoryStore(sqlite.Open(filepath.Join(t.TempDir(), "sqlite.db")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenSyncJobStore(coverStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for _, job := range []SyncJobModel{
+		{SrvName: "staging_th_apa_goc_echoserver_master_845820727e", GitCommit: "845820727e", Owner: "a"},
+		{SrvName: "staging_th_apa_goc_echoserver_master_518e0a570c", GitCommit: "518e0a570c", Owner: "b"},
+	} {
+		job.Status = JobStatusRunning
+		job.HeartbeatAt = sql.NullTime{Time: now.Add(-time.Hour), Valid: true}
+		if _, _, err := store.CreateOrGetActiveJob(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.RefreshJobsHeartbeat("a", now); err != nil {
+		t.Fatal(err)
+	}
+
+	expiredAt := now.Add(-time.Minute)
+	jobs, err := store.ClaimExpiredJobs("c", expiredAt, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].GitCommit != "518e0a570c" || jobs[0].Owner != "c" || jobs[0].Status != JobStatusPending {
+		t.Fatalf("want expired job of b claimed, got: %+v", jobs)
+	}
+	job, err := store.GetJob(jobs[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Owner != "c" || job.Status != JobStatusPending || job.ActiveKey == nil {
+		t.Fatalf("want claimed job saved, got: %+v", job)
+	}
+
+	// claimed job is not claimed again
+	if jobs, err = store.ClaimExpiredJobs("d", expiredAt, now); err != nil || len(jobs) != 0 {
+		t.Fatalf("want no job claimed, got %d jobs, err: %v", len(jobs), err)
+	}
+}
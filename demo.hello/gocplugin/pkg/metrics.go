@@ -0,0 +1,175 @@
+package pkg
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"demo.hello/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsNamespace = "goc_plugin"
+
+var (
+	srvCoverTotalGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "srv_cover_total",
+		Help:      "Latest cover total (percent) of service.",
+	}, []string{"env", "region", "app", "branch", "commit"})
+
+	srvCoverSyncDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "srv_cover_sync_duration_seconds",
+		Help:      "Duration of service cover sync job run.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"env", "region", "app", "status"})
+
+	srvCoverSyncFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "srv_cover_sync_failures_total",
+		Help:      "Count of failed service cover sync job runs.",
+	}, []string{"env", "region", "app"})
+
+	scheduleTaskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "schedule_task_duration_seconds",
+		Help:      "Duration of schedule task run.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 300, 600, 1800},
+	}, []string{"task"})
+
+	scheduleTaskFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "schedule_task_failures_total",
+		Help:      "Count of failed schedule task runs.",
+	}, []string{"task"})
+
+	registerSrvsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "goc_register_services",
+		Help:      "Number of services in goc register list.",
+	})
+
+	watcherCovFilesGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "watcher_cov_files",
+		Help:      "Number of raw cov files saved by watcher for service, which are not fetched by report server.",
+	}, []string{"env", "region", "app"})
+)
+
+var (
+	// srvCoverTotalLabels latest labels of cover total gauge by service key "env_region_app".
+	srvCoverTotalLabels     = make(map[string]prometheus.Labels)
+	srvCoverTotalLabelsLock sync.Mutex
+)
+
+// ObserveSrvCoverTotal sets cover total gauge of service, and removes the gauge of previous commit.
+func ObserveSrvCoverTotal(srvName, coverTotal string) {
+	value, err := parseCoverTotal(coverTotal)
+	if err != nil {
+		log.Println("ObserveSrvCoverTotal error:", err)
+		return
+	}
+	observeSrvCoverTotalByMeta(GetSrvMetaFromName(srvName), value)
+}
+
+// InitSrvCoverTotalMetrics seeds cover total gauges by latest rows of services in store, so that gauges are
+// available after restart before services are synced.
+func InitSrvCoverTotalMetrics(store CoverHistoryStore) error {
+	rows, err := store.ListSrvCoverRows()
+	if err != nil {
+		return fmt.Errorf("InitSrvCoverTotalMetrics error: %w", err)
+	}
+
+	latestRows := make(map[string]GocSrvCoverModel, len(rows))
+	for _, row := range rows {
+		if !row.IsLatest || !row.CoverTotal.Valid {
+			continue
+		}
+		key := getSrvCoverTotalKey(row.SrvCoverMeta)
+		// latest row of each commit is kept, and the last updated commit is used
+		if pre, ok := latestRows[key]; ok && isSrvCoverRowUpdatedAfter(pre, row) {
+			continue
+		}
+		latestRows[key] = row
+	}
+
+	for _, row := range latestRows {
+		value, err := parseCoverTotal(row.CoverTotal.String)
+		if err != nil {
+			log.Println("InitSrvCoverTotalMetrics error:", err)
+			continue
+		}
+		observeSrvCoverTotalByMeta(row.SrvCoverMeta, value)
+	}
+	return nil
+}
+
+func isSrvCoverRowUpdatedAfter(row, other GocSrvCoverModel) bool {
+	if row.UpdatedAt.Equal(other.UpdatedAt) {
+		return row.ID > other.ID
+	}
+	return row.UpdatedAt.After(other.UpdatedAt)
+}
+
+func observeSrvCoverTotalByMeta(meta SrvCoverMeta, value float64) {
+	labels := prometheus.Labels{
+		"env":    meta.Env,
+		"region": meta.Region,
+		"app":    meta.AppName,
+		"branch": meta.GitBranch,
+		"commit": meta.GitCommit,
+	}
+	key := getSrvCoverTotalKey(meta)
+
+	srvCoverTotalLabelsLock.Lock()
+	defer srvCoverTotalLabelsLock.Unlock()
+	if preLabels, ok := srvCoverTotalLabels[key]; ok {
+		srvCoverTotalGauge.Delete(preLabels)
+	}
+	srvCoverTotalLabels[key] = labels
+	srvCoverTotalGauge.With(labels).Set(value)
+}
+
+func getSrvCoverTotalKey(meta SrvCoverMeta) string {
+	return strings.Join([]string{meta.Env, meta.Region, meta.AppName}, "_")
+}
+
+func observeSrvCoverSync(srvName string, start time.Time, err error) {
+	meta := GetSrvMetaFromName(srvName)
+	status := "success"
+	if err != nil {
+		status = "failed"
+		srvCoverSyncFailures.WithLabelValues(meta.Env, meta.Region, meta.AppName).Inc()
+	}
+	srvCoverSyncDuration.WithLabelValues(meta.Env, meta.Region, meta.AppName, status).Observe(time.Since(start).Seconds())
+}
+
+func observeRegisterSrvs(srvs map[string][]string) {
+	registerSrvsGauge.Set(float64(len(srvs)))
+}
+
+func observeWatcherCovFiles(srvName, savedDir string) {
+	fileNames, err := utils.ListFilesInDir(savedDir, "cov")
+	if err != nil {
+		log.Println("observeWatcherCovFiles error:", err)
+		return
+	}
+	meta := GetSrvMetaFromName(srvName)
+	watcherCovFilesGauge.WithLabelValues(meta.Env, meta.Region, meta.AppName).Set(float64(len(fileNames)))
+}
+
+// runScheduleTask runs task fn, and records duration and failure.
+func runScheduleTask(task string, fn func() error) error {
+	timer := prometheus.NewTimer(scheduleTaskDuration.WithLabelValues(task))
+	defer timer.ObserveDuration()
+
+	err := fn()
+	if err != nil {
+		scheduleTaskFailures.WithLabelValues(task).Inc()
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+package pkg
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveSrvCoverTotal(t *testing.T) {
+	ObserveSrvCoverTotal("staging_th_apa_goc_echoserver_master_518e0a570c", "37.92")
+	ObserveSrvCoverTotal("staging_th_apa_goc_echoserver_master_845820727e", "50.00")
+	ObserveSrvCoverTotal("staging_th_apa_goc_echoserver_v2_master_845820727e", "60.00")
+
+	// gauge of previous commit is removed
+	if count := testutil.CollectAndCount(srvCoverTotalGauge); count != 2 {
+		t.Fatalf("want 2 cover total gauges, got %d", count)
+	}
+	gauge := srvCoverTotalGauge.WithLabelValues("staging", "th", "apa_goc_echoserver", "master", "845820727e")
+	if value := testutil.ToFloat64(gauge); value != 50.0 {
+		t.Fatalf("want cover total 50.0, got %.2f", value)
+	}
+}
+
+func TestInitSrvCoverTotalMetrics(t *testing.T) {
+	store := NewMemCoverHistoryStore()
+	if err := store.InsertSrvCoverRow(newTestSrvCoverRow("staging_th_apa_goc_metrics_master_518e0a570c", "60.00")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertSrvCoverRow(newTestSrvCoverRow("staging_th_apa_goc_metrics_master_845820727e", "62.50")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertSrvCoverRow(newTestSrvCoverRow("staging_th_apa_goc_metrics_v2_master_845820727e", "70.00")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := InitSrvCoverTotalMetrics(store); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		app    string
+		commit string
+		want   float64
+	}{
+		{"apa_goc_metrics", "845820727e", 62.5},
+		{"apa_goc_metrics_v2", "845820727e", 70.0},
+	} {
+		gauge := srvCoverTotalGauge.WithLabelValues("staging", "th", c.app, "master", c.commit)
+		if value := testutil.ToFloat64(gauge); value != c.want {
+			t.Fatalf("app [%s]: want cover total %.2f, got %.2f", c.app, c.want, value)
+		}
+	}
+}
+
+func TestRunScheduleTask(t *testing.T) {
+	const task = "TestTask"
+	_ = runScheduleTask(task, func() error { return nil })
+	if err := runScheduleTask(task, func() error { return fmt.Errorf("mock error") }); err == nil {
+		t.Fatal("want task error")
+	}
+	if value := testutil.ToFloat64(scheduleTaskFailures.WithLabelValues(task)); value != 1 {
+		t.Fatalf("want 1 failure, got %.0f", value)
+	}
+}
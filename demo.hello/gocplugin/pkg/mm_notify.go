@@ -30,6 +30,7 @@ type MatterMostNotify struct {
 	baseURL string
 	token   string
 	channel string
+	user    string
 	client  *utils.HTTPUtils
 }
 
@@ -40,12 +41,29 @@ func NewMatterMostNotify() *MatterMostNotify {
 			baseURL: getParamFromEnv("MM_URL"),
 			token:   getParamFromEnv("MM_TOKEN"),
 			channel: getParamFromEnv("MM_CHANNEL"),
+			user:    defaultUser,
 			client:  utils.NewDefaultHTTPUtils(),
 		}
 	})
 	return notify
 }
 
+// NewMatterMostNotifyByConfig creates notify for a sink, and messages are sent to @user if user is not empty.
+func NewMatterMostNotifyByConfig(baseURL, token, channel, user string) *MatterMostNotify {
+	return &MatterMostNotify{
+		baseURL: baseURL,
+		token:   token,
+		channel: channel,
+		user:    user,
+		client:  utils.NewDefaultHTTPUtils(),
+	}
+}
+
+// Send implements Notifier.
+func (notify *MatterMostNotify) Send(ctx context.Context, msg NotifyMessage) error {
+	return notify.SendMessageToUser(ctx, notify.user, msg.Text)
+}
+
 // MustSendMessageToDefaultUser sends notify message without return a error.
 func (notify *MatterMostNotify) MustSendMessageToDefaultUser(text string) {
 	ctx, cancel := context.WithTimeout(context.Background(), Wait)
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"sync"
 
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -35,49 +37,6 @@ func (GocSrvCoverModel) TableName() string {
 	return "goc_o_staging_service_cover"
 }
 
-// GocSrvCoverDBInstance .
-type GocSrvCoverDBInstance struct {
-	sqliteDB *gorm.DB
-}
-
-var (
-	instance  GocSrvCoverDBInstance
-	modelOnce sync.Once
-)
-
-// NewGocSrvCoverDBInstance .
-func NewGocSrvCoverDBInstance() GocSrvCoverDBInstance {
-	modelOnce.Do(func() {
-		const dbFile = "sqlite.db"
-		dbPath := filepath.Join(AppConfig.RootDir, dbFile)
-		sqliteDB, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
-		if err != nil {
-			panic("NewGocSrvCoverDBInstance open db error: " + err.Error())
-		}
-
-		if err := sqliteDB.AutoMigrate(&GocSrvCoverModel{}); err != nil {
-			panic("NewGocSrvCoverDBInstance db migrate error: " + err.Error())
-		}
-
-		instance = GocSrvCoverDBInstance{
-			sqliteDB: sqliteDB,
-		}
-	})
-	return instance
-}
-
-// InsertSrvCoverRow .
-func (in *GocSrvCoverDBInstance) InsertSrvCoverRow(row GocSrvCoverModel) error {
-	return in.insertSrvCoverRowByDB(in.sqliteDB, row)
-}
-
-func (*GocSrvCoverDBInstance) insertSrvCoverRowByDB(db *gorm.DB, row GocSrvCoverModel) error {
-	if result := db.Create(&row); result.Error != nil {
-		return fmt.Errorf("insertSrvCoverRowByDB error: %w", result.Error)
-	}
-	return nil
-}
-
 var (
 	// ErrSrvCoverLatestRowNotFound .
 	ErrSrvCoverLatestRowNotFound = errors.New("ErrSrvCoverLatestRowNotFound")
@@ -85,121 +44,81 @@ var (
 	ErrMoreThanOneSrvCoverLatestRow = errors.New("ErrMoreThanOneSrvCoverLatestRow")
 )
 
-// GetLatestSrvCoverRow .
-func (in *GocSrvCoverDBInstance) GetLatestSrvCoverRow(meta SrvCoverMeta) (GocSrvCoverModel, error) {
-	return in.GetLatestSrvCoverRowByDB(in.sqliteDB, meta)
-}
-
-// GetLatestSrvCoverRowByDB .
-func (in *GocSrvCoverDBInstance) GetLatestSrvCoverRowByDB(db *gorm.DB, meta SrvCoverMeta) (GocSrvCoverModel, error) {
-	rows, err := in.getLatestSrvCoverRowsByDB(in.sqliteDB, meta)
-	if err != nil {
-		return GocSrvCoverModel{}, fmt.Errorf("GetLatestSrvCoverRowByDB error: %w", err)
-	}
-
-	if len(rows) == 0 {
-		return GocSrvCoverModel{}, fmt.Errorf("GetLatestSrvCoverRowByDB error: %w", ErrSrvCoverLatestRowNotFound)
-	}
-	if len(rows) != 1 {
-		return GocSrvCoverModel{}, fmt.Errorf("GetLatestSrvCoverRowByDB error: %w", ErrMoreThanOneSrvCoverLatestRow)
-	}
-	return rows[0], nil
-}
-
-func (*GocSrvCoverDBInstance) getLatestSrvCoverRowsByDB(db *gorm.DB, meta SrvCoverMeta) ([]GocSrvCoverModel, error) {
-	var rows []GocSrvCoverModel
-	condition := "env = ? and region = ? and app_name = ? and git_commit = ? and is_latest = ?"
-	if result := db.Where(condition, meta.Env, meta.Region, meta.AppName, meta.GitCommit, true).Find(&rows); result.Error != nil {
-		return nil, fmt.Errorf("getLatestSrvCoverRowByDB query error: %w", result.Error)
-	}
-	return rows, nil
-}
-
-// GetLimitedHistorySrvCoverRows .
-func (in *GocSrvCoverDBInstance) GetLimitedHistorySrvCoverRows(meta SrvCoverMeta, limit int) ([]GocSrvCoverModel, error) {
-	var rows []GocSrvCoverModel
-	condition := "env = ? and region = ? and app_name = ? and is_latest = ?"
-	if result := in.sqliteDB.Where(condition, meta.Env, meta.Region, meta.AppName, true).Order("id desc").Limit(limit).Find(&rows); result.Error != nil {
-		return nil, fmt.Errorf("GetLimitedHistorySrvCoverRows query error: %w", result.Error)
-	}
-	return rows, nil
-}
-
-// UpdateLatestSrvCoverRowToFalse .
-func (in *GocSrvCoverDBInstance) UpdateLatestSrvCoverRowToFalse(meta SrvCoverMeta) error {
-	return in.updateLatestSrvCoverRowToFalseByDB(in.sqliteDB, meta)
-}
+// CoverHistoryStore stores history cover rows of services.
+type CoverHistoryStore interface {
+	InsertSrvCoverRow(row GocSrvCoverModel) error
+	GetLatestSrvCoverRow(meta SrvCoverMeta) (GocSrvCoverModel, error)
+	GetLimitedHistorySrvCoverRows(meta SrvCoverMeta, limit int) ([]GocSrvCoverModel, error)
+	UpdateLatestSrvCoverRowToFalse(meta SrvCoverMeta) error
+	UpdateCovFileOfLatestSrvCoverRow(meta SrvCoverMeta, covFilePath string) error
+	UpdateSrvCoverTotalByCommit(total, commit string) error
+	// ListSrvCoverRows returns all rows which have cov file, ordered by id.
+	ListSrvCoverRows() ([]GocSrvCoverModel, error)
+	UpdateCovFileOfSrvCoverRow(id uint, covFilePath string) error
+	// AddLatestSrvCoverRow marks current latest row as not latest, and inserts new row in one transaction.
+	AddLatestSrvCoverRow(row GocSrvCoverModel) error
+	// Transaction runs fn with a transactional store, and rollbacks if fn returns error.
+	Transaction(fn func(tx CoverHistoryStore) error) error
+}
+
+const (
+	// StoreTypeSQLite .
+	StoreTypeSQLite = "sqlite"
+	// StoreTypeMySQL .
+	StoreTypeMySQL = "mysql"
+	// StoreTypePostgres .
+	StoreTypePostgres = "postgres"
+	// StoreTypeMemory .
+	StoreTypeMemory = "memory"
+)
 
-func (in *GocSrvCoverDBInstance) updateLatestSrvCoverRowToFalseByDB(db *gorm.DB, meta SrvCoverMeta) error {
-	row, err := in.GetLatestSrvCoverRow(meta)
-	if err != nil {
-		return fmt.Errorf("updateLatestSrvCoverRowToFalseByDB error: %w", err)
-	}
+var (
+	coverStore     CoverHistoryStore
+	coverStoreOnce sync.Once
+)
 
-	data := map[string]interface{}{
-		"IsLatest": false,
-	}
-	if result := db.Model(&GocSrvCoverModel{}).Where("id = ?", row.ID).Updates(data); result.Error != nil {
-		return fmt.Errorf("updateLatestSrvCoverRowToFalseByDB update row error: %w", result.Error)
+// NewCoverHistoryStore returns global cover history store which is created by AppConfig.Store.
+func NewCoverHistoryStore() CoverHistoryStore {
+	coverStoreOnce.Do(func() {
+		store, err := OpenCoverHistoryStore(AppConfig.Store)
+		if err != nil {
+			panic("NewCoverHistoryStore error: " + err.Error())
+		}
+		coverStore = store
+	})
+	return coverStore
+}
+
+// SetCoverHistoryStore replaces global cover history store, it should be invoked before any task runs.
+func SetCoverHistoryStore(store CoverHistoryStore) {
+	coverStoreOnce.Do(func() {})
+	coverStore = store
+}
+
+// OpenCoverHistoryStore .
+func OpenCoverHistoryStore(cfg StoreConfig) (CoverHistoryStore, error) {
+	var dialector gorm.Dialector
+	switch cfg.Type {
+	case StoreTypeMemory:
+		return NewMemCoverHistoryStore(), nil
+	case "", StoreTypeSQLite:
+		dsn := cfg.DSN
+		if len(dsn) == 0 {
+			const dbFile = "sqlite.db"
+			dsn = filepath.Join(AppConfig.RootDir, dbFile)
+		}
+		dialector = sqlite.Open(dsn)
+	case StoreTypeMySQL:
+		dialector = mysql.Open(cfg.DSN)
+	case StoreTypePostgres:
+		dialector = postgres.Open(cfg.DSN)
+	default:
+		return nil, fmt.Errorf("OpenCoverHistoryStore invalid store type: %s", cfg.Type)
 	}
-	return nil
-}
-
-// UpdateCovFileOfLatestSrvCoverRow .
-func (in *GocSrvCoverDBInstance) UpdateCovFileOfLatestSrvCoverRow(meta SrvCoverMeta, covFilePath string) error {
-	return in.UpdateCovFileOfLatestSrvCoverRowByDB(in.sqliteDB, meta, covFilePath)
-}
 
-// UpdateCovFileOfLatestSrvCoverRowByDB .
-func (in *GocSrvCoverDBInstance) UpdateCovFileOfLatestSrvCoverRowByDB(db *gorm.DB, meta SrvCoverMeta, covFilePath string) error {
-	row, err := in.GetLatestSrvCoverRowByDB(db, meta)
+	store, err := NewGormCoverHistoryStore(dialector)
 	if err != nil {
-		return fmt.Errorf("UpdateCovFileOfLatestSrvCoverRowByDB error: %w", err)
+		return nil, fmt.Errorf("OpenCoverHistoryStore error: %w", err)
 	}
-
-	data := GocSrvCoverModel{
-		CovFilePath: covFilePath,
-	}
-	if result := db.Model(&GocSrvCoverModel{}).Where("id = ?", row.ID).Updates(data); result.Error != nil {
-		return fmt.Errorf("UpdateCovFileOfLatestSrvCoverRowByDB update row error: %w", result.Error)
-	}
-	return nil
-}
-
-// UpdateSrvCoverTotalByCommit .
-func (in *GocSrvCoverDBInstance) UpdateSrvCoverTotalByCommit(total, commit string) error {
-	data := GocSrvCoverModel{
-		CoverTotal: sql.NullString{
-			String: total,
-			Valid:  true,
-		},
-	}
-	if result := in.sqliteDB.Model(&GocSrvCoverModel{}).Where("git_commit = ? and is_latest = ?", commit, true).Updates(data); result.Error != nil {
-		return fmt.Errorf("UpdateSrvCoverTotalByCommit error: %w", result.Error)
-	}
-	return nil
-}
-
-// AddLatestSrvCoverRow adds latest service cover row with transaction.
-func (in *GocSrvCoverDBInstance) AddLatestSrvCoverRow(row GocSrvCoverModel) error {
-	transaction := in.BeginTransaction()
-	if err := in.updateLatestSrvCoverRowToFalseByDB(transaction, row.SrvCoverMeta); err != nil {
-		transaction.Rollback()
-		return fmt.Errorf("InsertLatestSrvCoverRow error: %w", err)
-	}
-
-	if err := in.insertSrvCoverRowByDB(transaction, row); err != nil {
-		transaction.Rollback()
-		return fmt.Errorf("InsertLatestSrvCoverRow error: %w", err)
-	}
-
-	if result := transaction.Commit(); result.Error != nil {
-		return fmt.Errorf("InsertLatestSrvCoverRow transaction commit error: %w", result.Error)
-	}
-	return nil
-}
-
-// BeginTransaction .
-func (in *GocSrvCoverDBInstance) BeginTransaction() *gorm.DB {
-	return in.sqliteDB.Begin()
+	return store, nil
 }
@@ -22,7 +22,7 @@ func TestInsertSrvCoverRow(t *testing.T) {
 	}
 
 	AppConfig.RootDir = "/tmp/test"
-	instance := NewGocSrvCoverDBInstance()
+	instance := NewCoverHistoryStore()
 	if err := instance.InsertSrvCoverRow(row); err != nil {
 		t.Fatal(err)
 	}
@@ -30,7 +30,7 @@ func TestInsertSrvCoverRow(t *testing.T) {
 
 func TestGetLatestSrvCoverRow(t *testing.T) {
 	AppConfig.RootDir = "/tmp/test"
-	instance := NewGocSrvCoverDBInstance()
+	instance := NewCoverHistoryStore()
 
 	srvName := "staging_th_apa_goc_echoserver_master_518e0a570c"
 	meta := GetSrvMetaFromName(srvName)
@@ -46,7 +46,7 @@ func TestGetLatestSrvCoverRow(t *testing.T) {
 
 func TestGetLatestSrvCoverRowNotFound(t *testing.T) {
 	AppConfig.RootDir = "/tmp/test"
-	instance := NewGocSrvCoverDBInstance()
+	instance := NewCoverHistoryStore()
 
 	srvName := "staging_vn_apa_goc_echoserver_master_518e0a570c"
 	meta := GetSrvMetaFromName(srvName)
@@ -62,7 +62,7 @@ func TestGetLatestSrvCoverRowMoreThanOneErr(t *testing.T) {
 	srvName := "staging_th_apa_goc_echoserver_master_518e0a570c"
 	meta := GetSrvMetaFromName(srvName)
 
-	dbInstance := NewGocSrvCoverDBInstance()
+	dbInstance := NewCoverHistoryStore()
 	_, err := dbInstance.GetLatestSrvCoverRow(meta)
 	fmt.Println("error:", err)
 	if !errors.Is(err, ErrMoreThanOneSrvCoverLatestRow) {
@@ -75,7 +75,7 @@ func TestGetLimitedHistorySrvCoverRows(t *testing.T) {
 	srvName := "staging_th_apa_echoserver_master_c32684d0b1"
 	meta := GetSrvMetaFromName(srvName)
 
-	dbInstance := NewGocSrvCoverDBInstance()
+	dbInstance := NewCoverHistoryStore()
 	rows, err := dbInstance.GetLimitedHistorySrvCoverRows(meta, 3)
 	if err != nil {
 		t.Fatal(err)
@@ -89,7 +89,7 @@ func TestGetLimitedHistorySrvCoverRows(t *testing.T) {
 
 func TestUpdateLatestRowToFalse(t *testing.T) {
 	AppConfig.RootDir = "/tmp/test"
-	instance := NewGocSrvCoverDBInstance()
+	instance := NewCoverHistoryStore()
 
 	srvName := "staging_th_apa_goc_echoserver_master_518e0a570c"
 	meta := GetSrvMetaFromName(srvName)
@@ -100,7 +100,7 @@ func TestUpdateLatestRowToFalse(t *testing.T) {
 
 func TestAddLatestSrvCoverRow(t *testing.T) {
 	AppConfig.RootDir = "/tmp/test"
-	instance := NewGocSrvCoverDBInstance()
+	instance := NewCoverHistoryStore()
 
 	srvName := "staging_th_apa_goc_echoserver_master_518e0a570d"
 	meta := GetSrvMetaFromName(srvName)
@@ -122,7 +122,7 @@ func TestAddLatestSrvCoverRow(t *testing.T) {
 
 func TestUpdateCovFileOfLatestSrvCoverRow(t *testing.T) {
 	AppConfig.RootDir = "/tmp/test"
-	instance := NewGocSrvCoverDBInstance()
+	instance := NewCoverHistoryStore()
 
 	srvName := "staging_th_apa_goc_echoserver_master_518e0a570c"
 	meta := GetSrvMetaFromName(srvName)
@@ -134,7 +134,7 @@ func TestUpdateCovFileOfLatestSrvCoverRow(t *testing.T) {
 
 func TestUpdateSrvCoverTotal(t *testing.T) {
 	AppConfig.RootDir = "/tmp/test"
-	instance := NewGocSrvCoverDBInstance()
+	instance := NewCoverHistoryStore()
 
 	srvName := "staging_th_apa_goc_echoserver_master_518e0a570c"
 	meta := GetSrvMetaFromName(srvName)
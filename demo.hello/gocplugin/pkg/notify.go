@@ -0,0 +1,355 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	retry "github.com/avast/retry-go/v3"
+)
+
+const (
+	// NotifyEventSyncFailed .
+	NotifyEventSyncFailed = "sync_failed"
+	// NotifyEventTaskFailed .
+	NotifyEventTaskFailed = "task_failed"
+	// NotifyEventGateResult .
+	NotifyEventGateResult = "gate_result"
+
+	// NotifySinkMatterMost .
+	NotifySinkMatterMost = "mattermost"
+	// NotifySinkWebhook .
+	NotifySinkWebhook = "webhook"
+	// NotifySinkSlack .
+	NotifySinkSlack = "slack"
+	// NotifySinkEmail .
+	NotifySinkEmail = "email"
+)
+
+// NotifyMessage .
+type NotifyMessage struct {
+	Event   string `json:"event"`
+	SrvName string `json:"srv_name"`
+	Env     string `json:"env"`
+	AppName string `json:"app_name"`
+	Title   string `json:"title"`
+	Text    string `json:"text"`
+}
+
+// NewNotifyMessage creates message with env and app name parsed from service name.
+func NewNotifyMessage(event, srvName, text string) NotifyMessage {
+	msg := NotifyMessage{
+		Event:   event,
+		SrvName: srvName,
+		Title:   fmt.Sprintf("[Goc Plugin] %s", event),
+		Text:    text,
+	}
+	if strings.Count(srvName, "_") >= 2 {
+		meta := GetSrvMetaFromName(srvName)
+		msg.Env = meta.Env
+		msg.AppName = meta.AppName
+	}
+	return msg
+}
+
+// Notifier sends notify message to a sink.
+type Notifier interface {
+	Send(ctx context.Context, msg NotifyMessage) error
+}
+
+// NotifyConfig sinks (name:config) and routing rules for notify message.
+// If no sinks configured, messages are sent to default MatterMost user.
+type NotifyConfig struct {
+	Sinks map[string]NotifySinkConfig `json:"sinks"`
+	// Routes are matched in order, message is sent to sinks of all matched routes.
+	Routes []NotifyRoute `json:"routes"`
+	// DefaultSinks sinks for message which matches no route, and message is dropped if it's empty.
+	DefaultSinks []string          `json:"default_sinks"`
+	Retry        NotifyRetryConfig `json:"retry"`
+}
+
+// NotifySinkConfig .
+type NotifySinkConfig struct {
+	Type     string            `json:"type"`
+	URL      string            `json:"url"`
+	Token    string            `json:"token"`
+	Channel  string            `json:"channel"`
+	User     string            `json:"user"`
+	Headers  map[string]string `json:"headers"`
+	SMTP     SMTPConfig        `json:"smtp"`
+	Template string            `json:"template"`
+}
+
+// NotifyRoute empty field matches all, and AppName supports glob pattern like "apa_*".
+type NotifyRoute struct {
+	Env     string   `json:"env"`
+	AppName string   `json:"app_name"`
+	Events  []string `json:"events"`
+	Sinks   []string `json:"sinks"`
+}
+
+// NotifyRetryConfig .
+type NotifyRetryConfig struct {
+	Attempts uint `json:"attempts"`
+	DelayMs  int  `json:"delay_ms"`
+}
+
+func (route NotifyRoute) isMatch(msg NotifyMessage) bool {
+	if len(route.Env) > 0 && route.Env != msg.Env {
+		return false
+	}
+	if len(route.AppName) > 0 {
+		if ok, err := path.Match(route.AppName, msg.AppName); err != nil || !ok {
+			return false
+		}
+	}
+	if len(route.Events) == 0 {
+		return true
+	}
+	for _, event := range route.Events {
+		if event == msg.Event {
+			return true
+		}
+	}
+	return false
+}
+
+//
+// Dispatcher
+//
+
+var (
+	dispatcher     *NotifyDispatcher
+	dispatcherOnce sync.Once
+)
+
+// NotifyDispatcher sends message to sinks by routing rules, and to default sinks if no route matched.
+type NotifyDispatcher struct {
+	sinks        map[string]Notifier
+	routes       []NotifyRoute
+	defaultSinks []Notifier
+}
+
+// InitNotifyDispatcher creates global dispatcher by AppConfig.Notify, it should be invoked at startup so that
+// invalid notify config is reported to caller.
+func InitNotifyDispatcher() error {
+	d, err := NewNotifyDispatcherByConfig(AppConfig.Notify)
+	if err != nil {
+		return err
+	}
+	SetNotifyDispatcher(d)
+	return nil
+}
+
+// NewNotifyDispatcher returns global dispatcher. If it's not initialized by InitNotifyDispatcher, dispatcher is
+// created by AppConfig.Notify, and it falls back to default sinks for invalid config.
+func NewNotifyDispatcher() *NotifyDispatcher {
+	dispatcherOnce.Do(func() {
+		d, err := NewNotifyDispatcherByConfig(AppConfig.Notify)
+		if err != nil {
+			log.Println("NewNotifyDispatcher error, use default sinks:", err)
+			d = newDefaultNotifyDispatcher(AppConfig.Notify.Retry)
+		}
+		dispatcher = d
+	})
+	return dispatcher
+}
+
+// SetNotifyDispatcher replaces global dispatcher.
+func SetNotifyDispatcher(d *NotifyDispatcher) {
+	dispatcherOnce.Do(func() {})
+	dispatcher = d
+}
+
+// NewNotifyDispatcherByConfig .
+func NewNotifyDispatcherByConfig(cfg NotifyConfig) (*NotifyDispatcher, error) {
+	if len(cfg.Sinks) == 0 {
+		return newDefaultNotifyDispatcher(cfg.Retry), nil
+	}
+
+	d := &NotifyDispatcher{
+		sinks:  make(map[string]Notifier, len(cfg.Sinks)),
+		routes: cfg.Routes,
+	}
+
+	for name, sinkCfg := range cfg.Sinks {
+		notifier, err := newNotifierBySinkConfig(sinkCfg)
+		if err != nil {
+			return nil, fmt.Errorf("NewNotifyDispatcherByConfig sink [%s] error: %w", name, err)
+		}
+		if len(sinkCfg.Template) > 0 {
+			if notifier, err = NewTemplateNotifier(notifier, sinkCfg.Template); err != nil {
+				return nil, fmt.Errorf("NewNotifyDispatcherByConfig sink [%s] error: %w", name, err)
+			}
+		}
+		d.sinks[name] = NewRetryNotifier(notifier, cfg.Retry)
+	}
+
+	for _, route := range cfg.Routes {
+		for _, name := range route.Sinks {
+			if _, ok := d.sinks[name]; !ok {
+				return nil, fmt.Errorf("NewNotifyDispatcherByConfig sink [%s] in routes is not defined", name)
+			}
+		}
+	}
+	for _, name := range cfg.DefaultSinks {
+		notifier, ok := d.sinks[name]
+		if !ok {
+			return nil, fmt.Errorf("NewNotifyDispatcherByConfig sink [%s] in default sinks is not defined", name)
+		}
+		d.defaultSinks = append(d.defaultSinks, notifier)
+	}
+	return d, nil
+}
+
+func newDefaultNotifyDispatcher(retryCfg NotifyRetryConfig) *NotifyDispatcher {
+	return &NotifyDispatcher{
+		sinks:        map[string]Notifier{},
+		defaultSinks: []Notifier{NewRetryNotifier(NewMatterMostNotify(), retryCfg)},
+	}
+}
+
+func newNotifierBySinkConfig(cfg NotifySinkConfig) (Notifier, error) {
+	switch cfg.Type {
+	case NotifySinkMatterMost:
+		return NewMatterMostNotifyByConfig(cfg.URL, cfg.Token, cfg.Channel, cfg.User), nil
+	case NotifySinkWebhook:
+		return NewWebhookNotifier(cfg.URL, cfg.Headers), nil
+	case NotifySinkSlack:
+		return NewSlackNotifier(cfg.URL, cfg.Channel), nil
+	case NotifySinkEmail:
+		return NewEmailNotifier(cfg.SMTP), nil
+	default:
+		return nil, fmt.Errorf("invalid sink type: %s", cfg.Type)
+	}
+}
+
+// Notify sends message to sinks of matched routes, and returns the last error if any sink fails.
+func (d *NotifyDispatcher) Notify(ctx context.Context, msg NotifyMessage) error {
+	var lastErr error
+	for _, notifier := range d.getMatchedSinks(msg) {
+		if err := notifier.Send(ctx, msg); err != nil {
+			log.Println("Notify send message error:", err)
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return fmt.Errorf("Notify error: %w", lastErr)
+	}
+	return nil
+}
+
+// MustNotify sends message without return a error.
+func (d *NotifyDispatcher) MustNotify(msg NotifyMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), LongWait)
+	defer cancel()
+	if err := d.Notify(ctx, msg); err != nil {
+		log.Println("MustNotify error:", err)
+	}
+}
+
+func (d *NotifyDispatcher) getMatchedSinks(msg NotifyMessage) []Notifier {
+	names := make(map[string]struct{}, len(d.sinks))
+	notifiers := make([]Notifier, 0, len(d.sinks))
+	for _, route := range d.routes {
+		if !route.isMatch(msg) {
+			continue
+		}
+		for _, name := range route.Sinks {
+			if _, ok := names[name]; ok {
+				continue
+			}
+			names[name] = struct{}{}
+			notifiers = append(notifiers, d.sinks[name])
+		}
+	}
+
+	if len(notifiers) > 0 {
+		return notifiers
+	}
+	if len(d.defaultSinks) == 0 {
+		log.Printf("Notify no route matched, and message is dropped: event=%s, srv=%s", msg.Event, msg.SrvName)
+	}
+	return d.defaultSinks
+}
+
+//
+// Notifier Wrappers
+//
+
+// TemplateNotifier renders message text by template before send.
+type TemplateNotifier struct {
+	notifier Notifier
+	tmpl     *template.Template
+}
+
+// NewTemplateNotifier creates notifier with template, which is executed with NotifyMessage,
+// i.e. "{{.Title}}: {{.SrvName}}\n{{.Text}}".
+func NewTemplateNotifier(notifier Notifier, text string) (*TemplateNotifier, error) {
+	tmpl, err := template.New("notify").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("NewTemplateNotifier parse template error: %w", err)
+	}
+	return &TemplateNotifier{
+		notifier: notifier,
+		tmpl:     tmpl,
+	}, nil
+}
+
+// Send .
+func (n *TemplateNotifier) Send(ctx context.Context, msg NotifyMessage) error {
+	var buf bytes.Buffer
+	if err := n.tmpl.Execute(&buf, msg); err != nil {
+		return fmt.Errorf("TemplateNotifier execute template error: %w", err)
+	}
+	msg.Text = buf.String()
+	return n.notifier.Send(ctx, msg)
+}
+
+// RetryNotifier retries send with exponential backoff.
+type RetryNotifier struct {
+	notifier Notifier
+	attempts uint
+	delay    time.Duration
+}
+
+// NewRetryNotifier .
+func NewRetryNotifier(notifier Notifier, cfg NotifyRetryConfig) *RetryNotifier {
+	const (
+		defaultAttempts = 3
+		defaultDelay    = 500 * time.Millisecond
+	)
+	n := &RetryNotifier{
+		notifier: notifier,
+		attempts: cfg.Attempts,
+		delay:    time.Duration(cfg.DelayMs) * time.Millisecond,
+	}
+	if n.attempts == 0 {
+		n.attempts = defaultAttempts
+	}
+	if n.delay == 0 {
+		n.delay = defaultDelay
+	}
+	return n
+}
+
+// Send .
+func (n *RetryNotifier) Send(ctx context.Context, msg NotifyMessage) error {
+	return retry.Do(func() error {
+		return n.notifier.Send(ctx, msg)
+	},
+		retry.Context(ctx),
+		retry.Attempts(n.attempts),
+		retry.Delay(n.delay),
+		retry.DelayType(retry.BackOffDelay),
+		retry.LastErrorOnly(true),
+		retry.OnRetry(func(i uint, err error) {
+			log.Printf("Notify send message error: %v, retry %d", err, i+1)
+		}))
+}
@@ -0,0 +1,165 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"demo.hello/utils"
+)
+
+// WebhookNotifier posts notify message as json to a generic webhook.
+type WebhookNotifier struct {
+	url     string
+	headers map[string]string
+	client  *utils.HTTPUtils
+}
+
+// NewWebhookNotifier .
+func NewWebhookNotifier(url string, headers map[string]string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:     url,
+		headers: headers,
+		client:  utils.NewDefaultHTTPUtils(),
+	}
+}
+
+// Send .
+func (n *WebhookNotifier) Send(ctx context.Context, msg NotifyMessage) error {
+	b, err := json.Marshal(&msg)
+	if err != nil {
+		return fmt.Errorf("WebhookNotifier json marshal failed: %w", err)
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+	for k, v := range n.headers {
+		headers[k] = v
+	}
+	if err := postAndCheckStatus(ctx, n.client, n.url, headers, string(b)); err != nil {
+		return fmt.Errorf("WebhookNotifier send message failed: %w", err)
+	}
+	return nil
+}
+
+// SlackMessage body of slack compatible incoming webhook.
+type SlackMessage struct {
+	Channel string `json:"channel,omitempty"`
+	Text    string `json:"text"`
+}
+
+// SlackNotifier sends message by slack compatible incoming webhook.
+type SlackNotifier struct {
+	url     string
+	channel string
+	client  *utils.HTTPUtils
+}
+
+// NewSlackNotifier .
+func NewSlackNotifier(url, channel string) *SlackNotifier {
+	return &SlackNotifier{
+		url:     url,
+		channel: channel,
+		client:  utils.NewDefaultHTTPUtils(),
+	}
+}
+
+// Send .
+func (n *SlackNotifier) Send(ctx context.Context, msg NotifyMessage) error {
+	message := SlackMessage{
+		Channel: n.channel,
+		Text:    msg.Text,
+	}
+	b, err := json.Marshal(&message)
+	if err != nil {
+		return fmt.Errorf("SlackNotifier json marshal failed: %w", err)
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+	if err := postAndCheckStatus(ctx, n.client, n.url, headers, string(b)); err != nil {
+		return fmt.Errorf("SlackNotifier send message failed: %w", err)
+	}
+	return nil
+}
+
+func postAndCheckStatus(ctx context.Context, client *utils.HTTPUtils, url string, headers map[string]string, body string) error {
+	resp, respBody, err := client.PostV2(ctx, url, headers, body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("http status code %d, body: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+// SMTPConfig .
+type SMTPConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	UserName string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// EmailNotifier sends message by smtp.
+type EmailNotifier struct {
+	cfg      SMTPConfig
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewEmailNotifier .
+func NewEmailNotifier(cfg SMTPConfig) *EmailNotifier {
+	return &EmailNotifier{
+		cfg:      cfg,
+		sendMail: smtp.SendMail,
+	}
+}
+
+// Send .
+func (n *EmailNotifier) Send(ctx context.Context, msg NotifyMessage) error {
+	if len(n.cfg.To) == 0 {
+		return fmt.Errorf("EmailNotifier no receivers")
+	}
+
+	var auth smtp.Auth
+	if len(n.cfg.UserName) > 0 {
+		auth = smtp.PlainAuth("", n.cfg.UserName, n.cfg.Password, n.cfg.Host)
+	}
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- n.sendMail(addr, auth, n.cfg.From, n.cfg.To, n.buildMail(msg))
+	}()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("EmailNotifier send mail failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("EmailNotifier send mail failed: %w", ctx.Err())
+	}
+}
+
+func (n *EmailNotifier) buildMail(msg NotifyMessage) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + n.cfg.From + "\r\n")
+	b.WriteString("To: " + strings.Join(n.cfg.To, ",") + "\r\n")
+	b.WriteString("Subject: " + msg.Title + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Text)
+	return []byte(b.String())
+}
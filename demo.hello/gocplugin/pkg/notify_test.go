@@ -0,0 +1,177 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNotifyDispatcherRoutes(t *testing.T) {
+	var webhookCount, slackCount int32
+	var slackText string
+	webhookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&webhookCount, 1)
+		if r.Header.Get("X-Token") != "test" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer webhookSrv.Close()
+
+	slackSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&slackCount, 1)
+		b, _ := ioutil.ReadAll(r.Body)
+		var msg SlackMessage
+		if err := json.Unmarshal(b, &msg); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		slackText = msg.Text
+	}))
+	defer slackSrv.Close()
+
+	cfg := NotifyConfig{
+		Sinks: map[string]NotifySinkConfig{
+			"hook": {
+				Type:    NotifySinkWebhook,
+				URL:     webhookSrv.URL,
+				Headers: map[string]string{"X-Token": "test"},
+			},
+			"slack": {
+				Type:     NotifySinkSlack,
+				URL:      slackSrv.URL,
+				Template: "{{.AppName}}: {{.Text}}",
+			},
+		},
+		Routes: []NotifyRoute{
+			{Env: "staging", AppName: "apa_*", Sinks: []string{"hook", "slack"}},
+			{Events: []string{NotifyEventGateResult}, Sinks: []string{"slack"}},
+		},
+	}
+	d, err := NewNotifyDispatcherByConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	msg := NewNotifyMessage(NotifyEventSyncFailed, "staging_th_apa_goc_echoserver_master_845820727e", "sync failed")
+	if err := d.Notify(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	if webhookCount != 1 || slackCount != 1 {
+		t.Fatalf("want 1 webhook and 1 slack message, got %d and %d", webhookCount, slackCount)
+	}
+	if want := "apa_goc_echoserver: sync failed"; slackText != want {
+		t.Fatalf("want slack text %q, got %q", want, slackText)
+	}
+
+	// no route matched
+	msg = NewNotifyMessage(NotifyEventSyncFailed, "test_th_apa_goc_echoserver_master_845820727e", "sync failed")
+	if err := d.Notify(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	if webhookCount != 1 || slackCount != 1 {
+		t.Fatalf("want no message sent, got %d and %d", webhookCount, slackCount)
+	}
+	// no route matched, and sent to default sinks
+	cfg.DefaultSinks = []string{"hook"}
+	if d, err = NewNotifyDispatcherByConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Notify(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	if webhookCount != 2 || slackCount != 1 {
+		t.Fatalf("want 1 message sent to default sink, got %d and %d", webhookCount-1, slackCount-1)
+	}
+}
+
+func TestNotifyDispatcherInvalidConfig(t *testing.T) {
+	cfg := NotifyConfig{
+		Sinks: map[string]NotifySinkConfig{
+			"hook": {Type: NotifySinkWebhook},
+		},
+		Routes: []NotifyRoute{
+			{Sinks: []string{"slack"}},
+		},
+	}
+	if _, err := NewNotifyDispatcherByConfig(cfg); err == nil {
+		t.Fatal("want error for undefined sink in routes")
+	}
+
+	cfg.Routes = nil
+	cfg.DefaultSinks = []string{"slack"}
+	if _, err := NewNotifyDispatcherByConfig(cfg); err == nil {
+		t.Fatal("want error for undefined sink in default sinks")
+	}
+
+	cfg.Sinks["hook"] = NotifySinkConfig{Type: "sms"}
+	if _, err := NewNotifyDispatcherByConfig(cfg); err == nil {
+		t.Fatal("want error for invalid sink type")
+	}
+}
+
+func TestInitNotifyDispatcherInvalidConfig(t *testing.T) {
+	cfg := AppConfig.Notify
+	defer func() {
+		AppConfig.Notify = cfg
+	}()
+
+	AppConfig.Notify = NotifyConfig{
+		Sinks: map[string]NotifySinkConfig{
+			"hook": {Type: "sms"},
+		},
+	}
+	if err := InitNotifyDispatcher(); err == nil {
+		t.Fatal("want error for invalid notify config")
+	}
+}
+
+func TestRetryNotifier(t *testing.T) {
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	notifier := NewRetryNotifier(NewWebhookNotifier(srv.URL, nil), NotifyRetryConfig{Attempts: 3, DelayMs: 10})
+	if err := notifier.Send(context.Background(), NotifyMessage{Text: "retry"}); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("want 3 requests, got %d", count)
+	}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	notifier := NewEmailNotifier(SMTPConfig{
+		Host: "smtp.example.com",
+		Port: 25,
+		From: "goc@example.com",
+		To:   []string{"dev@example.com"},
+	})
+
+	var mail string
+	notifier.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		if addr != "smtp.example.com:25" {
+			t.Fatalf("invalid smtp addr: %s", addr)
+		}
+		mail = string(msg)
+		return nil
+	}
+
+	msg := NotifyMessage{Title: "[Goc Plugin] gate_result", Text: "gate passed"}
+	if err := notifier.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(mail, "Subject: [Goc Plugin] gate_result\r\n") || !strings.HasSuffix(mail, "\r\n\r\ngate passed") {
+		t.Fatalf("invalid mail:\n%s", mail)
+	}
+}
@@ -0,0 +1,394 @@
+package pkg
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	s3storage "demo.hello/apps/s3storage/pkg"
+	"demo.hello/utils"
+)
+
+const (
+	// RetentionActionCompress .
+	RetentionActionCompress = "compress"
+	// RetentionActionArchive .
+	RetentionActionArchive = "archive"
+	// RetentionActionDelete .
+	RetentionActionDelete = "delete"
+
+	archivedCovFilePrefix = "s3://"
+	compressedCovFileExt  = ".gz"
+)
+
+// RetentionConfig retention policy of cov files, and it's disabled when KeepLastCommits is 0.
+// KeepLastCommits: cov files of last N commits per branch are kept.
+// KeepTagPatterns: glob patterns of release tags (i.e. "v*"), and cov files of tagged commits are kept forever.
+// CompressAfterDays: history (not latest) cov files of kept commits are compressed after days, 0 means never.
+// Archive: cov files of expired commits are moved to s3 if enabled, otherwise they are deleted.
+type RetentionConfig struct {
+	KeepLastCommits   int           `json:"keep_last_commits"`
+	KeepTagPatterns   []string      `json:"keep_tag_patterns"`
+	CompressAfterDays int           `json:"compress_after_days"`
+	Archive           ArchiveConfig `json:"archive"`
+}
+
+// ArchiveConfig s3 config for archived cov files, and access key is set by env S3_ACCESS_KEY and S3_SECRET_KEY.
+type ArchiveConfig struct {
+	Enabled bool   `json:"enabled"`
+	Host    string `json:"host"`
+	Bucket  string `json:"bucket"`
+	Prefix  string `json:"prefix"`
+	// AccessKey and SecretKey are from env S3_ACCESS_KEY and S3_SECRET_KEY if not set.
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+}
+
+// RetentionItem action of a cov file in retention plan.
+type RetentionItem struct {
+	RowID       uint   `json:"row_id"`
+	Env         string `json:"env"`
+	Region      string `json:"region"`
+	AppName     string `json:"app_name"`
+	GitBranch   string `json:"git_branch"`
+	GitCommit   string `json:"git_commit"`
+	CovFilePath string `json:"cov_file_path"`
+	Action      string `json:"action"`
+	Reason      string `json:"reason"`
+	// Target new location of cov file after action applied.
+	Target string `json:"target,omitempty"`
+	ErrMsg string `json:"error,omitempty"`
+}
+
+// RetentionResult .
+// Missing: number of items whose cov file does not exist, and they are not applied.
+type RetentionResult struct {
+	DryRun  bool            `json:"dry_run"`
+	Items   []RetentionItem `json:"items"`
+	Failed  int             `json:"failed"`
+	Missing int             `json:"missing"`
+}
+
+var errCovFileNotExist = errors.New("cov file not exist")
+
+// CoverArchiver moves cov file to remote store, and returns archived location.
+type CoverArchiver interface {
+	Archive(srcPath, key string) (string, error)
+}
+
+type s3CoverArchiver struct {
+	ceph   *s3storage.CephMgmt
+	bucket string
+}
+
+func newS3CoverArchiver(cfg ArchiveConfig) (*s3CoverArchiver, error) {
+	if len(cfg.Host) == 0 || len(cfg.Bucket) == 0 {
+		return nil, errors.New("newS3CoverArchiver error: host and bucket of archive should be set")
+	}
+	if len(cfg.AccessKey) == 0 {
+		cfg.AccessKey = os.Getenv("S3_ACCESS_KEY")
+	}
+	if len(cfg.SecretKey) == 0 {
+		cfg.SecretKey = os.Getenv("S3_SECRET_KEY")
+	}
+	if len(cfg.AccessKey) == 0 || len(cfg.SecretKey) == 0 {
+		return nil, errors.New("newS3CoverArchiver error: access key and secret key of archive should be set")
+	}
+	return &s3CoverArchiver{
+		ceph:   s3storage.NewWithCredentials(cfg.Host, cfg.Bucket, cfg.AccessKey, cfg.SecretKey),
+		bucket: cfg.Bucket,
+	}, nil
+}
+
+// Archive .
+func (a *s3CoverArchiver) Archive(srcPath, key string) (string, error) {
+	if _, err := a.ceph.Upload(srcPath, key); err != nil {
+		return "", fmt.Errorf("Archive upload [%s] error: %w", srcPath, err)
+	}
+	return archivedCovFilePrefix + path.Join(a.bucket, key), nil
+}
+
+// CoverRetention applies retention policy on cov files of history cover rows.
+type CoverRetention struct {
+	store    CoverHistoryStore
+	policy   RetentionConfig
+	archiver CoverArchiver
+	// getTaggedCommits returns app name to tagged short commit ids.
+	getTaggedCommits func(appNames []string, patterns []string) map[string]map[string]string
+}
+
+// NewCoverRetention creates cover retention by policy, and s3 archiver is used if archive enabled.
+func NewCoverRetention(store CoverHistoryStore, policy RetentionConfig) (*CoverRetention, error) {
+	var archiver CoverArchiver
+	if policy.Archive.Enabled {
+		s3Archiver, err := newS3CoverArchiver(policy.Archive)
+		if err != nil {
+			return nil, fmt.Errorf("NewCoverRetention error: %w", err)
+		}
+		archiver = s3Archiver
+	}
+	return NewCoverRetentionWithArchiver(store, policy, archiver), nil
+}
+
+// NewCoverRetentionWithArchiver .
+func NewCoverRetentionWithArchiver(store CoverHistoryStore, policy RetentionConfig, archiver CoverArchiver) *CoverRetention {
+	return &CoverRetention{
+		store:            store,
+		policy:           policy,
+		archiver:         archiver,
+		getTaggedCommits: loadSrvRepoTaggedCommits,
+	}
+}
+
+// Run plans actions of cov files by policy, and applies them if not dry run.
+func (r *CoverRetention) Run(dryRun bool) (RetentionResult, error) {
+	result := RetentionResult{
+		DryRun: dryRun,
+	}
+	rows, err := r.store.ListSrvCoverRows()
+	if err != nil {
+		return result, fmt.Errorf("CoverRetention Run error: %w", err)
+	}
+
+	tagged := make(map[string]map[string]string)
+	if len(r.policy.KeepTagPatterns) > 0 {
+		tagged = r.getTaggedCommits(getAppNamesOfRows(rows), r.policy.KeepTagPatterns)
+	}
+
+	result.Items = PlanCoverRetention(rows, r.policy, tagged, time.Now())
+	if dryRun {
+		return result, nil
+	}
+
+	for idx := range result.Items {
+		item := &result.Items[idx]
+		if err := r.apply(item); err != nil {
+			if errors.Is(err, errCovFileNotExist) {
+				log.Printf("CoverRetention skip [%s] on [%s]: %v", item.Action, item.CovFilePath, err)
+				item.ErrMsg = err.Error()
+				result.Missing++
+				continue
+			}
+			log.Printf("CoverRetention apply [%s] on [%s] error: %v", item.Action, item.CovFilePath, err)
+			item.ErrMsg = err.Error()
+			result.Failed++
+		}
+	}
+	return result, nil
+}
+
+func (r *CoverRetention) apply(item *RetentionItem) error {
+	if !utils.IsExist(item.CovFilePath) {
+		// row is kept unchanged, and reported in result
+		return errCovFileNotExist
+	}
+
+	switch item.Action {
+	case RetentionActionCompress:
+		dst, err := compressCovFile(item.CovFilePath)
+		if err != nil {
+			return err
+		}
+		item.Target = dst
+	case RetentionActionArchive:
+		if r.archiver == nil {
+			return errors.New("archiver is not set")
+		}
+		src := item.CovFilePath
+		if !isCompressedCovFile(src) {
+			dst, err := compressCovFile(src)
+			if err != nil {
+				return err
+			}
+			// row points to compressed file before archived, and it's still valid if upload fails
+			if err := r.store.UpdateCovFileOfSrvCoverRow(item.RowID, dst); err != nil {
+				return err
+			}
+			src = dst
+		}
+		key := path.Join(r.policy.Archive.Prefix, item.AppName, filepath.Base(src))
+		location, err := r.archiver.Archive(src, key)
+		if err != nil {
+			return err
+		}
+		removeCovFileResults(src)
+		item.Target = location
+	case RetentionActionDelete:
+		removeCovFileResults(item.CovFilePath)
+		item.Target = CovFilePathNullValue
+	default:
+		return fmt.Errorf("invalid retention action: %s", item.Action)
+	}
+	return r.store.UpdateCovFileOfSrvCoverRow(item.RowID, item.Target)
+}
+
+// PlanCoverRetention returns actions of cov files by policy, and kept cov files are not included.
+// taggedCommits: app name to tagged short commit ids.
+func PlanCoverRetention(rows []GocSrvCoverModel, policy RetentionConfig, taggedCommits map[string]map[string]string, now time.Time) []RetentionItem {
+	items := make([]RetentionItem, 0)
+	if policy.KeepLastCommits <= 0 {
+		return items
+	}
+
+	commitRanks := getCommitRanksOfBranch(rows)
+	compressBefore := now.Add(-time.Duration(policy.CompressAfterDays) * 24 * time.Hour)
+	for _, row := range rows {
+		if row.CovFilePath == CovFilePathNullValue || isArchivedCovFile(row.CovFilePath) {
+			continue
+		}
+
+		item := RetentionItem{
+			RowID:       row.ID,
+			Env:         row.Env,
+			Region:      row.Region,
+			AppName:     row.AppName,
+			GitBranch:   row.GitBranch,
+			GitCommit:   row.GitCommit,
+			CovFilePath: row.CovFilePath,
+		}
+		_, isTagged := taggedCommits[row.AppName][row.GitCommit]
+		isRecent := commitRanks[getBranchKeyOfRow(row)][row.GitCommit] < policy.KeepLastCommits
+		switch {
+		case !isRecent && !isTagged:
+			item.Action = RetentionActionDelete
+			if policy.Archive.Enabled {
+				item.Action = RetentionActionArchive
+			}
+			item.Reason = fmt.Sprintf("commit is not in last %d commits of branch", policy.KeepLastCommits)
+		case row.IsLatest:
+			continue
+		case policy.CompressAfterDays > 0 && row.CreatedAt.Before(compressBefore) && !isCompressedCovFile(row.CovFilePath):
+			item.Action = RetentionActionCompress
+			item.Reason = fmt.Sprintf("history cov file is older than %d days", policy.CompressAfterDays)
+		default:
+			continue
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// getCommitRanksOfBranch returns rank of commits per branch, and the newest commit (by max row id) is 0.
+func getCommitRanksOfBranch(rows []GocSrvCoverModel) map[string]map[string]int {
+	lastIDs := make(map[string]map[string]uint)
+	for _, row := range rows {
+		key := getBranchKeyOfRow(row)
+		if _, ok := lastIDs[key]; !ok {
+			lastIDs[key] = make(map[string]uint)
+		}
+		if row.ID > lastIDs[key][row.GitCommit] {
+			lastIDs[key][row.GitCommit] = row.ID
+		}
+	}
+
+	ranks := make(map[string]map[string]int, len(lastIDs))
+	for key, commitIDs := range lastIDs {
+		commits := make([]string, 0, len(commitIDs))
+		for commit := range commitIDs {
+			commits = append(commits, commit)
+		}
+		sort.Slice(commits, func(i, j int) bool {
+			return commitIDs[commits[i]] > commitIDs[commits[j]]
+		})
+
+		ranks[key] = make(map[string]int, len(commits))
+		for idx, commit := range commits {
+			ranks[key][commit] = idx
+		}
+	}
+	return ranks
+}
+
+func getBranchKeyOfRow(row GocSrvCoverModel) string {
+	return strings.Join([]string{row.Env, row.Region, row.AppName, row.GitBranch}, "_")
+}
+
+func getAppNamesOfRows(rows []GocSrvCoverModel) []string {
+	set := make(map[string]struct{})
+	names := make([]string, 0)
+	for _, row := range rows {
+		if _, ok := set[row.AppName]; !ok {
+			set[row.AppName] = struct{}{}
+			names = append(names, row.AppName)
+		}
+	}
+	return names
+}
+
+// loadSrvRepoTaggedCommits loads tagged commits from checked out repo of apps, and app without repo is skipped.
+func loadSrvRepoTaggedCommits(appNames []string, patterns []string) map[string]map[string]string {
+	ret := make(map[string]map[string]string, len(appNames))
+	for _, name := range appNames {
+		repoDir := filepath.Join(AppConfig.RootDir, name, srvRepoDirName)
+		if !utils.IsDirExist(repoDir) {
+			log.Printf("loadSrvRepoTaggedCommits repo of [%s] not found, and tagged commits are not kept", name)
+			continue
+		}
+		commits, err := NewGitRepo(repoDir).GetTaggedCommits(patterns)
+		if err != nil {
+			log.Println("loadSrvRepoTaggedCommits error:", err)
+			continue
+		}
+		ret[name] = commits
+	}
+	return ret
+}
+
+func isArchivedCovFile(covFilePath string) bool {
+	return strings.HasPrefix(covFilePath, archivedCovFilePrefix)
+}
+
+func isCompressedCovFile(covFilePath string) bool {
+	return strings.HasSuffix(covFilePath, compressedCovFileExt)
+}
+
+// compressCovFile gzips cov file to "{file}.gz", and removes the source file.
+func compressCovFile(covFilePath string) (string, error) {
+	dstPath := covFilePath + compressedCovFileExt
+	if err := func() error {
+		src, err := os.Open(covFilePath)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		dst, err := os.Create(dstPath)
+		if err != nil {
+			return err
+		}
+		defer dst.Close()
+
+		zw := gzip.NewWriter(dst)
+		zw.Name = filepath.Base(covFilePath)
+		if _, err := io.Copy(zw, src); err != nil {
+			return err
+		}
+		return zw.Close()
+	}(); err != nil {
+		os.Remove(dstPath)
+		return "", fmt.Errorf("compressCovFile error: %w", err)
+	}
+
+	if err := os.Remove(covFilePath); err != nil {
+		return "", fmt.Errorf("compressCovFile remove source error: %w", err)
+	}
+	return dstPath, nil
+}
+
+// removeCovFileResults removes cov file and its func and module reports.
+func removeCovFileResults(covFilePath string) {
+	basePath := getFilePathWithoutExt(strings.TrimSuffix(covFilePath, compressedCovFileExt))
+	for _, filePath := range [4]string{covFilePath, basePath + ".cov", basePath + ".func", basePath + ".module"} {
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			log.Println("removeCovFileResults error:", err)
+		}
+	}
+}
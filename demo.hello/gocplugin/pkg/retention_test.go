@@ -0,0 +1,234 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func newTestRetentionRow(id uint, commit string, isLatest bool, createdAt time.Time) GocSrvCoverModel {
+	row := newTestSrvCoverRow("staging_th_apa_goc_echoserver_master_"+commit, "10.00")
+	row.ID = id
+	row.IsLatest = isLatest
+	row.CreatedAt = createdAt
+	return row
+}
+
+func TestPlanCoverRetention(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-10 * 24 * time.Hour)
+	rows := []GocSrvCoverModel{
+		newTestRetentionRow(1, "1111111111", true, old),
+		newTestRetentionRow(2, "2222222222", false, old),
+		newTestRetentionRow(3, "2222222222", true, old),
+		newTestRetentionRow(4, "3333333333", false, old),
+		newTestRetentionRow(5, "3333333333", false, now),
+		newTestRetentionRow(6, "3333333333", true, now),
+		newTestRetentionRow(7, "4444444444", true, old),
+	}
+	rows[0].CovFilePath = CovFilePathNullValue
+
+	policy := RetentionConfig{
+		KeepLastCommits:   1,
+		CompressAfterDays: 7,
+	}
+	tagged := map[string]map[string]string{
+		"apa_goc_echoserver": {"3333333333": "v1.0.0"},
+	}
+	items := PlanCoverRetention(rows, policy, tagged, now)
+
+	// 4444444444: last commit, 3333333333: tagged, 2222222222: expired
+	want := map[uint]string{
+		2: RetentionActionDelete,
+		3: RetentionActionDelete,
+		4: RetentionActionCompress,
+	}
+	if len(items) != len(want) {
+		t.Fatalf("want %d items, got %d: %+v", len(want), len(items), items)
+	}
+	for _, item := range items {
+		if want[item.RowID] != item.Action {
+			t.Fatalf("row %d: want action [%s], got [%s]", item.RowID, want[item.RowID], item.Action)
+		}
+	}
+
+	policy.Archive.Enabled = true
+	items = PlanCoverRetention(rows, policy, nil, now)
+	for _, item := range items {
+		if item.GitCommit != "4444444444" && item.Action != RetentionActionArchive {
+			t.Fatalf("row %d: want action [%s], got [%s]", item.RowID, RetentionActionArchive, item.Action)
+		}
+	}
+
+	if items = PlanCoverRetention(rows, RetentionConfig{}, nil, now); len(items) != 0 {
+		t.Fatalf("want no items when retention is disabled, got %d", len(items))
+	}
+}
+
+type testCoverArchiver struct {
+	keys []string
+}
+
+func (a *testCoverArchiver) Archive(srcPath, key string) (string, error) {
+	a.keys = append(a.keys, key)
+	return archivedCovFilePrefix + "bucket/" + key, nil
+}
+
+func TestCoverRetentionRun(t *testing.T) {
+	dir := t.TempDir()
+	store := NewMemCoverHistoryStore()
+	for _, commit := range []string{"1111111111", "2222222222"} {
+		row := newTestRetentionRow(0, commit, true, time.Now())
+		row.CovFilePath = filepath.Join(dir, commit+".cov")
+		if err := os.WriteFile(row.CovFilePath, []byte("mode: set\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.InsertSrvCoverRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archiver := &testCoverArchiver{}
+	policy := RetentionConfig{
+		KeepLastCommits: 1,
+		Archive: ArchiveConfig{
+			Enabled: true,
+			Prefix:  "goc",
+		},
+	}
+	retention := NewCoverRetentionWithArchiver(store, policy, archiver)
+
+	result, err := retention.Run(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Items) != 1 || len(archiver.keys) != 0 {
+		t.Fatalf("dry run: want 1 item and no archived files, got %d items and %d archived", len(result.Items), len(archiver.keys))
+	}
+
+	result, err = retention.Run(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed != 0 {
+		t.Fatalf("want no failed items, got: %+v", result.Items)
+	}
+
+	wantKey := "goc/apa_goc_echoserver/1111111111.cov.gz"
+	if len(archiver.keys) != 1 || archiver.keys[0] != wantKey {
+		t.Fatalf("want archived key %s, got %v", wantKey, archiver.keys)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "1111111111.cov.gz")); !os.IsNotExist(err) {
+		t.Fatal("want archived cov file removed")
+	}
+
+	rows, err := store.ListSrvCoverRows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].CovFilePath != archivedCovFilePrefix+"bucket/"+wantKey {
+		t.Fatalf("want row points to archived location, got %s", rows[0].CovFilePath)
+	}
+
+	if result, err = retention.Run(false); err != nil || len(result.Items) != 0 {
+		t.Fatalf("want archived files skipped, got %d items, err: %v", len(result.Items), err)
+	}
+}
+
+func TestGetTaggedCommits(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("main.go"); err != nil {
+		t.Fatal(err)
+	}
+	sign := &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()}
+	hash, err := wt.Commit("init", &git.CommitOptions{Author: sign})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateTag("v1.0.0", hash, &git.CreateTagOptions{Tagger: sign, Message: "release"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateTag("dev-1", hash, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := NewGitRepo(dir).GetTaggedCommits([]string{"v*", "release-*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || commits[hash.String()[:10]] != "v1.0.0" {
+		t.Fatalf("want commit tagged by v1.0.0, got: %v", commits)
+	}
+}
+
+func TestNewCoverRetentionInvalidArchive(t *testing.T) {
+	policy := RetentionConfig{
+		KeepLastCommits: 1,
+		Archive: ArchiveConfig{
+			Enabled: true,
+			Bucket:  "goc",
+		},
+	}
+	if _, err := NewCoverRetention(NewMemCoverHistoryStore(), policy); err == nil {
+		t.Fatal("want error when archive host is not set")
+	}
+
+	t.Setenv("S3_ACCESS_KEY", "")
+	t.Setenv("S3_SECRET_KEY", "")
+	policy.Archive.Host = "http://s3.example.com"
+	if _, err := NewCoverRetention(NewMemCoverHistoryStore(), policy); err == nil {
+		t.Fatal("want error when archive credentials are not set")
+	}
+	policy.Archive.AccessKey = "access"
+	policy.Archive.SecretKey = "secret"
+	if _, err := NewCoverRetention(NewMemCoverHistoryStore(), policy); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCoverRetentionRunMissingCovFile(t *testing.T) {
+	dir := t.TempDir()
+	store := NewMemCoverHistoryStore()
+	for _, commit := range []string{"1111111111", "2222222222"} {
+		row := newTestRetentionRow(0, commit, true, time.Now())
+		row.CovFilePath = filepath.Join(dir, commit+".cov")
+		if err := store.InsertSrvCoverRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	retention, err := NewCoverRetention(store, RetentionConfig{KeepLastCommits: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := retention.Run(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Missing != 1 || result.Failed != 0 {
+		t.Fatalf("want 1 missing item, got: %+v", result)
+	}
+
+	rows, err := store.ListSrvCoverRows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].CovFilePath != filepath.Join(dir, "1111111111.cov") {
+		t.Fatalf("want row of missing cov file unchanged, got: %+v", rows)
+	}
+}
@@ -20,14 +20,14 @@ var (
 
 // Scheduler runs schedule tasks.
 type Scheduler struct {
-	notify *MatterMostNotify
+	notify *NotifyDispatcher
 }
 
 // NewScheduler .
 func NewScheduler() *Scheduler {
 	schedulerOnce.Do(func() {
 		scheduler = &Scheduler{
-			notify: NewMatterMostNotify(),
+			notify: NewNotifyDispatcher(),
 		}
 	})
 	return scheduler
@@ -40,9 +40,9 @@ func (s *Scheduler) RemoveUnhealthSrvTask(ctx context.Context, interval time.Dur
 		for {
 			select {
 			case <-tick:
-				if err := RemoveUnhealthSrvInGocTask(); err != nil {
+				if err := runScheduleTask("RemoveUnhealthSrvTask", RemoveUnhealthSrvInGocTask); err != nil {
 					errMsg := fmt.Sprintln("RemoveUnhealthSrvTask error:", err)
-					s.notify.MustSendMessageToDefaultUser(errMsg)
+					s.notify.MustNotify(NewNotifyMessage(NotifyEventTaskFailed, "", errMsg))
 				}
 			case <-ctx.Done():
 				log.Println("RemoveUnhealthSrvTask exit.")
@@ -59,9 +59,9 @@ func (s *Scheduler) SyncRegisterSrvsCoverReportTask(ctx context.Context, interva
 		for {
 			select {
 			case <-tick:
-				if err := syncRegisterSrvsCoverReport(); err != nil {
+				if err := runScheduleTask("SyncRegisterSrvsCoverReportTask", syncRegisterSrvsCoverReport); err != nil {
 					errMsg := fmt.Sprintln("SyncRegisterSrvsCoverReportTask error:", err)
-					s.notify.MustSendMessageToDefaultUser(errMsg)
+					s.notify.MustNotify(NewNotifyMessage(NotifyEventTaskFailed, "", errMsg))
 				}
 			case <-ctx.Done():
 				log.Println("SyncRegisterSrvsCoverReportTask exit.")
@@ -78,9 +78,9 @@ func (s *Scheduler) SyncSrvsRawCoverTask(ctx context.Context, interval time.Dura
 		for {
 			select {
 			case <-tick:
-				if err := s.fetchAndSaveCoverForRegisterSrvs(); err != nil {
+				if err := runScheduleTask("SyncSrvsRawCoverTask", s.fetchAndSaveCoverForRegisterSrvs); err != nil {
 					errMsg := fmt.Sprintln("SyncRegisterSrvsCoverTask error:", err)
-					s.notify.MustSendMessageToDefaultUser(errMsg)
+					s.notify.MustNotify(NewNotifyMessage(NotifyEventTaskFailed, "", errMsg))
 				}
 			case <-ctx.Done():
 				log.Println("SyncRegisterSrvsCoverTask exit.")
@@ -100,6 +100,7 @@ func (s *Scheduler) fetchAndSaveCoverForRegisterSrvs() error {
 	if err != nil {
 		return fmt.Errorf("fetchAndSaveCoverForRegisterSrvs error: %w", err)
 	}
+	observeRegisterSrvs(srvs)
 
 	const limit = 5
 	semaphore := make(chan struct{}, limit)
@@ -122,17 +123,18 @@ func (s *Scheduler) fetchAndSaveCoverForRegisterSrvs() error {
 			lastCovFileName, err := utils.GetLatestFileInDir(savedDir, "cov")
 			if err != nil && !errors.Is(err, utils.ErrNoFilesExistInDir) {
 				errMsg := fmt.Sprintln("fetchAndSaveCoverForRegisterSrvs error:", err)
-				s.notify.MustSendMessageToDefaultUser(errMsg)
+				s.notify.MustNotify(NewNotifyMessage(NotifyEventSyncFailed, srvName, errMsg))
 				return
 			}
 
 			savedCovPath, err := FetchAndSaveSrvCover(savedDir, srvName)
 			if err != nil {
 				errMsg := fmt.Sprintln("fetchAndSaveCoverForRegisterSrvs error:", err)
-				s.notify.MustSendMessageToDefaultUser(errMsg)
+				s.notify.MustNotify(NewNotifyMessage(NotifyEventSyncFailed, srvName, errMsg))
 				return
 			}
 			removeDuplicatedCovFile(filepath.Join(savedDir, lastCovFileName), savedCovPath)
+			observeWatcherCovFiles(srvName, savedDir)
 		}(srvName)
 	}
 	wg.Wait()
@@ -158,15 +160,49 @@ func removeDuplicatedCovFile(preCovFilePath, curCovFilePath string) {
 	}
 }
 
-// RemoveExpiredSrvCoverFilesTask .
-func (s *Scheduler) RemoveExpiredSrvCoverFilesTask() {
-	// TODO:
+// RemoveExpiredSrvCoverFilesTask applies retention policy on history cov files. (report)
+func (s *Scheduler) RemoveExpiredSrvCoverFilesTask(ctx context.Context, interval time.Duration) {
+	go func() {
+		tick := time.Tick(interval)
+		for {
+			select {
+			case <-tick:
+				if err := runScheduleTask("RemoveExpiredSrvCoverFilesTask", applyCoverRetentionPolicy); err != nil {
+					errMsg := fmt.Sprintln("RemoveExpiredSrvCoverFilesTask error:", err)
+					s.notify.MustNotify(NewNotifyMessage(NotifyEventTaskFailed, "", errMsg))
+				}
+			case <-ctx.Done():
+				log.Println("RemoveExpiredSrvCoverFilesTask exit.")
+				return
+			}
+		}
+	}()
+}
+
+func applyCoverRetentionPolicy() error {
+	retention, err := NewCoverRetention(NewCoverHistoryStore(), AppConfig.Retention)
+	if err != nil {
+		return fmt.Errorf("applyCoverRetentionPolicy error: %w", err)
+	}
+	result, err := retention.Run(false)
+	if err != nil {
+		return fmt.Errorf("applyCoverRetentionPolicy error: %w", err)
+	}
+	log.Printf("[applyCoverRetentionPolicy] apply %d actions, %d failed, and %d cov files not exist",
+		len(result.Items), result.Failed, result.Missing)
+	if result.Failed > 0 {
+		return fmt.Errorf("applyCoverRetentionPolicy %d of %d actions failed", result.Failed, len(result.Items))
+	}
+	return nil
 }
 
 //
 // Common
 //
 
+// syncSrvCoverWait max wait time of a sync job, and the job is left running after timeout.
+const syncSrvCoverWait = 10 * time.Minute
+
 func syncRegisterSrvsCoverReport() error {
 	log.Println("[syncRegisterSrvsCoverReport] start")
 	srvs, err := SyncAndListRegisterSrvsTask()
@@ -186,19 +222,20 @@ func syncRegisterSrvsCoverReport() error {
 	for srv, addrs := range srvs {
 		wg.Add(1)
 		go func(srv string, addrs []string) {
-			retCh := SubmitSrvCoverSyncTask(SyncSrvCoverParam{
+			defer wg.Done()
+			jobID, retCh := SubmitSrvCoverSyncTask(SyncSrvCoverParam{
 				SrvName:   srv,
 				Addresses: addrs,
 			})
-			defer func() {
-				close(retCh)
-				wg.Done()
-			}()
-			res := <-retCh
-			if err, ok := res.(error); ok {
-				mm := NewMatterMostNotify()
-				errMsg := fmt.Sprintf("syncRegisterSrvsCoverReport error: %v", err)
-				mm.MustSendMessageToDefaultUser(errMsg)
+			// job may be run by another report instance, and result is not sent to this one
+			select {
+			case res := <-retCh:
+				if err, ok := res.(error); ok {
+					errMsg := fmt.Sprintf("syncRegisterSrvsCoverReport error: %v", err)
+					NewNotifyDispatcher().MustNotify(NewNotifyMessage(NotifyEventSyncFailed, srv, errMsg))
+				}
+			case <-time.After(syncSrvCoverWait):
+				log.Printf("[syncRegisterSrvsCoverReport] wait sync job [%d] of srv [%s] timeout", jobID, srv)
 			}
 		}(srv, addrs)
 	}
@@ -0,0 +1,137 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"demo.hello/gocplugin/pkg/coverage"
+	"demo.hello/utils"
+)
+
+const (
+	srvRepoDirName          = "repo"
+	defaultRepoSourceBranch = "master"
+)
+
+// SrvRepos checked out repos of service.
+type SrvRepos struct {
+	// RepoDir dir of service's own repo.
+	RepoDir string
+	// ModuleDirs go module dirs of all repos.
+	ModuleDirs []string
+}
+
+// FileResolver returns resolver for files in profile of service.
+func (r SrvRepos) FileResolver() (coverage.FileResolver, error) {
+	return coverage.NewMultiModuleFileResolver(r.ModuleDirs)
+}
+
+// ModulePaths .
+func (r SrvRepos) ModulePaths() ([]string, error) {
+	paths := make([]string, 0, len(r.ModuleDirs))
+	for _, dir := range r.ModuleDirs {
+		path, err := coverage.GetModulePath(dir)
+		if err != nil {
+			return nil, fmt.Errorf("ModulePaths error: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// CheckoutSrvRepos checkouts service's own repo to service commit, and other repos to latest of configured branch.
+func CheckoutSrvRepos(srvName string) (SrvRepos, error) {
+	meta := GetSrvMetaFromName(srvName)
+	appDir := filepath.Join(AppConfig.RootDir, meta.AppName)
+	repos := SrvRepos{
+		RepoDir: filepath.Join(appDir, srvRepoDirName),
+	}
+	if err := checkoutSrvRepo(repos.RepoDir, srvName); err != nil {
+		return SrvRepos{}, fmt.Errorf("CheckoutSrvRepos error: %w", err)
+	}
+
+	sources, ok := AppRepoSourcesMap[meta.AppName]
+	if !ok {
+		sources = []RepoSource{{}}
+	}
+	for idx, source := range sources {
+		repoDir := repos.RepoDir
+		if idx > 0 {
+			var err error
+			if repoDir, err = getRepoSourceDir(appDir, source); err != nil {
+				return SrvRepos{}, fmt.Errorf("CheckoutSrvRepos error: %w", err)
+			}
+			if err := checkoutRepoSource(repoDir, source); err != nil {
+				return SrvRepos{}, fmt.Errorf("CheckoutSrvRepos error: %w", err)
+			}
+		}
+
+		moduleDirs, err := getRepoSourceModuleDirs(repoDir, source)
+		if err != nil {
+			return SrvRepos{}, fmt.Errorf("CheckoutSrvRepos error: %w", err)
+		}
+		repos.ModuleDirs = append(repos.ModuleDirs, moduleDirs...)
+	}
+	return repos, nil
+}
+
+func getRepoSourceDir(appDir string, source RepoSource) (string, error) {
+	if len(source.Dir) > 0 {
+		return filepath.Join(appDir, source.Dir), nil
+	}
+	name, err := getRepoNameFromURL(source.URL)
+	if err != nil {
+		return "", fmt.Errorf("getRepoSourceDir error: %w", err)
+	}
+	return filepath.Join(appDir, name), nil
+}
+
+func getRepoSourceModuleDirs(repoDir string, source RepoSource) ([]string, error) {
+	if len(source.ModuleDirs) == 0 {
+		return coverage.FindModuleDirs(repoDir)
+	}
+
+	dirs := make([]string, 0, len(source.ModuleDirs))
+	for _, dir := range source.ModuleDirs {
+		dirs = append(dirs, filepath.Join(repoDir, filepath.FromSlash(dir)))
+	}
+	return dirs, nil
+}
+
+func checkoutRepoSource(workingDir string, source RepoSource) error {
+	branch := source.Branch
+	if len(branch) == 0 {
+		branch = defaultRepoSourceBranch
+	}
+
+	if !utils.IsDirExist(workingDir) {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+		defer cancel()
+		head, err := GitClone(ctx, source.URL, workingDir)
+		if err != nil {
+			return fmt.Errorf("checkoutRepoSource error: %w", err)
+		}
+		log.Printf("Git clone repo [%s] with head [%s]", source.URL, head)
+	}
+
+	repo := NewGitRepo(workingDir)
+	isExist, err := repo.IsBranchExist(branch)
+	if err != nil {
+		return fmt.Errorf("checkoutRepoSource error: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+	if isExist {
+		_, err = repo.Pull(ctx, branch)
+	} else {
+		_, err = repo.CheckoutRemoteBranch(ctx, branch)
+	}
+	if err != nil {
+		return fmt.Errorf("checkoutRepoSource error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GormCoverHistoryStore cover history store for sqlite, mysql and postgres.
+type GormCoverHistoryStore struct {
+	db *gorm.DB
+}
+
+// NewGormCoverHistoryStore .
+func NewGormCoverHistoryStore(dialector gorm.Dialector) (*GormCoverHistoryStore, error) {
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("NewGormCoverHistoryStore open db error: %w", err)
+	}
+
+	if err := db.AutoMigrate(&GocSrvCoverModel{}); err != nil {
+		return nil, fmt.Errorf("NewGormCoverHistoryStore db migrate error: %w", err)
+	}
+
+	return &GormCoverHistoryStore{
+		db: db,
+	}, nil
+}
+
+// InsertSrvCoverRow .
+func (s *GormCoverHistoryStore) InsertSrvCoverRow(row GocSrvCoverModel) error {
+	if result := s.db.Create(&row); result.Error != nil {
+		return fmt.Errorf("InsertSrvCoverRow error: %w", result.Error)
+	}
+	return nil
+}
+
+// GetLatestSrvCoverRow .
+func (s *GormCoverHistoryStore) GetLatestSrvCoverRow(meta SrvCoverMeta) (GocSrvCoverModel, error) {
+	rows, err := s.getLatestSrvCoverRows(meta)
+	if err != nil {
+		return GocSrvCoverModel{}, fmt.Errorf("GetLatestSrvCoverRow error: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return GocSrvCoverModel{}, fmt.Errorf("GetLatestSrvCoverRow error: %w", ErrSrvCoverLatestRowNotFound)
+	}
+	if len(rows) != 1 {
+		return GocSrvCoverModel{}, fmt.Errorf("GetLatestSrvCoverRow error: %w", ErrMoreThanOneSrvCoverLatestRow)
+	}
+	return rows[0], nil
+}
+
+func (s *GormCoverHistoryStore) getLatestSrvCoverRows(meta SrvCoverMeta) ([]GocSrvCoverModel, error) {
+	var rows []GocSrvCoverModel
+	condition := "env = ? and region = ? and app_name = ? and git_commit = ? and is_latest = ?"
+	if result := s.db.Where(condition, meta.Env, meta.Region, meta.AppName, meta.GitCommit, true).Find(&rows); result.Error != nil {
+		return nil, fmt.Errorf("getLatestSrvCoverRows query error: %w", result.Error)
+	}
+	return rows, nil
+}
+
+// GetLimitedHistorySrvCoverRows .
+func (s *GormCoverHistoryStore) GetLimitedHistorySrvCoverRows(meta SrvCoverMeta, limit int) ([]GocSrvCoverModel, error) {
+	var rows []GocSrvCoverModel
+	condition := "env = ? and region = ? and app_name = ? and is_latest = ?"
+	if result := s.db.Where(condition, meta.Env, meta.Region, meta.AppName, true).Order("id desc").Limit(limit).Find(&rows); result.Error != nil {
+		return nil, fmt.Errorf("GetLimitedHistorySrvCoverRows query error: %w", result.Error)
+	}
+	return rows, nil
+}
+
+// UpdateLatestSrvCoverRowToFalse .
+func (s *GormCoverHistoryStore) UpdateLatestSrvCoverRowToFalse(meta SrvCoverMeta) error {
+	row, err := s.GetLatestSrvCoverRow(meta)
+	if err != nil {
+		return fmt.Errorf("UpdateLatestSrvCoverRowToFalse error: %w", err)
+	}
+
+	data := map[string]interface{}{
+		"IsLatest": false,
+	}
+	if result := s.db.Model(&GocSrvCoverModel{}).Where("id = ?", row.ID).Updates(data); result.Error != nil {
+		return fmt.Errorf("UpdateLatestSrvCoverRowToFalse update row error: %w", result.Error)
+	}
+	return nil
+}
+
+// UpdateCovFileOfLatestSrvCoverRow .
+func (s *GormCoverHistoryStore) UpdateCovFileOfLatestSrvCoverRow(meta SrvCoverMeta, covFilePath string) error {
+	row, err := s.GetLatestSrvCoverRow(meta)
+	if err != nil {
+		return fmt.Errorf("UpdateCovFileOfLatestSrvCoverRow error: %w", err)
+	}
+
+	data := GocSrvCoverModel{
+		CovFilePath: covFilePath,
+	}
+	if result := s.db.Model(&GocSrvCoverModel{}).Where("id = ?", row.ID).Updates(data); result.Error != nil {
+		return fmt.Errorf("UpdateCovFileOfLatestSrvCoverRow update row error: %w", result.Error)
+	}
+	return nil
+}
+
+// UpdateSrvCoverTotalByCommit .
+func (s *GormCoverHistoryStore) UpdateSrvCoverTotalByCommit(total, commit string) error {
+	data := GocSrvCoverModel{
+		CoverTotal: sql.NullString{
+			String: total,
+			Valid:  true,
+		},
+	}
+	if result := s.db.Model(&GocSrvCoverModel{}).Where("git_commit = ? and is_latest = ?", commit, true).Updates(data); result.Error != nil {
+		return fmt.Errorf("UpdateSrvCoverTotalByCommit error: %w", result.Error)
+	}
+	return nil
+}
+
+// ListSrvCoverRows .
+func (s *GormCoverHistoryStore) ListSrvCoverRows() ([]GocSrvCoverModel, error) {
+	var rows []GocSrvCoverModel
+	if result := s.db.Where("cov_file_path <> ?", CovFilePathNullValue).Order("id").Find(&rows); result.Error != nil {
+		return nil, fmt.Errorf("ListSrvCoverRows error: %w", result.Error)
+	}
+	return rows, nil
+}
+
+// UpdateCovFileOfSrvCoverRow .
+func (s *GormCoverHistoryStore) UpdateCovFileOfSrvCoverRow(id uint, covFilePath string) error {
+	data := GocSrvCoverModel{
+		CovFilePath: covFilePath,
+	}
+	if result := s.db.Model(&GocSrvCoverModel{}).Where("id = ?", id).Updates(data); result.Error != nil {
+		return fmt.Errorf("UpdateCovFileOfSrvCoverRow error: %w", result.Error)
+	}
+	return nil
+}
+
+// AddLatestSrvCoverRow .
+func (s *GormCoverHistoryStore) AddLatestSrvCoverRow(row GocSrvCoverModel) error {
+	if err := s.Transaction(func(tx CoverHistoryStore) error {
+		if err := tx.UpdateLatestSrvCoverRowToFalse(row.SrvCoverMeta); err != nil {
+			return err
+		}
+		return tx.InsertSrvCoverRow(row)
+	}); err != nil {
+		return fmt.Errorf("AddLatestSrvCoverRow error: %w", err)
+	}
+	return nil
+}
+
+// Transaction .
+func (s *GormCoverHistoryStore) Transaction(fn func(tx CoverHistoryStore) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&GormCoverHistoryStore{db: tx})
+	})
+}
+
+// GormSyncJobStore sync job store for sqlite, mysql and postgres.
+type GormSyncJobStore struct {
+	db *gorm.DB
+}
+
+// NewGormSyncJobStore .
+func NewGormSyncJobStore(db *gorm.DB) (*GormSyncJobStore, error) {
+	if err := db.AutoMigrate(&SyncJobModel{}); err != nil {
+		return nil, fmt.Errorf("NewGormSyncJobStore db migrate error: %w", err)
+	}
+	return &GormSyncJobStore{
+		db: db,
+	}, nil
+}
+
+// CreateOrGetActiveJob creates job, and the unique active key makes sure that only one of concurrent creates
+// succeeds, and others get the active job.
+func (s *GormSyncJobStore) CreateOrGetActiveJob(job SyncJobModel) (SyncJobModel, bool, error) {
+	active, err := s.getActiveJob(job)
+	if err != nil {
+		return SyncJobModel{}, false, fmt.Errorf("CreateOrGetActiveJob error: %w", err)
+	}
+	if active != nil {
+		return *active, false, nil
+	}
+
+	result := s.db.Create(&job)
+	if result.Error == nil {
+		return job, true, nil
+	}
+	// duplicated active key, job is created by another submit
+	active, err = s.getActiveJob(job)
+	if err != nil {
+		return SyncJobModel{}, false, fmt.Errorf("CreateOrGetActiveJob error: %w", err)
+	}
+	if active != nil {
+		return *active, false, nil
+	}
+	return SyncJobModel{}, false, fmt.Errorf("CreateOrGetActiveJob error: %w", result.Error)
+}
+
+func (s *GormSyncJobStore) getActiveJob(job SyncJobModel) (*SyncJobModel, error) {
+	var rows []SyncJobModel
+	condition := "srv_name = ? and git_commit = ? and status in ?"
+	if result := s.db.Where(condition, job.SrvName, job.GitCommit, []string{JobStatusPending, JobStatusRunning}).Limit(1).Find(&rows); result.Error != nil {
+		return nil, result.Error
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return &rows[0], nil
+}
+
+// GetJob .
+func (s *GormSyncJobStore) GetJob(id uint) (SyncJobModel, error) {
+	var rows []SyncJobModel
+	if result := s.db.Where("id = ?", id).Limit(1).Find(&rows); result.Error != nil {
+		return SyncJobModel{}, fmt.Errorf("GetJob error: %w", result.Error)
+	}
+	if len(rows) == 0 {
+		return SyncJobModel{}, fmt.Errorf("GetJob error: %w", ErrSyncJobNotFound)
+	}
+	return rows[0], nil
+}
+
+// ListJobs .
+func (s *GormSyncJobStore) ListJobs(filter SyncJobFilter) ([]SyncJobModel, error) {
+	query := s.db.Model(&SyncJobModel{})
+	if len(filter.SrvName) > 0 {
+		query = query.Where("srv_name = ?", filter.SrvName)
+	}
+	if len(filter.Status) > 0 {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var rows []SyncJobModel
+	if result := query.Order("id desc").Find(&rows); result.Error != nil {
+		return nil, fmt.Errorf("ListJobs error: %w", result.Error)
+	}
+	return rows, nil
+}
+
+// ListUnfinishedJobs .
+func (s *GormSyncJobStore) ListUnfinishedJobs() ([]SyncJobModel, error) {
+	var rows []SyncJobModel
+	if result := s.db.Where("status in ?", []string{JobStatusPending, JobStatusRunning}).Order("id").Find(&rows); result.Error != nil {
+		return nil, fmt.Errorf("ListUnfinishedJobs error: %w", result.Error)
+	}
+	return rows, nil
+}
+
+// UpdateJob .
+func (s *GormSyncJobStore) UpdateJob(job SyncJobModel) error {
+	if result := s.db.Save(&job); result.Error != nil {
+		return fmt.Errorf("UpdateJob error: %w", result.Error)
+	}
+	return nil
+}
+
+// RefreshJobsHeartbeat .
+func (s *GormSyncJobStore) RefreshJobsHeartbeat(owner string, at time.Time) error {
+	result := s.db.Session(&gorm.Session{SkipHooks: true}).Model(&SyncJobModel{}).
+		Where("owner = ? and status in ?", owner, activeJobStatuses).Update("heartbeat_at", at)
+	if result.Error != nil {
+		return fmt.Errorf("RefreshJobsHeartbeat error: %w", result.Error)
+	}
+	return nil
+}
+
+// ClaimExpiredJobs claims job by conditional update, and job which is claimed by another instance is skipped.
+func (s *GormSyncJobStore) ClaimExpiredJobs(owner string, expiredAt, now time.Time) ([]SyncJobModel, error) {
+	const condition = "status in ? and (heartbeat_at is null or heartbeat_at < ?)"
+	var rows []SyncJobModel
+	if result := s.db.Where(condition, activeJobStatuses, expiredAt).Order("id").Find(&rows); result.Error != nil {
+		return nil, fmt.Errorf("ClaimExpiredJobs error: %w", result.Error)
+	}
+
+	jobs := make([]SyncJobModel, 0, len(rows))
+	for _, row := range rows {
+		heartbeatAt := sql.NullTime{Time: now, Valid: true}
+		result := s.db.Session(&gorm.Session{SkipHooks: true}).Model(&SyncJobModel{}).
+			Where("id = ? and "+condition, row.ID, activeJobStatuses, expiredAt).
+			Updates(map[string]interface{}{
+				"owner":        owner,
+				"heartbeat_at": heartbeatAt,
+				"status":       JobStatusPending,
+			})
+		if result.Error != nil {
+			return nil, fmt.Errorf("ClaimExpiredJobs error: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		row.Owner = owner
+		row.HeartbeatAt = heartbeatAt
+		row.Status = JobStatusPending
+		jobs = append(jobs, row)
+	}
+	return jobs, nil
+}
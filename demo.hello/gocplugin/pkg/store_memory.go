@@ -0,0 +1,346 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemCoverHistoryStore in-memory cover history store, used for test and single instance debug.
+type MemCoverHistoryStore struct {
+	rows   []GocSrvCoverModel
+	lastID uint
+	lock   *sync.Mutex
+	// isTx is true for the store passed to Transaction fn, it's already guarded by parent lock.
+	isTx bool
+}
+
+// NewMemCoverHistoryStore .
+func NewMemCoverHistoryStore() *MemCoverHistoryStore {
+	const defaultSize = 16
+	return &MemCoverHistoryStore{
+		rows: make([]GocSrvCoverModel, 0, defaultSize),
+		lock: &sync.Mutex{},
+	}
+}
+
+func (s *MemCoverHistoryStore) doLock() func() {
+	if s.isTx {
+		return func() {}
+	}
+	s.lock.Lock()
+	return s.lock.Unlock
+}
+
+// InsertSrvCoverRow .
+func (s *MemCoverHistoryStore) InsertSrvCoverRow(row GocSrvCoverModel) error {
+	unlock := s.doLock()
+	defer unlock()
+	s.insert(row)
+	return nil
+}
+
+func (s *MemCoverHistoryStore) insert(row GocSrvCoverModel) {
+	s.lastID++
+	now := time.Now()
+	row.ID = s.lastID
+	row.CreatedAt = now
+	row.UpdatedAt = now
+	s.rows = append(s.rows, row)
+}
+
+// GetLatestSrvCoverRow .
+func (s *MemCoverHistoryStore) GetLatestSrvCoverRow(meta SrvCoverMeta) (GocSrvCoverModel, error) {
+	unlock := s.doLock()
+	defer unlock()
+
+	idx, err := s.getLatestRowIndex(meta)
+	if err != nil {
+		return GocSrvCoverModel{}, fmt.Errorf("GetLatestSrvCoverRow error: %w", err)
+	}
+	return s.rows[idx], nil
+}
+
+func (s *MemCoverHistoryStore) getLatestRowIndex(meta SrvCoverMeta) (int, error) {
+	retIdx := -1
+	for idx, row := range s.rows {
+		if row.IsLatest && isSameSrv(row.SrvCoverMeta, meta) && row.GitCommit == meta.GitCommit {
+			if retIdx != -1 {
+				return -1, ErrMoreThanOneSrvCoverLatestRow
+			}
+			retIdx = idx
+		}
+	}
+	if retIdx == -1 {
+		return -1, ErrSrvCoverLatestRowNotFound
+	}
+	return retIdx, nil
+}
+
+// GetLimitedHistorySrvCoverRows .
+func (s *MemCoverHistoryStore) GetLimitedHistorySrvCoverRows(meta SrvCoverMeta, limit int) ([]GocSrvCoverModel, error) {
+	unlock := s.doLock()
+	defer unlock()
+
+	rows := make([]GocSrvCoverModel, 0, limit)
+	for idx := len(s.rows) - 1; idx >= 0 && len(rows) < limit; idx-- {
+		row := s.rows[idx]
+		if row.IsLatest && isSameSrv(row.SrvCoverMeta, meta) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// UpdateLatestSrvCoverRowToFalse .
+func (s *MemCoverHistoryStore) UpdateLatestSrvCoverRowToFalse(meta SrvCoverMeta) error {
+	unlock := s.doLock()
+	defer unlock()
+
+	idx, err := s.getLatestRowIndex(meta)
+	if err != nil {
+		return fmt.Errorf("UpdateLatestSrvCoverRowToFalse error: %w", err)
+	}
+	s.rows[idx].IsLatest = false
+	s.rows[idx].UpdatedAt = time.Now()
+	return nil
+}
+
+// UpdateCovFileOfLatestSrvCoverRow .
+func (s *MemCoverHistoryStore) UpdateCovFileOfLatestSrvCoverRow(meta SrvCoverMeta, covFilePath string) error {
+	unlock := s.doLock()
+	defer unlock()
+
+	idx, err := s.getLatestRowIndex(meta)
+	if err != nil {
+		return fmt.Errorf("UpdateCovFileOfLatestSrvCoverRow error: %w", err)
+	}
+	s.rows[idx].CovFilePath = covFilePath
+	s.rows[idx].UpdatedAt = time.Now()
+	return nil
+}
+
+// UpdateSrvCoverTotalByCommit .
+func (s *MemCoverHistoryStore) UpdateSrvCoverTotalByCommit(total, commit string) error {
+	unlock := s.doLock()
+	defer unlock()
+
+	for idx := range s.rows {
+		if s.rows[idx].GitCommit == commit && s.rows[idx].IsLatest {
+			s.rows[idx].CoverTotal = sql.NullString{
+				String: total,
+				Valid:  true,
+			}
+			s.rows[idx].UpdatedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+// ListSrvCoverRows .
+func (s *MemCoverHistoryStore) ListSrvCoverRows() ([]GocSrvCoverModel, error) {
+	unlock := s.doLock()
+	defer unlock()
+
+	rows := make([]GocSrvCoverModel, 0, len(s.rows))
+	for _, row := range s.rows {
+		if row.CovFilePath != CovFilePathNullValue {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// UpdateCovFileOfSrvCoverRow .
+func (s *MemCoverHistoryStore) UpdateCovFileOfSrvCoverRow(id uint, covFilePath string) error {
+	unlock := s.doLock()
+	defer unlock()
+
+	for idx := range s.rows {
+		if s.rows[idx].ID == id {
+			s.rows[idx].CovFilePath = covFilePath
+			s.rows[idx].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return fmt.Errorf("UpdateCovFileOfSrvCoverRow error: row [%d] not found", id)
+}
+
+// AddLatestSrvCoverRow .
+func (s *MemCoverHistoryStore) AddLatestSrvCoverRow(row GocSrvCoverModel) error {
+	if err := s.Transaction(func(tx CoverHistoryStore) error {
+		if err := tx.UpdateLatestSrvCoverRowToFalse(row.SrvCoverMeta); err != nil {
+			return err
+		}
+		return tx.InsertSrvCoverRow(row)
+	}); err != nil {
+		return fmt.Errorf("AddLatestSrvCoverRow error: %w", err)
+	}
+	return nil
+}
+
+// Transaction runs fn on a copy of rows, and the copy replaces current rows only when fn returns nil.
+// Store is locked until fn returns, so fn should only use the given tx store.
+func (s *MemCoverHistoryStore) Transaction(fn func(tx CoverHistoryStore) error) error {
+	unlock := s.doLock()
+	defer unlock()
+
+	tx := &MemCoverHistoryStore{
+		rows:   make([]GocSrvCoverModel, len(s.rows)),
+		lastID: s.lastID,
+		lock:   s.lock,
+		isTx:   true,
+	}
+	copy(tx.rows, s.rows)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.rows = tx.rows
+	s.lastID = tx.lastID
+	return nil
+}
+
+func isSameSrv(src, dst SrvCoverMeta) bool {
+	return src.Env == dst.Env && src.Region == dst.Region && src.AppName == dst.AppName
+}
+
+// MemSyncJobStore in-memory sync job store.
+type MemSyncJobStore struct {
+	jobs   []SyncJobModel
+	lastID uint
+	lock   *sync.Mutex
+}
+
+// NewMemSyncJobStore .
+func NewMemSyncJobStore() *MemSyncJobStore {
+	const defaultSize = 16
+	return &MemSyncJobStore{
+		jobs: make([]SyncJobModel, 0, defaultSize),
+		lock: &sync.Mutex{},
+	}
+}
+
+// CreateOrGetActiveJob .
+func (s *MemSyncJobStore) CreateOrGetActiveJob(job SyncJobModel) (SyncJobModel, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, j := range s.jobs {
+		if j.SrvName == job.SrvName && j.GitCommit == job.GitCommit && !j.IsFinished() {
+			return j, false, nil
+		}
+	}
+
+	s.lastID++
+	now := time.Now()
+	job.ID = s.lastID
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	s.jobs = append(s.jobs, job)
+	return job, true, nil
+}
+
+// GetJob .
+func (s *MemSyncJobStore) GetJob(id uint) (SyncJobModel, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if idx := s.getJobIndex(id); idx != -1 {
+		return s.jobs[idx], nil
+	}
+	return SyncJobModel{}, fmt.Errorf("GetJob error: %w", ErrSyncJobNotFound)
+}
+
+func (s *MemSyncJobStore) getJobIndex(id uint) int {
+	for idx, job := range s.jobs {
+		if job.ID == id {
+			return idx
+		}
+	}
+	return -1
+}
+
+// ListJobs .
+func (s *MemSyncJobStore) ListJobs(filter SyncJobFilter) ([]SyncJobModel, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	jobs := make([]SyncJobModel, 0, len(s.jobs))
+	for idx := len(s.jobs) - 1; idx >= 0; idx-- {
+		if filter.Limit > 0 && len(jobs) >= filter.Limit {
+			break
+		}
+		job := s.jobs[idx]
+		if len(filter.SrvName) > 0 && job.SrvName != filter.SrvName {
+			continue
+		}
+		if len(filter.Status) > 0 && job.Status != filter.Status {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// ListUnfinishedJobs .
+func (s *MemSyncJobStore) ListUnfinishedJobs() ([]SyncJobModel, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	jobs := make([]SyncJobModel, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		if !job.IsFinished() {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// UpdateJob .
+func (s *MemSyncJobStore) UpdateJob(job SyncJobModel) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	idx := s.getJobIndex(job.ID)
+	if idx == -1 {
+		return fmt.Errorf("UpdateJob error: %w", ErrSyncJobNotFound)
+	}
+	job.UpdatedAt = time.Now()
+	s.jobs[idx] = job
+	return nil
+}
+
+// RefreshJobsHeartbeat .
+func (s *MemSyncJobStore) RefreshJobsHeartbeat(owner string, at time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for idx := range s.jobs {
+		if s.jobs[idx].Owner == owner && !s.jobs[idx].IsFinished() {
+			s.jobs[idx].HeartbeatAt = sql.NullTime{Time: at, Valid: true}
+		}
+	}
+	return nil
+}
+
+// ClaimExpiredJobs .
+func (s *MemSyncJobStore) ClaimExpiredJobs(owner string, expiredAt, now time.Time) ([]SyncJobModel, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	jobs := make([]SyncJobModel, 0)
+	for idx := range s.jobs {
+		job := &s.jobs[idx]
+		if job.IsFinished() || !job.isHeartbeatExpired(expiredAt) {
+			continue
+		}
+		job.Owner = owner
+		job.HeartbeatAt = sql.NullTime{Time: now, Valid: true}
+		job.Status = JobStatusPending
+		job.UpdatedAt = now
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,100 @@
+package pkg
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func newTestSrvCoverRow(srvName, total string) GocSrvCoverModel {
+	return GocSrvCoverModel{
+		SrvCoverMeta: GetSrvMetaFromName(srvName),
+		IsLatest:     true,
+		CovFilePath:  fmt.Sprintf("/tmp/test/%s.cov", srvName),
+		CoverTotal: sql.NullString{
+			String: total,
+			Valid:  true,
+		},
+	}
+}
+
+func TestMemStoreAddLatestSrvCoverRow(t *testing.T) {
+	store := NewMemCoverHistoryStore()
+	srvName := "staging_th_apa_goc_echoserver_master_518e0a570c"
+	if err := store.InsertSrvCoverRow(newTestSrvCoverRow(srvName, "10.00")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddLatestSrvCoverRow(newTestSrvCoverRow(srvName, "20.00")); err != nil {
+		t.Fatal(err)
+	}
+
+	row, err := store.GetLatestSrvCoverRow(GetSrvMetaFromName(srvName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.ID != 2 || row.CoverTotal.String != "20.00" {
+		t.Fatalf("want latest row id=2 and total=20.00, got id=%d and total=%s", row.ID, row.CoverTotal.String)
+	}
+
+	srvName = "staging_th_apa_goc_echoserver_master_518e0a570d"
+	if err := store.InsertSrvCoverRow(newTestSrvCoverRow(srvName, "30.00")); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := store.GetLimitedHistorySrvCoverRows(GetSrvMetaFromName(srvName), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].ID != 3 {
+		t.Fatalf("want 2 history rows start with id=3, got: %+v", rows)
+	}
+}
+
+func TestMemStoreGetLatestSrvCoverRowErr(t *testing.T) {
+	store := NewMemCoverHistoryStore()
+	srvName := "staging_th_apa_goc_echoserver_master_518e0a570c"
+	meta := GetSrvMetaFromName(srvName)
+	if _, err := store.GetLatestSrvCoverRow(meta); !errors.Is(err, ErrSrvCoverLatestRowNotFound) {
+		t.Fatal("assert err is ErrSrvCoverLatestRowNotFound failed:", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := store.InsertSrvCoverRow(newTestSrvCoverRow(srvName, "10.00")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.GetLatestSrvCoverRow(meta); !errors.Is(err, ErrMoreThanOneSrvCoverLatestRow) {
+		t.Fatal("assert err is ErrMoreThanOneSrvCoverLatestRow failed:", err)
+	}
+}
+
+func TestMemStoreTransactionRollback(t *testing.T) {
+	store := NewMemCoverHistoryStore()
+	srvName := "staging_th_apa_goc_echoserver_master_518e0a570c"
+	meta := GetSrvMetaFromName(srvName)
+	if err := store.InsertSrvCoverRow(newTestSrvCoverRow(srvName, "10.00")); err != nil {
+		t.Fatal(err)
+	}
+
+	errRollback := errors.New("rollback")
+	err := store.Transaction(func(tx CoverHistoryStore) error {
+		if err := tx.UpdateCovFileOfLatestSrvCoverRow(meta, "/tmp/test/new.cov"); err != nil {
+			return err
+		}
+		if err := tx.UpdateSrvCoverTotalByCommit("50.00", meta.GitCommit); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatal("assert err is errRollback failed:", err)
+	}
+
+	row, err := store.GetLatestSrvCoverRow(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.CovFilePath == "/tmp/test/new.cov" || row.CoverTotal.String != "10.00" {
+		t.Fatalf("transaction is not rollback: %+v", row)
+	}
+}
@@ -14,7 +14,6 @@ import (
 	"time"
 
 	"demo.hello/utils"
-	"gorm.io/gorm"
 )
 
 const (
@@ -42,6 +41,7 @@ func SyncAndListRegisterSrvsTask() (map[string][]string, error) {
 	if err != nil {
 		return nil, fmt.Errorf("SyncAndListRegisterSrvsTask error: %w", err)
 	}
+	observeRegisterSrvs(srvs)
 	return srvs, nil
 }
 
@@ -213,39 +213,37 @@ func GetSrvCoverAndCreateReportTask(param SyncSrvCoverParam) (string, error) {
 
 // reuseLastCoverResults: if coverage data is not changed, reuse the last results.
 func reuseLastCoverResults(covFile string, meta SrvCoverMeta) (string, error) {
-	dbInstance := NewGocSrvCoverDBInstance()
-	transaction := dbInstance.BeginTransaction()
-	row, err := updateCovFileOfLastSrvCoverRowInDB(transaction, covFile, meta)
-	if err != nil {
-		transaction.Rollback()
-		return "", fmt.Errorf("reuseLastCoverResults error: %w", err)
-	}
+	coverTotal := ""
+	store := NewCoverHistoryStore()
+	if err := store.Transaction(func(tx CoverHistoryStore) error {
+		row, err := updateCovFileOfLastSrvCoverRowInDB(tx, covFile, meta)
+		if err != nil {
+			return err
+		}
 
-	oldCovFile := row.CovFilePath
-	if err := renameLastSrvCoverResults(oldCovFile, covFile); err != nil {
-		transaction.Rollback()
-		return "", fmt.Errorf("reuseLastCoverResults error: %w", err)
-	}
-	if err := renameLastSrvHTMLCoverResults(meta.AppName, oldCovFile, covFile); err != nil {
-		transaction.Rollback()
+		oldCovFile := row.CovFilePath
+		if err := renameLastSrvCoverResults(oldCovFile, covFile); err != nil {
+			return err
+		}
+		if err := renameLastSrvHTMLCoverResults(meta.AppName, oldCovFile, covFile); err != nil {
+			return err
+		}
+		coverTotal = row.CoverTotal.String
+		return nil
+	}); err != nil {
 		return "", fmt.Errorf("reuseLastCoverResults error: %w", err)
 	}
-
-	if result := transaction.Commit(); result.Error != nil {
-		return "", fmt.Errorf("reuseLastCoverResults commit error: %w", result.Error)
-	}
-	return row.CoverTotal.String, nil
+	return coverTotal, nil
 }
 
 // updateCovFileOfLastSrvCoverRowInDB updates cov file of latest cover row, and returns the old one.
-func updateCovFileOfLastSrvCoverRowInDB(db *gorm.DB, covFilePath string, meta SrvCoverMeta) (GocSrvCoverModel, error) {
-	dbInstance := NewGocSrvCoverDBInstance()
-	row, err := dbInstance.GetLatestSrvCoverRowByDB(db, meta)
+func updateCovFileOfLastSrvCoverRowInDB(store CoverHistoryStore, covFilePath string, meta SrvCoverMeta) (GocSrvCoverModel, error) {
+	row, err := store.GetLatestSrvCoverRow(meta)
 	if err != nil {
 		return GocSrvCoverModel{}, fmt.Errorf("updateCovFileOfLastSrvCoverRowInDB error: %w", err)
 	}
 
-	err = dbInstance.UpdateCovFileOfLatestSrvCoverRowByDB(db, meta, covFilePath)
+	err = store.UpdateCovFileOfLatestSrvCoverRow(meta, covFilePath)
 	return row, err
 }
 
@@ -274,15 +272,15 @@ func renameLastSrvHTMLCoverResults(appName, src, dst string) error {
 }
 
 func saveSrvCoverInDB(row GocSrvCoverModel) error {
-	dbInstance := NewGocSrvCoverDBInstance()
-	if _, err := dbInstance.GetLatestSrvCoverRow(row.SrvCoverMeta); err != nil {
+	store := NewCoverHistoryStore()
+	if _, err := store.GetLatestSrvCoverRow(row.SrvCoverMeta); err != nil {
 		if errors.Is(err, ErrSrvCoverLatestRowNotFound) {
-			return dbInstance.InsertSrvCoverRow(row)
+			return store.InsertSrvCoverRow(row)
 		}
 		return fmt.Errorf("saveSrvCoverInDB get error: %w", err)
 	}
 
-	if err := dbInstance.AddLatestSrvCoverRow(row); err != nil {
+	if err := store.AddLatestSrvCoverRow(row); err != nil {
 		return fmt.Errorf("saveSrvCoverInDB save db error: %w", err)
 	}
 	return nil
@@ -293,22 +291,29 @@ func saveSrvCoverInDB(row GocSrvCoverModel) error {
 //
 
 // createSrvCoverReportTask
-// 1. sync repo with specified commit;
-// 2. run "go tool cover" to generate .func and .html coverage report.
+// 1. sync repos of service, and service's own repo is synced with specified commit;
+// 2. generate .func, .html and .module coverage report.
 func createSrvCoverReportTask(covFile, srvName string) (string, error) {
 	meta := GetSrvMetaFromName(srvName)
-	repoDir := filepath.Join(AppConfig.RootDir, meta.AppName, "repo")
-	cmd := NewShCmd()
-	if err := checkoutSrvRepo(repoDir, srvName); err != nil {
+	repos, err := CheckoutSrvRepos(srvName)
+	if err != nil {
 		return ZeroCoverTotal, fmt.Errorf("createSrvCoverReportTask error: %w", err)
 	}
 
-	coverTotal, err := cmd.GoToolCreateCoverFuncReport(repoDir, covFile)
+	coverTotal, err := CreateCoverFuncReport(repos.ModuleDirs, covFile)
 	if err != nil {
 		return ZeroCoverTotal, fmt.Errorf("createSrvCoverReportTask error: %w", err)
 	}
 
-	if _, err = cmd.GoToolCreateCoverHTMLReport(repoDir, meta.AppName, covFile); err != nil {
+	if _, err = CreateCoverHTMLReport(repos.ModuleDirs, meta.AppName, covFile); err != nil {
+		return ZeroCoverTotal, fmt.Errorf("createSrvCoverReportTask error: %w", err)
+	}
+
+	modulePaths, err := repos.ModulePaths()
+	if err != nil {
+		return ZeroCoverTotal, fmt.Errorf("createSrvCoverReportTask error: %w", err)
+	}
+	if _, err = CreateCoverModuleReport(modulePaths, covFile); err != nil {
 		return ZeroCoverTotal, fmt.Errorf("createSrvCoverReportTask error: %w", err)
 	}
 	return coverTotal, nil
@@ -391,8 +396,8 @@ func getSrvCoverTask(srvName string) (string, bool, error) {
 
 	isCovUpdated := true
 	meta := GetSrvMetaFromName(srvName)
-	dbInstance := NewGocSrvCoverDBInstance()
-	row, err := dbInstance.GetLatestSrvCoverRow(meta)
+	store := NewCoverHistoryStore()
+	row, err := store.GetLatestSrvCoverRow(meta)
 	if err != nil {
 		if errors.Is(err, ErrSrvCoverLatestRowNotFound) {
 			return savedPath, isCovUpdated, nil
@@ -470,10 +475,9 @@ func FetchAndSaveSrvCoverByAddr(savedDir string, param SyncSrvCoverParam) (strin
 		return savedPaths[0], nil
 	}
 
-	cmd := NewShCmd()
 	mergeFileName := getSavedCovFileNameWithSuffix(param.SrvName, "merge")
 	mergeFilePath := filepath.Join(savedDir, mergeFileName)
-	if err := cmd.GocToolMergeSrvCovers(savedPaths, mergeFilePath); err != nil {
+	if err := MergeSrvCovers(savedPaths, mergeFilePath); err != nil {
 		return "", fmt.Errorf("FetchAndSaveSrvCoverByAddr merge srv cov files error: %w", err)
 	}
 	return mergeFilePath, nil
@@ -115,7 +115,7 @@ func TestCheckoutSrvRepo(t *testing.T) {
 // run: go test -timeout 300s -run ^TestCreateSrvCoverReportTask$ demo.hello/gocplugin/pkg -v -count=1
 func TestCreateSrvCoverReportTask(t *testing.T) {
 	AppConfig.RootDir = "/tmp/test"
-	instance := NewGocSrvCoverDBInstance()
+	instance := NewCoverHistoryStore()
 
 	srvName := "staging_th_apa_goc_echoserver_master_845820727e"
 	meta := GetSrvMetaFromName(srvName)
@@ -1,60 +1,383 @@
 package pkg
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"log"
+	"os"
+	"strings"
 	"sync"
 	"time"
-
-	"demo.hello/utils"
 )
 
 var (
-	srvCoverSyncTasksPool     *utils.GoPool
-	srvCoverSyncTasksPoolOnce sync.Once
+	syncJobQueue     *SyncJobQueue
+	syncJobQueueOnce sync.Once
 )
 
-// InitSrvCoverSyncTasksPool .
+// InitSrvCoverSyncTasksPool inits global sync job queue, and re-submits unfinished jobs left by last run.
 func InitSrvCoverSyncTasksPool() {
-	srvCoverSyncTasksPoolOnce.Do(func() {
+	syncJobQueueOnce.Do(func() {
 		const (
-			coreSize  = 10
-			queueSize = 100
-			idleTime  = 3 * time.Minute
+			maxAttempts = 3
+			retryDelay  = 30 * time.Second
 		)
-		srvCoverSyncTasksPool = utils.NewGoPool(coreSize, queueSize, idleTime)
+		syncJobQueue = NewSyncJobQueue(NewSyncJobStore(), GetSrvCoverAndCreateReportTask, maxAttempts, retryDelay)
 	})
-	srvCoverSyncTasksPool.Start()
+	syncJobQueue.Start()
 }
 
 // CloseSrvCoverSyncTasksPool .
 func CloseSrvCoverSyncTasksPool() {
-	if srvCoverSyncTasksPool != nil {
-		const stopWaitSec = 60
-		srvCoverSyncTasksPool.Stop(stopWaitSec)
+	if syncJobQueue != nil {
+		syncJobQueue.Stop()
 	}
 }
 
-// SubmitSrvCoverSyncTask .
-func SubmitSrvCoverSyncTask(param SyncSrvCoverParam) chan interface{} {
-	const submitTimeout = 3 * time.Minute
+// SubmitSrvCoverSyncTask submits a sync job, and returns job id and a channel which receives
+// cover total or error when job finished. Sync of the same service commit is deduplicated.
+func SubmitSrvCoverSyncTask(param SyncSrvCoverParam) (uint, chan interface{}) {
+	return syncJobQueue.Submit(param)
+}
+
+// SyncJobRunFunc runs sync job and returns cover total.
+type SyncJobRunFunc func(param SyncSrvCoverParam) (string, error)
+
+// SyncJobQueue runs srv cover sync jobs by workers, and job states are persisted in SyncJobStore.
+// Jobs which are queued or wait for retry when queue stops are left pending, and re-submitted by next start.
+// Queue refreshes heartbeat of its jobs, and only claims unfinished jobs whose heartbeat is expired, so that jobs of
+// other report instances which share the store are not re-run.
+type SyncJobQueue struct {
+	store       SyncJobStore
+	owner       string
+	heartbeat   time.Duration
+	run         SyncJobRunFunc
+	workers     int
+	maxAttempts int
+	retryDelay  time.Duration
+	ids         chan uint
+	waiters     map[uint][]chan interface{}
+	timers      map[uint]*time.Timer
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          *sync.WaitGroup
+	isRunning   bool
+	lock        *sync.Mutex
+}
+
+// NewSyncJobQueue .
+func NewSyncJobQueue(store SyncJobStore, run SyncJobRunFunc, maxAttempts int, retryDelay time.Duration) *SyncJobQueue {
+	const (
+		workers   = 10
+		queueSize = 100
+		heartbeat = 30 * time.Second
+	)
+	return &SyncJobQueue{
+		store:       store,
+		owner:       newSyncJobOwner(),
+		heartbeat:   heartbeat,
+		run:         run,
+		workers:     workers,
+		maxAttempts: maxAttempts,
+		retryDelay:  retryDelay,
+		ids:         make(chan uint, queueSize),
+		waiters:     make(map[uint][]chan interface{}),
+		timers:      make(map[uint]*time.Timer),
+		wg:          &sync.WaitGroup{},
+		lock:        &sync.Mutex{},
+	}
+}
+
+func newSyncJobOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
+}
+
+// Start starts workers, and re-submits unfinished jobs whose heartbeat is expired.
+func (q *SyncJobQueue) Start() {
+	q.lock.Lock()
+	if q.isRunning {
+		q.lock.Unlock()
+		return
+	}
+	q.isRunning = true
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.worker(q.ctx)
+	}
+	q.wg.Add(1)
+	go q.heartbeatLoop(q.ctx)
+	q.lock.Unlock()
+
+	q.claimExpiredJobs()
+}
+
+// heartbeatLoop refreshes heartbeat of jobs owned by queue, and claims expired jobs left by stopped instances.
+func (q *SyncJobQueue) heartbeatLoop(ctx context.Context) {
+	defer q.wg.Done()
+	ticker := time.NewTicker(q.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := q.store.RefreshJobsHeartbeat(q.owner, time.Now()); err != nil {
+				log.Println("SyncJobQueue heartbeat error:", err)
+			}
+			q.claimExpiredJobs()
+		}
+	}
+}
+
+// claimExpiredJobs claims jobs whose heartbeat is not refreshed in 3 heartbeats, and re-submits them.
+func (q *SyncJobQueue) claimExpiredJobs() {
+	now := time.Now()
+	jobs, err := q.store.ClaimExpiredJobs(q.owner, now.Add(-3*q.heartbeat), now)
+	if err != nil {
+		log.Println("SyncJobQueue claim expired jobs error:", err)
+		return
+	}
+	for _, job := range jobs {
+		log.Printf("SyncJobQueue resubmit job: id=%d, srv=%s", job.ID, job.SrvName)
+		go q.enqueue(job.ID)
+	}
+}
+
+// Stop cancels retry timers and stops workers, and waits for running jobs done.
+func (q *SyncJobQueue) Stop() {
+	const stopWait = time.Minute
+	q.lock.Lock()
+	if !q.isRunning {
+		q.lock.Unlock()
+		return
+	}
+	q.isRunning = false
+	for id, timer := range q.timers {
+		timer.Stop()
+		delete(q.timers, id)
+	}
+	q.cancel()
+	q.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		// release jobs left pending, so that they're claimed by other instances or next start at once
+		if err := q.store.RefreshJobsHeartbeat(q.owner, time.Unix(0, 0)); err != nil {
+			log.Println("SyncJobQueue stop: release jobs error:", err)
+		}
+	case <-time.After(stopWait):
+		log.Println("SyncJobQueue stop: wait for running jobs timeout")
+	}
+
+	// jobs are left pending, and waiters will not get result
+	q.lock.Lock()
+	waiters := q.waiters
+	q.waiters = make(map[uint][]chan interface{})
+	q.lock.Unlock()
+	for id, chs := range waiters {
+		for _, ch := range chs {
+			ch <- fmt.Errorf("SyncJobQueue is stopped, job [%d] is pending", id)
+		}
+	}
+}
+
+// Submit submits job, and result is sent to the returned channel once. If the active job is finished before waiter
+// is added, result of the finished job is sent.
+func (q *SyncJobQueue) Submit(param SyncSrvCoverParam) (uint, chan interface{}) {
 	retCh := make(chan interface{}, 1)
+	meta := GetSrvMetaFromName(param.SrvName)
+	job, isCreated, err := q.store.CreateOrGetActiveJob(SyncJobModel{
+		SrvName:     param.SrvName,
+		GitCommit:   meta.GitCommit,
+		Addresses:   strings.Join(param.Addresses, ","),
+		Status:      JobStatusPending,
+		MaxAttempts: q.maxAttempts,
+		Owner:       q.owner,
+		HeartbeatAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		retCh <- fmt.Errorf("Submit sync job error: %w", err)
+		return 0, retCh
+	}
+
+	// job status is re-read under lock, as finishJob updates status before it notifies waiters under lock
+	q.lock.Lock()
+	if job, err = q.store.GetJob(job.ID); err != nil {
+		q.lock.Unlock()
+		retCh <- fmt.Errorf("Submit sync job error: %w", err)
+		return 0, retCh
+	}
+	if job.IsFinished() {
+		q.lock.Unlock()
+		retCh <- getSyncJobResult(job)
+		return job.ID, retCh
+	}
+	q.waiters[job.ID] = append(q.waiters[job.ID], retCh)
+	q.lock.Unlock()
+
+	if isCreated {
+		q.enqueue(job.ID)
+	} else {
+		log.Printf("Submit sync job: job is active for srv [%s], id=%d", job.SrvName, job.ID)
+	}
+	return job.ID, retCh
+}
+
+func (q *SyncJobQueue) worker(ctx context.Context) {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-q.ids:
+			if ctx.Err() != nil {
+				// left pending for next start
+				return
+			}
+			q.runJob(id)
+		}
+	}
+}
+
+// enqueue puts job to queue, and job is left pending if queue is stopped.
+func (q *SyncJobQueue) enqueue(id uint) {
+	const submitTimeout = 3 * time.Minute
+	q.lock.Lock()
+	if !q.isRunning {
+		q.lock.Unlock()
+		log.Printf("SyncJobQueue is not running, job [%d] is left pending", id)
+		return
+	}
+	ctx := q.ctx
+	q.lock.Unlock()
 
-	if err := srvCoverSyncTasksPool.SubmitWithTimeout(func() {
-		tasksState := NewSrvCoverSyncTasksState()
-		tasksState.Put(param.SrvName, StateRunning)
-		if coverTotal, err := GetSrvCoverAndCreateReportTask(param); err != nil {
-			tasksState.Delete(param.SrvName)
-			notify := NewMatterMostNotify()
-			err := fmt.Errorf("Async run GetSrvCoverAndCreateReportTask error: %w", err)
-			notify.MustSendMessageToDefaultUser(err.Error())
-			retCh <- err
-		} else {
-			tasksState.Put(param.SrvName, StateFreshed)
-			retCh <- coverTotal
+	select {
+	case q.ids <- id:
+	case <-ctx.Done():
+		log.Printf("SyncJobQueue is stopped, job [%d] is left pending", id)
+	case <-time.After(submitTimeout):
+		err := fmt.Errorf("Submit sync job error: timeout, exceed queue size %d", cap(q.ids))
+		job, getErr := q.store.GetJob(id)
+		if getErr != nil {
+			log.Println("enqueue error:", getErr)
+			q.notifyWaiters(id, err)
+			return
 		}
-	}, submitTimeout); err != nil {
-		retCh <- fmt.Errorf("Submit GetSrvCoverAndCreateReportTask error: %w", err)
+		q.finishJob(job, "", err)
+	}
+}
+
+// retry enqueues job after delay, and the timer is cancelled if queue stops.
+func (q *SyncJobQueue) retry(id uint, delay time.Duration) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if !q.isRunning {
+		return
 	}
+	q.timers[id] = time.AfterFunc(delay, func() {
+		q.lock.Lock()
+		delete(q.timers, id)
+		q.lock.Unlock()
+		q.enqueue(id)
+	})
+}
 
-	return retCh
+func (q *SyncJobQueue) runJob(id uint) {
+	job, err := q.store.GetJob(id)
+	if err != nil {
+		log.Println("runJob error:", err)
+		q.notifyWaiters(id, err)
+		return
+	}
+
+	job.Status = JobStatusRunning
+	job.Attempts++
+	job.StartedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	if err := q.updateActiveJob(job); err != nil {
+		log.Println("runJob error:", err)
+	}
+
+	tasksState := NewSrvCoverSyncTasksState()
+	tasksState.Put(job.SrvName, StateRunning)
+	start := time.Now()
+	coverTotal, err := q.run(job.GetSyncSrvCoverParam())
+	observeSrvCoverSync(job.SrvName, start, err)
+	if err != nil {
+		tasksState.Delete(job.SrvName)
+		if job.Attempts < job.MaxAttempts {
+			job.Status = JobStatusPending
+			job.ErrMsg = err.Error()
+			if err := q.updateActiveJob(job); err != nil {
+				log.Println("runJob error:", err)
+			}
+			delay := q.retryDelay * time.Duration(job.Attempts)
+			log.Printf("runJob: sync job [%d] failed, retry after %v: %v", job.ID, delay, err)
+			q.retry(job.ID, delay)
+			return
+		}
+		q.finishJob(job, "", fmt.Errorf("Async run GetSrvCoverAndCreateReportTask error: %w", err))
+		return
+	}
+
+	tasksState.Put(job.SrvName, StateFreshed)
+	ObserveSrvCoverTotal(job.SrvName, coverTotal)
+	q.finishJob(job, coverTotal, nil)
+}
+
+// updateActiveJob updates pending or running job, and job is owned by queue with heartbeat refreshed.
+func (q *SyncJobQueue) updateActiveJob(job SyncJobModel) error {
+	job.Owner = q.owner
+	job.HeartbeatAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return q.store.UpdateJob(job)
+}
+
+func getSyncJobResult(job SyncJobModel) interface{} {
+	if job.Status == JobStatusSucceeded {
+		return job.CoverTotal
+	}
+	return fmt.Errorf("sync job [%d] failed: %s", job.ID, job.ErrMsg)
+}
+
+func (q *SyncJobQueue) finishJob(job SyncJobModel, coverTotal string, err error) {
+	job.FinishedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	if err != nil {
+		job.Status = JobStatusFailed
+		job.ErrMsg = err.Error()
+		NewNotifyDispatcher().MustNotify(NewNotifyMessage(NotifyEventSyncFailed, job.SrvName, err.Error()))
+	} else {
+		job.Status = JobStatusSucceeded
+		job.CoverTotal = coverTotal
+		job.ErrMsg = ""
+	}
+	if updateErr := q.store.UpdateJob(job); updateErr != nil {
+		log.Println("finishJob error:", updateErr)
+	}
+
+	if err != nil {
+		q.notifyWaiters(job.ID, err)
+	} else {
+		q.notifyWaiters(job.ID, coverTotal)
+	}
+}
+
+func (q *SyncJobQueue) notifyWaiters(id uint, result interface{}) {
+	q.lock.Lock()
+	waiters := q.waiters[id]
+	delete(q.waiters, id)
+	q.lock.Unlock()
+
+	for _, ch := range waiters {
+		ch <- result
+	}
 }
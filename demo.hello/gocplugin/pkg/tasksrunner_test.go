@@ -1,8 +1,10 @@
 package pkg
 
 import (
+	"database/sql"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -45,7 +47,8 @@ func TestSubmitSrvCoverSyncTask(t *testing.T) {
 		SrvName:   "staging_th_apa_goc_echoserver_master_845820727e",
 		Addresses: []string{"http://127.0.0.1:51007"},
 	}
-	retCh := SubmitSrvCoverSyncTask(param)
+	jobID, retCh := SubmitSrvCoverSyncTask(param)
+	fmt.Println("job id:", jobID)
 	select {
 	case res := <-retCh:
 		switch res.(type) {
@@ -60,6 +63,237 @@ func TestSubmitSrvCoverSyncTask(t *testing.T) {
 	fmt.Println("srv cover sync task done")
 }
 
+func TestSyncJobQueue(t *testing.T) {
+	SetNotifyDispatcher(&NotifyDispatcher{})
+	store := NewMemSyncJobStore()
+
+	var runCount int32
+	release := make(chan struct{})
+	run := func(param SyncSrvCoverParam) (string, error) {
+		<-release
+		if atomic.AddInt32(&runCount, 1) == 1 {
+			return "", fmt.Errorf("mock sync error")
+		}
+		return "50.00", nil
+	}
+	q := NewSyncJobQueue(store, run, 2, 10*time.Millisecond)
+	q.Start()
+	defer q.Stop()
+
+	param := SyncSrvCoverParam{
+		SrvName:   "staging_th_apa_goc_echoserver_master_845820727e",
+		Addresses: []string{"http://127.0.0.1:51007"},
+	}
+	id1, retCh1 := q.Submit(param)
+	id2, retCh2 := q.Submit(param)
+	if id1 != id2 {
+		t.Fatalf("want deduplicated job, got ids %d and %d", id1, id2)
+	}
+	close(release)
+
+	for _, ch := range []chan interface{}{retCh1, retCh2} {
+		select {
+		case res := <-ch:
+			if res != "50.00" {
+				t.Fatalf("want cover total 50.00, got %v", res)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("wait sync job timeout")
+		}
+	}
+
+	job, err := store.GetJob(id1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != JobStatusSucceeded || job.Attempts != 2 || job.CoverTotal != "50.00" {
+		t.Fatalf("invalid job: status=%s, attempts=%d, cover_total=%s", job.Status, job.Attempts, job.CoverTotal)
+	}
+	if !job.FinishedAt.Valid || job.GitCommit != "845820727e" {
+		t.Fatalf("invalid job: finished_at=%v, commit=%s", job.FinishedAt, job.GitCommit)
+	}
+
+	// finished job is not reused
+	id3, _ := q.Submit(param)
+	if id3 == id1 {
+		t.Fatal("want new job for finished one")
+	}
+}
+
+func TestSyncJobQueueResumeJobs(t *testing.T) {
+	SetNotifyDispatcher(&NotifyDispatcher{})
+	store := NewMemSyncJobStore()
+	job, _, err := store.CreateOrGetActiveJob(SyncJobModel{
+		SrvName:     "staging_th_apa_goc_echoserver_master_845820727e",
+		GitCommit:   "845820727e",
+		Status:      JobStatusRunning,
+		Attempts:    1,
+		MaxAttempts: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	run := func(param SyncSrvCoverParam) (string, error) {
+		defer close(done)
+		return "", fmt.Errorf("mock sync error")
+	}
+	q := NewSyncJobQueue(store, run, 1, time.Millisecond)
+	q.Start()
+	defer q.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("wait resumed job timeout")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	jobs, err := store.ListJobs(SyncJobFilter{Status: JobStatusFailed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != job.ID || jobs[0].ErrMsg == "" {
+		t.Fatalf("want 1 failed job with error message, got: %+v", jobs)
+	}
+}
+
+// finishedJobStore finishes active job after it's returned, and before waiter is added.
+type finishedJobStore struct {
+	*MemSyncJobStore
+}
+
+func (s finishedJobStore) CreateOrGetActiveJob(job SyncJobModel) (SyncJobModel, bool, error) {
+	active, _, err := s.MemSyncJobStore.CreateOrGetActiveJob(job)
+	if err != nil {
+		return SyncJobModel{}, false, err
+	}
+	finished := active
+	finished.Status = JobStatusSucceeded
+	finished.CoverTotal = "50.00"
+	if err := s.UpdateJob(finished); err != nil {
+		return SyncJobModel{}, false, err
+	}
+	return active, false, nil
+}
+
+func TestSyncJobQueueSubmitFinishedJob(t *testing.T) {
+	SetNotifyDispatcher(&NotifyDispatcher{})
+	run := func(param SyncSrvCoverParam) (string, error) {
+		return "", fmt.Errorf("job should not run")
+	}
+	q := NewSyncJobQueue(finishedJobStore{NewMemSyncJobStore()}, run, 1, time.Millisecond)
+	q.Start()
+	defer q.Stop()
+
+	_, retCh := q.Submit(SyncSrvCoverParam{SrvName: "staging_th_apa_goc_echoserver_master_845820727e"})
+	select {
+	case res := <-retCh:
+		if res != "50.00" {
+			t.Fatalf("want cover total of finished job, got %v", res)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("want result of finished job sent at once")
+	}
+}
+
+func TestSyncJobQueueSkipJobsOfOtherOwner(t *testing.T) {
+	SetNotifyDispatcher(&NotifyDispatcher{})
+	store := NewMemSyncJobStore()
+	job, _, err := store.CreateOrGetActiveJob(SyncJobModel{
+		SrvName:     "staging_th_apa_goc_echoserver_master_845820727e",
+		GitCommit:   "845820727e",
+		Status:      JobStatusRunning,
+		Attempts:    1,
+		MaxAttempts: 3,
+		Owner:       "other-instance",
+		HeartbeatAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var runCount int32
+	run := func(param SyncSrvCoverParam) (string, error) {
+		atomic.AddInt32(&runCount, 1)
+		return "50.00", nil
+	}
+	q := NewSyncJobQueue(store, run, 3, time.Millisecond)
+	q.heartbeat = 100 * time.Millisecond
+	q.Start()
+	defer q.Stop()
+
+	// job is running by another instance with heartbeat
+	time.Sleep(150 * time.Millisecond)
+	if atomic.LoadInt32(&runCount) != 0 {
+		t.Fatal("want running job of other instance not re-run")
+	}
+
+	// heartbeat of other instance is expired, and job is claimed
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(20 * time.Millisecond) {
+		if job, err = store.GetJob(job.ID); err != nil {
+			t.Fatal(err)
+		}
+		if job.Status == JobStatusSucceeded {
+			break
+		}
+	}
+	if job.Status != JobStatusSucceeded || job.Owner != q.owner || atomic.LoadInt32(&runCount) != 1 {
+		t.Fatalf("want expired job claimed and run once, got: status=%s, owner=%s, runs=%d", job.Status, job.Owner, runCount)
+	}
+}
+
+func TestSyncJobQueueStopWithRetry(t *testing.T) {
+	SetNotifyDispatcher(&NotifyDispatcher{})
+	store := NewMemSyncJobStore()
+
+	failed := make(chan struct{}, 3)
+	run := func(param SyncSrvCoverParam) (string, error) {
+		failed <- struct{}{}
+		return "", fmt.Errorf("mock sync error")
+	}
+	const retryDelay = 200 * time.Millisecond
+	q := NewSyncJobQueue(store, run, 3, retryDelay)
+	q.Start()
+
+	id, retCh := q.Submit(SyncSrvCoverParam{
+		SrvName:   "staging_th_apa_goc_echoserver_master_845820727e",
+		Addresses: []string{"http://127.0.0.1:51007"},
+	})
+	select {
+	case <-failed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("wait sync job timeout")
+	}
+	time.Sleep(50 * time.Millisecond)
+	q.Stop()
+
+	// retry timer is cancelled, and job is left pending for next start
+	time.Sleep(3 * retryDelay)
+	if len(failed) != 0 {
+		t.Fatal("want no retry after queue stopped")
+	}
+	select {
+	case res := <-retCh:
+		if _, ok := res.(error); !ok {
+			t.Fatalf("want error for stopped queue, got %v", res)
+		}
+	default:
+		t.Fatal("want waiter notified when queue stopped")
+	}
+
+	job, err := store.GetJob(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != JobStatusPending || job.Attempts != 1 {
+		t.Fatalf("want pending job with 1 attempt, got: status=%s, attempts=%d", job.Status, job.Attempts)
+	}
+	// submit after stop does not panic
+	q.enqueue(id)
+}
+
 func TestChannel(t *testing.T) {
 	ch := make(chan struct{}, 1)
 	ch <- struct{}{}
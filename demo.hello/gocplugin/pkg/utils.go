@@ -71,9 +71,3 @@ func formatCoverPercentage(cover string) (string, error) {
 	}
 	return fmt.Sprintf("%.2f", total*100), nil
 }
-
-func getCoverTotalFromSummary(summary string) string {
-	items := strings.Split(summary, "\t")
-	total := items[len(items)-1]
-	return strings.Replace(total, "%", "", 1)
-}
@@ -3,8 +3,6 @@ package pkg
 import (
 	"fmt"
 	"testing"
-
-	"demo.hello/utils"
 )
 
 func TestGetSimpleDatetimeForNow(t *testing.T) {
@@ -41,14 +39,3 @@ func TestFormatCoverPercentage(t *testing.T) {
 		fmt.Println("float value:", res)
 	}
 }
-
-func TestReadCoverTotalFromResults(t *testing.T) {
-	filePath := "/tmp/test/echoserver/cover_data/staging_th_apa_goc_echoserver_master_845820727e_20220331_182224.func"
-	lines, err := utils.ReadLinesFile(filePath)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	summary := lines[len(lines)-1]
-	fmt.Println("cover total:", getCoverTotalFromSummary(summary))
-}